@@ -0,0 +1,140 @@
+// Package apierr defines the JSON error shape returned by every API handler:
+//
+//	{"error": {"code": "TEAM_NOT_FOUND", "message": "Team not found", "details": ...}}
+//
+// Codes are stable and meant for client-side handling; messages are for humans
+// and may change.
+package apierr
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable error codes
+const (
+	// Generic
+	InvalidRequest   = "INVALID_REQUEST" // malformed body or failed validation
+	InvalidID        = "INVALID_ID"      // non-numeric or missing path ID
+	Unauthorized     = "UNAUTHORIZED"
+	PermissionDenied = "PERMISSION_DENIED"
+	Internal         = "INTERNAL_ERROR"
+	Unavailable      = "SERVICE_UNAVAILABLE"
+	RateLimited      = "RATE_LIMITED"
+
+	// Auth
+	InvalidCredentials = "INVALID_CREDENTIALS"
+	AccountLocked      = "ACCOUNT_LOCKED"
+	InvalidToken       = "INVALID_TOKEN"
+	EmailTaken         = "EMAIL_TAKEN"
+	WeakPassword       = "WEAK_PASSWORD"
+	UserNotFound       = "USER_NOT_FOUND"
+
+	// API keys
+	APIKeyRequired  = "API_KEY_REQUIRED"
+	InvalidAPIKey   = "INVALID_API_KEY"
+	APIKeyExpired   = "API_KEY_EXPIRED"
+	APIKeyDisabled  = "API_KEY_DISABLED"
+	APIKeyNotFound  = "API_KEY_NOT_FOUND"
+	APIKeyNameTaken = "API_KEY_NAME_TAKEN"
+
+	// Public API deletes the team wants confirmed
+	DeleteConfirmationRequired = "DELETE_CONFIRMATION_REQUIRED"
+
+	// Webhooks
+	WebhookNotFound = "WEBHOOK_NOT_FOUND"
+
+	// Idempotency keys on public API writes
+	IdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"
+	IdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS"
+
+	// Teams
+	TeamNotFound          = "TEAM_NOT_FOUND"
+	TeamRequired          = "TEAM_REQUIRED"
+	TeamNotDeleted        = "TEAM_NOT_DELETED"
+	RestoreWindowExpired  = "RESTORE_WINDOW_EXPIRED"
+	MemberNotFound        = "MEMBER_NOT_FOUND"
+	AlreadyMember         = "ALREADY_MEMBER"
+	OwnerRoleProtected    = "OWNER_ROLE_PROTECTED"
+	InviteNotFound        = "INVITE_NOT_FOUND"
+	InviteAlreadySent     = "INVITE_ALREADY_SENT"
+	InviteExpired         = "INVITE_EXPIRED"
+	InviteNotPending      = "INVITE_NOT_PENDING"
+	InviteEmailMismatch   = "INVITE_EMAIL_MISMATCH"
+	PersonalTeamForbidden = "PERSONAL_TEAM_FORBIDDEN"
+
+	// Organizations
+	OrganizationNotFound    = "ORGANIZATION_NOT_FOUND"
+	TeamInOtherOrganization = "TEAM_IN_OTHER_ORGANIZATION"
+	LastOrganizationAdmin   = "LAST_ORGANIZATION_ADMIN"
+
+	// Collections and environments
+	CollectionNotFound  = "COLLECTION_NOT_FOUND"
+	ItemNotFound        = "COLLECTION_ITEM_NOT_FOUND"
+	CollectionExists    = "COLLECTION_EXISTS"
+	InvalidCollection   = "INVALID_COLLECTION"
+	EnvironmentNotFound = "ENVIRONMENT_NOT_FOUND"
+	EnvironmentInUse    = "ENVIRONMENT_IN_USE"
+	TabNotFound         = "TAB_NOT_FOUND"
+	TemplateNotFound    = "REQUEST_TEMPLATE_NOT_FOUND"
+	WorkflowNotFound    = "WORKFLOW_NOT_FOUND"
+	HostPolicyNotFound  = "HOST_POLICY_NOT_FOUND"
+
+	// Request execution
+	RequestFailed     = "REQUEST_FAILED"
+	RequestCancelled  = "REQUEST_CANCELLED"
+	UploadTooLarge    = "UPLOAD_TOO_LARGE"
+	ExecutionNotFound = "EXECUTION_NOT_FOUND"
+	InvalidPath       = "INVALID_PATH"
+	BodyNotJSON       = "BODY_NOT_JSON"
+	InvalidJSONBody   = "INVALID_JSON_BODY"
+	HostNotAllowed    = "HOST_NOT_ALLOWED"
+	OAuth2TokenFailed = "OAUTH2_TOKEN_FAILED"
+	ArtifactNotFound  = "RESPONSE_ARTIFACT_NOT_FOUND"
+
+	// GraphQL
+	GraphQLIntrospectionDisabled = "GRAPHQL_INTROSPECTION_DISABLED"
+
+	// AI
+	AINotConfigured    = "AI_NOT_CONFIGURED"
+	AISettingsNotFound = "AI_SETTINGS_NOT_FOUND"
+	AIRequestFailed    = "AI_REQUEST_FAILED"
+	AIInvalidResponse  = "AI_INVALID_RESPONSE"
+	InvalidDBML        = "INVALID_DBML"
+
+	// Email
+	EmailNotConfigured = "EMAIL_NOT_CONFIGURED"
+	EmailFailed        = "EMAIL_FAILED"
+)
+
+// Error is the body of the "error" field
+type Error struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Response is the full error response body
+type Response struct {
+	Error Error `json:"error"`
+}
+
+// New builds an error response body
+func New(code, message string, details interface{}) Response {
+	return Response{Error: Error{Code: code, Message: message, Details: details}}
+}
+
+// RespondError writes an error response with the given status and code
+func RespondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, New(code, message, nil))
+}
+
+// RespondErrorWithDetails is RespondError with extra machine-readable data
+func RespondErrorWithDetails(c *gin.Context, status int, code, message string, details interface{}) {
+	c.JSON(status, New(code, message, details))
+}
+
+// AbortWithError writes an error response and stops the handler chain. Use it
+// from middleware.
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, New(code, message, nil))
+}
@@ -0,0 +1,82 @@
+package apierr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]map[string]interface{} {
+	t.Helper()
+	var body map[string]map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode error body '%s': %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	RespondError(c, http.StatusNotFound, TeamNotFound, "Team not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+	body := decode(t, w)
+	if body["error"]["code"] != TeamNotFound {
+		t.Errorf("Expected code '%s', got '%v'", TeamNotFound, body["error"]["code"])
+	}
+	if body["error"]["message"] != "Team not found" {
+		t.Errorf("Expected message 'Team not found', got '%v'", body["error"]["message"])
+	}
+	if _, ok := body["error"]["details"]; ok {
+		t.Errorf("Expected details to be omitted, got '%v'", body["error"]["details"])
+	}
+}
+
+func TestRespondErrorWithDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	RespondErrorWithDetails(c, http.StatusConflict, CollectionExists, "Collection with this name already exists", gin.H{"existing_id": 7})
+
+	body := decode(t, w)
+	details, ok := body["error"]["details"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected details object, got '%v'", body["error"]["details"])
+	}
+	if details["existing_id"] != float64(7) {
+		t.Errorf("Expected existing_id 7, got '%v'", details["existing_id"])
+	}
+}
+
+func TestAbortWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	reached := false
+	r.GET("/", func(c *gin.Context) {
+		AbortWithError(c, http.StatusForbidden, PermissionDenied, "Write permission required")
+	}, func(c *gin.Context) {
+		reached = true
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if reached {
+		t.Error("Expected the handler chain to stop after AbortWithError")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	if code := decode(t, w)["error"]["code"]; code != PermissionDenied {
+		t.Errorf("Expected code '%s', got '%v'", PermissionDenied, code)
+	}
+}
@@ -0,0 +1,128 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON names rather than the Go ones
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// RespondBindError answers a failed ShouldBindJSON with a 400. Validation and
+// type errors list what's wrong per field in details, e.g.
+// {"email": "is required"}.
+func RespondBindError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		RespondErrorWithDetails(c, http.StatusBadRequest, InvalidRequest, "Validation failed", FieldErrors(validationErrs))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		RespondErrorWithDetails(c, http.StatusBadRequest, InvalidRequest, "Validation failed", map[string]string{
+			typeErr.Field: "must be " + jsonTypeName(typeErr.Type),
+		})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		RespondError(c, http.StatusBadRequest, InvalidRequest, "Request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		RespondError(c, http.StatusBadRequest, InvalidRequest, "Request body is required")
+	default:
+		RespondError(c, http.StatusBadRequest, InvalidRequest, err.Error())
+	}
+}
+
+// FieldErrors maps each failed field (by JSON path, e.g. "items[0].name") to
+// a short description of the rule it broke
+func FieldErrors(errs validator.ValidationErrors) map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		// The namespace starts with the struct's type name
+		path := fe.Namespace()
+		if _, rest, ok := strings.Cut(path, "."); ok {
+			path = rest
+		}
+		fields[path] = ruleMessage(fe)
+	}
+	return fields
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		return "must be at least " + sizeOf(fe.Kind(), param)
+	case "max", "lte":
+		return "must be at most " + sizeOf(fe.Kind(), param)
+	case "len":
+		return "must be exactly " + sizeOf(fe.Kind(), param)
+	case "gt":
+		return "must be more than " + sizeOf(fe.Kind(), param)
+	case "lt":
+		return "must be less than " + sizeOf(fe.Kind(), param)
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %s=%s check", fe.Tag(), param)
+	}
+	return fmt.Sprintf("failed the %s check", fe.Tag())
+}
+
+// sizeOf phrases a min/max bound for the kind of field it applies to
+func sizeOf(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return param + " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return param + " items"
+	}
+	return param
+}
+
+// jsonTypeName names a Go type the way a JSON client would think of it
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a " + t.String()
+}
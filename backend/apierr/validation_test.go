@@ -0,0 +1,114 @@
+package apierr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type validatedItem struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type validatedRequest struct {
+	Email    string          `json:"email" binding:"required,email"`
+	Password string          `json:"password" binding:"required,min=6"`
+	Nickname string          `json:"nickname" binding:"max=4"`
+	Role     string          `json:"role" binding:"omitempty,oneof=owner member"`
+	Age      int             `json:"age" binding:"gte=18"`
+	Tags     []string        `json:"tags" binding:"max=2"`
+	Website  string          `json:"website" binding:"omitempty,url"`
+	Items    []validatedItem `json:"items" binding:"dive"`
+}
+
+// bindErrorDetails binds body into a validatedRequest and returns the
+// message and field map RespondBindError answered with
+func bindErrorDetails(t *testing.T, body string) (string, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req validatedRequest
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		t.Fatalf("Expected %s to fail binding", body)
+	}
+	RespondBindError(c, err)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	errBody := decode(t, w)["error"]
+	if errBody["code"] != InvalidRequest {
+		t.Errorf("Expected code '%s', got '%v'", InvalidRequest, errBody["code"])
+	}
+	details, _ := errBody["details"].(map[string]interface{})
+	message, _ := errBody["message"].(string)
+	return message, details
+}
+
+const validBody = `"email":"ada@example.com","password":"secret1","age":30`
+
+func TestRespondBindErrorRules(t *testing.T) {
+	tests := []struct {
+		body  string
+		field string
+		want  string
+	}{
+		{`{"password":"secret1","age":30}`, "email", "is required"},
+		{`{"email":"not-an-email","password":"secret1","age":30}`, "email", "must be a valid email"},
+		{`{"email":"ada@example.com","password":"abc","age":30}`, "password", "must be at least 6 characters"},
+		{`{` + validBody + `,"nickname":"adalovelace"}`, "nickname", "must be at most 4 characters"},
+		{`{` + validBody + `,"role":"admin"}`, "role", "must be one of: owner, member"},
+		{`{"email":"ada@example.com","password":"secret1","age":12}`, "age", "must be at least 18"},
+		{`{` + validBody + `,"tags":["a","b","c"]}`, "tags", "must be at most 2 items"},
+		{`{` + validBody + `,"website":"nope"}`, "website", "must be a valid URL"},
+		{`{` + validBody + `,"items":[{"name":"a"},{}]}`, "items[1].name", "is required"},
+	}
+	for _, tt := range tests {
+		message, details := bindErrorDetails(t, tt.body)
+		if message != "Validation failed" {
+			t.Errorf("%s: expected message 'Validation failed', got '%s'", tt.body, message)
+		}
+		if details[tt.field] != tt.want {
+			t.Errorf("%s: expected %s '%s', got details %v", tt.body, tt.field, tt.want, details)
+		}
+	}
+}
+
+func TestRespondBindErrorListsEveryField(t *testing.T) {
+	_, details := bindErrorDetails(t, `{"age":30}`)
+	if len(details) != 2 || details["email"] != "is required" || details["password"] != "is required" {
+		t.Errorf("Expected email and password to be reported, got %v", details)
+	}
+}
+
+func TestRespondBindErrorTypeMismatch(t *testing.T) {
+	message, details := bindErrorDetails(t, `{`+validBody+`,"nickname":5}`)
+	if message != "Validation failed" || details["nickname"] != "must be a string" {
+		t.Errorf("Expected nickname to be reported as needing a string, got '%s' %v", message, details)
+	}
+	_, details = bindErrorDetails(t, `{"email":"ada@example.com","password":"secret1","age":"old"}`)
+	if details["age"] != "must be an integer" {
+		t.Errorf("Expected age to be reported as needing an integer, got %v", details)
+	}
+}
+
+func TestRespondBindErrorMalformedBody(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"email":`: "Request body is not valid JSON",
+		`{email}`:   "Request body is not valid JSON",
+		``:          "Request body is required",
+	} {
+		message, details := bindErrorDetails(t, body)
+		if message != want || details != nil {
+			t.Errorf("Body %q: expected '%s' without details, got '%s' %v", body, want, message, details)
+		}
+	}
+}
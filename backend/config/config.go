@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,12 +15,84 @@ type Config struct {
 	GoogleClientSecret    string
 	GoogleRedirectURL     string
 	FrontendURL           string
-	// Email configuration
-	SMTPHost     string
-	SMTPPort     int
-	SMTPUsername string
-	SMTPPassword string
-	SMTPFrom     string
+	// Embed the user's team IDs in access tokens and let team routes trust
+	// them, skipping the membership query, for tokens younger than
+	// JWTTeamClaimsGraceMinutes
+	JWTTeamClaims             bool
+	JWTTeamClaimsGraceMinutes int
+	// Email configuration. EmailProvider is "smtp" (the default) or
+	// "sendgrid"; both send from SMTPFrom.
+	EmailProvider  string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPFrom       string
+	SendGridAPIKey string
+	// Deleted teams can be restored within this window, then get purged
+	TeamRestoreWindowHours   int
+	TeamPurgeIntervalMinutes int
+	// Password policy
+	PasswordMinLength        int
+	PasswordRequireMixedCase bool
+	// Limits for files uploaded to multipart request execution
+	MaxUploadFiles      int
+	MaxUploadTotalBytes int64
+	// How long public API Idempotency-Key responses are kept for replay
+	IdempotencyTTLHours int
+	// Periodic removal of expired API keys and sessions
+	CleanupEnabled         bool
+	CleanupIntervalMinutes int
+	CleanupGraceDays       int
+	// Keys expiring within this many days are flagged, and their team owner
+	// emailed once, checked every APIKeyReminderIntervalMinutes (0 disables)
+	APIKeyExpiryWarningDays       int
+	APIKeyReminderIntervalMinutes int
+	// User-Agent sent by the executor when a request doesn't set one
+	DefaultUserAgent string
+	// Whether the executor adds http:// to URLs typed without a scheme
+	AutoPrependURLScheme bool
+	// Longest line an NDJSON execution accepts; longer ones end the stream
+	MaxNDJSONLineBytes int
+	// Requests per client IP per RateLimitWindowSeconds to public auth
+	// endpoints; 0 turns a limit off
+	RateLimitWindowSeconds int
+	RateLimitAuth          int
+	RateLimitLogin         int
+	RateLimitRegister      int
+	RateLimitInvite        int
+	RateLimitTestEmail     int
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed when working
+	// out the client IP for rate limits; none by default, so the address the
+	// request came from is used
+	TrustedProxies []string
+	// Accounts allowed into the /api/admin maintenance endpoints, lower-cased
+	AdminEmails []string
+	// Concurrent outbound executions overall and per team (0 for no cap), and
+	// how long an execution waits for a slot before failing (0 fails at once)
+	MaxConcurrentExecutions        int
+	MaxConcurrentExecutionsPerTeam int
+	ExecutionQueueWaitSeconds      int
+	// Headers (lower-cased) whose values are replaced with *** in request
+	// history and logs; live responses are never redacted
+	RedactedHeaders []string
+	// Headers (lower-cased) stripped from what users send, since net/http
+	// would pass them on and they can confuse the connection or enable
+	// request smuggling. Host is never on it: it overrides the request's host.
+	BlockedHeaders []string
+}
+
+// DefaultRedactedHeaders are redacted when REDACTED_HEADERS isn't set
+var DefaultRedactedHeaders = []string{
+	"authorization", "proxy-authorization", "cookie", "set-cookie",
+	"x-api-key", "x-auth-token", "x-csrf-token",
+}
+
+// DefaultBlockedHeaders are stripped when BLOCKED_HEADERS isn't set:
+// hop-by-hop headers, and those net/http writes itself
+var DefaultBlockedHeaders = []string{
+	"connection", "keep-alive", "proxy-connection", "transfer-encoding",
+	"te", "trailer", "upgrade", "content-length",
 }
 
 var AppConfig *Config
@@ -32,13 +106,88 @@ func LoadConfig() {
 		GoogleClientSecret:    getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:     getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/auth/google/callback"),
 		FrontendURL:           getEnv("FRONTEND_URL", "http://localhost:5173"),
+		// Team claims in access tokens
+		JWTTeamClaims:             getEnvBool("JWT_TEAM_CLAIMS", false),
+		JWTTeamClaimsGraceMinutes: getEnvInt("JWT_TEAM_CLAIMS_GRACE_MINUTES", 5),
 		// Email configuration
-		SMTPHost:     getEnv("SMTP_HOST", ""),
-		SMTPPort:     getEnvInt("SMTP_PORT", 587),
-		SMTPUsername: getEnv("SMTP_USERNAME", ""),
-		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
-		SMTPFrom:     getEnv("SMTP_FROM", ""),
+		EmailProvider:  strings.ToLower(getEnv("EMAIL_PROVIDER", "smtp")),
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:       getEnv("SMTP_FROM", ""),
+		SendGridAPIKey: getEnv("SENDGRID_API_KEY", ""),
+		// Team deletion
+		TeamRestoreWindowHours:   getEnvInt("TEAM_RESTORE_WINDOW_HOURS", 72),
+		TeamPurgeIntervalMinutes: getEnvInt("TEAM_PURGE_INTERVAL_MINUTES", 60),
+		// Password policy
+		PasswordMinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireMixedCase: getEnvBool("PASSWORD_REQUIRE_MIXED_CASE", false),
+		// Multipart uploads
+		MaxUploadFiles:      getEnvInt("MAX_UPLOAD_FILES", 20),
+		MaxUploadTotalBytes: int64(getEnvInt("MAX_UPLOAD_TOTAL_BYTES", 100<<20)),
+		// Public API idempotency
+		IdempotencyTTLHours: getEnvInt("IDEMPOTENCY_TTL_HOURS", 24),
+		// Expired credential cleanup
+		CleanupEnabled:         getEnvBool("CLEANUP_ENABLED", true),
+		CleanupIntervalMinutes: getEnvInt("CLEANUP_INTERVAL_MINUTES", 60),
+		CleanupGraceDays:       getEnvInt("CLEANUP_GRACE_DAYS", 7),
+		// API key expiry warnings
+		APIKeyExpiryWarningDays:       getEnvInt("API_KEY_EXPIRY_WARNING_DAYS", 7),
+		APIKeyReminderIntervalMinutes: getEnvInt("API_KEY_REMINDER_INTERVAL_MINUTES", 60),
+		// Request execution
+		DefaultUserAgent:     getEnv("DEFAULT_USER_AGENT", "PostmanXodja/1.0"),
+		AutoPrependURLScheme: getEnvBool("AUTO_PREPEND_URL_SCHEME", true),
+		MaxNDJSONLineBytes:   getEnvInt("MAX_NDJSON_LINE_BYTES", 1<<20),
+		// Public auth endpoint throttling
+		RateLimitWindowSeconds: getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60),
+		RateLimitAuth:          getEnvInt("RATE_LIMIT_AUTH", 60),
+		RateLimitLogin:         getEnvInt("RATE_LIMIT_LOGIN", 10),
+		RateLimitRegister:      getEnvInt("RATE_LIMIT_REGISTER", 3),
+		RateLimitInvite:        getEnvInt("RATE_LIMIT_INVITE", 30),
+		RateLimitTestEmail:     getEnvInt("RATE_LIMIT_TEST_EMAIL", 5),
+		TrustedProxies:         getEnvList("TRUSTED_PROXIES"),
+		// Maintenance access
+		AdminEmails: getEnvList("ADMIN_EMAILS"),
+		// Outbound execution concurrency
+		MaxConcurrentExecutions:        getEnvInt("MAX_CONCURRENT_EXECUTIONS", 100),
+		MaxConcurrentExecutionsPerTeam: getEnvInt("MAX_CONCURRENT_EXECUTIONS_PER_TEAM", 20),
+		ExecutionQueueWaitSeconds:      getEnvInt("EXECUTION_QUEUE_WAIT_SECONDS", 30),
+		// Redaction of stored and logged headers
+		RedactedHeaders: getEnvListOr("REDACTED_HEADERS", DefaultRedactedHeaders),
+		// Headers users can't send
+		BlockedHeaders: getEnvListOr("BLOCKED_HEADERS", DefaultBlockedHeaders),
+	}
+}
+
+// IsAdmin reports whether email is one of AdminEmails
+func (c *Config) IsAdmin(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, admin := range c.AdminEmails {
+		if email != "" && email == admin {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects settings the server can't run with
+func (c *Config) Validate() error {
+	if c.JWTExpirationHours <= 0 {
+		return fmt.Errorf("JWT_EXPIRATION_HOURS must be positive, got %d", c.JWTExpirationHours)
+	}
+	if c.RefreshExpirationDays <= 0 {
+		return fmt.Errorf("REFRESH_EXPIRATION_DAYS must be positive, got %d", c.RefreshExpirationDays)
+	}
+	if c.RateLimitWindowSeconds <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW_SECONDS must be positive, got %d", c.RateLimitWindowSeconds)
+	}
+	switch c.EmailProvider {
+	case "", "smtp", "sendgrid":
+	default:
+		return fmt.Errorf("EMAIL_PROVIDER must be smtp or sendgrid, got %q", c.EmailProvider)
 	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -56,3 +205,33 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated variable, lower-casing and dropping
+// empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvListOr is getEnvList with defaultValue for an unset (or empty)
+// variable
+func getEnvListOr(key string, defaultValue []string) []string {
+	if values := getEnvList(key); len(values) > 0 {
+		return values
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
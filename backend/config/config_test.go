@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+func TestValidateRejectsNonPositiveExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		jwtHours    int
+		refreshDays int
+		wantErr     bool
+	}{
+		{"defaults", 24, 7, false},
+		{"zero jwt hours", 0, 7, true},
+		{"negative jwt hours", -1, 7, true},
+		{"zero refresh days", 24, 0, true},
+		{"negative refresh days", 24, -3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{JWTExpirationHours: tt.jwtHours, RefreshExpirationDays: tt.refreshDays, RateLimitWindowSeconds: 60}
+			err := cfg.Validate()
+			if tt.wantErr && err == nil {
+				t.Errorf("Expected an error for jwt=%d refresh=%d", tt.jwtHours, tt.refreshDays)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsNonPositiveRateLimitWindow(t *testing.T) {
+	cfg := &Config{JWTExpirationHours: 24, RefreshExpirationDays: 7}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for a zero rate limit window")
+	}
+}
+
+func TestValidateRejectsUnknownEmailProvider(t *testing.T) {
+	cfg := &Config{JWTExpirationHours: 24, RefreshExpirationDays: 7, RateLimitWindowSeconds: 60, EmailProvider: "ses"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for an unknown email provider")
+	}
+	cfg.EmailProvider = "sendgrid"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected sendgrid to be accepted, got '%v'", err)
+	}
+}
@@ -5,13 +5,20 @@ import (
 	"log"
 	"os"
 	"postmanxodja/models"
+	"strings"
+	"sync/atomic"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// memoryDBCount gives every in-memory SQLite database its own name
+var memoryDBCount atomic.Int64
+
 // InitDB initializes the database connection
 func InitDB() error {
 	// Get database connection string from environment or use default
@@ -21,11 +28,14 @@ func InitDB() error {
 	}
 
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(dialector(dsn), gormConfig(dsn))
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Personal teams used to be recognized by name only
+	hadPersonalFlag := DB.Migrator().HasColumn(&models.Team{}, "is_personal")
+
 	// Auto-migrate models
 	if err := DB.AutoMigrate(
 		&models.User{},
@@ -33,18 +43,178 @@ func InitDB() error {
 		&models.TeamMember{},
 		&models.TeamInvite{},
 		&models.TeamAPIKey{},
+		&models.TeamAPIKeySettings{},
 		&models.TeamAISettings{},
 		&models.Collection{},
 		&models.Environment{},
 		&models.SavedTab{},
+		&models.Session{},
+		&models.RequestTemplate{},
+		&models.Webhook{},
+		&models.TeamHostPolicy{},
+		&models.Workflow{},
+		&models.TeamRequestDefaults{},
+		&models.RequestHistory{},
+		&models.CollectionItemTiming{},
+		&models.Organization{},
+		&models.OrganizationMember{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if !hadPersonalFlag {
+		if err := DB.Unscoped().Model(&models.Team{}).Where("name = ?", "Personal").
+			UpdateColumn("is_personal", true).Error; err != nil {
+			return fmt.Errorf("failed to flag personal teams: %w", err)
+		}
+	}
+
+	if err := backfillRequestCounts(); err != nil {
+		return fmt.Errorf("failed to backfill collection request counts: %w", err)
+	}
+
+	if err := normalizeEmails(); err != nil {
+		return fmt.Errorf("failed to normalize emails: %w", err)
+	}
+
+	if err := uniqueAPIKeyNames(); err != nil {
+		return fmt.Errorf("failed to make API key names unique: %w", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 	return nil
 }
 
+// dialector picks the driver for a DATABASE_URL. "sqlite:<path>" opens a
+// SQLite file and "sqlite::memory:" a fresh in-memory database; both are
+// meant for tests, everything else is a Postgres DSN.
+func dialector(dsn string) gorm.Dialector {
+	path, ok := strings.CutPrefix(dsn, "sqlite:")
+	if !ok {
+		return postgres.Open(dsn)
+	}
+	if path == ":memory:" {
+		// Shared cache keeps one database across the connection pool, the
+		// unique name keeps separate InitDB calls apart
+		path = fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", memoryDBCount.Add(1))
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	// Postgres enforces foreign keys, so SQLite should too
+	return sqlite.Open(path + separator + "_pragma=foreign_keys(1)")
+}
+
+// gormConfig keeps SQLite test databases quiet; their schema is rebuilt on
+// every run and the SQL log only adds noise to test output
+func gormConfig(dsn string) *gorm.Config {
+	if strings.HasPrefix(dsn, "sqlite:") {
+		return &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	}
+	return &gorm.Config{}
+}
+
+// backfillRequestCounts fills request_count for collections saved before the
+// column existed. Collections that really are empty are cheap to recount.
+func backfillRequestCounts() error {
+	var collections []models.Collection
+	return DB.Unscoped().Select("id", "raw_json").Where("request_count = 0").
+		FindInBatches(&collections, 100, func(tx *gorm.DB, batch int) error {
+			for _, collection := range collections {
+				count := models.CountCollectionRequests(collection.RawJSON)
+				if count == 0 {
+					continue
+				}
+				if err := DB.Unscoped().Model(&models.Collection{}).Where("id = ?", collection.ID).
+					UpdateColumn("request_count", count).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// normalizeEmails lowercases and trims emails saved before they were
+// normalized on the way in, then adds a unique index on LOWER(email) so
+// "Alice@X.com" and "alice@x.com" can't both be registered. Accounts whose
+// normalized email already belongs to another account are left alone and
+// keep the index from being created; they're logged to be merged by hand.
+func normalizeEmails() error {
+	var users []models.User
+	if err := DB.Select("id", "email").Where("email <> LOWER(TRIM(email))").Find(&users).Error; err != nil {
+		return err
+	}
+	conflicts := 0
+	for _, user := range users {
+		normalized := strings.ToLower(strings.TrimSpace(user.Email))
+		var taken int64
+		DB.Model(&models.User{}).Where("LOWER(TRIM(email)) = ? AND id <> ?", normalized, user.ID).Count(&taken)
+		if taken > 0 {
+			log.Printf("User %d's email %q differs from another account's only by case or whitespace", user.ID, user.Email)
+			conflicts++
+			continue
+		}
+		if err := DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("email", normalized).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := DB.Model(&models.TeamInvite{}).Where("invitee_email <> LOWER(TRIM(invitee_email))").
+		UpdateColumn("invitee_email", gorm.Expr("LOWER(TRIM(invitee_email))")).Error; err != nil {
+		return err
+	}
+
+	if conflicts > 0 {
+		log.Printf("Skipping the case-insensitive email index until %d conflicting accounts are merged", conflicts)
+		return nil
+	}
+	return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))").Error
+}
+
+// uniqueAPIKeyNames trims API key names and numbers the ones a team used
+// more than once ("CI", "CI (2)", ...), since names used to be free-form,
+// then adds the unique index on (team_id, LOWER(name)) that keeps them apart
+func uniqueAPIKeyNames() error {
+	var keys []models.TeamAPIKey
+	if err := DB.Select("id", "team_id", "name").Order("team_id, id").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	// Numbered names mustn't collide with names already in use either
+	existing := make(map[string]bool, len(keys))
+	claimed := make(map[string]bool, len(keys))
+	teamKey := func(teamID uint, name string) string {
+		return fmt.Sprintf("%d\x00%s", teamID, strings.ToLower(name))
+	}
+	for _, key := range keys {
+		existing[teamKey(key.TeamID, strings.TrimSpace(key.Name))] = true
+	}
+	for _, key := range keys {
+		base := strings.TrimSpace(key.Name)
+		if base == "" {
+			base = "API key"
+		}
+		name := base
+		for n := 2; claimed[teamKey(key.TeamID, name)]; n++ {
+			name = fmt.Sprintf("%s (%d)", base, n)
+			if existing[teamKey(key.TeamID, name)] {
+				name = base
+			}
+		}
+		claimed[teamKey(key.TeamID, name)] = true
+		if name == key.Name {
+			continue
+		}
+		if err := DB.Model(&models.TeamAPIKey{}).Where("id = ?", key.ID).UpdateColumn("name", name).Error; err != nil {
+			return err
+		}
+		log.Printf("Renamed API key %d of team %d from %q to %q", key.ID, key.TeamID, key.Name, name)
+	}
+
+	return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_team_api_keys_team_name_lower ON team_api_keys (team_id, LOWER(name))").Error
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
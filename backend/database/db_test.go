@@ -0,0 +1,165 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+func TestInitDBWithInMemorySQLite(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := InitDB(); err != nil {
+		t.Fatalf("Expected in-memory database to migrate, got %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := DB.DB()
+		sqlDB.Close()
+	})
+
+	if name := DB.Dialector.Name(); name != "sqlite" {
+		t.Errorf("Expected sqlite dialector, got '%s'", name)
+	}
+
+	team := models.Team{Name: "Acme"}
+	if err := DB.Create(&team).Error; err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	env := models.Environment{Name: "dev", TeamID: &team.ID, Variables: models.Variables{"base_url": "http://localhost"}}
+	if err := DB.Create(&env).Error; err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+
+	var loaded models.Environment
+	if err := DB.First(&loaded, env.ID).Error; err != nil {
+		t.Fatalf("Failed to load environment: %v", err)
+	}
+	if loaded.Variables["base_url"] != "http://localhost" {
+		t.Errorf("Expected variables to round-trip, got %v", loaded.Variables)
+	}
+}
+
+func TestInMemoryDatabasesAreSeparate(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	first := DB
+	first.Create(&models.Team{Name: "Only here"})
+
+	if err := InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, db := range []*gorm.DB{first, DB} {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		}
+	})
+
+	var count int64
+	DB.Model(&models.Team{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected a fresh database, got %d teams", count)
+	}
+}
+
+func TestNormalizeEmails(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := DB.DB()
+		sqlDB.Close()
+	})
+
+	alice := models.User{Email: " Alice@X.com"}
+	bob := models.User{Email: "bob@x.com"}
+	bobUpper := models.User{Email: "BOB@x.com"}
+	for _, user := range []*models.User{&alice, &bob, &bobUpper} {
+		DB.Create(user)
+	}
+	team := models.Team{Name: "Acme"}
+	DB.Create(&team)
+	if err := DB.Create(&models.TeamInvite{TeamID: team.ID, InviterID: bob.ID, InviteeEmail: "Carol@X.com", Token: "t"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := normalizeEmails(); err != nil {
+		t.Fatal(err)
+	}
+	DB.First(&alice, alice.ID)
+	DB.First(&bobUpper, bobUpper.ID)
+	if alice.Email != "alice@x.com" {
+		t.Errorf("Expected the email to be normalized, got '%s'", alice.Email)
+	}
+	if bobUpper.Email != "BOB@x.com" {
+		t.Errorf("Expected a conflicting email to be left alone, got '%s'", bobUpper.Email)
+	}
+	var invite models.TeamInvite
+	DB.First(&invite)
+	if invite.InviteeEmail != "carol@x.com" {
+		t.Errorf("Expected the invite email to be normalized, got '%s'", invite.InviteeEmail)
+	}
+
+	// With the conflict resolved the case-insensitive index goes in
+	DB.Delete(&bobUpper)
+	if err := normalizeEmails(); err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Create(&models.User{Email: "ALICE@x.com"}).Error; err == nil {
+		t.Error("Expected the index to reject an email differing only by case")
+	}
+}
+
+func TestUniqueAPIKeyNames(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := DB.DB()
+		sqlDB.Close()
+	})
+
+	// As if the keys were saved before names had to be unique
+	if err := DB.Exec("DROP INDEX idx_team_api_keys_team_name_lower").Error; err != nil {
+		t.Fatal(err)
+	}
+	acme := models.Team{Name: "Acme"}
+	other := models.Team{Name: "Other"}
+	DB.Create(&acme)
+	DB.Create(&other)
+	var keys []*models.TeamAPIKey
+	for i, key := range []struct {
+		teamID uint
+		name   string
+	}{
+		{acme.ID, "CI"}, {acme.ID, "ci "}, {acme.ID, "CI (2)"}, {acme.ID, "  "}, {other.ID, "CI"},
+	} {
+		record := &models.TeamAPIKey{TeamID: key.teamID, Name: key.name, Key: "pmx_" + string(rune('a'+i)), KeyPrefix: "pmx_", CreatedBy: 1}
+		if err := DB.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, record)
+	}
+
+	if err := uniqueAPIKeyNames(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, key := range keys {
+		DB.First(key, key.ID)
+		names = append(names, key.Name)
+	}
+	if want := []string{"CI", "ci (3)", "CI (2)", "API key", "CI"}; strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected names %v, got %v", want, names)
+	}
+
+	if err := DB.Create(&models.TeamAPIKey{TeamID: acme.ID, Name: "api KEY", Key: "pmx_z", KeyPrefix: "pmx_", CreatedBy: 1}).Error; err == nil {
+		t.Error("Expected the index to reject a name differing only by case")
+	}
+}
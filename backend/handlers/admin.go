@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"postmanxodja/apierr"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SendTestEmail sends a test message to the caller's own address so admins
+// can check the email settings. Send errors are returned as they are; they
+// never include the configured credentials.
+func SendTestEmail(c *gin.Context) {
+	emailSender := services.NewEmailSender()
+	if !emailSender.IsConfigured() {
+		apierr.RespondError(c, http.StatusServiceUnavailable, apierr.EmailNotConfigured, "Email is not configured for the selected EMAIL_PROVIDER")
+		return
+	}
+
+	to := c.GetString("email")
+	if err := emailSender.SendTestEmail(to); err != nil {
+		log.Printf("Test email to %s failed: %v", to, err)
+		apierr.RespondError(c, http.StatusBadGateway, apierr.EmailFailed, "Failed to send test email: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test email sent", "to": to})
+}
+
+// Reindex recomputes denormalized fields such as collection request counts
+// and reports how many rows it corrected. It's safe to run repeatedly.
+func Reindex(c *gin.Context) {
+	result, err := services.Reindex()
+	if err != nil {
+		log.Printf("Reindex failed: %v", err)
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to reindex")
+		return
+	}
+
+	log.Printf("Reindex by %s corrected %d rows", c.GetString("email"), result.Total)
+	c.JSON(http.StatusOK, result)
+}
+
+// GetResourceOwner tells support staff which team a collection or
+// environment belongs to (?type=collection&id=N), to tell a cross-team 404
+// from a real one without going to the database. Content is never included.
+func GetResourceOwner(c *gin.Context) {
+	resourceType := c.Query("type")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil || id == 0 {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "id must be a positive integer")
+		return
+	}
+
+	owner, err := services.LookupResourceOwner(resourceType, uint(id))
+	switch {
+	case errors.Is(err, services.ErrUnknownResourceType):
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound) && resourceType == "collection":
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
+		return
+	case err != nil:
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to look up resource")
+		return
+	}
+
+	log.Printf("Resource owner lookup by %s: %s %d", c.GetString("email"), resourceType, id)
+	c.JSON(http.StatusOK, owner)
+}
+
+// GetExecutionStats reports how many outbound executions are running and
+// queued against the concurrency limits. It's for admins only: the load of
+// the server isn't something to show on the unauthenticated health check.
+func GetExecutionStats(c *gin.Context) {
+	c.JSON(http.StatusOK, services.CurrentExecutionStats())
+}
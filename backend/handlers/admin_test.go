@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useEmailSender makes the handlers send through sender
+func useEmailSender(t *testing.T, sender services.EmailSender) {
+	t.Helper()
+	previous := services.NewEmailSender
+	services.NewEmailSender = func() services.EmailSender { return sender }
+	t.Cleanup(func() { services.NewEmailSender = previous })
+}
+
+func sendTestEmail(email string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/test-email", func(c *gin.Context) {
+		c.Set("email", email)
+	}, SendTestEmail)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/test-email", nil))
+	return w
+}
+
+func TestSendTestEmail(t *testing.T) {
+	sender := &services.RecordingEmailSender{Configured: true}
+	useEmailSender(t, sender)
+
+	w := sendTestEmail("ops@example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	sent := sender.Sent()
+	if len(sent) != 1 || sent[0].To != "ops@example.com" || sent[0].Subject != "PostmanXodja test email" {
+		t.Errorf("Expected one test email to the caller, got %+v", sent)
+	}
+}
+
+func TestSendTestEmailFailures(t *testing.T) {
+	useEmailSender(t, &services.RecordingEmailSender{})
+	if w := sendTestEmail("ops@example.com"); w.Code != http.StatusServiceUnavailable || decodeError(t, w).Error.Code != apierr.EmailNotConfigured {
+		t.Errorf("Expected 503 EMAIL_NOT_CONFIGURED, got %d: %s", w.Code, w.Body.String())
+	}
+
+	useEmailSender(t, &services.RecordingEmailSender{Configured: true, Err: errors.New("535 Authentication credentials invalid")})
+	w := sendTestEmail("ops@example.com")
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "535 Authentication credentials invalid") {
+		t.Errorf("Expected 502 with the send error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func reindex(t *testing.T) models.ReindexResult {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/reindex", Reindex)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reindex", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result models.ReindexResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestReindex(t *testing.T) {
+	useTestDB(t)
+	_, team := createTestTeam(t, "reindex@example.com")
+	broken := models.Collection{Name: "Broken", TeamID: &team.ID, RawJSON: `{"info":{"name":"Broken"},"item":[
+		{"name":"List","request":{"method":"GET","url":"https://api.example.com/items"}},
+		{"name":"Folder","item":[{"name":"Create","request":{"method":"POST","url":"https://api.example.com/items"}}]}]}`}
+	correct := models.Collection{Name: "Correct", TeamID: &team.ID, RawJSON: `{"info":{"name":"Correct"},"item":[]}`}
+	for _, collection := range []*models.Collection{&broken, &correct} {
+		if err := database.DB.Create(collection).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Drift the denormalized fields behind the hooks' back
+	database.DB.Model(&broken).UpdateColumn("request_count", 7)
+	database.DB.Model(&correct).UpdateColumn("updated_at", nil)
+
+	result := reindex(t)
+	if result.CollectionRequestCounts != 1 || result.CollectionUpdatedAt != 1 || result.Total != 2 {
+		t.Errorf("Expected one request count and one updated_at fixed, got %+v", result)
+	}
+
+	var reloaded models.Collection
+	database.DB.First(&reloaded, broken.ID)
+	if reloaded.RequestCount != 2 {
+		t.Errorf("Expected request_count 2, got %d", reloaded.RequestCount)
+	}
+	database.DB.First(&reloaded, correct.ID)
+	if !reloaded.UpdatedAt.Equal(reloaded.CreatedAt) {
+		t.Errorf("Expected updated_at backfilled from created_at, got %v (created %v)", reloaded.UpdatedAt, reloaded.CreatedAt)
+	}
+
+	if again := reindex(t); again.Total != 0 {
+		t.Errorf("Expected a second run to change nothing, got %+v", again)
+	}
+}
+
+func getResourceOwner(query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/resource-owner", GetResourceOwner)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/resource-owner?"+query, nil))
+	return w
+}
+
+func TestGetResourceOwner(t *testing.T) {
+	useTestDB(t)
+	_, team := createTestTeam(t, "owner-lookup@example.com")
+	collection := models.Collection{Name: "Payments", TeamID: &team.ID, RawJSON: `{"info":{"name":"Payments"},"item":[
+		{"name":"Charge","request":{"method":"POST","url":"https://api.example.com/charge","header":[{"key":"Authorization","value":"Bearer live-secret"}]}}]}`}
+	database.DB.Create(&collection)
+	environment := models.Environment{Name: "Prod", TeamID: &team.ID, Variables: models.Variables{"token": "env-secret"}}
+	database.DB.Create(&environment)
+
+	w := getResourceOwner("type=collection&id=" + strconv.Itoa(int(collection.ID)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var owner models.ResourceOwner
+	json.Unmarshal(w.Body.Bytes(), &owner)
+	if owner.Name != "Payments" || owner.Team == nil || owner.Team.ID != team.ID || owner.Team.MemberCount != 1 ||
+		owner.RequestCount == nil || *owner.RequestCount != 1 {
+		t.Errorf("Expected the collection's team, got %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "live-secret") {
+		t.Errorf("Expected no collection content, got %s", w.Body.String())
+	}
+
+	w = getResourceOwner("type=environment&id=" + strconv.Itoa(int(environment.ID)))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "env-secret") || !strings.Contains(w.Body.String(), `"variable_count":1`) {
+		t.Errorf("Expected the environment without its values, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Deleted resources are still found, marked as deleted
+	database.DB.Delete(&collection)
+	w = getResourceOwner("type=collection&id=" + strconv.Itoa(int(collection.ID)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deleted_at"`) {
+		t.Errorf("Expected the deleted collection, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := getResourceOwner("type=collection&id=99999"); w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != apierr.CollectionNotFound {
+		t.Errorf("Expected 404 COLLECTION_NOT_FOUND, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := getResourceOwner("type=workflow&id=1"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown type, got %d", w.Code)
+	}
+	if w := getResourceOwner("type=collection&id=abc"); w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != apierr.InvalidID {
+		t.Errorf("Expected 400 INVALID_ID, got %d", w.Code)
+	}
+}
+
+func TestGetExecutionStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/executions", GetExecutionStats)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/executions", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats map[string]int
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"active", "queued", "limit", "per_team_limit"} {
+		if _, ok := stats[key]; !ok {
+			t.Errorf("Expected %s in the stats, got %s", key, w.Body.String())
+		}
+	}
+}
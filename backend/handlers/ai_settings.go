@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -53,41 +54,40 @@ func UpdateAISettings(c *gin.Context) {
 
 	// Only team owner can manage AI settings
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can manage AI settings"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage AI settings")
 		return
 	}
 
 	var req models.AISettingsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	// Validate provider
 	validProviders := map[string]bool{"openai": true}
 	if req.Provider != "" && !validProviders[req.Provider] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider. Supported: openai"})
-		return
-	}
-
-	// Validate model
-	validModels := map[string]bool{
-		"gpt-4o":        true,
-		"gpt-4o-mini":   true,
-		"gpt-4-turbo":   true,
-		"gpt-3.5-turbo": true,
-		"o1":            true,
-		"o1-mini":       true,
-		"o3-mini":       true,
-	}
-	if req.Model != "" && !validModels[req.Model] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid model. Supported: gpt-4o, gpt-4o-mini, gpt-4-turbo, gpt-3.5-turbo, o1, o1-mini, o3-mini"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid provider. Supported: openai")
 		return
 	}
 
 	var settings models.TeamAISettings
 	result := database.DB.Where("team_id = ?", teamID).First(&settings)
 
+	// Validate model against what the (new or saved) key can use
+	if req.Model != "" {
+		apiKey := req.APIKey
+		if apiKey == "" && result.Error == nil {
+			apiKey = settings.APIKey
+		}
+		if ok, available := services.IsAvailableAIModel(apiKey, req.Model); !ok {
+			apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidRequest,
+				"Invalid model. Supported: "+strings.Join(available, ", "),
+				gin.H{"supported_models": available})
+			return
+		}
+	}
+
 	if result.Error != nil {
 		// Create new
 		settings = models.TeamAISettings{
@@ -98,7 +98,7 @@ func UpdateAISettings(c *gin.Context) {
 			IsEnabled: true,
 		}
 		if err := database.DB.Create(&settings).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save AI settings"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save AI settings")
 			return
 		}
 	} else {
@@ -114,7 +114,7 @@ func UpdateAISettings(c *gin.Context) {
 		}
 		settings.IsEnabled = true
 		if err := database.DB.Save(&settings).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update AI settings"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update AI settings")
 			return
 		}
 	}
@@ -138,19 +138,35 @@ func DeleteAISettings(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can manage AI settings"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage AI settings")
 		return
 	}
 
 	result := database.DB.Where("team_id = ?", teamID).Delete(&models.TeamAISettings{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "AI settings not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.AISettingsNotFound, "AI settings not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "AI settings deleted"})
 }
 
+// GetAIModels lists the chat models the team's API key can use, falling back
+// to a static list without a key or when the provider can't be reached
+func GetAIModels(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+
+	var settings models.TeamAISettings
+	database.DB.Where("team_id = ?", teamID).Limit(1).Find(&settings)
+
+	available, source := services.AvailableAIModels(settings.APIKey)
+	c.JSON(http.StatusOK, gin.H{
+		"provider": defaultString(settings.Provider, "openai"),
+		"models":   available,
+		"source":   source,
+	})
+}
+
 // AIAnalyzeDBML uses the team's OpenAI key to analyze DBML and return a smart collection structure
 func AIAnalyzeDBML(c *gin.Context) {
 	teamID := c.GetUint("team_id")
@@ -158,13 +174,21 @@ func AIAnalyzeDBML(c *gin.Context) {
 	// Get AI settings
 	var settings models.TeamAISettings
 	if err := database.DB.Where("team_id = ? AND is_enabled = ?", teamID, true).First(&settings).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "AI is not configured for this team. Go to AI Settings to add your OpenAI API key."})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.AINotConfigured, "AI is not configured for this team. Go to AI Settings to add your OpenAI API key.")
 		return
 	}
 
 	var req models.AIAnalyzeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	// Don't spend the team's tokens on input that clearly isn't DBML
+	if err := services.ValidateDBML(req.DBML); err != nil {
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidDBML, "Input doesn't look like DBML", gin.H{
+			"problem": err.Error(),
+		})
 		return
 	}
 
@@ -226,7 +250,7 @@ IMPORTANT RULES:
 	// Call OpenAI API
 	aiResponse, err := callOpenAI(settings.APIKey, settings.Model, systemPrompt, userPrompt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("AI analysis failed: %v", err)})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.AIRequestFailed, fmt.Sprintf("AI analysis failed: %v", err))
 		return
 	}
 
@@ -236,8 +260,7 @@ IMPORTANT RULES:
 		// Try to extract JSON from markdown code blocks
 		cleaned := extractJSON(aiResponse)
 		if err2 := json.Unmarshal([]byte(cleaned), &analysisResult); err2 != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":        "AI returned invalid JSON",
+			apierr.RespondErrorWithDetails(c, http.StatusInternalServerError, apierr.AIInvalidResponse, "AI returned invalid JSON", gin.H{
 				"raw_response": aiResponse,
 			})
 			return
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -31,29 +33,40 @@ func CreateAPIKey(c *gin.Context) {
 
 	// Only team owners can create API keys
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owners can create API keys"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owners can create API keys")
 		return
 	}
 
 	var req models.CreateAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	// Names tell keys apart on the dashboard, so they're unique per team
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidRequest, "Validation failed", gin.H{"name": "is required"})
+		return
+	}
+	if services.APIKeyNameTaken(teamID, req.Name) {
+		apierr.RespondError(c, http.StatusConflict, apierr.APIKeyNameTaken, "The team already has an API key named \""+req.Name+"\"")
 		return
 	}
 
 	// Validate permissions
 	if req.Permissions == "" {
-		req.Permissions = "read"
+		req.Permissions = services.DefaultAPIKeyPermission(teamID)
 	}
-	if req.Permissions != "read" && req.Permissions != "write" && req.Permissions != "read_write" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid permissions. Must be: read, write, or read_write"})
+	if !services.ValidAPIKeyPermission(req.Permissions) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid permissions. Must be: read, write, or read_write")
 		return
 	}
 
 	// Generate API key
 	key, err := generateAPIKey()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to generate API key")
 		return
 	}
 
@@ -73,10 +86,23 @@ func CreateAPIKey(c *gin.Context) {
 	}
 
 	if err := database.GetDB().Create(&apiKey).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		// Lost a race with a key of the same name
+		if services.APIKeyNameTaken(teamID, req.Name) {
+			apierr.RespondError(c, http.StatusConflict, apierr.APIKeyNameTaken, "The team already has an API key named \""+req.Name+"\"")
+			return
+		}
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create API key")
 		return
 	}
 
+	services.DispatchEvent(teamID, models.EventAPIKeyCreated, gin.H{
+		"api_key_id":  apiKey.ID,
+		"name":        apiKey.Name,
+		"key_prefix":  apiKey.KeyPrefix,
+		"permissions": apiKey.Permissions,
+		"created_by":  userID,
+	})
+
 	// Return response with full key (only shown once)
 	c.JSON(http.StatusCreated, models.APIKeyResponse{
 		ID:          apiKey.ID,
@@ -85,6 +111,7 @@ func CreateAPIKey(c *gin.Context) {
 		Key:         key, // Only returned on creation
 		KeyPrefix:   apiKey.KeyPrefix,
 		Permissions: apiKey.Permissions,
+		Enabled:     true,
 		LastUsedAt:  apiKey.LastUsedAt,
 		ExpiresAt:   apiKey.ExpiresAt,
 		CreatedAt:   apiKey.CreatedAt,
@@ -97,28 +124,133 @@ func GetAPIKeys(c *gin.Context) {
 
 	var keys []models.TeamAPIKey
 	if err := database.GetDB().Where("team_id = ?", teamID).Find(&keys).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch API keys")
 		return
 	}
 
 	// Convert to response format (without full keys)
+	now := time.Now()
+	warning := services.APIKeyExpiryWarning()
 	response := make([]models.APIKeyResponse, len(keys))
 	for i, key := range keys {
+		daysUntilExpiry, expiringSoon := services.APIKeyExpiry(key.ExpiresAt, now, warning)
 		response[i] = models.APIKeyResponse{
-			ID:          key.ID,
-			TeamID:      key.TeamID,
-			Name:        key.Name,
-			KeyPrefix:   key.KeyPrefix,
-			Permissions: key.Permissions,
-			LastUsedAt:  key.LastUsedAt,
-			ExpiresAt:   key.ExpiresAt,
-			CreatedAt:   key.CreatedAt,
+			ID:              key.ID,
+			TeamID:          key.TeamID,
+			Name:            key.Name,
+			KeyPrefix:       key.KeyPrefix,
+			Permissions:     key.Permissions,
+			Enabled:         key.Enabled,
+			LastUsedAt:      key.LastUsedAt,
+			ExpiresAt:       key.ExpiresAt,
+			CreatedAt:       key.CreatedAt,
+			DaysUntilExpiry: daysUntilExpiry,
+			ExpiringSoon:    expiringSoon,
 		}
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetAPIKeySettings returns the team's API key settings
+func GetAPIKeySettings(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+
+	var settings models.TeamAPIKeySettings
+	if err := database.GetDB().Where("team_id = ?", teamID).First(&settings).Error; err != nil {
+		c.JSON(http.StatusOK, models.TeamAPIKeySettings{TeamID: teamID, DefaultPermission: "read"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateAPIKeySettings sets the permission new keys get when created without
+// one, and whether public API deletes need confirming (owner only)
+func UpdateAPIKeySettings(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owners can manage API key settings")
+		return
+	}
+
+	var req models.APIKeySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if !services.ValidAPIKeyPermission(req.DefaultPermission) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid default_permission. Must be: read, write, or read_write")
+		return
+	}
+
+	var settings models.TeamAPIKeySettings
+	database.GetDB().Where("team_id = ?", teamID).Limit(1).Find(&settings)
+	settings.TeamID = teamID
+	settings.DefaultPermission = req.DefaultPermission
+	if req.RequireDeleteConfirmation != nil {
+		settings.RequireDeleteConfirmation = *req.RequireDeleteConfirmation
+	}
+	settings.UpdatedBy = userID
+	if err := database.GetDB().Save(&settings).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save API key settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateAPIKey disables or re-enables an API key (team owners only). A
+// disabled key keeps its history and can be turned back on, unlike a deleted
+// one.
+func UpdateAPIKey(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owners can update API keys")
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid key ID")
+		return
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	var key models.TeamAPIKey
+	if err := database.GetDB().Where("id = ? AND team_id = ?", keyID, teamID).First(&key).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.APIKeyNotFound, "API key not found")
+		return
+	}
+	if err := database.GetDB().Model(&key).Update("enabled", *req.Enabled).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update API key")
+		return
+	}
+
+	daysUntilExpiry, expiringSoon := services.APIKeyExpiry(key.ExpiresAt, time.Now(), services.APIKeyExpiryWarning())
+	c.JSON(http.StatusOK, models.APIKeyResponse{
+		ID:              key.ID,
+		TeamID:          key.TeamID,
+		Name:            key.Name,
+		KeyPrefix:       key.KeyPrefix,
+		Permissions:     key.Permissions,
+		Enabled:         key.Enabled,
+		LastUsedAt:      key.LastUsedAt,
+		ExpiresAt:       key.ExpiresAt,
+		CreatedAt:       key.CreatedAt,
+		DaysUntilExpiry: daysUntilExpiry,
+		ExpiringSoon:    expiringSoon,
+	})
+}
+
 // DeleteAPIKey deletes an API key
 func DeleteAPIKey(c *gin.Context) {
 	teamID := c.GetUint("team_id")
@@ -127,19 +259,19 @@ func DeleteAPIKey(c *gin.Context) {
 
 	// Only team owners can delete API keys
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owners can delete API keys"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owners can delete API keys")
 		return
 	}
 
 	keyIDInt, err := strconv.ParseUint(keyID, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid key ID")
 		return
 	}
 
 	result := database.GetDB().Where("id = ? AND team_id = ?", keyIDInt, teamID).Delete(&models.TeamAPIKey{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.APIKeyNotFound, "API key not found")
 		return
 	}
 
@@ -150,13 +282,34 @@ func DeleteAPIKey(c *gin.Context) {
 // Public API endpoints (authenticated via API key)
 // ============================================================
 
+// PublicWhoAmI describes the API key the request was made with, so
+// integrations can check their key and what it's allowed to do at startup
+func PublicWhoAmI(c *gin.Context) {
+	var key models.TeamAPIKey
+	if err := database.GetDB().First(&key, c.GetUint("api_key_id")).Error; err != nil {
+		apierr.RespondError(c, http.StatusUnauthorized, apierr.InvalidAPIKey, "Invalid API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIKeyWhoAmIResponse{
+		KeyID:       key.ID,
+		TeamID:      key.TeamID,
+		Name:        key.Name,
+		KeyPrefix:   key.KeyPrefix,
+		Permissions: key.Permissions,
+		CanWrite:    key.Permissions == "write" || key.Permissions == "read_write",
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+	})
+}
+
 // PublicGetCollections returns all collections for the team
 func PublicGetCollections(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 
 	var collections []models.Collection
 	if err := database.GetDB().Where("team_id = ?", teamID).Find(&collections).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch collections")
 		return
 	}
 
@@ -170,20 +323,24 @@ func PublicGetCollection(c *gin.Context) {
 
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	if collectionNotModified(c, &collection) {
 		return
 	}
 
 	// Parse the raw JSON to return structured data
 	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
 		return
 	}
 
@@ -204,13 +361,17 @@ func PublicGetCollectionRaw(c *gin.Context) {
 
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	if collectionNotModified(c, &collection) {
 		return
 	}
 
@@ -225,7 +386,7 @@ func PublicUpdateCollection(c *gin.Context) {
 
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
@@ -234,35 +395,38 @@ func PublicUpdateCollection(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	// Validate it's a valid collection
 	parsed, err := services.ParsePostmanCollection(req.RawJSON)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection format"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, "Invalid collection format")
+		return
+	}
+	if err := services.ValidateCollectionHeaders(parsed); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, err.Error())
 		return
 	}
 
 	// Get existing collection
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
-	// Update
-	name, description := services.ExtractCollectionInfo(parsed)
+	// Update, keeping the name and description columns in step with info
 	collection.RawJSON = req.RawJSON
-	collection.Name = name
-	collection.Description = description
+	services.ApplyCollectionInfo(&collection, parsed)
 
 	if err := database.GetDB().Save(&collection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
 		return
 	}
 
+	services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&collection))
 	c.JSON(http.StatusOK, collection)
 }
 
@@ -277,7 +441,7 @@ func PublicCreateCollection(c *gin.Context) {
 	// First, try to read raw body
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Failed to read request body")
 		return
 	}
 
@@ -296,7 +460,11 @@ func PublicCreateCollection(c *gin.Context) {
 		if wrapperReq.RawJSON != "" {
 			parsed, err := services.ParsePostmanCollection(wrapperReq.RawJSON)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection format in raw_json"})
+				apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, "Invalid collection format in raw_json")
+				return
+			}
+			if err := services.ValidateCollectionHeaders(parsed); err != nil {
+				apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, err.Error())
 				return
 			}
 			rawJSON = wrapperReq.RawJSON
@@ -310,7 +478,11 @@ func PublicCreateCollection(c *gin.Context) {
 		// Try to parse as direct Postman collection format
 		parsed, err := services.ParsePostmanCollection(string(bodyBytes))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format. Send either {\"raw_json\": \"...\"} or direct Postman collection JSON"})
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, "Invalid request format. Send either {\"raw_json\": \"...\"} or direct Postman collection JSON")
+			return
+		}
+		if err := services.ValidateCollectionHeaders(parsed); err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, err.Error())
 			return
 		}
 		rawJSON = string(bodyBytes)
@@ -324,9 +496,10 @@ func PublicCreateCollection(c *gin.Context) {
 		existingCollection.RawJSON = rawJSON
 		existingCollection.Description = description
 		if err := database.GetDB().Save(&existingCollection).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update existing collection"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update existing collection")
 			return
 		}
+		services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&existingCollection))
 		c.JSON(http.StatusOK, gin.H{
 			"message":    "Collection updated (already existed)",
 			"collection": existingCollection,
@@ -343,27 +516,34 @@ func PublicCreateCollection(c *gin.Context) {
 	}
 
 	if err := database.GetDB().Create(&dbCollection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create collection")
 		return
 	}
 
 	c.JSON(http.StatusCreated, dbCollection)
 }
 
-// PublicDeleteCollection deletes a collection
+// PublicDeleteCollection deletes a collection. Teams with
+// RequireDeleteConfirmation on must also send X-Confirm-Delete: true.
 func PublicDeleteCollection(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 	id := c.Param("id")
 
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	if services.RequiresDeleteConfirmation(teamID) && !strings.EqualFold(c.GetHeader("X-Confirm-Delete"), "true") {
+		apierr.RespondError(c, http.StatusPreconditionRequired, apierr.DeleteConfirmationRequired,
+			"This team requires deletes to be confirmed with the X-Confirm-Delete: true header")
 		return
 	}
 
-	result := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).Delete(&models.Collection{})
+	result := database.GetDB().Unscoped().Where("id = ? AND team_id = ?", collectionID, teamID).Delete(&models.Collection{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
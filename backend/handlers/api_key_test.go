@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/middleware"
+	"postmanxodja/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPublicWhoAmI(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/whoami", middleware.APIKeyMiddleware(), PublicWhoAmI)
+
+	for _, tc := range []struct {
+		permissions string
+		canWrite    bool
+	}{
+		{"read", false},
+		{"read_write", true},
+	} {
+		secret := "pmx_" + tc.permissions + "_secret"
+		key := models.TeamAPIKey{TeamID: team.ID, Name: tc.permissions + " key", Key: secret, KeyPrefix: secret[:8], Permissions: tc.permissions, CreatedBy: user.ID}
+		if err := database.DB.Create(&key).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("X-API-Key", secret)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tc.permissions, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("%s: expected the key itself not to be returned, got %s", tc.permissions, w.Body.String())
+		}
+
+		var whoami models.APIKeyWhoAmIResponse
+		json.Unmarshal(w.Body.Bytes(), &whoami)
+		if whoami.KeyID != key.ID || whoami.TeamID != team.ID || whoami.Name != key.Name || whoami.Permissions != tc.permissions {
+			t.Errorf("%s: unexpected response %+v", tc.permissions, whoami)
+		}
+		if whoami.CanWrite != tc.canWrite {
+			t.Errorf("%s: expected can_write %v, got %v", tc.permissions, tc.canWrite, whoami.CanWrite)
+		}
+		if whoami.LastUsedAt == nil {
+			t.Errorf("%s: expected last_used_at to be set", tc.permissions)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("X-API-Key", "pmx_unknown")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown key to be rejected, got %d", w.Code)
+	}
+}
+
+func TestDisabledAPIKeyIsRejected(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	key := models.TeamAPIKey{TeamID: team.ID, Name: "CI", Key: "pmx_ci_secret", KeyPrefix: "pmx_ci_s", CreatedBy: user.ID}
+	if err := database.DB.Create(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	public := gin.New()
+	public.GET("/whoami", middleware.APIKeyMiddleware(), PublicWhoAmI)
+	callPublic := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("X-API-Key", key.Key)
+		public.ServeHTTP(w, req)
+		return w
+	}
+
+	r := teamRouter(team.ID, user.ID)
+	r.PATCH("/api-keys/:key_id", UpdateAPIKey)
+	setEnabled := func(enabled string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/api-keys/"+strconv.Itoa(int(key.ID)), strings.NewReader(`{"enabled":`+enabled+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := callPublic(); w.Code != http.StatusOK {
+		t.Fatalf("Expected a new key to be enabled, got %d: %s", w.Code, w.Body.String())
+	}
+	var used models.TeamAPIKey
+	database.DB.First(&used, key.ID)
+
+	w := setEnabled("false")
+	var updated models.APIKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Enabled {
+		t.Fatalf("Expected the key to be disabled, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = callPublic()
+	if w.Code != http.StatusUnauthorized || decodeError(t, w).Error.Code != apierr.APIKeyDisabled {
+		t.Fatalf("Expected API_KEY_DISABLED, got %d: %s", w.Code, w.Body.String())
+	}
+	var disabled models.TeamAPIKey
+	database.DB.First(&disabled, key.ID)
+	if disabled.LastUsedAt == nil || !disabled.LastUsedAt.Equal(*used.LastUsedAt) {
+		t.Errorf("Expected the last use to be kept and not bumped by a rejected call, got %v", disabled.LastUsedAt)
+	}
+
+	if w := setEnabled("true"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the key to be re-enabled, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := callPublic(); w.Code != http.StatusOK {
+		t.Errorf("Expected a re-enabled key to work, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := setEnabled("null"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected enabled to be required, got %d", w.Code)
+	}
+}
+
+func TestCreateAPIKeyNames(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	database.DB.Create(&models.TeamAPIKey{TeamID: other.ID, Name: "Deploy", Key: "pmx_other", KeyPrefix: "pmx_othe", CreatedBy: user.ID})
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/api-keys", CreateAPIKey)
+	r.PUT("/api-key-settings", UpdateAPIKeySettings)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api-keys", `{"name":"  Deploy  "}`)
+	var created models.APIKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated || created.Name != "Deploy" || created.Permissions != "read" {
+		t.Fatalf("Expected a trimmed read key, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, name := range []string{"Deploy", "deploy", " DEPLOY "} {
+		w := send(http.MethodPost, "/api-keys", `{"name":"`+name+`"}`)
+		if w.Code != http.StatusConflict || decodeError(t, w).Error.Code != apierr.APIKeyNameTaken {
+			t.Errorf("%q: expected API_KEY_NAME_TAKEN, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	if w := send(http.MethodPost, "/api-keys", `{"name":"   "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a blank name to be rejected, got %d", w.Code)
+	}
+
+	if w := send(http.MethodPut, "/api-key-settings", `{"default_permission":"admin"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown permission to be rejected, got %d", w.Code)
+	}
+	if w := send(http.MethodPut, "/api-key-settings", `{"default_permission":"read_write"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the setting to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+	w = send(http.MethodPost, "/api-keys", `{"name":"CI"}`)
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated || created.Permissions != "read_write" {
+		t.Errorf("Expected the team's default permission, got %d: %s", w.Code, w.Body.String())
+	}
+	w = send(http.MethodPost, "/api-keys", `{"name":"Reader","permissions":"read"}`)
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Permissions != "read" {
+		t.Errorf("Expected an explicit permission to win, got %s", created.Permissions)
+	}
+}
+
+func TestPublicDeleteCollectionConfirmation(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+
+	r := teamRouter(team.ID, user.ID)
+	r.PUT("/api-key-settings", UpdateAPIKeySettings)
+	r.DELETE("/public/collections/:id", PublicDeleteCollection)
+	newCollection := func() string {
+		collection := models.Collection{Name: "Shop", TeamID: &team.ID}
+		database.DB.Create(&collection)
+		return "/public/collections/" + strconv.Itoa(int(collection.ID))
+	}
+	remove := func(path, confirm string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		if confirm != "" {
+			req.Header.Set("X-Confirm-Delete", confirm)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	settings := func(body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api-key-settings", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the settings to be saved, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Off by default
+	if w := remove(newCollection(), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected an unconfirmed delete to work by default, got %d: %s", w.Code, w.Body.String())
+	}
+
+	settings(`{"default_permission":"read","require_delete_confirmation":true}`)
+	path := newCollection()
+	for _, confirm := range []string{"", "false", "yes"} {
+		if w := remove(path, confirm); w.Code != http.StatusPreconditionRequired || decodeError(t, w).Error.Code != apierr.DeleteConfirmationRequired {
+			t.Errorf("X-Confirm-Delete %q: expected 428, got %d: %s", confirm, w.Code, w.Body.String())
+		}
+	}
+	var count int64
+	database.DB.Model(&models.Collection{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("Expected the collection to survive unconfirmed deletes, %d left", count)
+	}
+	if w := remove(path, "true"); w.Code != http.StatusOK {
+		t.Errorf("Expected a confirmed delete to work, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Changing only the default permission leaves the requirement on
+	settings(`{"default_permission":"write"}`)
+	if w := remove(newCollection(), ""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected the requirement to stay on, got %d", w.Code)
+	}
+	settings(`{"default_permission":"write","require_delete_confirmation":false}`)
+	if w := remove(newCollection(), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected unconfirmed deletes to work again, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -13,21 +18,42 @@ import (
 func Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
+		return
+	}
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	req.Email = email
+	if req.Name = services.NormalizeName(req.Name); req.Name == "" {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "name is required")
 		return
 	}
 
-	// Check if user already exists
+	if err := services.ValidatePasswordStrength(req.Password); err != nil {
+		var weak *services.PasswordStrengthError
+		if errors.As(err, &weak) {
+			apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.WeakPassword, err.Error(), gin.H{"unmet": weak.Unmet})
+			return
+		}
+		apierr.RespondError(c, http.StatusBadRequest, apierr.WeakPassword, err.Error())
+		return
+	}
+
+	// Check if user already exists; LOWER also catches accounts saved before
+	// emails were normalized
 	var existingUser models.User
-	if result := database.DB.Where("email = ?", req.Email).First(&existingUser); result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+	if result := database.DB.Where("LOWER(email) = ?", req.Email).First(&existingUser); result.Error == nil {
+		apierr.RespondError(c, http.StatusConflict, apierr.EmailTaken, "User with this email already exists")
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := services.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to hash password")
 		return
 	}
 
@@ -39,23 +65,24 @@ func Register(c *gin.Context) {
 	}
 
 	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create user")
 		return
 	}
 
 	// Create personal team for the user
 	_, err = services.CreatePersonalTeam(user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create personal team"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create personal team")
 		return
 	}
 
 	// Generate tokens
 	authResponse, err := services.GenerateTokenPair(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to generate tokens")
 		return
 	}
+	attachBootstrap(authResponse)
 
 	c.JSON(http.StatusCreated, authResponse)
 }
@@ -63,52 +90,103 @@ func Register(c *gin.Context) {
 func Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
+		return
+	}
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	req.Email = email
+
+	if remaining := services.LoginLockedFor(req.Email); remaining > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+		apierr.RespondError(c, http.StatusTooManyRequests, apierr.AccountLocked, "Too many failed login attempts. Try again later.")
 		return
 	}
 
 	// Find user
 	var user models.User
-	if result := database.DB.Where("email = ?", req.Email).First(&user); result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	if result := database.DB.Where("LOWER(email) = ?", req.Email).First(&user); result.Error != nil {
+		services.RecordFailedLogin(req.Email)
+		apierr.RespondError(c, http.StatusUnauthorized, apierr.InvalidCredentials, "Invalid email or password")
 		return
 	}
 
 	// Check password
 	if !services.CheckPasswordHash(req.Password, user.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		if locked, sendAlert := services.RecordFailedLogin(req.Email); locked && sendAlert {
+			sendLockoutAlert(user.Email, c.ClientIP())
+		}
+		apierr.RespondError(c, http.StatusUnauthorized, apierr.InvalidCredentials, "Invalid email or password")
 		return
 	}
+	services.ResetFailedLogins(req.Email)
+
+	// Make up for a personal team that failed to be created earlier
+	if err := services.EnsureUserHasTeam(user.ID); err != nil {
+		log.Printf("Failed to ensure a team for user %d: %v", user.ID, err)
+	}
 
 	// Generate tokens
 	authResponse, err := services.GenerateTokenPair(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to generate tokens")
 		return
 	}
+	attachBootstrap(authResponse)
 
 	c.JSON(http.StatusOK, authResponse)
 }
 
+// attachBootstrap saves the client fetching teams right after signing in. The
+// user is already signed in at this point, so a failure only drops the extra
+// fields and the client falls back to /auth/bootstrap.
+func attachBootstrap(authResponse *models.AuthResponse) {
+	if err := services.AttachBootstrap(authResponse); err != nil {
+		log.Printf("Failed to load bootstrap data for user %d: %v", authResponse.User.ID, err)
+	}
+}
+
+// sendLockoutAlert emails the account owner in the background; failures are
+// only logged since the login response must not depend on SMTP
+func sendLockoutAlert(email, ip string) {
+	emailSender := services.NewEmailSender()
+	if !emailSender.IsConfigured() {
+		return
+	}
+	at := time.Now()
+	go func() {
+		if err := emailSender.SendSecurityAlertEmail(email, "Your account was temporarily locked after repeated failed logins", ip, at); err != nil {
+			log.Printf("Failed to send security alert email: %v", err)
+		}
+	}()
+}
+
 func RefreshToken(c *gin.Context) {
 	var req models.RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
-	// For simplicity, we'll just validate the refresh token exists
-	// In production, you'd store refresh tokens in DB and validate them
 	if req.RefreshToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token required"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Refresh token required")
 		return
 	}
 
-	// Get user from the Authorization header (access token might be expired but we need user info)
-	// For now, we'll require the user to re-login if refresh token is used
-	// A more sophisticated approach would store refresh tokens with user IDs
+	authResponse, err := services.RefreshSession(req.RefreshToken)
+	if err != nil {
+		if err == services.ErrInvalidRefreshToken {
+			apierr.RespondError(c, http.StatusUnauthorized, apierr.InvalidToken, "Invalid or expired refresh token")
+			return
+		}
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to refresh session")
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Please login again"})
+	c.JSON(http.StatusOK, authResponse)
 }
 
 func GetCurrentUser(c *gin.Context) {
@@ -116,15 +194,53 @@ func GetCurrentUser(c *gin.Context) {
 
 	var user models.User
 	if result := database.DB.First(&user, userID); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.UserNotFound, "User not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
+// Bootstrap returns the current user, their teams and pending invite count in
+// one call so the client doesn't need several round-trips on load
+func Bootstrap(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.UserNotFound, "User not found")
+		return
+	}
+
+	bootstrap, err := services.BuildBootstrap(&user)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to load user data")
+		return
+	}
+
+	c.JSON(http.StatusOK, bootstrap)
+}
+
 func Logout(c *gin.Context) {
-	// In a production app, you'd invalidate the refresh token here
-	// For now, the client just needs to delete the tokens
+	// Revoke the current session so its refresh token can't be used again
+	if sessionID := c.GetUint("session_id"); sessionID != 0 {
+		if err := services.RevokeSession(sessionID); err != nil {
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to revoke session")
+			return
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// RevokeOtherSessions signs the user out everywhere except the current session
+func RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	count, err := services.RevokeUserSessions(userID, c.GetUint("session_id"))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to revoke sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": count})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/middleware"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterIncludesTeams(t *testing.T) {
+	useTestDB(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", JWTExpirationHours: 1, RefreshExpirationDays: 1, PasswordMinLength: 8}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", Register)
+	w := httptest.NewRecorder()
+	body := `{"email":"ada@example.com","password":"Correct-horse-1","name":"Ada"}`
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.AccessToken == "" {
+		t.Error("Expected an access token")
+	}
+	if len(response.Teams) != 1 {
+		t.Fatalf("Expected the personal team in the response, got %v", response.Teams)
+	}
+	if team := response.Teams[0]; team.YourRole != "owner" || team.MemberCount != 1 {
+		t.Errorf("Expected to own a team of one, got role %q with %d members", team.YourRole, team.MemberCount)
+	}
+}
+
+func TestRegisterValidationErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", Register)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"email":"ada@example.com"}`)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeError(t, w)
+	details, _ := resp.Error.Details.(map[string]interface{})
+	if len(details) != 2 || details["password"] != "is required" || details["name"] != "is required" {
+		t.Errorf("Expected password and name to be reported by field, got %v", resp.Error.Details)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	useTestDB(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", JWTExpirationHours: 1, RefreshExpirationDays: 1}
+	t.Cleanup(func() { config.AppConfig = previous })
+	user, _ := createTestTeam(t, "ada@example.com")
+	other, _ := createTestTeam(t, "bob@example.com")
+
+	var current string
+	refreshTokens := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		pair, err := services.GenerateTokenPair(&user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refreshTokens = append(refreshTokens, pair.RefreshToken)
+		if i == 0 {
+			current = pair.AccessToken
+		}
+	}
+	theirs, _ := services.GenerateTokenPair(&other)
+
+	r := gin.New()
+	r.DELETE("/auth/sessions", middleware.AuthMiddleware(), RevokeOtherSessions)
+	req := httptest.NewRequest(http.MethodDelete, "/auth/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+current)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"revoked":2}` {
+		t.Fatalf("Expected 2 sessions revoked, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := services.RefreshSession(refreshTokens[0]); err != nil {
+		t.Errorf("Expected the current session to keep working, got %v", err)
+	}
+	for _, token := range refreshTokens[1:] {
+		if _, err := services.RefreshSession(token); err != services.ErrInvalidRefreshToken {
+			t.Errorf("Expected the other sessions to be revoked, got %v", err)
+		}
+	}
+	if _, err := services.RefreshSession(theirs.RefreshToken); err != nil {
+		t.Errorf("Expected another user's session to be left alone, got %v", err)
+	}
+}
+
+func TestLoginAndRefreshTokenExpiry(t *testing.T) {
+	useTestDB(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", JWTExpirationHours: 2, RefreshExpirationDays: 3}
+	t.Cleanup(func() { config.AppConfig = previous })
+	hash, _ := services.HashPassword("Correct-horse-1")
+	user := models.User{Email: "ada@example.com", Name: "Ada", PasswordHash: hash}
+	database.DB.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/login", Login)
+	r.POST("/auth/refresh", RefreshToken)
+	post := func(path, body string) models.AuthResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var response models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	// near reports whether got is within a few seconds of want
+	near := func(got, want time.Time) bool {
+		return got.Sub(want).Abs() < 5*time.Second
+	}
+	checkAccessToken := func(step string, response models.AuthResponse) {
+		t.Helper()
+		claims, err := services.ValidateJWT(response.AccessToken)
+		if err != nil {
+			t.Fatalf("%s: expected a valid access token, got %v", step, err)
+		}
+		if want := time.Now().Add(2 * time.Hour); !near(claims.ExpiresAt.Time, want) || response.ExpiresIn != 7200 {
+			t.Errorf("%s: expected the access token to expire in 2 hours, got %v (expires_in %d)",
+				step, time.Until(claims.ExpiresAt.Time), response.ExpiresIn)
+		}
+	}
+
+	loggedIn := post("/auth/login", `{"email":"ada@example.com","password":"Correct-horse-1"}`)
+	checkAccessToken("login", loggedIn)
+	var session models.Session
+	database.DB.Where("user_id = ?", user.ID).First(&session)
+	sessionExpiry := session.ExpiresAt
+	if !near(sessionExpiry, time.Now().Add(3*24*time.Hour)) {
+		t.Errorf("Expected the refresh token to expire in 3 days, got %v", time.Until(sessionExpiry))
+	}
+
+	refreshed := post("/auth/refresh", `{"refresh_token":"`+loggedIn.RefreshToken+`"}`)
+	checkAccessToken("refresh", refreshed)
+	database.DB.First(&session, session.ID)
+	if !session.ExpiresAt.Equal(sessionExpiry) {
+		t.Errorf("Expected refreshing to keep the session's expiry %v, got %v", sessionExpiry, session.ExpiresAt)
+	}
+}
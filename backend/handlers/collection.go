@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"archive/zip"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,7 +29,7 @@ func CreateCollection(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
@@ -37,7 +44,7 @@ func CreateCollection(c *gin.Context) {
 	}
 
 	if err := database.GetDB().Create(&dbCollection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create collection")
 		return
 	}
 
@@ -55,14 +62,29 @@ func ImportCollection(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
-	// Parse collection
-	collection, err := services.ParsePostmanCollection(req.CollectionJSON)
+	// Parse collection; lenient imports also accept comments and trailing
+	// commas, and store the cleaned-up JSON
+	var collection *models.PostmanCollection
+	var err error
+	if c.Query("lenient") == "true" {
+		collection, req.CollectionJSON, err = services.ParsePostmanCollectionLenient(req.CollectionJSON)
+	} else {
+		collection, err = services.ParsePostmanCollection(req.CollectionJSON)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Postman collection format"})
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidCollection, "Invalid Postman collection format", gin.H{
+			"problem": err.Error(),
+		})
+		return
+	}
+	if err := services.ValidateCollectionHeaders(collection); err != nil {
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidCollection, "Collection contains an invalid header", gin.H{
+			"problem": err.Error(),
+		})
 		return
 	}
 
@@ -75,8 +97,7 @@ func ImportCollection(c *gin.Context) {
 
 	if hasExisting && req.Mode == "" {
 		// Return conflict so frontend can ask user what to do
-		c.JSON(http.StatusConflict, gin.H{
-			"error":       "Collection with this name already exists",
+		apierr.RespondErrorWithDetails(c, http.StatusConflict, apierr.CollectionExists, "Collection with this name already exists", gin.H{
 			"existing_id": existing.ID,
 			"name":        name,
 		})
@@ -98,7 +119,7 @@ func ImportCollection(c *gin.Context) {
 
 			if existing.EnvironmentID != nil {
 				// Update existing linked environment
-				database.GetDB().Model(&models.Environment{}).Where("id = ?", *existing.EnvironmentID).Updates(map[string]interface{}{
+				database.GetDB().Model(&models.Environment{}).Where("id = ? AND team_id = ?", *existing.EnvironmentID, teamID).Updates(map[string]interface{}{
 					"variables": variables,
 				})
 			} else {
@@ -114,9 +135,10 @@ func ImportCollection(c *gin.Context) {
 		}
 
 		if err := database.GetDB().Save(&existing).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
 			return
 		}
+		services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&existing))
 
 		c.JSON(http.StatusOK, existing)
 		return
@@ -149,7 +171,7 @@ func ImportCollection(c *gin.Context) {
 	}
 
 	if err := database.GetDB().Create(&dbCollection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save collection")
 		return
 	}
 
@@ -162,8 +184,8 @@ func GetCollections(c *gin.Context) {
 
 	var collections []models.Collection
 
-	if err := database.GetDB().Where("team_id = ?", teamID).Find(&collections).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+	if err := database.GetDB().Where("team_id = ?", teamID).Order("pinned DESC, id ASC").Find(&collections).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch collections")
 		return
 	}
 
@@ -176,20 +198,24 @@ func GetCollection(c *gin.Context) {
 	id := c.Param("id")
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	if collectionNotModified(c, &collection) {
 		return
 	}
 
 	// Parse the raw JSON to return structured data
 	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
 		return
 	}
 
@@ -200,6 +226,7 @@ func GetCollection(c *gin.Context) {
 		"team_id":        collection.TeamID,
 		"environment_id": collection.EnvironmentID,
 		"created_at":     collection.CreatedAt,
+		"updated_at":     collection.UpdatedAt,
 		// raw_json is what the desktop client deserializes back into its
 		// Collection model; without it, desktop sync wipes the local copy of
 		// the items because it ends up overwriting raw_json with empty.
@@ -208,36 +235,54 @@ func GetCollection(c *gin.Context) {
 	})
 }
 
-// UpdateCollection updates a collection's raw JSON or name
+// UpdateCollection replaces a collection's raw JSON, or updates just its
+// name and/or description (PUT and PATCH both accept either form). Either
+// form can also set environment_id, the environment the collection runs
+// against by default.
 func UpdateCollection(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 	id := c.Param("id")
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
 	var req struct {
 		RawJSON string `json:"raw_json"`
 		Name    string `json:"name"`
+		// A pointer so an empty string can clear the description
+		Description *string `json:"description"`
+		// Absent leaves the linked environment alone; null unlinks it
+		EnvironmentID json.RawMessage `json:"environment_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	// At least one field must be provided
-	if req.RawJSON == "" && req.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Either raw_json or name must be provided"})
+	if req.RawJSON == "" && req.Name == "" && req.Description == nil && req.EnvironmentID == nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "One of raw_json, name, description or environment_id must be provided")
 		return
 	}
+	var environmentID *uint
+	if req.EnvironmentID != nil {
+		if err := json.Unmarshal(req.EnvironmentID, &environmentID); err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "environment_id must be an environment ID or null")
+			return
+		}
+		if environmentID != nil && !teamHasEnvironment(teamID, *environmentID) {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+			return
+		}
+	}
 
 	// Get existing collection
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
@@ -245,104 +290,514 @@ func UpdateCollection(c *gin.Context) {
 	if req.RawJSON != "" {
 		parsed, err := services.ParsePostmanCollection(req.RawJSON)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection format"})
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, "Invalid collection format")
+			return
+		}
+		if err := services.ValidateCollectionHeaders(parsed); err != nil {
+			apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidCollection, "Collection contains an invalid header", gin.H{
+				"problem": err.Error(),
+			})
 			return
 		}
-		name, description := services.ExtractCollectionInfo(parsed)
 		collection.RawJSON = req.RawJSON
-		collection.Name = name
-		collection.Description = description
-	} else if req.Name != "" {
-		// Update just the name - need to update both Name field and info.name in raw_json
-		collection.Name = req.Name
-		// Update the name in raw_json as well
-		updatedRawJSON, err := services.UpdateCollectionName(collection.RawJSON, req.Name)
+		services.ApplyCollectionInfo(&collection, parsed)
+	} else if req.Name != "" || req.Description != nil {
+		// Update just the metadata - both the columns and info in raw_json
+		var name *string
+		if req.Name != "" {
+			name = &req.Name
+			collection.Name = req.Name
+		}
+		if req.Description != nil {
+			collection.Description = *req.Description
+		}
+		updatedRawJSON, err := services.UpdateCollectionInfo(collection.RawJSON, name, req.Description)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection name"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection info")
 			return
 		}
 		collection.RawJSON = updatedRawJSON
 	}
+	if req.EnvironmentID != nil {
+		collection.EnvironmentID = environmentID
+	}
 
 	if err := database.GetDB().Save(&collection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
 		return
 	}
 
+	services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&collection))
 	c.JSON(http.StatusOK, collection)
 }
 
+// ResyncCollection re-parses a collection's stored raw JSON and updates the
+// name, description and request count columns to match, for a collection
+// whose JSON was changed without them. Responds with the collection and the
+// columns that were out of step.
+func ResyncCollection(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	changed, err := services.SyncCollectionInfo(&collection)
+	if err != nil {
+		apierr.RespondError(c, http.StatusUnprocessableEntity, apierr.InvalidCollection, "Stored collection JSON is not a valid collection")
+		return
+	}
+	if len(changed) > 0 {
+		if err := database.GetDB().Save(&collection).Error; err != nil {
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
+			return
+		}
+		services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&collection))
+	}
+	c.JSON(http.StatusOK, gin.H{"collection": collection, "changed": changed})
+}
+
+// collectionEventData is the webhook payload data for collection events
+func collectionEventData(collection *models.Collection) gin.H {
+	return gin.H{
+		"collection_id": collection.ID,
+		"name":          collection.Name,
+		"request_count": collection.RequestCount,
+	}
+}
+
 // DeleteCollection deletes a collection
 func DeleteCollection(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 	id := c.Param("id")
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
-	result := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).Delete(&models.Collection{})
+	result := database.GetDB().Unscoped().Where("id = ? AND team_id = ?", collectionID, teamID).Delete(&models.Collection{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
 }
 
+// PinCollection pins a collection for the whole team
+func PinCollection(c *gin.Context) {
+	setCollectionPinned(c, true)
+}
+
+// UnpinCollection unpins a collection for the whole team
+func UnpinCollection(c *gin.Context) {
+	setCollectionPinned(c, false)
+}
+
+func setCollectionPinned(c *gin.Context, pinned bool) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+	// UpdateColumn skips BeforeSave, which would reparse the collection
+	if err := database.GetDB().Model(&collection).UpdateColumn("pinned", pinned).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
+		return
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
 // ExportCollection exports a collection in Postman-compatible JSON format
 func ExportCollection(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 	id := c.Param("id")
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
-	exportJSON := collection.RawJSON
+	// Set headers for file download
+	c.Header("Content-Disposition", "attachment; filename=\""+sanitizeFilename(collection.Name)+".postman_collection.json\"")
+	c.Header("Content-Type", "application/json")
+	c.String(http.StatusOK, collectionExportJSON(&collection, teamID))
+}
 
-	// If collection has a linked environment, embed its variables
-	if collection.EnvironmentID != nil {
+// GetVariableUsage lists the requests that reference each variable in the
+// collection, or only the variable named by ?var=
+func GetVariableUsage(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
+		return
+	}
+
+	if name, ok := c.GetQuery("var"); ok {
+		if strings.TrimSpace(name) == "" {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "var must not be empty")
+			return
+		}
+		c.JSON(http.StatusOK, services.VariableUsageOf(parsed, name))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"variables": services.CollectionVariableUsage(parsed)})
+}
+
+// LintCollection reports likely mistakes in the collection's requests, see
+// services.LintCollection
+func LintCollection(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"findings": services.LintCollection(parsed)})
+}
+
+// PreviewCollection returns the collection's requests with the variables of
+// ?environment_id= (the linked environment by default) substituted, see
+// services.PreviewCollection. Secrets stay masked unless
+// ?reveal_secrets=true.
+func PreviewCollection(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
+		return
+	}
+
+	// Collection variables, overridden by those of the ?environment_id=
+	// environment, or of the collection's linked one when none is given
+	variables := services.CollectionVariables(parsed)
+	environmentID := collection.EnvironmentID
+	if raw := c.Query("environment_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid environment ID")
+			return
+		}
+		envID := uint(id)
+		environmentID = &envID
+	}
+	if environmentID != nil {
 		var env models.Environment
-		if err := database.GetDB().Where("id = ?", *collection.EnvironmentID).First(&env).Error; err == nil {
-			parsed, err := services.ParsePostmanCollection(exportJSON)
-			if err == nil {
-				vars := make([]models.PostmanVariable, 0, len(env.Variables))
-				for key, value := range env.Variables {
-					vars = append(vars, models.PostmanVariable{
-						Key:   key,
-						Value: value,
-						Type:  "default",
-					})
-				}
-				parsed.Variable = vars
+		if err := database.GetDB().Where("id = ? AND team_id = ?", *environmentID, teamID).First(&env).Error; err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+			return
+		}
+		for key, value := range env.Variables {
+			variables[key] = value
+		}
+	}
 
-				updatedJSON, err := json.MarshalIndent(parsed, "", "  ")
-				if err == nil {
-					exportJSON = string(updatedJSON)
-				}
+	c.JSON(http.StatusOK, services.PreviewCollection(parsed, variables, c.Query("reveal_secrets") == "true"))
+}
+
+// exportOrders maps ExportAllCollections' ?order= to the order of the zip
+// entries; the ID keeps collections with the same name or time stable
+var exportOrders = map[string]string{
+	"name":    "LOWER(name), id",
+	"created": "created_at, id",
+}
+
+const (
+	exportBatchSize      = 50
+	maxExportConcurrency = 8
+)
+
+// ExportAllCollections streams every team collection as a zip of
+// Postman-compatible files, ordered by ?order= (name, the default, or
+// created). ?include_environments=true adds the team's environments under
+// environments/, and ?concurrency= (1 to 8, default 1) prepares that many
+// collections of a batch at once. Collections are loaded in batches and
+// written straight to the response, so memory doesn't grow with the team.
+func ExportAllCollections(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+
+	orderBy, ok := exportOrders[c.DefaultQuery("order", "name")]
+	if !ok {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "order must be name or created")
+		return
+	}
+	concurrency := 1
+	if raw := c.Query("concurrency"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxExportConcurrency {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, fmt.Sprintf("concurrency must be between 1 and %d", maxExportConcurrency))
+			return
+		}
+		concurrency = n
+	}
+	includeEnvironments := c.Query("include_environments") == "true"
+
+	var team models.Team
+	if err := database.GetDB().Select("id", "name").Where("id = ?", teamID).First(&team).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
+		return
+	}
+
+	// Only the IDs are held for the whole export; the collections themselves
+	// are loaded a batch at a time in this order
+	var ids []uint
+	if err := database.GetDB().Model(&models.Collection{}).Where("team_id = ?", teamID).Order(orderBy).Pluck("id", &ids).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to list collections")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+sanitizeFilename(team.Name)+".collections.zip\"")
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	used := make(map[string]bool)
+	err := writeCollectionEntries(archive, used, ids, teamID, concurrency)
+	if err == nil && includeEnvironments {
+		err = writeEnvironmentEntries(archive, used, teamID)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	// The status line has been sent, so a failure can only cut the archive short
+	if err != nil {
+		log.Printf("Failed to export collections for team %d: %v", teamID, err)
+	}
+}
+
+// writeCollectionEntries adds the collections with the given IDs to archive
+// in that order, preparing up to concurrency of each batch in parallel.
+// Collections deleted since the IDs were listed are left out.
+func writeCollectionEntries(archive *zip.Writer, used map[string]bool, ids []uint, teamID uint, concurrency int) error {
+	for start := 0; start < len(ids); start += exportBatchSize {
+		batchIDs := ids[start:min(start+exportBatchSize, len(ids))]
+		var collections []models.Collection
+		if err := database.GetDB().Where("id IN ? AND team_id = ?", batchIDs, teamID).Find(&collections).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.Collection, len(collections))
+		for i := range collections {
+			byID[collections[i].ID] = &collections[i]
+		}
+
+		exports := make([]string, len(batchIDs))
+		slots := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for i, id := range batchIDs {
+			collection, ok := byID[id]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer func() { <-slots; wg.Done() }()
+				exports[i] = collectionExportJSON(collection, teamID)
+			}()
+		}
+		wg.Wait()
+
+		for i, id := range batchIDs {
+			collection, ok := byID[id]
+			if !ok {
+				continue
+			}
+			entry, err := archive.Create(uniqueFilename(used, sanitizeFilename(collection.Name), ".postman_collection.json", collection.ID))
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(entry, exports[i]); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+// writeEnvironmentEntries adds the team's environments to archive under
+// environments/, by name, in Postman's environment format
+func writeEnvironmentEntries(archive *zip.Writer, used map[string]bool, teamID uint) error {
+	var environments []models.Environment
+	if err := database.GetDB().Where("team_id = ?", teamID).Order(exportOrders["name"]).Find(&environments).Error; err != nil {
+		return err
+	}
+	for i := range environments {
+		data, err := json.MarshalIndent(services.ToPostmanEnvironment(&environments[i]), "", "  ")
+		if err != nil {
+			return err
+		}
+		entry, err := archive.Create(uniqueFilename(used, "environments/"+sanitizeFilename(environments[i].Name), ".postman_environment.json", environments[i].ID))
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectionExportJSON returns the collection's JSON with the variables of
+// its linked environment embedded, if it has one
+func collectionExportJSON(collection *models.Collection, teamID uint) string {
+	exportJSON := collection.RawJSON
+	if collection.EnvironmentID == nil {
+		return exportJSON
+	}
+
+	var env models.Environment
+	if err := database.GetDB().Where("id = ? AND team_id = ?", *collection.EnvironmentID, teamID).First(&env).Error; err != nil {
+		return exportJSON
+	}
+	parsed, err := services.ParsePostmanCollection(exportJSON)
+	if err != nil {
+		return exportJSON
+	}
+	vars := make([]models.PostmanVariable, 0, len(env.Variables))
+	for key, value := range env.Variables {
+		vars = append(vars, models.PostmanVariable{
+			Key:   key,
+			Value: value,
+			Type:  "default",
+		})
+	}
+	parsed.Variable = vars
+
+	updatedJSON, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return exportJSON
+	}
+	return string(updatedJSON)
+}
 
-	// Sanitize filename - remove special characters
-	filename := collection.Name
+// sanitizeFilename replaces characters that aren't allowed in filenames
+func sanitizeFilename(name string) string {
 	for _, char := range []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"} {
-		filename = strings.ReplaceAll(filename, char, "_")
+		name = strings.ReplaceAll(name, char, "_")
 	}
+	return name
+}
 
-	// Set headers for file download
-	c.Header("Content-Disposition", "attachment; filename=\""+filename+".postman_collection.json\"")
-	c.Header("Content-Type", "application/json")
-	c.String(http.StatusOK, exportJSON)
+// collectionNotModified sets ETag and Last-Modified for the collection and
+// reports whether the client's copy is still current, in which case it has
+// responded 304. The ETag comes from the ID and updated_at, which every save
+// bumps, so checking it never needs the raw JSON.
+func collectionNotModified(c *gin.Context, collection *models.Collection) bool {
+	modified := collection.UpdatedAt
+	if modified.IsZero() {
+		// Not saved since updated_at was added
+		modified = collection.CreatedAt
+	}
+	etag := fmt.Sprintf(`"%d-%x"`, collection.ID, modified.UnixNano())
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+
+	// If-None-Match wins over If-Modified-Since when both are sent
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. Weak
+// validators compare equal to strong ones, as GET allows.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueFilename returns name+ext, adding the ID when that's already used
+// (ignoring case) so collections with the same name don't overwrite each
+// other when unzipped
+func uniqueFilename(used map[string]bool, name, ext string, id uint) string {
+	filename := name + ext
+	if used[strings.ToLower(filename)] {
+		filename = name + " (" + strconv.FormatUint(uint64(id), 10) + ")" + ext
+	}
+	// Only when another name already ends in the same "(id)"
+	for n := 2; used[strings.ToLower(filename)]; n++ {
+		filename = name + " (" + strconv.FormatUint(uint64(id), 10) + "-" + strconv.Itoa(n) + ")" + ext
+	}
+	used[strings.ToLower(filename)] = true
+	return filename
+}
+
+// teamHasEnvironment reports whether environmentID is one of the team's
+// environments
+func teamHasEnvironment(teamID, environmentID uint) bool {
+	var count int64
+	database.GetDB().Model(&models.Environment{}).Where("id = ? AND team_id = ?", environmentID, teamID).Count(&count)
+	return count > 0
 }
 
 // SetCollectionEnvironment links or unlinks an environment to a collection
@@ -351,7 +806,7 @@ func SetCollectionEnvironment(c *gin.Context) {
 	id := c.Param("id")
 	collectionID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
 		return
 	}
 
@@ -359,30 +814,205 @@ func SetCollectionEnvironment(c *gin.Context) {
 		EnvironmentID *uint `json:"environment_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	// Validate environment belongs to same team if non-null
-	if req.EnvironmentID != nil {
-		var env models.Environment
-		if err := database.GetDB().Where("id = ? AND team_id = ?", *req.EnvironmentID, teamID).First(&env).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Environment not found"})
-			return
-		}
+	if req.EnvironmentID != nil && !teamHasEnvironment(teamID, *req.EnvironmentID) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+		return
 	}
 
 	var collection models.Collection
 	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
 		return
 	}
 
 	collection.EnvironmentID = req.EnvironmentID
 	if err := database.GetDB().Save(&collection).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
 		return
 	}
 
 	c.JSON(http.StatusOK, collection)
 }
+
+// ReorderCollection persists a new order for the items of one or more
+// folders, so the client doesn't have to send the whole raw_json after a drag
+func ReorderCollection(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var req models.ReorderCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+	if _, err := services.ParsePostmanCollection(collection.RawJSON); err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
+		return
+	}
+
+	// Reorder the stored JSON itself, a round trip through the models would
+	// drop every field they don't know about
+	rawJSON, err := services.ReorderCollectionItems(collection.RawJSON, req.Folders)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	collection.RawJSON = rawJSON
+
+	if err := database.GetDB().Save(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update collection")
+		return
+	}
+
+	services.DispatchEvent(teamID, models.EventCollectionUpdated, collectionEventData(&collection))
+	c.JSON(http.StatusOK, collection)
+}
+
+// ExecuteCollectionItem runs a request stored in a collection, so the client
+// only has to send the item's path instead of the whole request
+func ExecuteCollectionItem(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	var req models.ExecuteCollectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	parsed, err := services.ParsePostmanCollection(collection.RawJSON)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.InvalidCollection, "Failed to parse collection")
+		return
+	}
+
+	item, err := services.FindCollectionItem(parsed, req.ItemPath)
+	if err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.ItemNotFound, err.Error())
+		return
+	}
+
+	// Requests without their own auth inherit it from their folders / the collection
+	execReq, err := services.ItemToExecuteRequest(item, services.EffectiveAuth(parsed, req.ItemPath))
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidCollection, err.Error())
+		return
+	}
+
+	// Collection variables, overridden by the environment's
+	variables := services.CollectionVariables(parsed)
+	environmentID := req.EnvironmentID
+	if environmentID == nil {
+		environmentID = collection.EnvironmentID
+	}
+	if environmentID != nil {
+		var env models.Environment
+		if err := database.GetDB().Where("id = ? AND team_id = ?", *environmentID, teamID).First(&env).Error; err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+			return
+		}
+		for key, value := range env.Variables {
+			variables[key] = value
+		}
+	}
+
+	if !applyTeamDefaults(c, teamID, execReq) {
+		return
+	}
+	unresolved := services.ReplaceInRequest(execReq, variables)
+	normalizedURL, err := services.NormalizeRequestURL(execReq.URL)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	execReq.URL = normalizedURL
+
+	if !enforceHostPolicy(c, teamID, execReq.URL) {
+		return
+	}
+
+	ctx, executionID, done, ok := beginExecution(c, req.ExecutionID, teamID)
+	if !ok {
+		return
+	}
+	defer done()
+	release, ok := acquireExecutionSlot(c, ctx, executionID, teamID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	response, err := services.ExecuteHTTPRequestContext(ctx, execReq)
+	recordHistory(c, teamID, execReq.Method, execReq.URL, services.RequestHeaders(execReq), response, err)
+	if err != nil {
+		respondExecutionError(c, executionID, err)
+		return
+	}
+	if err := services.RecordItemTiming(teamID, collection.ID, req.ItemPath, response.Time, time.Now()); err != nil {
+		log.Printf("Failed to record timing of collection %d item %v: %v", collection.ID, req.ItemPath, err)
+	}
+
+	response.UnresolvedVariables = unresolved
+	response.ExecutionID = executionID
+	services.AttachResponseArtifact(c.GetUint("user_id"), response, time.Now())
+	c.JSON(http.StatusOK, response)
+}
+
+// GetItemTimings returns the daily response time rollups of a collection's
+// items, as recorded by ExecuteCollectionItem: count, min, max, mean and
+// approximate p50/p95. ?days= picks the period (default 30, at most 365) and
+// repeated ?item_path= one item.
+func GetItemTimings(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid collection ID")
+		return
+	}
+
+	days := 0
+	if raw := c.Query("days"); raw != "" {
+		if days, err = strconv.Atoi(raw); err != nil || days <= 0 {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "days must be a positive integer")
+			return
+		}
+	}
+
+	var collection models.Collection
+	if err := database.GetDB().Select("id").Where("id = ? AND team_id = ?", collectionID, teamID).First(&collection).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+		return
+	}
+
+	timings, err := services.ItemTimings(collection.ID, c.QueryArray("item_path"), days, time.Now())
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to load timings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": timings})
+}
@@ -0,0 +1,564 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestExportAllCollections(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	env := models.Environment{Name: "dev", TeamID: &team.ID, Variables: models.Variables{"base_url": "https://dev.example.com"}}
+	database.DB.Create(&env)
+	var lowerOrders models.Collection
+	for _, collection := range []models.Collection{
+		{Name: "Orders", TeamID: &team.ID, RawJSON: `{"info":{"name":"Orders"},"item":[]}`, EnvironmentID: &env.ID},
+		{Name: "Users/Admin", TeamID: &team.ID, RawJSON: `{"info":{"name":"Users"},"item":[]}`},
+		{Name: "orders", TeamID: &team.ID, RawJSON: `{"info":{"name":"orders"},"item":[]}`},
+		{Name: "Theirs", TeamID: &other.ID, RawJSON: `{"info":{"name":"Theirs"},"item":[]}`},
+	} {
+		database.DB.Create(&collection)
+		if collection.Name == "orders" {
+			lowerOrders = collection
+		}
+	}
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections/export-all", ExportAllCollections)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections/export-all", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); disposition != `attachment; filename="owner@example.com's team.collections.zip"` {
+		t.Errorf("Expected a zip attachment, got '%s'", disposition)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Expected a valid zip, got %v", err)
+	}
+	if len(archive.File) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(archive.File))
+	}
+
+	// Alphabetical whatever the case; the second "orders" gets its ID
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	want := []string{"Orders.postman_collection.json", "orders (" + strconv.Itoa(int(lowerOrders.ID)) + ").postman_collection.json", "Users_Admin.postman_collection.json"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected entries %v, got %v", want, names)
+	}
+
+	entry, _ := archive.Open("Orders.postman_collection.json")
+	data, _ := io.ReadAll(entry)
+	if !strings.Contains(string(data), "https://dev.example.com") {
+		t.Errorf("Expected the linked environment's variables to be embedded, got '%s'", data)
+	}
+}
+
+func TestExportAllCollectionsOrderAndEnvironments(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	database.DB.Create(&models.Environment{Name: "staging", TeamID: &team.ID, Variables: models.Variables{"b": "2", "a": "1"}})
+	upper := models.Environment{Name: "Staging", TeamID: &team.ID}
+	database.DB.Create(&upper)
+	for _, name := range []string{"Zeta", "alpha", "Mid"} {
+		database.DB.Create(&models.Collection{Name: name, TeamID: &team.ID, RawJSON: `{"info":{"name":"` + name + `"},"item":[]}`})
+	}
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections/export-all", ExportAllCollections)
+	entries := func(query string) (*zip.Reader, []string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections/export-all"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for '%s', got %d: %s", query, w.Code, w.Body.String())
+		}
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("Expected a valid zip, got %v", err)
+		}
+		var names []string
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+		}
+		return archive, names
+	}
+
+	archive, names := entries("?include_environments=true&concurrency=3")
+	want := []string{
+		"alpha.postman_collection.json", "Mid.postman_collection.json", "Zeta.postman_collection.json",
+		"environments/staging.postman_environment.json", "environments/Staging (" + strconv.Itoa(int(upper.ID)) + ").postman_environment.json",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected entries %v, got %v", want, names)
+	}
+	entry, _ := archive.Open("environments/staging.postman_environment.json")
+	var env models.PostmanEnvironment
+	json.NewDecoder(entry).Decode(&env)
+	if env.Name != "staging" || len(env.Values) != 2 || env.Values[0].Key != "a" || env.Scope != "environment" {
+		t.Errorf("Expected a Postman environment with sorted values, got %+v", env)
+	}
+
+	if _, names := entries("?order=created"); strings.Join(names, ",") != "Zeta.postman_collection.json,alpha.postman_collection.json,Mid.postman_collection.json" {
+		t.Errorf("Expected creation order without environments, got %v", names)
+	}
+
+	for _, query := range []string{"?order=size", "?concurrency=0", "?concurrency=9"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections/export-all"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected '%s' to be rejected, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestPinCollectionListsItFirst(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	var ids []uint
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		collection := models.Collection{Name: name, TeamID: &team.ID, RawJSON: `{"info":{"name":"` + name + `"},"item":[]}`}
+		database.DB.Create(&collection)
+		ids = append(ids, collection.ID)
+	}
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections", GetCollections)
+	r.PUT("/collections/:id/pin", PinCollection)
+	r.DELETE("/collections/:id/pin", UnpinCollection)
+	names := func() []string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections", nil))
+		var collections []models.Collection
+		json.Unmarshal(w.Body.Bytes(), &collections)
+		var names []string
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+		return names
+	}
+	pin := func(method string, id uint) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/collections/"+strconv.Itoa(int(id))+"/pin", nil))
+		return w
+	}
+
+	if w := pin(http.MethodPut, ids[2]); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"pinned":true`) {
+		t.Fatalf("Expected the collection to be pinned, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.Join(names(), ","); got != "Gamma,Alpha,Beta" {
+		t.Errorf("Expected the pinned collection first, got %s", got)
+	}
+
+	pin(http.MethodDelete, ids[2])
+	if got := strings.Join(names(), ","); got != "Alpha,Beta,Gamma" {
+		t.Errorf("Expected the original order once unpinned, got %s", got)
+	}
+
+	_, other := createTestTeam(t, "other@example.com")
+	theirs := models.Collection{Name: "Theirs", TeamID: &other.ID}
+	database.DB.Create(&theirs)
+	if w := pin(http.MethodPut, theirs.ID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another team's collection to be not found, got %d", w.Code)
+	}
+}
+
+func TestReorderCollection(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: `{"info":{"name":"Shop"},"item":[
+		{"name":"Login","request":{"method":"POST","url":"http://api.test/login"}},
+		{"name":"Users","item":[
+			{"name":"List","request":{"method":"GET","url":"http://api.test/users"}},
+			{"name":"Create","event":[{"listen":"test","script":{"exec":["pm.test()"]}}],"request":{"method":"POST","url":"http://api.test/users"}}
+		]}
+	]}`}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/collections/:id/reorder", ReorderCollection)
+	reorder := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/collections/"+strconv.Itoa(int(collection.ID))+"/reorder", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := reorder(`{"folders":[{"path":[],"items":["Users","Login"]},{"path":["Users"],"items":["Create","List"]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.Collection
+	database.DB.First(&stored, collection.ID)
+	var parsed models.PostmanCollection
+	json.Unmarshal([]byte(stored.RawJSON), &parsed)
+	if len(parsed.Item) != 2 || parsed.Item[0].Name != "Users" || parsed.Item[1].Name != "Login" {
+		t.Fatalf("Expected the top level reordered, got %+v", parsed.Item)
+	}
+	if users := parsed.Item[0].Item; len(users) != 2 || users[0].Name != "Create" || users[1].Name != "List" {
+		t.Errorf("Expected the folder reordered, got %+v", users)
+	}
+	if parsed.Info.Name != "Shop" {
+		t.Errorf("Expected the collection info to survive, got '%s'", parsed.Info.Name)
+	}
+	if !strings.Contains(stored.RawJSON, `"event":[{"listen":"test"`) {
+		t.Errorf("Expected the item's event to survive the reorder, got %s", stored.RawJSON)
+	}
+
+	w = reorder(`{"folders":[{"path":[],"items":["Users"]}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a partial item list to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	database.DB.First(&stored, collection.ID)
+	json.Unmarshal([]byte(stored.RawJSON), &parsed)
+	if parsed.Item[0].Name != "Users" {
+		t.Errorf("Expected a rejected reorder to leave the collection alone, got %+v", parsed.Item)
+	}
+}
+
+func TestUpdateCollectionRejectsInvalidHeaders(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	original := `{"info":{"name":"Shop"},"item":[]}`
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: original}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.PUT("/collections/:id", UpdateCollection)
+	r.PUT("/public/collections/:id", PublicUpdateCollection)
+	smuggled, _ := json.Marshal(map[string]string{"raw_json": `{"info":{"name":"Shop"},"item":[
+		{"name":"Ping","request":{"method":"GET","url":"http://api.test","header":[{"key":"X-Note","value":"a\r\nX-Admin: true"}]}}
+	]}`})
+
+	for _, path := range []string{"/collections/", "/public/collections/"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, path+strconv.Itoa(int(collection.ID)), bytes.NewReader(smuggled))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != apierr.InvalidCollection {
+			t.Errorf("%s: expected 400 %s, got %d: %s", path, apierr.InvalidCollection, w.Code, w.Body.String())
+		}
+	}
+	var stored models.Collection
+	database.DB.First(&stored, collection.ID)
+	if stored.RawJSON != original {
+		t.Errorf("Expected the collection to be left alone, got %s", stored.RawJSON)
+	}
+}
+
+func TestExecuteCollectionItemUsesLinkedEnvironment(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("X-Tenant")))
+	}))
+	defer upstream.Close()
+
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	env := models.Environment{Name: "dev", TeamID: &team.ID, Variables: models.Variables{"base_url": upstream.URL, "tenant": "acme"}}
+	database.DB.Create(&env)
+	theirs := models.Environment{Name: "theirs", TeamID: &other.ID}
+	database.DB.Create(&theirs)
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: `{"info":{"name":"Shop"},"item":[
+		{"name":"Ping","request":{"method":"GET","url":"{{base_url}}/ping","header":[{"key":"X-Tenant","value":"{{tenant}}"}]}}
+	]}`}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.PATCH("/collections/:id", UpdateCollection)
+	r.POST("/collections/:id/items/execute", ExecuteCollectionItem)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/collections/"+strconv.Itoa(int(collection.ID))+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(http.MethodPatch, "", `{"environment_id":`+strconv.Itoa(int(theirs.ID))+`}`); w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != "ENVIRONMENT_NOT_FOUND" {
+		t.Errorf("Expected another team's environment to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPatch, "", `{"environment_id":`+strconv.Itoa(int(env.ID))+`}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the environment to be linked, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.Collection
+	database.DB.First(&stored, collection.ID)
+	if stored.EnvironmentID == nil || *stored.EnvironmentID != env.ID || stored.Name != "Shop" {
+		t.Fatalf("Expected environment %d linked and the rest untouched, got %+v", env.ID, stored)
+	}
+
+	w := send(http.MethodPost, "/items/execute", `{"item_path":["Ping"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.ExecuteResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Body != "/ping acme" {
+		t.Errorf("Expected the linked environment's variables to be used, got '%s' (unresolved %v)", resp.Body, resp.UnresolvedVariables)
+	}
+
+	if w := send(http.MethodPatch, "", `{"environment_id":null}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the environment to be unlinked, got %d: %s", w.Code, w.Body.String())
+	}
+	database.DB.First(&stored, collection.ID)
+	if stored.EnvironmentID != nil {
+		t.Errorf("Expected no linked environment, got %d", *stored.EnvironmentID)
+	}
+}
+
+func TestGetCollectionETag(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	collection := models.Collection{Name: "Orders", TeamID: &team.ID, RawJSON: `{"info":{"name":"Orders"},"item":[]}`}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections/:id", GetCollection)
+	r.GET("/collections/:id/raw", PublicGetCollectionRaw)
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	path := "/collections/" + strconv.Itoa(int(collection.ID))
+
+	for _, p := range []string{path, path + "/raw"} {
+		w := get(p, "")
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" || w.Header().Get("Last-Modified") == "" {
+			t.Fatalf("%s: expected 200 with ETag and Last-Modified, got %d %v", p, w.Code, w.Header())
+		}
+
+		w = get(p, etag)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 without a body for a matching ETag, got %d '%s'", p, w.Code, w.Body.String())
+		}
+		if w := get(p, `"stale", W/`+etag); w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected a weak match in a list to count, got %d", p, w.Code)
+		}
+		if w := get(p, `"stale"`); w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for a stale ETag, got %d", p, w.Code)
+		}
+	}
+
+	etag := get(path, "").Header().Get("ETag")
+	collection.Name = "Orders v2"
+	database.DB.Save(&collection)
+	w := get(path, etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected a saved collection to get a new ETag, got %d with %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestGetItemTimingsAfterExecution(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	defer upstream.Close()
+
+	user, team := createTestTeam(t, "owner@example.com")
+	otherUser, other := createTestTeam(t, "other@example.com")
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: `{"info":{"name":"Shop"},"item":[
+		{"name":"Ping","request":{"method":"GET","url":"` + upstream.URL + `/ping"}}
+	]}`}
+	database.DB.Create(&collection)
+	path := "/collections/" + strconv.Itoa(int(collection.ID)) + "/items"
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/collections/:id/items/execute", ExecuteCollectionItem)
+	r.GET("/collections/:id/items/timings", GetItemTimings)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path+"/execute", strings.NewReader(`{"item_path":["Ping"]}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"/timings?days=7&item_path=Ping", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Items []models.ItemTimings `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Items) != 1 || body.Items[0].Count != 3 || body.Items[0].ItemPath[0] != "Ping" || len(body.Items[0].Days) != 1 {
+		t.Errorf("Expected 3 executions of Ping today, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"/timings?days=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected days=0 to be rejected, got %d", w.Code)
+	}
+
+	theirs := teamRouter(other.ID, otherUser.ID)
+	theirs.GET("/collections/:id/items/timings", GetItemTimings)
+	w = httptest.NewRecorder()
+	theirs.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"/timings", nil))
+	if w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != "COLLECTION_NOT_FOUND" {
+		t.Errorf("Expected another team's collection to be hidden, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestResyncCollectionWithDivergentName(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: `{"info":{"name":"Shop"},"item":[]}`}
+	database.DB.Create(&collection)
+	// Edited behind the app's back: the columns still say "Shop" and 0 requests
+	database.DB.Model(&collection).UpdateColumn("raw_json",
+		`{"info":{"name":"Store","description":"v2"},"item":[{"name":"Ping","request":{"method":"GET","url":"https://example.com"}}]}`)
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/collections/:id/resync", ResyncCollection)
+	r.PUT("/public/collections/:id", PublicUpdateCollection)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	resync := "/collections/" + strconv.Itoa(int(collection.ID)) + "/resync"
+
+	w := send(http.MethodPost, resync, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Collection models.Collection `json:"collection"`
+		Changed    []string          `json:"changed"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if strings.Join(body.Changed, ",") != "name,description,request_count" {
+		t.Errorf("Expected name, description and request_count to be fixed, got %v", body.Changed)
+	}
+	var stored models.Collection
+	database.DB.First(&stored, collection.ID)
+	if stored.Name != "Store" || stored.Description != "v2" || stored.RequestCount != 1 {
+		t.Errorf("Expected the columns to match the raw JSON, got %q %q %d", stored.Name, stored.Description, stored.RequestCount)
+	}
+
+	w = send(http.MethodPost, resync, "")
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || len(body.Changed) != 0 {
+		t.Errorf("Expected nothing left to fix, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The public raw update keeps the columns in step by itself
+	w = send(http.MethodPut, "/public/collections/"+strconv.Itoa(int(collection.ID)),
+		`{"raw_json":"{\"info\":{\"name\":\"Market\"},\"item\":[]}"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	database.DB.First(&stored, collection.ID)
+	if stored.Name != "Market" || stored.Description != "" || stored.RequestCount != 0 {
+		t.Errorf("Expected the public update to sync the columns, got %q %q %d", stored.Name, stored.Description, stored.RequestCount)
+	}
+
+	database.DB.Model(&collection).UpdateColumn("raw_json", "not json")
+	if w := send(http.MethodPost, resync, ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected unparseable JSON to be reported, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPreviewCollectionWithEnvironment(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	env := models.Environment{Name: "Staging", TeamID: &team.ID, Variables: models.Variables{"base": "http://staging.test", "api_token": "s3cr3t"}}
+	database.DB.Create(&env)
+	collection := models.Collection{Name: "Shop", TeamID: &team.ID, RawJSON: `{"info":{"name":"Shop"},"item":[
+		{"name":"Orders","item":[
+			{"name":"List","request":{"method":"GET","url":"{{base}}/orders?token={{api_token}}"}},
+			{"name":"Get","request":{"method":"GET","url":"{{base}}/orders/{{order_id}}"}}
+		]}
+	]}`}
+	database.DB.Create(&collection)
+	path := "/collections/" + strconv.Itoa(int(collection.ID)) + "/preview?environment_id=" + strconv.Itoa(int(env.ID))
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections/:id/preview", PreviewCollection)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var preview models.CollectionPreview
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if len(preview.UnresolvedVariables) != 1 || preview.UnresolvedVariables[0] != "order_id" {
+		t.Errorf("Expected only order_id unresolved, got %v", preview.UnresolvedVariables)
+	}
+	orders := preview.Items[0].Item
+	if orders[0].Request.URL != "http://staging.test/orders?token=***" || orders[1].Request.URL != "http://staging.test/orders/{{order_id}}" {
+		t.Errorf("Expected the token masked and order_id kept, got %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "s3cr3t") {
+		t.Errorf("Expected the secret not to appear in the preview, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"&reveal_secrets=true", nil))
+	if !strings.Contains(w.Body.String(), "orders?token=s3cr3t") {
+		t.Errorf("Expected the secret with reveal_secrets=true, got %s", w.Body.String())
+	}
+
+	_, other := createTestTeam(t, "other@example.com")
+	theirs := models.Environment{Name: "Theirs", TeamID: &other.ID}
+	database.DB.Create(&theirs)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections/"+strconv.Itoa(int(collection.ID))+"/preview?environment_id="+strconv.Itoa(int(theirs.ID)), nil))
+	if w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != "ENVIRONMENT_NOT_FOUND" {
+		t.Errorf("Expected another team's environment to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExportCollectionSkipsAnotherTeamsEnvironment(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	theirs := models.Environment{Name: "theirs", TeamID: &other.ID, Variables: models.Variables{"token": "their-secret"}}
+	database.DB.Create(&theirs)
+	collection := models.Collection{Name: "API", TeamID: &team.ID, EnvironmentID: &theirs.ID,
+		RawJSON: `{"info":{"name":"API","schema":"https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},"item":[]}`}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/collections/:id/export", ExportCollection)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collections/"+strconv.Itoa(int(collection.ID))+"/export", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "their-secret") {
+		t.Errorf("Expected another team's environment not to be embedded, got %s", w.Body.String())
+	}
+}
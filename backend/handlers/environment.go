@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
+	"postmanxodja/services"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetEnvironments returns all environments for a team
@@ -16,7 +21,7 @@ func GetEnvironments(c *gin.Context) {
 	var environments []models.Environment
 
 	if err := database.GetDB().Where("team_id = ?", teamID).Find(&environments).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch environments"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch environments")
 		return
 	}
 
@@ -30,14 +35,14 @@ func CreateEnvironment(c *gin.Context) {
 	var env models.Environment
 
 	if err := c.ShouldBindJSON(&env); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	env.TeamID = &teamID
 
 	if err := database.GetDB().Create(&env).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create environment"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create environment")
 		return
 	}
 
@@ -50,19 +55,19 @@ func UpdateEnvironment(c *gin.Context) {
 	id := c.Param("id")
 	envID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid environment ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid environment ID")
 		return
 	}
 
 	var env models.Environment
 	if err := database.GetDB().Where("id = ? AND team_id = ?", envID, teamID).First(&env).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
 		return
 	}
 
 	var updates models.Environment
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
@@ -70,7 +75,126 @@ func UpdateEnvironment(c *gin.Context) {
 	env.Variables = updates.Variables
 
 	if err := database.GetDB().Save(&env).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update environment"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update environment")
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// PatchEnvironmentVariables sets and removes individual variables without
+// sending the whole map. The read-modify-write runs under a row lock so
+// concurrent patches don't lose each other's changes.
+func PatchEnvironmentVariables(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	envID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid environment ID")
+		return
+	}
+
+	var req models.PatchVariablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if len(req.Set) == 0 && len(req.Unset) == 0 {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Either set or unset must be provided")
+		return
+	}
+
+	var env models.Environment
+	errNotFound := errors.New("environment not found")
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND team_id = ?", envID, teamID).First(&env).Error; err != nil {
+			return errNotFound
+		}
+
+		patched, err := services.ApplyVariablePatch(env.Variables, req.Set, req.Unset)
+		if err != nil {
+			return err
+		}
+		env.Variables = patched
+		return tx.Model(&env).Update("variables", env.Variables).Error
+	})
+
+	var patchErr *services.VariablePatchError
+	switch {
+	case err == nil:
+	case errors.Is(err, errNotFound):
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
+		return
+	case errors.As(err, &patchErr):
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidRequest, patchErr.Error(), gin.H{
+			"conflicting": patchErr.Conflicting,
+			"invalid":     patchErr.Invalid,
+		})
+		return
+	default:
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update variables")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variables": env.Variables})
+}
+
+// CopyEnvironmentVariables copies variables from another environment of the
+// team into this one and returns the updated environment. Like
+// PatchEnvironmentVariables it locks the target row for the read-modify-write.
+func CopyEnvironmentVariables(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	envID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid environment ID")
+		return
+	}
+
+	var req models.CopyVariablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if req.SourceID == uint(envID) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Source and target environments must differ")
+		return
+	}
+
+	var source models.Environment
+	if err := database.GetDB().Where("id = ? AND team_id = ?", req.SourceID, teamID).First(&source).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Source environment not found")
+		return
+	}
+
+	var env models.Environment
+	errNotFound := errors.New("environment not found")
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND team_id = ?", envID, teamID).First(&env).Error; err != nil {
+			return errNotFound
+		}
+
+		copied, err := services.CopyVariables(env.Variables, source.Variables, req.Keys, req.Overwrite)
+		if err != nil {
+			return err
+		}
+		env.Variables = copied
+		return tx.Model(&env).Update("variables", env.Variables).Error
+	})
+
+	var missingErr *services.MissingVariablesError
+	switch {
+	case err == nil:
+	case errors.Is(err, errNotFound):
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
+		return
+	case errors.As(err, &missingErr):
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidRequest, missingErr.Error(), gin.H{
+			"missing": missingErr.Keys,
+		})
+		return
+	default:
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to copy variables")
 		return
 	}
 
@@ -83,13 +207,34 @@ func DeleteEnvironment(c *gin.Context) {
 	id := c.Param("id")
 	envID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid environment ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid environment ID")
 		return
 	}
 
-	result := database.GetDB().Where("id = ? AND team_id = ?", envID, teamID).Delete(&models.Environment{})
+	var env models.Environment
+	if err := database.GetDB().Where("id = ? AND team_id = ?", envID, teamID).First(&env).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
+		return
+	}
+
+	// Refuse to break what depends on the environment unless asked to
+	if c.Query("force") != "true" {
+		references, err := services.EnvironmentReferences(teamID, env.ID)
+		if err != nil {
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to check environment references")
+			return
+		}
+		if len(references) > 0 {
+			apierr.RespondErrorWithDetails(c, http.StatusConflict, apierr.EnvironmentInUse, "Environment is in use. Pass force=true to delete it anyway.", gin.H{
+				"references": references,
+			})
+			return
+		}
+	}
+
+	result := database.GetDB().Unscoped().Where("id = ? AND team_id = ?", envID, teamID).Delete(&models.Environment{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found")
 		return
 	}
 
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useTestDB points database.DB at a fresh in-memory SQLite database
+func useTestDB(t *testing.T) {
+	t.Helper()
+	previous := database.DB
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = previous
+	})
+}
+
+// teamRouter serves handlers as if TeamAccessMiddleware had let userID into
+// teamID
+func teamRouter(teamID, userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("team_id", teamID)
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	return r
+}
+
+// createTestTeam creates a user who owns a new team
+func createTestTeam(t *testing.T, email string) (models.User, models.Team) {
+	t.Helper()
+	user := models.User{Email: email, Name: email}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	team := models.Team{Name: email + "'s team"}
+	if err := database.DB.Create(&team).Error; err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Create(&models.TeamMember{TeamID: team.ID, UserID: user.ID, Role: "owner"})
+	return user, team
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) apierr.Response {
+	t.Helper()
+	var body apierr.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error body, got '%s'", w.Body.String())
+	}
+	return body
+}
+
+func TestCopyEnvironmentVariables(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	target := models.Environment{Name: "dev", TeamID: &team.ID, Variables: models.Variables{"host": "localhost", "token": "old"}}
+	source := models.Environment{Name: "prod", TeamID: &team.ID, Variables: models.Variables{"host": "api.test", "token": "new", "port": "443"}}
+	theirs := models.Environment{Name: "theirs", TeamID: &other.ID, Variables: models.Variables{"secret": "x"}}
+	database.DB.Create(&target)
+	database.DB.Create(&source)
+	database.DB.Create(&theirs)
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/environments/:id/copy-from", CopyEnvironmentVariables)
+	copyFrom := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/environments/"+strconv.Itoa(int(target.ID))+"/copy-from", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := copyFrom(`{"source_id":` + strconv.Itoa(int(source.ID)) + `,"keys":["token","port"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated models.Environment
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.Variables["token"] != "old" || updated.Variables["port"] != "443" || updated.Variables["host"] != "localhost" {
+		t.Errorf("Expected only port to be copied, got %v", updated.Variables)
+	}
+
+	w = copyFrom(`{"source_id":` + strconv.Itoa(int(source.ID)) + `,"keys":["token"],"overwrite":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.Environment
+	database.DB.First(&stored, target.ID)
+	if len(stored.Variables) != 3 || stored.Variables["token"] != "new" || stored.Variables["host"] != "localhost" {
+		t.Errorf("Expected token to be overwritten, got %v", stored.Variables)
+	}
+
+	if w := copyFrom(`{"source_id":` + strconv.Itoa(int(theirs.ID)) + `}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another team's environment to be rejected, got %d", w.Code)
+	}
+	w = copyFrom(`{"source_id":` + strconv.Itoa(int(source.ID)) + `,"keys":["nope"]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"missing":["nope"]`) {
+		t.Errorf("Expected the missing key to be reported, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPatchEnvironmentVariables(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	env := models.Environment{Name: "dev", TeamID: &team.ID, Variables: models.Variables{"host": "localhost", "token": "old"}}
+	database.DB.Create(&env)
+
+	r := teamRouter(team.ID, user.ID)
+	r.PATCH("/environments/:id/variables", PatchEnvironmentVariables)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/environments/"+strconv.Itoa(int(env.ID))+"/variables",
+		strings.NewReader(`{"set":{"port":"8080"},"unset":["token"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.Environment
+	database.DB.First(&stored, env.ID)
+	if len(stored.Variables) != 2 || stored.Variables["host"] != "localhost" || stored.Variables["port"] != "8080" {
+		t.Errorf("Expected host and port only, got %v", stored.Variables)
+	}
+}
+
+func TestDeleteEnvironmentOfAnotherTeam(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	env := models.Environment{Name: "theirs", TeamID: &other.ID}
+	database.DB.Create(&env)
+
+	r := teamRouter(team.ID, user.ID)
+	r.DELETE("/environments/:id", DeleteEnvironment)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/environments/"+strconv.Itoa(int(env.ID)), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoadEnvironmentVariablesIsTeamScoped(t *testing.T) {
+	useTestDB(t)
+	_, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	own := models.Environment{Name: "mine", TeamID: &team.ID, Variables: models.Variables{"host": "mine"}}
+	theirs := models.Environment{Name: "theirs", TeamID: &other.ID, Variables: models.Variables{"host": "theirs"}}
+	database.DB.Create(&own)
+	database.DB.Create(&theirs)
+
+	if got := loadEnvironmentVariables(team.ID, &own.ID); got["host"] != "mine" {
+		t.Errorf("Expected the team's own environment, got %v", got)
+	}
+	if got := loadEnvironmentVariables(team.ID, &theirs.ID); got != nil {
+		t.Errorf("Expected another team's environment to be treated as missing, got %v", got)
+	}
+}
+
+func TestDeleteEnvironmentInUseNeedsForce(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	env := models.Environment{Name: "dev", TeamID: &team.ID}
+	database.DB.Create(&env)
+	collection := models.Collection{Name: "Orders", TeamID: &team.ID, EnvironmentID: &env.ID}
+	database.DB.Create(&collection)
+
+	r := teamRouter(team.ID, user.ID)
+	r.DELETE("/environments/:id", DeleteEnvironment)
+	path := "/environments/" + strconv.Itoa(int(env.ID))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeError(t, w)
+	if body.Error.Code != apierr.EnvironmentInUse {
+		t.Errorf("Expected code '%s', got '%s'", apierr.EnvironmentInUse, body.Error.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"name":"Orders"`) {
+		t.Errorf("Expected the collection to be listed, got '%s'", w.Body.String())
+	}
+	var count int64
+	database.DB.Model(&models.Environment{}).Where("id = ?", env.ID).Count(&count)
+	if count != 1 {
+		t.Fatal("Expected the environment to be kept without force")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path+"?force=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 with force, got %d: %s", w.Code, w.Body.String())
+	}
+
+	database.DB.Unscoped().Model(&models.Environment{}).Where("id = ?", env.ID).Count(&count)
+	if count != 0 {
+		t.Error("Expected the environment to be deleted")
+	}
+	database.DB.First(&collection, collection.ID)
+	if collection.EnvironmentID != nil {
+		t.Errorf("Expected the collection's environment to be cleared, got %d", *collection.EnvironmentID)
+	}
+
+	// Nothing links to an unused environment, so it goes without force
+	unused := models.Environment{Name: "unused", TeamID: &team.ID}
+	database.DB.Create(&unused)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/environments/"+strconv.Itoa(int(unused.ID)), nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected an unused environment to be deleted without force, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"postmanxodja/apierr"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntrospectGraphQL sends the introspection query to a GraphQL endpoint and
+// returns its schema's types and fields. The endpoint goes through the same
+// team defaults, variable substitution and host policy as ExecuteRequest.
+func IntrospectGraphQL(c *gin.Context) {
+	var req models.GraphQLIntrospectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	execReq := models.ExecuteRequest{
+		URL:             req.URL,
+		Headers:         req.Headers,
+		EnvironmentID:   req.EnvironmentID,
+		TeamID:          req.TeamID,
+		InlineVariables: req.InlineVariables,
+		Auth:            req.Auth,
+	}
+	teamID, ok := executionTeam(c, req.TeamID, req.EnvironmentID)
+	if !ok || !applyTeamDefaults(c, teamID, &execReq) {
+		return
+	}
+
+	variables := services.MergeVariables(loadEnvironmentVariables(teamID, req.EnvironmentID), req.InlineVariables)
+	services.ReplaceInRequest(&execReq, variables)
+	normalizedURL, err := services.NormalizeRequestURL(execReq.URL)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	execReq.URL = normalizedURL
+	if err := services.ValidateRequestHeaders(&execReq); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.ValidateRequestAuth(execReq.Auth); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	if !enforceHostPolicy(c, teamID, execReq.URL) {
+		return
+	}
+	if execReq.Auth != nil && execReq.Auth.OAuth2 != nil && !enforceHostPolicy(c, teamID, execReq.Auth.OAuth2.TokenURL) {
+		return
+	}
+
+	ctx, executionID, done, ok := beginExecution(c, req.ExecutionID, teamID)
+	if !ok {
+		return
+	}
+	defer done()
+	release, ok := acquireExecutionSlot(c, ctx, executionID, teamID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	schema, response, err := services.IntrospectGraphQL(ctx, &execReq)
+	switch {
+	case errors.Is(err, services.ErrIntrospectionDisabled):
+		apierr.RespondErrorWithDetails(c, http.StatusUnprocessableEntity, apierr.GraphQLIntrospectionDisabled,
+			"The endpoint doesn't allow introspection, so its schema can't be fetched: "+err.Error(),
+			gin.H{"status": response.Status})
+		return
+	case errors.Is(err, services.ErrGraphQLResponse):
+		apierr.RespondErrorWithDetails(c, http.StatusBadGateway, apierr.RequestFailed, err.Error(),
+			gin.H{"status": response.Status})
+		return
+	case err != nil:
+		respondExecutionError(c, executionID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"postmanxodja/apierr"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordHistory adds an execution to the user's history. Cancelled runs
+// aren't recorded, and a failure to record never fails the request.
+func recordHistory(c *gin.Context, teamID uint, method, url string, requestHeaders map[string]string,
+	response *models.ExecuteResponse, execErr error) {
+	userID := c.GetUint("user_id")
+	if userID == 0 || errors.Is(execErr, context.Canceled) {
+		return
+	}
+	entry := models.RequestHistory{
+		UserID:         userID,
+		Method:         strings.ToUpper(method),
+		URL:            url,
+		RequestHeaders: services.RedactHeaders(requestHeaders),
+	}
+	if entry.Method == "" {
+		entry.Method = http.MethodGet
+	}
+	if teamID != 0 {
+		entry.TeamID = &teamID
+	}
+	if response != nil {
+		entry.Status = response.Status
+		entry.Time = response.Time
+		entry.ResponseHeaders = services.RedactHeaders(response.Headers)
+	}
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+	if err := services.RecordRequestHistory(&entry); err != nil {
+		log.Printf("Failed to record request history: %v", err)
+	}
+}
+
+// GetRequestHistory lists the user's executed requests, newest first.
+// Filters: method (comma-separated), status_min, status_max, url (substring),
+// from and to (RFC 3339 times or YYYY-MM-DD dates; a date in to includes the
+// whole day). Pages continue from the previous page's next_cursor via cursor.
+func GetRequestHistory(c *gin.Context) {
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	page, err := services.ListRequestHistory(c.GetUint("user_id"), filter)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch request history")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ClearRequestHistory deletes all of the user's history
+func ClearRequestHistory(c *gin.Context) {
+	deleted, err := services.ClearRequestHistory(c.GetUint("user_id"))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to clear request history")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+func parseHistoryFilter(c *gin.Context) (models.HistoryFilter, error) {
+	var filter models.HistoryFilter
+	if methods := c.Query("method"); methods != "" {
+		filter.Methods = strings.Split(methods, ",")
+	}
+	filter.URL = c.Query("url")
+
+	ints := []struct {
+		name   string
+		target *int
+	}{
+		{"status_min", &filter.StatusMin},
+		{"status_max", &filter.StatusMax},
+		{"limit", &filter.Limit},
+	}
+	for _, param := range ints {
+		if value := c.Query(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return filter, fmt.Errorf("%s must be a non-negative number", param.name)
+			}
+			*param.target = n
+		}
+	}
+	if filter.StatusMin > 0 && filter.StatusMax > 0 && filter.StatusMin > filter.StatusMax {
+		return filter, fmt.Errorf("status_min must not be greater than status_max")
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		id, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor")
+		}
+		filter.Cursor = uint(id)
+	}
+
+	var err error
+	if filter.From, err = parseHistoryTime(c.Query("from"), false); err != nil {
+		return filter, fmt.Errorf("from: %v", err)
+	}
+	if filter.To, err = parseHistoryTime(c.Query("to"), true); err != nil {
+		return filter, fmt.Errorf("to: %v", err)
+	}
+	return filter, nil
+}
+
+// parseHistoryTime reads an RFC 3339 time or a date. With endOfDay a date
+// means the start of the next day, so filtering before it includes the date.
+func parseHistoryTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("expected an RFC 3339 time or YYYY-MM-DD date, got %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
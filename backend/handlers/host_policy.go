@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func toHostPolicyResponse(teamID uint, policy *models.TeamHostPolicy) models.HostPolicyResponse {
+	if policy == nil {
+		return models.HostPolicyResponse{TeamID: teamID, Allow: []string{}, Deny: []string{}}
+	}
+	return models.HostPolicyResponse{
+		TeamID:    teamID,
+		Allow:     policy.AllowList(),
+		Deny:      policy.DenyList(),
+		UpdatedAt: &policy.UpdatedAt,
+	}
+}
+
+// GetHostPolicy returns the team's host policy. Any member can read it, so
+// they know which hosts they can reach.
+func GetHostPolicy(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+
+	var policy models.TeamHostPolicy
+	if err := database.DB.Where("team_id = ?", teamID).First(&policy).Error; err != nil {
+		// No policy - every host is allowed
+		c.JSON(http.StatusOK, toHostPolicyResponse(teamID, nil))
+		return
+	}
+	c.JSON(http.StatusOK, toHostPolicyResponse(teamID, &policy))
+}
+
+// UpdateHostPolicy creates or replaces the team's host policy (owner only)
+func UpdateHostPolicy(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage the host policy")
+		return
+	}
+
+	var req models.HostPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	allow, err := services.NormalizeHostPatterns(req.Allow)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	deny, err := services.NormalizeHostPatterns(req.Deny)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	var policy models.TeamHostPolicy
+	database.DB.Where("team_id = ?", teamID).Limit(1).Find(&policy)
+	policy.TeamID = teamID
+	policy.Allow = strings.Join(allow, ",")
+	policy.Deny = strings.Join(deny, ",")
+	policy.UpdatedBy = userID
+	if err := database.DB.Save(&policy).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save host policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, toHostPolicyResponse(teamID, &policy))
+}
+
+// DeleteHostPolicy removes the team's host policy, allowing every host again
+func DeleteHostPolicy(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage the host policy")
+		return
+	}
+
+	result := database.DB.Where("team_id = ?", teamID).Delete(&models.TeamHostPolicy{})
+	if result.RowsAffected == 0 {
+		apierr.RespondError(c, http.StatusNotFound, apierr.HostPolicyNotFound, "Host policy not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Host policy deleted"})
+}
+
+// executionTeam picks the team whose host policy applies to an execution:
+// the team_id the client sent, otherwise the selected environment's team,
+// otherwise the user's only team. Every execution runs on behalf of a team,
+// so a user in several teams has to say which. When both are given, the
+// environment has to be one of that team's, so one team's variables never
+// run under another team's policy. It writes the error response itself and
+// returns ok=false when no team applies, the user isn't a member of the
+// given one or the environment isn't the team's.
+func executionTeam(c *gin.Context, teamID, environmentID *uint) (uint, bool) {
+	userID := c.GetUint("user_id")
+	if teamID != nil {
+		if !services.UserBelongsToTeam(userID, *teamID) {
+			apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Access denied to this team")
+			return 0, false
+		}
+		if environmentID != nil {
+			var count int64
+			database.GetDB().Model(&models.Environment{}).
+				Where("id = ? AND team_id = ?", *environmentID, *teamID).
+				Count(&count)
+			if count == 0 {
+				apierr.RespondError(c, http.StatusNotFound, apierr.EnvironmentNotFound, "Environment not found in this team")
+				return 0, false
+			}
+		}
+		return *teamID, true
+	}
+	if environmentID != nil {
+		var env models.Environment
+		if err := database.GetDB().
+			Joins("JOIN team_members ON team_members.team_id = environments.team_id AND team_members.user_id = ?", userID).
+			Where("environments.id = ?", *environmentID).
+			First(&env).Error; err == nil && env.TeamID != nil {
+			return *env.TeamID, true
+		}
+	}
+
+	var teamIDs []uint
+	if err := database.GetDB().Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ?", userID).
+		Limit(2).Pluck("team_members.team_id", &teamIDs).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to load teams")
+		return 0, false
+	}
+	if len(teamIDs) != 1 {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.TeamRequired, "team_id is required: requests run on behalf of one of your teams")
+		return 0, false
+	}
+	return teamIDs[0], true
+}
+
+// enforceHostPolicy responds 403 and returns false when the team's host
+// policy doesn't allow rawURL
+func enforceHostPolicy(c *gin.Context, teamID uint, rawURL string) bool {
+	err := services.CheckHostPolicy(teamID, rawURL)
+	switch {
+	case errors.Is(err, services.ErrHostNotAllowed):
+		apierr.RespondErrorWithDetails(c, http.StatusForbidden, apierr.HostNotAllowed,
+			"The team's host policy doesn't allow requests to this host",
+			gin.H{"host": services.RequestHost(rawURL), "team_id": teamID})
+		return false
+	case err != nil:
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to check host policy")
+		return false
+	}
+	return true
+}
@@ -3,8 +3,11 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -17,33 +20,39 @@ func CreateInvite(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can invite members"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can invite members")
 		return
 	}
 
 	// Check if this is a Personal team (cannot invite to personal teams)
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
 		return
 	}
-	if team.Name == "Personal" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot invite members to Personal workspace"})
+	if team.IsPersonal {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PersonalTeamForbidden, "Cannot invite members to Personal workspace")
 		return
 	}
 
 	var req models.InviteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	req.Email = email
 
 	// Check if user is already a member
 	var existingMember models.TeamMember
 	if result := database.DB.Joins("JOIN users ON users.id = team_members.user_id").
-		Where("team_members.team_id = ? AND users.email = ?", teamID, req.Email).
+		Where("team_members.team_id = ? AND LOWER(users.email) = ?", teamID, req.Email).
 		First(&existingMember); result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User is already a team member"})
+		apierr.RespondError(c, http.StatusConflict, apierr.AlreadyMember, "User is already a team member")
 		return
 	}
 
@@ -51,7 +60,7 @@ func CreateInvite(c *gin.Context) {
 	var existingInvite models.TeamInvite
 	if result := database.DB.Where("team_id = ? AND invitee_email = ? AND status = ?", teamID, req.Email, "pending").
 		First(&existingInvite); result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Invite already sent to this email"})
+		apierr.RespondError(c, http.StatusConflict, apierr.InviteAlreadySent, "Invite already sent to this email")
 		return
 	}
 
@@ -66,7 +75,7 @@ func CreateInvite(c *gin.Context) {
 	}
 
 	if err := database.DB.Create(&invite).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create invite")
 		return
 	}
 
@@ -74,10 +83,10 @@ func CreateInvite(c *gin.Context) {
 	database.DB.Preload("Team").Preload("Inviter").First(&invite, invite.ID)
 
 	// Send invite email
-	emailService := services.NewEmailService()
-	if emailService.IsConfigured() {
+	emailSender := services.NewEmailSender()
+	if emailSender.IsConfigured() {
 		go func() {
-			if err := emailService.SendTeamInviteEmail(
+			if err := emailSender.SendTeamInviteEmail(
 				invite.InviteeEmail,
 				invite.Inviter.Name,
 				invite.Team.Name,
@@ -96,11 +105,11 @@ func GetUserInvites(c *gin.Context) {
 
 	var invites []models.TeamInvite
 	result := database.DB.Preload("Team").Preload("Inviter").
-		Where("invitee_email = ? AND status = ? AND expires_at > ?", email, "pending", time.Now()).
+		Where("invitee_email = ? AND status = ? AND expires_at > ?", strings.ToLower(email), "pending", time.Now()).
 		Find(&invites)
 
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invites"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get invites")
 		return
 	}
 
@@ -114,19 +123,19 @@ func AcceptInvite(c *gin.Context) {
 
 	var invite models.TeamInvite
 	if result := database.DB.Where("token = ? AND status = ?", token, "pending").First(&invite); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or already used"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.InviteNotFound, "Invite not found or already used")
 		return
 	}
 
 	// Check if invite is for this user
-	if invite.InviteeEmail != email {
-		c.JSON(http.StatusForbidden, gin.H{"error": "This invite is not for your email"})
+	if !strings.EqualFold(invite.InviteeEmail, email) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.InviteEmailMismatch, "This invite is not for your email")
 		return
 	}
 
 	// Check if invite is expired
 	if invite.ExpiresAt.Before(time.Now()) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite has expired"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InviteExpired, "Invite has expired")
 		return
 	}
 
@@ -141,7 +150,7 @@ func AcceptInvite(c *gin.Context) {
 
 	if err := tx.Create(&member).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to team"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to add to team")
 		return
 	}
 
@@ -149,12 +158,14 @@ func AcceptInvite(c *gin.Context) {
 	invite.Status = "accepted"
 	if err := tx.Save(&invite).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invite"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update invite")
 		return
 	}
 
 	tx.Commit()
 
+	services.DispatchEvent(invite.TeamID, models.EventMemberJoined, gin.H{"user_id": userID, "role": member.Role})
+
 	// Get team details for response
 	var team models.Team
 	database.DB.First(&team, invite.TeamID)
@@ -168,13 +179,13 @@ func DeclineInvite(c *gin.Context) {
 
 	var invite models.TeamInvite
 	if result := database.DB.Where("token = ? AND status = ?", token, "pending").First(&invite); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or already used"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.InviteNotFound, "Invite not found or already used")
 		return
 	}
 
 	// Check if invite is for this user
-	if invite.InviteeEmail != email {
-		c.JSON(http.StatusForbidden, gin.H{"error": "This invite is not for your email"})
+	if !strings.EqualFold(invite.InviteeEmail, email) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.InviteEmailMismatch, "This invite is not for your email")
 		return
 	}
 
@@ -184,20 +195,52 @@ func DeclineInvite(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Invite declined"})
 }
 
+// GetTeamInvites lists the team's invites, newest first. ?status= is pending
+// (the default, expired ones included), accepted, declined, cancelled,
+// expired or all.
+// The body is the array of invites, limit (default 50) at a time; the
+// X-Total-Count header counts all matches and, when there's more, a Link
+// header points at the next page (?cursor=).
 func GetTeamInvites(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 
-	var invites []models.TeamInvite
-	result := database.DB.Preload("Inviter").
-		Where("team_id = ? AND status = ?", teamID, "pending").
-		Find(&invites)
+	filter := models.InviteFilter{Status: c.Query("status")}
+	if !services.IsValidInviteStatus(filter.Status) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid status. Must be: pending, accepted, declined, cancelled, expired, or all")
+		return
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "limit must be a non-negative number")
+			return
+		}
+		filter.Limit = n
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		id, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "invalid cursor")
+			return
+		}
+		filter.Cursor = uint(id)
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invites"})
+	page, err := services.ListTeamInvites(teamID, filter, time.Now())
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get invites")
 		return
 	}
 
-	c.JSON(http.StatusOK, invites)
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != nil {
+		next := *c.Request.URL
+		query := next.Query()
+		query.Set("cursor", strconv.FormatUint(uint64(*page.NextCursor), 10))
+		next.RawQuery = query.Encode()
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	c.JSON(http.StatusOK, page.Invites)
 }
 
 // GetInviteByToken returns invite details for public viewing (no auth required)
@@ -207,18 +250,18 @@ func GetInviteByToken(c *gin.Context) {
 	var invite models.TeamInvite
 	if result := database.DB.Preload("Team").Preload("Inviter").
 		Where("token = ?", token).First(&invite); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.InviteNotFound, "Invite not found")
 		return
 	}
 
 	// Check if invite is still valid
 	if invite.Status != "pending" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite has already been " + invite.Status})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InviteNotPending, "Invite has already been "+invite.Status)
 		return
 	}
 
 	if invite.ExpiresAt.Before(time.Now()) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite has expired"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InviteExpired, "Invite has expired")
 		return
 	}
 
@@ -239,19 +282,19 @@ func AcceptInvitePublic(c *gin.Context) {
 
 	var invite models.TeamInvite
 	if result := database.DB.Where("token = ? AND status = ?", token, "pending").First(&invite); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or already used"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.InviteNotFound, "Invite not found or already used")
 		return
 	}
 
 	// Check if invite is for this user
-	if invite.InviteeEmail != email {
-		c.JSON(http.StatusForbidden, gin.H{"error": "This invite is not for your email address"})
+	if !strings.EqualFold(invite.InviteeEmail, email) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.InviteEmailMismatch, "This invite is not for your email address")
 		return
 	}
 
 	// Check if invite is expired
 	if invite.ExpiresAt.Before(time.Now()) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite has expired"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InviteExpired, "Invite has expired")
 		return
 	}
 
@@ -280,7 +323,7 @@ func AcceptInvitePublic(c *gin.Context) {
 
 	if err := tx.Create(&member).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to team"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to add to team")
 		return
 	}
 
@@ -288,12 +331,14 @@ func AcceptInvitePublic(c *gin.Context) {
 	invite.Status = "accepted"
 	if err := tx.Save(&invite).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invite"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update invite")
 		return
 	}
 
 	tx.Commit()
 
+	services.DispatchEvent(invite.TeamID, models.EventMemberJoined, gin.H{"user_id": userID, "role": member.Role})
+
 	// Get team details for response
 	var team models.Team
 	database.DB.First(&team, invite.TeamID)
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestGetTeamInvitesArrayWithPagingHeaders(t *testing.T) {
+	useTestDB(t)
+	owner, team := createTestTeam(t, "owner@example.com")
+	invitee := models.User{Email: "ada@example.com", Name: "Ada"}
+	database.DB.Create(&invitee)
+	week := time.Now().Add(7 * 24 * time.Hour)
+	for i, email := range []string{"ada@example.com", "bob@example.com", "cy@example.com"} {
+		database.DB.Create(&models.TeamInvite{TeamID: team.ID, InviterID: owner.ID, InviteeEmail: email,
+			Status: "pending", Token: "invite-" + string(rune('a'+i)), ExpiresAt: week})
+	}
+
+	r := teamRouter(team.ID, owner.ID)
+	r.GET("/invites", GetTeamInvites)
+	list := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/invites"+query, nil))
+		return w
+	}
+
+	w := list("?limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var invites []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &invites); err != nil {
+		t.Fatalf("Expected a bare array of invites, got %s", w.Body.String())
+	}
+	if len(invites) != 2 || invites[0]["invitee_email"] != "cy@example.com" {
+		t.Errorf("Expected the two newest invites, got %v", invites)
+	}
+	if total := w.Header().Get("X-Total-Count"); total != "3" {
+		t.Errorf("Expected X-Total-Count 3, got '%s'", total)
+	}
+	link := w.Header().Get("Link")
+	if !strings.Contains(link, `rel="next"`) || !strings.Contains(link, "limit=2") {
+		t.Fatalf("Expected a Link to the next page, got '%s'", link)
+	}
+
+	next := link[strings.Index(link, "?"):strings.Index(link, ">")]
+	w = list(next)
+	invites = nil
+	json.Unmarshal(w.Body.Bytes(), &invites)
+	if len(invites) != 1 || invites[0]["invitee_email"] != "ada@example.com" || w.Header().Get("Link") != "" {
+		t.Fatalf("Expected the last page to hold only ada, got %s (Link '%s')", w.Body.String(), w.Header().Get("Link"))
+	}
+	if _, ok := invites[0]["invitee"]; ok || invites[0]["invitee_user_id"] != float64(invitee.ID) {
+		t.Errorf("Expected only the invitee's user ID, got %v", invites[0])
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"postmanxodja/apierr"
 	"postmanxodja/config"
 	"postmanxodja/database"
 	"postmanxodja/models"
@@ -153,7 +155,7 @@ func DesktopLogin(c *gin.Context) {
 // instead of FRONTEND_URL — used by the desktop app's loopback callback server.
 func GoogleLogin(c *gin.Context) {
 	if config.AppConfig.GoogleClientID == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google OAuth not configured"})
+		apierr.RespondError(c, http.StatusServiceUnavailable, apierr.Unavailable, "Google OAuth not configured")
 		return
 	}
 
@@ -207,15 +209,21 @@ func GoogleCallback(c *gin.Context) {
 		return
 	}
 
+	email, err := services.NormalizeEmail(userInfo.Email)
+	if err != nil {
+		redirectWithError(c, "Invalid email from Google", desktopPort)
+		return
+	}
+
 	// Find or create user
 	var user models.User
-	result := database.DB.Where("email = ?", userInfo.Email).First(&user)
+	result := database.DB.Where("LOWER(email) = ?", email).First(&user)
 
 	if result.Error != nil {
 		// Create new user (no password for OAuth users)
 		user = models.User{
-			Email:          userInfo.Email,
-			Name:           userInfo.Name,
+			Email:          email,
+			Name:           services.NormalizeName(userInfo.Name),
 			PasswordHash:   "",
 			GoogleID:       &userInfo.ID,
 			ProfilePicture: &userInfo.Picture,
@@ -227,8 +235,8 @@ func GoogleCallback(c *gin.Context) {
 		}
 
 		// Create personal team for new user
-		if _, err := services.CreateTeamWithOwner("Personal", user.ID); err != nil {
-			// Log but don't fail - user can create team later
+		if _, err := services.CreatePersonalTeam(user.ID); err != nil {
+			// Log but don't fail - it's retried on the next login
 			fmt.Println("Failed to create personal team:", err.Error())
 		}
 	} else {
@@ -238,6 +246,11 @@ func GoogleCallback(c *gin.Context) {
 			user.ProfilePicture = &userInfo.Picture
 			database.DB.Save(&user)
 		}
+
+		// Make up for a personal team that failed to be created earlier
+		if err := services.EnsureUserHasTeam(user.ID); err != nil {
+			log.Printf("Failed to ensure a team for user %d: %v", user.ID, err)
+		}
 	}
 
 	// Generate JWT tokens
@@ -247,6 +260,9 @@ func GoogleCallback(c *gin.Context) {
 		return
 	}
 
+	// Teams don't fit in the redirect like they do in the Login response; the
+	// client loads them from /auth/bootstrap after the callback
+
 	// Pick redirect target: desktop loopback or web frontend
 	target := config.AppConfig.FrontendURL + "/auth/callback"
 	if desktopPort > 0 {
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicOperation describes one /api/v1 endpoint for the OpenAPI document.
+// Keep this list in sync with the publicApi routes in main.go.
+type publicOperation struct {
+	Method      string
+	Path        string // OpenAPI style, e.g. /collections/{id}
+	Summary     string
+	Write       bool   // requires a write or read_write key
+	RequestBody string // schema name, empty when there's no body
+	Headers     []publicHeader
+	Responses   map[int]publicResponse
+}
+
+// publicHeader is a request header an operation reads, besides the
+// Idempotency-Key every write takes
+type publicHeader struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+type publicResponse struct {
+	Description string
+	Schema      string // schema name, empty for no body
+	ContentType string // defaults to application/json
+	IsArray     bool
+}
+
+var publicOperations = []publicOperation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/whoami",
+		Summary: "Describe the API key the request is made with",
+		Responses: map[int]publicResponse{
+			http.StatusOK: {Description: "The key's team, name, permissions and expiry", Schema: "APIKey"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/collections",
+		Summary: "List the team's collections",
+		Responses: map[int]publicResponse{
+			http.StatusOK: {Description: "Collections", Schema: "Collection", IsArray: true},
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/collections",
+		Summary:     "Create a collection, or update the one with the same name",
+		Write:       true,
+		RequestBody: "CreateCollectionRequest",
+		Responses: map[int]publicResponse{
+			http.StatusCreated:    {Description: "Collection created", Schema: "Collection"},
+			http.StatusOK:         {Description: "Existing collection with the same name updated", Schema: "CollectionUpdatedResponse"},
+			http.StatusBadRequest: {Description: "Invalid collection", Schema: "Error"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/collections/{id}",
+		Summary: "Get a collection with its parsed contents",
+		Responses: map[int]publicResponse{
+			http.StatusOK:          {Description: "Collection", Schema: "CollectionDetail"},
+			http.StatusNotModified: {Description: "Unchanged since the ETag sent in If-None-Match"},
+			http.StatusBadRequest:  {Description: "Invalid collection ID", Schema: "Error"},
+			http.StatusNotFound:    {Description: "Collection not found", Schema: "Error"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/collections/{id}/raw",
+		Summary: "Get a collection's raw Postman JSON",
+		Responses: map[int]publicResponse{
+			http.StatusOK:          {Description: "Postman collection v2.1 JSON", Schema: "PostmanCollection"},
+			http.StatusNotModified: {Description: "Unchanged since the ETag sent in If-None-Match"},
+			http.StatusBadRequest:  {Description: "Invalid collection ID", Schema: "Error"},
+			http.StatusNotFound:    {Description: "Collection not found", Schema: "Error"},
+		},
+	},
+	{
+		Method:      http.MethodPut,
+		Path:        "/collections/{id}",
+		Summary:     "Replace a collection's raw JSON",
+		Write:       true,
+		RequestBody: "UpdateCollectionRequest",
+		Responses: map[int]publicResponse{
+			http.StatusOK:         {Description: "Collection updated", Schema: "Collection"},
+			http.StatusBadRequest: {Description: "Invalid collection ID or collection JSON", Schema: "Error"},
+			http.StatusNotFound:   {Description: "Collection not found", Schema: "Error"},
+		},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/collections/{id}",
+		Summary: "Delete a collection",
+		Write:   true,
+		Headers: []publicHeader{
+			{Name: "X-Confirm-Delete", Description: "Must be true when the team requires deletes to be confirmed", Enum: []string{"true"}},
+		},
+		Responses: map[int]publicResponse{
+			http.StatusOK:                   {Description: "Collection deleted", Schema: "Message"},
+			http.StatusBadRequest:           {Description: "Invalid collection ID", Schema: "Error"},
+			http.StatusNotFound:             {Description: "Collection not found", Schema: "Error"},
+			http.StatusPreconditionRequired: {Description: "The team requires X-Confirm-Delete: true (DELETE_CONFIRMATION_REQUIRED)", Schema: "Error"},
+		},
+	},
+}
+
+// publicSchemas are the component schemas referenced by publicOperations
+var publicSchemas = map[string]interface{}{
+	"Error": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"code":    map[string]interface{}{"type": "string", "description": "Stable machine-readable code, e.g. COLLECTION_NOT_FOUND"},
+					"message": map[string]interface{}{"type": "string"},
+					"details": map[string]interface{}{"type": "object"},
+				},
+				"required": []string{"code", "message"},
+			},
+		},
+		"required": []string{"error"},
+	},
+	"Message": map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"message": map[string]interface{}{"type": "string"}},
+	},
+	"APIKey": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key_id":       map[string]interface{}{"type": "integer"},
+			"team_id":      map[string]interface{}{"type": "integer"},
+			"name":         map[string]interface{}{"type": "string"},
+			"key_prefix":   map[string]interface{}{"type": "string", "description": "First characters of the key, to tell keys apart"},
+			"permissions":  map[string]interface{}{"type": "string", "enum": []string{"read", "write", "read_write"}},
+			"can_write":    map[string]interface{}{"type": "boolean", "description": "Whether the key may call the write endpoints"},
+			"expires_at":   map[string]interface{}{"type": "string", "format": "date-time", "nullable": true},
+			"last_used_at": map[string]interface{}{"type": "string", "format": "date-time", "nullable": true},
+		},
+	},
+	"Collection": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":             map[string]interface{}{"type": "integer"},
+			"name":           map[string]interface{}{"type": "string"},
+			"description":    map[string]interface{}{"type": "string"},
+			"raw_json":       map[string]interface{}{"type": "string", "description": "Postman collection v2.1 JSON"},
+			"environment_id": map[string]interface{}{"type": "integer", "nullable": true},
+			"team_id":        map[string]interface{}{"type": "integer"},
+			"created_at":     map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+	},
+	"CollectionDetail": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":          map[string]interface{}{"type": "integer"},
+			"name":        map[string]interface{}{"type": "string"},
+			"description": map[string]interface{}{"type": "string"},
+			"team_id":     map[string]interface{}{"type": "integer"},
+			"created_at":  map[string]interface{}{"type": "string", "format": "date-time"},
+			"collection":  map[string]interface{}{"$ref": "#/components/schemas/PostmanCollection"},
+		},
+	},
+	"CollectionUpdatedResponse": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"message":    map[string]interface{}{"type": "string"},
+			"collection": map[string]interface{}{"$ref": "#/components/schemas/Collection"},
+		},
+	},
+	"PostmanCollection": map[string]interface{}{
+		"type":        "object",
+		"description": "Postman collection v2.1 (https://schema.getpostman.com/json/collection/v2.1.0/collection.json)",
+		"properties": map[string]interface{}{
+			"info":     map[string]interface{}{"type": "object"},
+			"item":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+			"variable": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+		},
+		"required": []string{"info", "item"},
+	},
+	"CreateCollectionRequest": map[string]interface{}{
+		"description": "Either a wrapper object or a Postman collection sent directly",
+		"oneOf": []interface{}{
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"raw_json":    map[string]interface{}{"type": "string"},
+					"name":        map[string]interface{}{"type": "string"},
+					"description": map[string]interface{}{"type": "string"},
+				},
+			},
+			map[string]interface{}{"$ref": "#/components/schemas/PostmanCollection"},
+		},
+	},
+	"UpdateCollectionRequest": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"raw_json": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"raw_json"},
+	},
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// BuildOpenAPISpec assembles the OpenAPI 3 document for the public API
+func BuildOpenAPISpec() map[string]interface{} {
+	paths := make(map[string]interface{})
+
+	for _, op := range publicOperations {
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		responses := make(map[string]interface{})
+		for status, resp := range op.Responses {
+			response := map[string]interface{}{"description": resp.Description}
+			if resp.Schema != "" {
+				schema := schemaRef(resp.Schema)
+				if resp.IsArray {
+					schema = map[string]interface{}{"type": "array", "items": schemaRef(resp.Schema)}
+				}
+				contentType := resp.ContentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				response["content"] = map[string]interface{}{
+					contentType: map[string]interface{}{"schema": schema},
+				}
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+
+		// Errors every endpoint can return from the API key middleware
+		responses["401"] = map[string]interface{}{
+			"description": "Missing, invalid or expired API key",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemaRef("Error")},
+			},
+		}
+		if op.Write {
+			responses["403"] = map[string]interface{}{
+				"description": "API key lacks write permission",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaRef("Error")},
+				},
+			}
+		}
+
+		operation := map[string]interface{}{
+			"summary":   op.Summary,
+			"responses": responses,
+		}
+		var parameters []interface{}
+		if strings.Contains(op.Path, "{id}") {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "integer"},
+			})
+		}
+		for _, header := range op.Headers {
+			schema := map[string]interface{}{"type": "string"}
+			if len(header.Enum) > 0 {
+				schema["enum"] = header.Enum
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name":        header.Name,
+				"in":          "header",
+				"required":    false,
+				"description": header.Description,
+				"schema":      schema,
+			})
+		}
+		if op.Write {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        "Idempotency-Key",
+				"in":          "header",
+				"required":    false,
+				"description": "Retries with the same key (and the same request) get the original response back instead of running again",
+				"schema":      map[string]interface{}{"type": "string", "maxLength": 255},
+			})
+			responses["409"] = map[string]interface{}{
+				"description": "A request with the same Idempotency-Key is still in progress",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaRef("Error")},
+				},
+			}
+			responses["422"] = map[string]interface{}{
+				"description": "Idempotency-Key was already used for a different request",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaRef("Error")},
+				},
+			}
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.RequestBody != "" {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaRef(op.RequestBody)},
+				},
+			}
+		}
+		if op.Write {
+			operation["description"] = "Requires an API key with write or read_write permission."
+		}
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "PostmanXodja Public API",
+			"version":     "1.0.0",
+			"description": "Third-party access to a team's collections. Authenticate with a team API key.",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": "/api/v1"},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"ApiKeyHeader": []string{}},
+			map[string]interface{}{"ApiKeyAuthorization": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"ApiKeyHeader": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+				"ApiKeyAuthorization": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "Send the key as \"ApiKey <key>\"",
+				},
+			},
+			"schemas": publicSchemas,
+		},
+	}
+}
+
+// PublicOpenAPISpec serves the OpenAPI document for /api/v1 (no auth required)
+func PublicOpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, BuildOpenAPISpec())
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserOrganizations lists the organizations the user belongs to
+func GetUserOrganizations(c *gin.Context) {
+	orgs, err := services.GetUserOrganizations(c.GetUint("user_id"))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get organizations")
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// CreateOrganization creates an organization with the caller as its admin
+func CreateOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	org, err := services.CreateOrganizationWithAdmin(req.Name, c.GetUint("user_id"))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create organization")
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// GetOrganizationTeams lists the organization's teams: all of them for org
+// admins, the ones they belong to for other members
+func GetOrganizationTeams(c *gin.Context) {
+	orgID := c.GetUint("organization_id")
+	isAdmin := c.GetString("organization_role") == services.OrganizationAdmin
+
+	teams, err := services.GetOrganizationTeams(orgID, c.GetUint("user_id"), isAdmin)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get organization teams")
+		return
+	}
+	c.JSON(http.StatusOK, teams)
+}
+
+// AddOrganizationTeam moves a team into the organization. The caller has to
+// be an org admin and the team's owner.
+func AddOrganizationTeam(c *gin.Context) {
+	orgID := c.GetUint("organization_id")
+	userID := c.GetUint("user_id")
+
+	if c.GetString("organization_role") != services.OrganizationAdmin {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only organization admins can add teams")
+		return
+	}
+
+	var req models.AddOrganizationTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, req.TeamID).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
+		return
+	}
+	if !services.IsTeamOwner(userID, team.ID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only the team owner can add the team to an organization")
+		return
+	}
+
+	if err := services.AddTeamToOrganization(orgID, &team); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPersonalTeam):
+			apierr.RespondError(c, http.StatusBadRequest, apierr.PersonalTeamForbidden, "Personal teams can't join an organization")
+		case errors.Is(err, services.ErrTeamInOtherOrganization):
+			apierr.RespondError(c, http.StatusConflict, apierr.TeamInOtherOrganization, "Team already belongs to another organization")
+		default:
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to add team")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+// RemoveOrganizationTeam takes a team out of the organization (org admins
+// and the team's owner)
+func RemoveOrganizationTeam(c *gin.Context) {
+	orgID := c.GetUint("organization_id")
+	userID := c.GetUint("user_id")
+
+	teamID, err := strconv.ParseUint(c.Param("team_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid team ID")
+		return
+	}
+	if c.GetString("organization_role") != services.OrganizationAdmin && !services.IsTeamOwner(userID, uint(teamID)) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only organization admins or the team owner can remove a team")
+		return
+	}
+
+	removed, err := services.RemoveTeamFromOrganization(orgID, uint(teamID))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to remove team")
+		return
+	}
+	if !removed {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found in this organization")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Team removed from organization"})
+}
+
+// AddOrganizationMember adds an existing user to the organization (org
+// admins only)
+func AddOrganizationMember(c *gin.Context) {
+	orgID := c.GetUint("organization_id")
+
+	if c.GetString("organization_role") != services.OrganizationAdmin {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only organization admins can add members")
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = services.OrganizationMember
+	}
+	if req.Role != services.OrganizationAdmin && req.Role != services.OrganizationMember {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid role. Must be: admin or member")
+		return
+	}
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.UserNotFound, "No account with this email")
+		return
+	}
+	if services.GetOrganizationRole(user.ID, orgID) != "" {
+		apierr.RespondError(c, http.StatusConflict, apierr.AlreadyMember, "User is already an organization member")
+		return
+	}
+
+	member := models.OrganizationMember{OrganizationID: orgID, UserID: user.ID, Role: req.Role}
+	if err := database.DB.Create(&member).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to add member")
+		return
+	}
+	member.User = &user
+	c.JSON(http.StatusCreated, member)
+}
+
+// RemoveOrganizationMember takes a user out of the organization. Org admins
+// can remove anyone, other members only themselves.
+func RemoveOrganizationMember(c *gin.Context) {
+	orgID := c.GetUint("organization_id")
+
+	memberUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid user ID")
+		return
+	}
+	if c.GetString("organization_role") != services.OrganizationAdmin && uint(memberUserID) != c.GetUint("user_id") {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only organization admins can remove other members")
+		return
+	}
+
+	removed, err := services.RemoveOrganizationMember(orgID, uint(memberUserID))
+	if errors.Is(err, services.ErrLastOrganizationAdmin) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.LastOrganizationAdmin, "Cannot remove the organization's last admin")
+		return
+	}
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to remove member")
+		return
+	}
+	if !removed {
+		apierr.RespondError(c, http.StatusNotFound, apierr.MemberNotFound, "Member not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed from organization"})
+}
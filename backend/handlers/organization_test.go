@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/middleware"
+	"postmanxodja/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// organizationRouter serves the organization routes as userID
+func organizationRouter(userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	r.POST("/organizations", CreateOrganization)
+	org := r.Group("/organizations/:org_id")
+	org.Use(middleware.OrganizationAccessMiddleware())
+	org.GET("/teams", GetOrganizationTeams)
+	org.POST("/teams", AddOrganizationTeam)
+	org.POST("/members", AddOrganizationMember)
+	org.DELETE("/members/:user_id", RemoveOrganizationMember)
+	return r
+}
+
+func serveJSON(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOrganizationTeams(t *testing.T) {
+	useTestDB(t)
+	admin, team := createTestTeam(t, "admin@example.com")
+	member, memberTeam := createTestTeam(t, "member@example.com")
+	asAdmin := organizationRouter(admin.ID)
+	asMember := organizationRouter(member.ID)
+
+	w := serveJSON(asAdmin, http.MethodPost, "/organizations", `{"name":"Acme"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var org models.Organization
+	json.Unmarshal(w.Body.Bytes(), &org)
+	base := "/organizations/" + strconv.FormatUint(uint64(org.ID), 10)
+
+	// Outsiders don't even learn the organization exists
+	if w := serveJSON(asMember, http.MethodGet, base+"/teams", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a non-member, got %d", w.Code)
+	}
+
+	if w := serveJSON(asAdmin, http.MethodPost, base+"/members", `{"email":"MEMBER@example.com"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveJSON(asAdmin, http.MethodPost, base+"/members", `{"email":"member@example.com"}`); w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for an existing member, got %d", w.Code)
+	}
+
+	// The admin can only bring in teams they own
+	body := `{"team_id":` + strconv.FormatUint(uint64(memberTeam.ID), 10) + `}`
+	if w := serveJSON(asAdmin, http.MethodPost, base+"/teams", body); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for someone else's team, got %d", w.Code)
+	}
+	// and members can't add teams at all
+	if w := serveJSON(asMember, http.MethodPost, base+"/teams", body); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a non-admin, got %d", w.Code)
+	}
+
+	body = `{"team_id":` + strconv.FormatUint(uint64(team.ID), 10) + `}`
+	if w := serveJSON(asAdmin, http.MethodPost, base+"/teams", body); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	other, _ := createTestTeam(t, "other@example.com")
+	w = serveJSON(organizationRouter(other.ID), http.MethodPost, "/organizations", `{"name":"Other"}`)
+	var otherOrg models.Organization
+	json.Unmarshal(w.Body.Bytes(), &otherOrg)
+	database.DB.Create(&models.OrganizationMember{OrganizationID: otherOrg.ID, UserID: admin.ID, Role: "admin"})
+	w = serveJSON(asAdmin, http.MethodPost, "/organizations/"+strconv.FormatUint(uint64(otherOrg.ID), 10)+"/teams", body)
+	if w.Code != http.StatusConflict || decodeError(t, w).Error.Code != apierr.TeamInOtherOrganization {
+		t.Fatalf("Expected TEAM_IN_OTHER_ORGANIZATION, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var teams []models.TeamResponse
+	w = serveJSON(asAdmin, http.MethodGet, base+"/teams", "")
+	json.Unmarshal(w.Body.Bytes(), &teams)
+	if len(teams) != 1 || teams[0].ID != team.ID {
+		t.Fatalf("Expected the admin to see the team, got %s", w.Body.String())
+	}
+
+	// The member isn't in the team, so they don't see it
+	w = serveJSON(asMember, http.MethodGet, base+"/teams", "")
+	teams = nil
+	json.Unmarshal(w.Body.Bytes(), &teams)
+	if w.Code != http.StatusOK || len(teams) != 0 {
+		t.Fatalf("Expected the member to see no teams, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRemoveOrganizationMember(t *testing.T) {
+	useTestDB(t)
+	admin, _ := createTestTeam(t, "admin@example.com")
+	member, _ := createTestTeam(t, "member@example.com")
+	other, _ := createTestTeam(t, "other@example.com")
+	asAdmin, asMember := organizationRouter(admin.ID), organizationRouter(member.ID)
+
+	w := serveJSON(asAdmin, http.MethodPost, "/organizations", `{"name":"Acme"}`)
+	var org models.Organization
+	json.Unmarshal(w.Body.Bytes(), &org)
+	base := "/organizations/" + strconv.FormatUint(uint64(org.ID), 10) + "/members"
+	for _, email := range []string{"member@example.com", "other@example.com"} {
+		if w := serveJSON(asAdmin, http.MethodPost, base, `{"email":"`+email+`"}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected %s to be added, got %d: %s", email, w.Code, w.Body.String())
+		}
+	}
+	memberPath := func(user models.User) string {
+		return base + "/" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+
+	// Members can't remove anyone but themselves
+	if w := serveJSON(asMember, http.MethodDelete, memberPath(other), ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a member removing someone else, got %d", w.Code)
+	}
+	if w := serveJSON(asMember, http.MethodDelete, memberPath(member), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected a member to leave, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveJSON(asAdmin, http.MethodDelete, memberPath(other), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the admin to remove a member, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveJSON(asAdmin, http.MethodDelete, memberPath(other), ""); w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != apierr.MemberNotFound {
+		t.Errorf("Expected 404 for someone no longer in the organization, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveJSON(asAdmin, http.MethodDelete, memberPath(admin), ""); w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != apierr.LastOrganizationAdmin {
+		t.Errorf("Expected the last admin to stay, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var members int64
+	database.DB.Model(&models.OrganizationMember{}).Where("organization_id = ?", org.ID).Count(&members)
+	if members != 1 {
+		t.Errorf("Expected only the admin left, got %d members", members)
+	}
+	// They can be added back after leaving
+	if w := serveJSON(asAdmin, http.MethodPost, base, `{"email":"member@example.com"}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected a removed member to be added again, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
-	"net/url"
+	"postmanxodja/apierr"
+	"postmanxodja/config"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -24,43 +28,344 @@ func ExecuteRequest(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	if !services.IsValidQueryMergePolicy(req.QueryMergePolicy) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid query_merge_policy. Must be: add, override, or append")
+		return
+	}
+	if !services.IsValidBodyTransfer(req.BodyTransfer) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid body_transfer. Must be: content-length or chunked")
 		return
 	}
 
 	log.Printf("Executing request: %s %s", req.Method, req.URL)
 
-	// Get environment variables if environment ID is provided
-	var variables models.Variables
-	if req.EnvironmentID != nil {
-		var env models.Environment
-		if err := database.GetDB().First(&env, *req.EnvironmentID).Error; err == nil {
-			variables = env.Variables
-			log.Printf("Loaded %d variables from environment: %s", len(variables), env.Name)
-		} else {
-			log.Printf("Failed to load environment ID %d: %v", *req.EnvironmentID, err)
-		}
+	// The team's default headers go in first so {{placeholders}} in them
+	// resolve too; the request's own headers win
+	teamID, ok := executionTeam(c, req.TeamID, req.EnvironmentID)
+	if !ok || !applyTeamDefaults(c, teamID, &req) {
+		return
 	}
 
+	// Get environment variables if environment ID is provided; inline
+	// variables take precedence over them
+	variables := services.MergeVariables(loadEnvironmentVariables(teamID, req.EnvironmentID), req.InlineVariables)
+
 	// Replace variables in request
-	if len(variables) > 0 {
-		log.Printf("Replacing variables in request. URL before: %s", req.URL)
-		services.ReplaceInRequest(&req, variables)
-		log.Printf("URL after variable replacement: %s", req.URL)
+	log.Printf("Replacing variables in request. URL before: %s", req.URL)
+	unresolved := services.ReplaceInRequest(&req, variables)
+	normalizedURL, err := services.NormalizeRequestURL(req.URL)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	req.URL = normalizedURL
+
+	if err := services.ApplyFormData(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.PrepareJSONBody(&req); err != nil {
+		respondJSONBodyError(c, err)
+		return
+	}
+	if _, err := services.RequestBody(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.ValidateRequestHeaders(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.ValidateRequestAuth(req.Auth); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if req.PreviewBytes < 0 {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "preview_bytes must not be negative")
+		return
+	}
+	if err := services.ValidateNDJSON(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.ValidateCacheTTL(req.CacheTTLMs); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	if !enforceHostPolicy(c, teamID, req.URL) {
+		return
+	}
+	if req.Auth != nil && req.Auth.OAuth2 != nil && !enforceHostPolicy(c, teamID, req.Auth.OAuth2.TokenURL) {
+		return
 	}
 
+	ctx, executionID, done, ok := beginExecution(c, req.ExecutionID, teamID)
+	if !ok {
+		return
+	}
+	defer done()
+	release, ok := acquireExecutionSlot(c, ctx, executionID, teamID)
+	if !ok {
+		return
+	}
+	defer release()
+
 	// Execute the request
-	response, err := services.ExecuteHTTPRequest(&req)
-	log.Default().Print(response, "heeeeeereee reponse")
+	response, err := services.ExecuteWithETags(ctx, &req, c.GetUint("user_id"))
+	if response != nil {
+		// The URL is left out: after substitution it can carry credentials
+		log.Printf("Response %d for %s request, headers %v", response.Status, req.Method, services.RedactHeaders(response.Headers))
+	}
+	recordHistory(c, teamID, req.Method, req.URL, services.RequestHeaders(&req), response, err)
 	if err != nil {
 		log.Printf("Request execution failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondExecutionError(c, executionID, err)
 		return
 	}
 
+	response.UnresolvedVariables = unresolved
+	response.ExecutionID = executionID
+	services.AttachResponseArtifact(c.GetUint("user_id"), response, time.Now())
 	c.JSON(http.StatusOK, response)
 }
 
+// respondJSONBodyError reports a body PrepareJSONBody refused, with where
+// parsing failed when the body isn't valid JSON
+func respondJSONBodyError(c *gin.Context, err error) {
+	var bodyErr *services.JSONBodyError
+	if errors.As(err, &bodyErr) {
+		apierr.RespondErrorWithDetails(c, http.StatusBadRequest, apierr.InvalidJSONBody, err.Error(),
+			gin.H{"line": bodyErr.Line, "column": bodyErr.Column, "offset": bodyErr.Offset})
+		return
+	}
+	apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+}
+
+// statusClientClosedRequest is returned to the caller of a cancelled execution
+const statusClientClosedRequest = 499
+
+// beginExecution registers a cancelable execution for the current user on
+// behalf of teamID, whose host policy the context carries for redirects and
+// dialing. It writes the error response itself and returns ok=false when
+// the requested ID is taken.
+func beginExecution(c *gin.Context, executionID string, teamID uint) (context.Context, string, func(), bool) {
+	if executionID == "" {
+		executionID = services.NewExecutionID()
+	}
+
+	ctx, done, err := services.StartExecution(services.WithHostPolicy(c.Request.Context(), teamID), executionID, c.GetUint("user_id"))
+	if err != nil {
+		apierr.RespondError(c, http.StatusConflict, apierr.InvalidRequest, err.Error())
+		return nil, "", nil, false
+	}
+	return ctx, executionID, done, true
+}
+
+// acquireExecutionSlot waits for the concurrency limiter to let one of
+// teamID's executions run. It writes the error response itself and returns
+// ok=false when no slot frees up in time or the execution is cancelled.
+func acquireExecutionSlot(c *gin.Context, ctx context.Context, executionID string, teamID uint) (func(), bool) {
+	release, err := services.AcquireExecutionSlot(ctx, teamID)
+	if err != nil {
+		respondExecutionError(c, executionID, err)
+		return nil, false
+	}
+	return release, true
+}
+
+// respondExecutionError reports a failed execution, distinguishing a user
+// cancellation from other failures
+func respondExecutionError(c *gin.Context, executionID string, err error) {
+	if errors.Is(err, context.Canceled) {
+		apierr.RespondErrorWithDetails(c, statusClientClosedRequest, apierr.RequestCancelled, "Request was cancelled", gin.H{"execution_id": executionID})
+		return
+	}
+	if errors.Is(err, services.ErrOAuth2TokenFailed) {
+		apierr.RespondError(c, http.StatusBadGateway, apierr.OAuth2TokenFailed, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrHostNotAllowed) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.HostNotAllowed, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrExecutionQueueTimeout) {
+		apierr.RespondError(c, http.StatusServiceUnavailable, apierr.Unavailable, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrInvalidHeader) || errors.Is(err, services.ErrInvalidURL) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, err.Error())
+}
+
+// FetchOAuth2Token performs an OAuth2 token request for the client, with
+// environment variables substituted, and returns the token. Tokens are
+// cached per environment until they expire, and the executor's oauth2 auth
+// type uses the same cache.
+func FetchOAuth2Token(c *gin.Context) {
+	var req models.OAuth2TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	teamID, ok := executionTeam(c, nil, req.EnvironmentID)
+	if !ok {
+		return
+	}
+	variables := services.MergeVariables(loadEnvironmentVariables(teamID, req.EnvironmentID), req.InlineVariables)
+	services.ReplaceInOAuth2Config(&req.OAuth2Config, services.NewVariableReplacer(variables))
+	if err := services.ValidateOAuth2Config(&req.OAuth2Config); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if !enforceHostPolicy(c, teamID, req.TokenURL) {
+		return
+	}
+
+	var environmentID uint
+	if req.EnvironmentID != nil {
+		environmentID = *req.EnvironmentID
+	}
+	token, err := services.FetchOAuth2Token(services.WithHostPolicy(c.Request.Context(), teamID), &req.OAuth2Config, environmentID, req.ForceRefresh)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadGateway, apierr.OAuth2TokenFailed, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// CancelExecution aborts one of the current user's running executions
+func CancelExecution(c *gin.Context) {
+	executionID := c.Param("execution_id")
+	if !services.CancelExecution(executionID, c.GetUint("user_id")) {
+		apierr.RespondError(c, http.StatusNotFound, apierr.ExecutionNotFound, "Execution not found or already finished")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"execution_id": executionID, "status": "cancelled"})
+}
+
+// ValidateRequest resolves a request exactly like ExecuteRequest (environment,
+// variable substitution, URL building) and returns it without sending it
+func ValidateRequest(c *gin.Context) {
+	var req models.ExecuteRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	// Environment variables are looked up for the execution's team; without
+	// a team or an environment there are none to load
+	var variables models.Variables
+	if req.TeamID != nil || req.EnvironmentID != nil {
+		teamID, ok := executionTeam(c, req.TeamID, req.EnvironmentID)
+		if !ok {
+			return
+		}
+		variables = loadEnvironmentVariables(teamID, req.EnvironmentID)
+	}
+	variables = services.MergeVariables(variables, req.InlineVariables)
+	unresolved := services.ReplaceInRequest(&req, variables)
+	if err := services.ApplyFormData(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if err := services.PrepareJSONBody(&req); err != nil {
+		respondJSONBodyError(c, err)
+		return
+	}
+
+	httpReq, err := services.BuildHTTPRequest(&req)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	var host string
+	if httpReq.Host != httpReq.URL.Host {
+		host = httpReq.Host
+	}
+	c.JSON(http.StatusOK, models.ResolvedRequest{
+		Method:              httpReq.Method,
+		URL:                 httpReq.URL.String(),
+		Headers:             services.FlattenHeaders(httpReq.Header),
+		Body:                req.Body,
+		UnresolvedVariables: unresolved,
+		Host:                host,
+	})
+}
+
+// ExtractFromBody evaluates a path expression against a JSON body and returns
+// the matched value, so clients can pick values out of large responses
+func ExtractFromBody(c *gin.Context) {
+	var req models.ExtractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	result, err := services.ExtractJSONPath(req.Body, req.Path)
+	switch {
+	case errors.Is(err, services.ErrInvalidPath):
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidPath, err.Error())
+		return
+	case errors.Is(err, services.ErrBodyNotJSON):
+		apierr.RespondError(c, http.StatusUnprocessableEntity, apierr.BodyNotJSON, "Body is not valid JSON, so paths can't be extracted from it")
+		return
+	case err != nil:
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DownloadResponseArtifact serves the binary body of a recent execution (see
+// ExecuteResponse.ArtifactID) as a file, with the upstream's Content-Type and
+// the filename it gave in Content-Disposition
+func DownloadResponseArtifact(c *gin.Context) {
+	artifact, ok := services.GetResponseArtifact(c.Param("artifact_id"), c.GetUint("user_id"), time.Now())
+	if !ok {
+		apierr.RespondError(c, http.StatusNotFound, apierr.ArtifactNotFound, "Response artifact not found or expired")
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, artifact.ContentType, artifact.Body)
+}
+
+// loadEnvironmentVariables returns the variables of the given environment, or
+// nil when no environment is selected or it can't be loaded. Only the
+// environments of the team the execution runs for (see executionTeam) are
+// visible, so another team's environment ID behaves exactly like one that
+// doesn't exist.
+func loadEnvironmentVariables(teamID uint, environmentID *uint) models.Variables {
+	if environmentID == nil {
+		return nil
+	}
+
+	var env models.Environment
+	if err := database.GetDB().
+		Where("id = ? AND team_id = ?", *environmentID, teamID).
+		First(&env).Error; err != nil {
+		log.Printf("Failed to load environment ID %d: %v", *environmentID, err)
+		return nil
+	}
+
+	log.Printf("Loaded %d variables from environment: %s", len(env.Variables), env.Name)
+	return env.Variables
+}
+
 // RequestMeta represents the metadata sent with multipart requests
 type RequestMeta struct {
 	Method        string            `json:"method"`
@@ -68,7 +373,117 @@ type RequestMeta struct {
 	Headers       map[string]string `json:"headers"`
 	QueryParams   map[string]string `json:"query_params"`
 	EnvironmentID *uint             `json:"environment_id"`
+	TeamID        *uint             `json:"team_id"`
 	BodyType      string            `json:"body_type"`
+	// QueryMergePolicy, InlineVariables and ExecutionID work as in
+	// models.ExecuteRequest
+	QueryMergePolicy string            `json:"query_merge_policy"`
+	InlineVariables  map[string]string `json:"inline_variables"`
+	ExecutionID      string            `json:"execution_id"`
+	// SavedTabID replays the text fields saved with that tab's form; fields
+	// sent with the request win over saved ones with the same key
+	SavedTabID *uint `json:"saved_tab_id"`
+	// IncludeTLSInfo works as in models.ExecuteRequest
+	IncludeTLSInfo bool `json:"include_tls_info"`
+	// BodyTransfer is content-length to buffer the streamed body and send
+	// its length, for upstreams that reject chunked uploads, or chunked;
+	// empty streams it chunked
+	BodyTransfer string `json:"body_transfer"`
+	// Host works as in models.ExecuteRequest
+	Host string `json:"host"`
+}
+
+// formItem is one field of the outgoing multipart body
+type formItem struct {
+	key      string
+	value    string
+	isFile   bool
+	file     multipart.File
+	filename string
+}
+
+// closeFormFiles closes the uploads still open in items. Safe to call twice.
+func closeFormFiles(items []formItem) {
+	for i := range items {
+		if items[i].file != nil {
+			items[i].file.Close()
+			items[i].file = nil
+		}
+	}
+}
+
+// writeMultipartBody writes items to writer and closes it. Each upload is
+// closed once copied, and all remaining ones are closed if writing fails.
+func writeMultipartBody(writer *multipart.Writer, items []formItem) error {
+	defer closeFormFiles(items)
+
+	for i := range items {
+		item := &items[i]
+		if !item.isFile {
+			if err := writer.WriteField(item.key, item.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		part, err := writer.CreateFormFile(item.key, item.filename)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, item.file); err != nil {
+			return err
+		}
+		item.file.Close()
+		item.file = nil
+	}
+	return writer.Close()
+}
+
+// byteCounter counts what passes through to the wrapped writer
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (b *byteCounter) Write(p []byte) (int, error) {
+	n, err := b.w.Write(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// multipartResult is what the body writer reports once it's finished
+type multipartResult struct {
+	bytes int64
+	err   error
+}
+
+// streamMultipartBody writes items through a pipe in its own goroutine so the
+// body goes to the upstream as it's produced instead of being buffered. The
+// goroutine owns (and closes) the files from here on. The returned channel
+// receives exactly one result, after the body is fully written or the reader
+// side is closed.
+func streamMultipartBody(items []formItem) (*io.PipeReader, string, <-chan multipartResult) {
+	pr, pw := io.Pipe()
+	counter := &byteCounter{w: pw}
+	writer := multipart.NewWriter(counter)
+	results := make(chan multipartResult, 1)
+
+	go func() {
+		err := writeMultipartBody(writer, items)
+		pw.CloseWithError(err)
+		results <- multipartResult{bytes: counter.n, err: err}
+	}()
+
+	return pr, writer.FormDataContentType(), results
+}
+
+// bodyFailed reports whether the multipart writer failed on its own, rather
+// than because we (or the transport, with cause) closed the pipe under it
+func bodyFailed(writeErr, cause error) bool {
+	if writeErr == nil || errors.Is(writeErr, io.ErrClosedPipe) {
+		return false
+	}
+	return cause == nil || !errors.Is(writeErr, cause)
 }
 
 // ExecuteMultipartRequest handles multipart form-data requests with file uploads
@@ -76,79 +491,84 @@ func ExecuteMultipartRequest(c *gin.Context) {
 	// Parse multipart form (32 MB max memory)
 	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
 		log.Printf("Failed to parse multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse multipart form: " + err.Error()})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Failed to parse multipart form: "+err.Error())
 		return
 	}
 
 	// Get request metadata
 	metaJSON := c.Request.FormValue("_request_meta")
 	if metaJSON == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "_request_meta is required"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "_request_meta is required")
 		return
 	}
 
 	var meta RequestMeta
 	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
 		log.Printf("Failed to parse request meta: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid _request_meta JSON: " + err.Error()})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid _request_meta JSON: "+err.Error())
+		return
+	}
+
+	if !services.IsValidQueryMergePolicy(meta.QueryMergePolicy) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid query_merge_policy. Must be: add, override, or append")
+		return
+	}
+	if !services.IsValidBodyTransfer(meta.BodyTransfer) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid body_transfer. Must be: content-length or chunked")
 		return
 	}
 
 	log.Printf("Executing multipart request: %s %s", meta.Method, meta.URL)
 
-	// Get environment variables if environment ID is provided
-	var variables models.Variables
-	if meta.EnvironmentID != nil {
-		var env models.Environment
-		if err := database.GetDB().First(&env, *meta.EnvironmentID).Error; err == nil {
-			variables = env.Variables
-			log.Printf("Loaded %d variables from environment: %s", len(variables), env.Name)
-		}
+	teamID, ok := executionTeam(c, meta.TeamID, meta.EnvironmentID)
+	if !ok {
+		return
 	}
 
+	// Get environment variables if environment ID is provided
+	variables := services.MergeVariables(loadEnvironmentVariables(teamID, meta.EnvironmentID), meta.InlineVariables)
+	replacer := services.NewVariableReplacer(variables)
+
 	// Replace variables in URL
-	targetURL := meta.URL
-	if len(variables) > 0 {
-		targetURL = services.ReplaceVariables(targetURL, variables)
+	targetURL, err := services.NormalizeRequestURL(replacer.Replace(meta.URL))
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	if !enforceHostPolicy(c, teamID, targetURL) {
+		return
+	}
+	defaults, err := services.TeamDefaultHeaders(teamID)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to load request defaults")
+		return
 	}
+	meta.Headers = services.MergeDefaultHeaders(meta.Headers, defaults)
 
 	// Rewrite localhost URLs when running inside Docker
 	targetURL = services.RewriteLocalhostURL(targetURL)
 
 	// Build URL with query parameters
 	if len(meta.QueryParams) > 0 {
-		parsedURL, err := url.Parse(targetURL)
-		if err == nil {
-			existingParams := parsedURL.Query()
-			for key, value := range meta.QueryParams {
-				replacedValue := value
-				if len(variables) > 0 {
-					replacedValue = services.ReplaceVariables(value, variables)
-				}
-				if existingParams.Get(key) == "" {
-					existingParams.Add(key, replacedValue)
-				}
-			}
-			parsedURL.RawQuery = existingParams.Encode()
-			targetURL = parsedURL.String()
+		queryParams := make(map[string]string, len(meta.QueryParams))
+		for key, value := range meta.QueryParams {
+			queryParams[key] = replacer.Replace(value)
 		}
+		targetURL = services.MergeQueryParams(targetURL, queryParams, meta.QueryMergePolicy)
 	}
 
 	// Collect form data items from the incoming request
-	type formItem struct {
-		key      string
-		value    string
-		isFile   bool
-		file     multipart.File
-		filename string
-	}
-
 	var formItems []formItem
 	fileRegex := regexp.MustCompile(`^file_(\d+)$`)
 	textKeyRegex := regexp.MustCompile(`^text_(\d+)_key$`)
 
+	// Every upload opened below is closed on all return paths
+	defer func() { closeFormFiles(formItems) }()
+
 	// Process files
 	if c.Request.MultipartForm != nil && c.Request.MultipartForm.File != nil {
+		var fileCount int
+		var totalBytes int64
 		for fieldName, fileHeaders := range c.Request.MultipartForm.File {
 			matches := fileRegex.FindStringSubmatch(fieldName)
 			if matches != nil && len(fileHeaders) > 0 {
@@ -159,6 +579,19 @@ func ExecuteMultipartRequest(c *gin.Context) {
 					key = fieldName
 				}
 
+				fileCount++
+				totalBytes += fileHeaders[0].Size
+				if fileCount > config.AppConfig.MaxUploadFiles {
+					apierr.RespondError(c, http.StatusRequestEntityTooLarge, apierr.UploadTooLarge,
+						fmt.Sprintf("Too many files: at most %d can be uploaded", config.AppConfig.MaxUploadFiles))
+					return
+				}
+				if totalBytes > config.AppConfig.MaxUploadTotalBytes {
+					apierr.RespondError(c, http.StatusRequestEntityTooLarge, apierr.UploadTooLarge,
+						fmt.Sprintf("Uploaded files exceed the %d byte limit", config.AppConfig.MaxUploadTotalBytes))
+					return
+				}
+
 				file, err := fileHeaders[0].Open()
 				if err != nil {
 					log.Printf("Failed to open uploaded file: %v", err)
@@ -184,10 +617,8 @@ func ExecuteMultipartRequest(c *gin.Context) {
 				key := c.Request.FormValue("text_" + index + "_key")
 				value := c.Request.FormValue("text_" + index + "_value")
 
-				if len(variables) > 0 {
-					key = services.ReplaceVariables(key, variables)
-					value = services.ReplaceVariables(value, variables)
-				}
+				key = replacer.Replace(key)
+				value = replacer.Replace(value)
 
 				formItems = append(formItems, formItem{
 					key:    key,
@@ -198,55 +629,110 @@ func ExecuteMultipartRequest(c *gin.Context) {
 		}
 	}
 
-	startTime := time.Now()
-
-	// Build the outgoing multipart request
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
+	// Add the saved form's text fields that weren't sent again
+	if meta.SavedTabID != nil {
+		var tab models.SavedTab
+		if err := database.DB.Where("id = ? AND user_id = ?", *meta.SavedTabID, c.GetUint("user_id")).First(&tab).Error; err != nil {
+			apierr.RespondError(c, http.StatusNotFound, apierr.TabNotFound, "Saved tab not found")
+			return
+		}
 
-	for _, item := range formItems {
-		if item.isFile {
-			part, err := writer.CreateFormFile(item.key, item.filename)
-			if err != nil {
-				log.Printf("Failed to create form file: %v", err)
+		sent := make(map[string]bool, len(formItems))
+		for _, item := range formItems {
+			sent[item.key] = true
+		}
+		for _, field := range services.SavedTextFields(services.DecodeFormFields(tab.FormFields)) {
+			key := replacer.Replace(field.Key)
+			if sent[key] {
 				continue
 			}
-			io.Copy(part, item.file)
-			item.file.Close()
-		} else {
-			writer.WriteField(item.key, item.value)
+			formItems = append(formItems, formItem{
+				key:   key,
+				value: replacer.Replace(field.Value),
+			})
+		}
+	}
+
+	for key, value := range meta.Headers {
+		if err := services.ValidateHeader(key, replacer.Replace(value)); err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+			return
 		}
 	}
-	writer.Close()
+
+	ctx, executionID, done, ok := beginExecution(c, meta.ExecutionID, teamID)
+	if !ok {
+		return
+	}
+	defer done()
+	release, ok := acquireExecutionSlot(c, ctx, executionID, teamID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	startTime := time.Now()
+
+	// Stream the outgoing multipart body instead of building it in memory
+	requestBody, contentType, bodyResult := streamMultipartBody(formItems)
+	formItems = nil // the body writer closes the files now
+	defer requestBody.Close()
 
 	// Create the HTTP request
-	httpReq, err := http.NewRequest(meta.Method, targetURL, &requestBody)
+	httpReq, err := http.NewRequestWithContext(ctx, services.CanonicalMethod(meta.Method), targetURL, requestBody)
 	if err != nil {
 		log.Printf("Failed to create request: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request: " + err.Error()})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, "Failed to create request: "+err.Error())
 		return
 	}
 
 	// Set Content-Type with boundary
-	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Content-Type", contentType)
 
 	// Add custom headers (but don't override Content-Type)
 	for key, value := range meta.Headers {
 		if !strings.EqualFold(key, "Content-Type") {
-			replacedValue := value
-			if len(variables) > 0 {
-				replacedValue = services.ReplaceVariables(value, variables)
-			}
-			httpReq.Header.Set(key, replacedValue)
+			httpReq.Header.Set(key, replacer.Replace(value))
 		}
 	}
+	if err := services.ApplyHeaderPolicy(httpReq, replacer.Replace(meta.Host)); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+	services.SetDefaultUserAgent(httpReq)
+
+	if err := services.ApplyBodyTransfer(httpReq, meta.BodyTransfer); err != nil {
+		// Buffering read the whole body, so the writer has finished
+		result := <-bodyResult
+		log.Printf("Failed to build multipart body: %v", result.err)
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, "Failed to build request body: "+err.Error())
+		return
+	}
+
+	headerBytes := services.HeaderBytes(httpReq.Header)
 
 	// Execute the request (relaxed TLS for localhost)
-	client := services.HttpClientFor(targetURL)
+	client, err := services.HostPolicyClient(ctx, services.HttpClientFor(targetURL))
+	if err != nil {
+		requestBody.CloseWithError(err)
+		<-bodyResult
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to check host policy")
+		return
+	}
 	resp, err := client.Do(httpReq)
 	if err != nil {
+		// Unblock the writer if the transport gave up before reading the body
+		requestBody.CloseWithError(err)
+		if result := <-bodyResult; bodyFailed(result.err, err) {
+			// The body itself failed (e.g. an upload couldn't be read)
+			log.Printf("Failed to build multipart body: %v", result.err)
+			recordHistory(c, teamID, meta.Method, targetURL, services.FlattenHeaders(httpReq.Header), nil, result.err)
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, "Failed to build request body: "+result.err.Error())
+			return
+		}
 		log.Printf("Request execution failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Request failed: " + err.Error()})
+		recordHistory(c, teamID, meta.Method, targetURL, services.FlattenHeaders(httpReq.Header), nil, err)
+		respondExecutionError(c, executionID, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -255,25 +741,51 @@ func ExecuteMultipartRequest(c *gin.Context) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("Failed to read response body: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response: " + err.Error()})
+		recordHistory(c, teamID, meta.Method, targetURL, services.FlattenHeaders(httpReq.Header), nil, err)
+		if errors.Is(err, context.Canceled) {
+			respondExecutionError(c, executionID, err)
+			return
+		}
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, "Failed to read response: "+err.Error())
 		return
 	}
 
+	// The upstream may answer without reading the whole body; stop the writer
+	// and wait for it so the files are closed before we return
+	requestBody.Close()
+	result := <-bodyResult
+	if bodyFailed(result.err, nil) {
+		log.Printf("Failed to build multipart body: %v", result.err)
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.RequestFailed, "Failed to build request body: "+result.err.Error())
+		return
+	}
+	requestBytes := headerBytes + result.bytes
+
 	elapsed := time.Since(startTime).Milliseconds()
 
-	// Build response headers map
-	respHeaders := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			respHeaders[key] = values[0]
-		}
+	var tlsInfo *models.TLSInfo
+	if meta.IncludeTLSInfo {
+		tlsInfo = services.TLSInfoFrom(resp.TLS)
 	}
 
-	c.JSON(http.StatusOK, models.ExecuteResponse{
-		Status:     resp.StatusCode,
-		StatusText: resp.Status,
-		Headers:    respHeaders,
-		Body:       string(bodyBytes),
-		Time:       elapsed,
-	})
+	body, charset := services.DecodeBody(resp.Header.Get("Content-Type"), bodyBytes)
+
+	response := models.ExecuteResponse{
+		Status:      resp.StatusCode,
+		StatusText:  resp.Status,
+		Headers:     services.FlattenHeaders(resp.Header),
+		Body:        body,
+		Charset:     charset,
+		BodyPresent: len(bodyBytes) > 0,
+		Time:        elapsed,
+		// Placeholders left in the URL, query, headers or text fields
+		UnresolvedVariables: replacer.Unresolved(),
+		RequestBytes:        requestBytes,
+		ResponseBytes:       services.HeaderBytes(resp.Header) + int64(len(bodyBytes)),
+		ExecutionID:         executionID,
+		TLS:                 tlsInfo,
+	}
+	recordHistory(c, teamID, meta.Method, targetURL, services.FlattenHeaders(httpReq.Header), &response, nil)
+	services.AttachResponseArtifact(c.GetUint("user_id"), &response, time.Now())
+	c.JSON(http.StatusOK, response)
 }
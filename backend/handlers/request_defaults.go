@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRequestDefaults returns the headers added to the team's requests. Any
+// member can read them, since they're sent with everyone's requests.
+func GetRequestDefaults(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+
+	var defaults models.TeamRequestDefaults
+	if err := database.DB.Where("team_id = ?", teamID).First(&defaults).Error; err != nil {
+		c.JSON(http.StatusOK, models.TeamRequestDefaults{TeamID: teamID, Headers: models.Variables{}})
+		return
+	}
+	c.JSON(http.StatusOK, defaults)
+}
+
+// UpdateRequestDefaults replaces the team's default headers (owner only)
+func UpdateRequestDefaults(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage request defaults")
+		return
+	}
+
+	var req models.RequestDefaultsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if err := services.ValidateDefaultHeaders(req.Headers); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	var defaults models.TeamRequestDefaults
+	database.DB.Where("team_id = ?", teamID).Limit(1).Find(&defaults)
+	defaults.TeamID = teamID
+	defaults.Headers = req.Headers
+	if defaults.Headers == nil {
+		defaults.Headers = models.Variables{}
+	}
+	defaults.UpdatedBy = userID
+	if err := database.DB.Save(&defaults).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save request defaults")
+		return
+	}
+
+	c.JSON(http.StatusOK, defaults)
+}
+
+// DeleteRequestDefaults removes the team's default headers
+func DeleteRequestDefaults(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can manage request defaults")
+		return
+	}
+
+	database.DB.Where("team_id = ?", teamID).Delete(&models.TeamRequestDefaults{})
+	c.JSON(http.StatusOK, gin.H{"message": "Request defaults deleted"})
+}
+
+// applyTeamDefaults adds the team's default headers to req, responding 500
+// itself when they can't be loaded
+func applyTeamDefaults(c *gin.Context, teamID uint, req *models.ExecuteRequest) bool {
+	defaults, err := services.TeamDefaultHeaders(teamID)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to load request defaults")
+		return false
+	}
+	services.ApplyDefaultHeaders(req, defaults)
+	return true
+}
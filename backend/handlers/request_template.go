@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// templateMethods are the methods a template may use
+var templateMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+	http.MethodDelete: true, http.MethodHead: true, http.MethodOptions: true,
+}
+
+// validateRequestTemplate normalizes the method (default GET) and checks the
+// template's fields
+func validateRequestTemplate(req *models.RequestTemplateRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !templateMethods[req.Method] {
+		return fmt.Errorf("unsupported method %q", req.Method)
+	}
+	for key := range req.Headers {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("header names can't be empty")
+		}
+	}
+	return nil
+}
+
+// templateToTab turns a template into a new, unsaved tab. Placeholders are
+// copied as-is so they resolve against the tab's environment on execute.
+func templateToTab(template *models.RequestTemplate, apply models.ApplyTemplateRequest) TabResponse {
+	name := apply.Name
+	if name == "" {
+		name = template.Name
+	}
+	headers := make(map[string]string, len(template.Headers))
+	for key, value := range template.Headers {
+		headers[key] = value
+	}
+	queryParams := make(map[string]string, len(template.QueryParams))
+	for key, value := range template.QueryParams {
+		queryParams[key] = value
+	}
+	return TabResponse{
+		TabID:       apply.TabID,
+		Name:        name,
+		Method:      template.Method,
+		URL:         template.URL,
+		Headers:     headers,
+		Body:        template.Body,
+		QueryParams: queryParams,
+		BodyType:    "raw",
+	}
+}
+
+// findRequestTemplate loads one of the team's templates, responding with an
+// error itself when it can't
+func findRequestTemplate(c *gin.Context) (*models.RequestTemplate, bool) {
+	templateID, err := strconv.ParseUint(c.Param("template_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid template ID")
+		return nil, false
+	}
+
+	var template models.RequestTemplate
+	if err := database.DB.Where("id = ? AND team_id = ?", templateID, c.GetUint("team_id")).First(&template).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TemplateNotFound, "Request template not found")
+		return nil, false
+	}
+	return &template, true
+}
+
+// GetRequestTemplates lists the team's request templates
+func GetRequestTemplates(c *gin.Context) {
+	var templates []models.RequestTemplate
+	if err := database.DB.Where("team_id = ?", c.GetUint("team_id")).Order("name").Find(&templates).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch request templates")
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateRequestTemplate adds a request template to the team
+func CreateRequestTemplate(c *gin.Context) {
+	var req models.RequestTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if err := validateRequestTemplate(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	template := models.RequestTemplate{
+		TeamID:      c.GetUint("team_id"),
+		Name:        req.Name,
+		Method:      req.Method,
+		URL:         req.URL,
+		Headers:     req.Headers,
+		QueryParams: req.QueryParams,
+		Body:        req.Body,
+		CreatedBy:   c.GetUint("user_id"),
+	}
+	if err := database.DB.Create(&template).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create request template")
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// GetRequestTemplate returns one request template
+func GetRequestTemplate(c *gin.Context) {
+	template, ok := findRequestTemplate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateRequestTemplate replaces a request template's contents
+func UpdateRequestTemplate(c *gin.Context) {
+	template, ok := findRequestTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req models.RequestTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if err := validateRequestTemplate(&req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	template.Name = req.Name
+	template.Method = req.Method
+	template.URL = req.URL
+	template.Headers = req.Headers
+	template.QueryParams = req.QueryParams
+	template.Body = req.Body
+	if err := database.DB.Save(template).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update request template")
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteRequestTemplate removes a request template
+func DeleteRequestTemplate(c *gin.Context) {
+	template, ok := findRequestTemplate(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(template).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to delete request template")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Request template deleted successfully"})
+}
+
+// ApplyRequestTemplate returns a new tab prefilled from the template, for
+// the client to open and save with its other tabs
+func ApplyRequestTemplate(c *gin.Context) {
+	template, ok := findRequestTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req models.ApplyTemplateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierr.RespondBindError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, templateToTab(template, req))
+}
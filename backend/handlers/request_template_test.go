@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/apierr"
+	"postmanxodja/models"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateRequestTemplate(t *testing.T) {
+	req := models.RequestTemplateRequest{Name: "  Paginated list ", Method: "get"}
+	if err := validateRequestTemplate(&req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Name != "Paginated list" || req.Method != "GET" {
+		t.Errorf("Expected normalized name and method, got '%s' '%s'", req.Name, req.Method)
+	}
+
+	req = models.RequestTemplateRequest{Name: "No method"}
+	validateRequestTemplate(&req)
+	if req.Method != "GET" {
+		t.Errorf("Expected method to default to GET, got '%s'", req.Method)
+	}
+
+	for _, bad := range []models.RequestTemplateRequest{
+		{Name: " "},
+		{Name: "Bad method", Method: "FETCH"},
+		{Name: "Bad header", Headers: map[string]string{"": "x"}},
+	} {
+		if err := validateRequestTemplate(&bad); err == nil {
+			t.Errorf("Expected template %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestTemplateToTab(t *testing.T) {
+	template := &models.RequestTemplate{
+		Name:        "Auth header",
+		Method:      "POST",
+		URL:         "{{base_url}}/items",
+		Headers:     models.Variables{"Authorization": "Bearer {{token}}"},
+		QueryParams: models.Variables{"page": "1"},
+		Body:        `{"name":"{{name}}"}`,
+	}
+
+	tab := templateToTab(template, models.ApplyTemplateRequest{TabID: "tab-1"})
+	if tab.TabID != "tab-1" || tab.Name != "Auth header" {
+		t.Errorf("Expected tab 'tab-1' named after the template, got '%s' '%s'", tab.TabID, tab.Name)
+	}
+	if tab.URL != "{{base_url}}/items" || tab.Headers["Authorization"] != "Bearer {{token}}" {
+		t.Errorf("Expected placeholders to be kept for execute time, got '%s' / %v", tab.URL, tab.Headers)
+	}
+	if tab.QueryParams["page"] != "1" || tab.Body != template.Body || tab.Method != "POST" {
+		t.Errorf("Expected the template's request, got %+v", tab)
+	}
+
+	// The tab gets its own maps
+	tab.Headers["X-Extra"] = "1"
+	if _, ok := template.Headers["X-Extra"]; ok {
+		t.Error("Expected editing the tab not to change the template")
+	}
+
+	renamed := templateToTab(template, models.ApplyTemplateRequest{Name: "My request"})
+	if renamed.Name != "My request" {
+		t.Errorf("Expected name 'My request', got '%s'", renamed.Name)
+	}
+}
+
+func TestRequestTemplateHandlers(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	outsider, other := createTestTeam(t, "other@example.com")
+	router := func(teamID, userID uint) *gin.Engine {
+		r := teamRouter(teamID, userID)
+		r.GET("/request-templates", GetRequestTemplates)
+		r.POST("/request-templates", CreateRequestTemplate)
+		r.GET("/request-templates/:template_id", GetRequestTemplate)
+		r.DELETE("/request-templates/:template_id", DeleteRequestTemplate)
+		r.POST("/request-templates/:template_id/apply", ApplyRequestTemplate)
+		return r
+	}
+	ours, theirs := router(team.ID, user.ID), router(other.ID, outsider.ID)
+	send := func(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(ours, http.MethodPost, "/request-templates",
+		`{"name":"Auth header","method":"post","url":"{{base_url}}/items","headers":{"Authorization":"Bearer {{token}}"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.RequestTemplate
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.TeamID != team.ID || created.CreatedBy != user.ID || created.Method != "POST" {
+		t.Errorf("Expected a POST template owned by the team, got %+v", created)
+	}
+	if w := send(ours, http.MethodPost, "/request-templates", `{"name":"Bad","method":"FETCH"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid template to be rejected, got %d", w.Code)
+	}
+
+	for r, want := range map[*gin.Engine]int{ours: 1, theirs: 0} {
+		var templates []models.RequestTemplate
+		json.Unmarshal(send(r, http.MethodGet, "/request-templates", "").Body.Bytes(), &templates)
+		if len(templates) != want {
+			t.Errorf("Expected %d templates listed, got %+v", want, templates)
+		}
+	}
+
+	path := "/request-templates/" + strconv.Itoa(int(created.ID))
+	w = send(ours, http.MethodPost, path+"/apply", `{"tab_id":"tab-1"}`)
+	var tab TabResponse
+	json.Unmarshal(w.Body.Bytes(), &tab)
+	if w.Code != http.StatusOK || tab.TabID != "tab-1" || tab.URL != "{{base_url}}/items" || tab.Headers["Authorization"] != "Bearer {{token}}" {
+		t.Errorf("Expected a tab prefilled from the template, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Another team can't see, apply or delete the template
+	for _, request := range [][2]string{{http.MethodGet, path}, {http.MethodPost, path + "/apply"}, {http.MethodDelete, path}} {
+		w := send(theirs, request[0], request[1], "")
+		if w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != apierr.TemplateNotFound {
+			t.Errorf("%s %s: expected 404 from another team, got %d: %s", request[0], request[1], w.Code, w.Body.String())
+		}
+	}
+	if w := send(ours, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the team to delete its template, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,635 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func useUploadLimits(t *testing.T, maxFiles int, maxBytes int64) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{MaxUploadFiles: maxFiles, MaxUploadTotalBytes: maxBytes}
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+// multipartExecuteRequest builds an execute-multipart request with the given
+// number of files of size bytes each
+func multipartExecuteRequest(t *testing.T, files, size int) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("_request_meta", `{"method":"POST","url":"http://127.0.0.1:1/upload"}`)
+	for i := 0; i < files; i++ {
+		part, err := writer.CreateFormFile(fmt.Sprintf("file_%d", i), fmt.Sprintf("f%d.bin", i))
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write(bytes.Repeat([]byte("x"), size))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// executeMultipart runs req as a user whose only team the execution is for
+func executeMultipart(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	useTestDB(t)
+	user, team := createTestTeam(t, "uploader@example.com")
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/requests/execute-multipart", ExecuteMultipartRequest)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func assertUploadTooLarge(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+	var body apierr.Response
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Error.Code != apierr.UploadTooLarge {
+		t.Errorf("Expected code '%s', got '%s'", apierr.UploadTooLarge, body.Error.Code)
+	}
+}
+
+func TestExecuteMultipartRequestTooManyFiles(t *testing.T) {
+	useUploadLimits(t, 2, 1<<20)
+	assertUploadTooLarge(t, executeMultipart(t, multipartExecuteRequest(t, 3, 10)))
+}
+
+func TestExecuteMultipartRequestTotalSizeLimit(t *testing.T) {
+	useUploadLimits(t, 10, 100)
+	assertUploadTooLarge(t, executeMultipart(t, multipartExecuteRequest(t, 2, 60)))
+}
+
+// countedFile is an in-memory upload that tracks how many are still open
+type countedFile struct {
+	*bytes.Reader
+	open *int
+}
+
+func (f countedFile) Close() error {
+	*f.open--
+	return nil
+}
+
+func countedItems(open *int, n int) []formItem {
+	items := []formItem{{key: "title", value: "doc"}}
+	for i := 0; i < n; i++ {
+		*open++
+		items = append(items, formItem{
+			key:      fmt.Sprintf("file%d", i),
+			isFile:   true,
+			file:     countedFile{Reader: bytes.NewReader([]byte("data")), open: open},
+			filename: fmt.Sprintf("f%d.txt", i),
+		})
+	}
+	return items
+}
+
+// failingWriter fails every write, like an upstream that went away
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func TestWriteMultipartBodyClosesFilesOnFailure(t *testing.T) {
+	open := 0
+	items := countedItems(&open, 3)
+
+	if err := writeMultipartBody(multipart.NewWriter(failingWriter{}), items); err == nil {
+		t.Fatal("Expected an error from the failing writer")
+	}
+	if open != 0 {
+		t.Errorf("Expected all files to be closed, %d still open", open)
+	}
+
+	// Closing again (the handler's deferred cleanup) is a no-op
+	closeFormFiles(items)
+	if open != 0 {
+		t.Errorf("Expected no double close, open count is %d", open)
+	}
+}
+
+func TestWriteMultipartBodyClosesFilesOnSuccess(t *testing.T) {
+	open := 0
+	items := countedItems(&open, 2)
+
+	var body bytes.Buffer
+	if err := writeMultipartBody(multipart.NewWriter(&body), items); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if open != 0 {
+		t.Errorf("Expected all files to be closed, %d still open", open)
+	}
+	if !bytes.Contains(body.Bytes(), []byte(`filename="f1.txt"`)) {
+		t.Errorf("Expected the files in the body, got '%s'", body.String())
+	}
+}
+
+// syntheticFile is an upload of size bytes that's generated as it's read, so
+// nothing large is ever held in memory
+type syntheticFile struct {
+	remaining int64
+	err       error // returned once the data runs out, instead of io.EOF
+	closed    bool
+}
+
+func (f *syntheticFile) Read(p []byte) (int, error) {
+	if f.remaining <= 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	f.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+func (f *syntheticFile) ReadAt([]byte, int64) (int, error) { return 0, errors.New("not supported") }
+func (f *syntheticFile) Seek(int64, int) (int64, error)    { return 0, errors.New("not supported") }
+func (f *syntheticFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestStreamMultipartBodyDoesNotBuffer(t *testing.T) {
+	const size = 64 << 20
+	file := &syntheticFile{remaining: size}
+	items := []formItem{{key: "upload", isFile: true, file: file, filename: "big.bin"}}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	body, contentType, results := streamMultipartBody(items)
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("Expected a valid Content-Type, got '%s'", contentType)
+	}
+
+	// Read it back like an upstream would, without keeping the file data
+	reader := multipart.NewReader(body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read part: %v", err)
+	}
+	n, err := io.Copy(io.Discard, part)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("Expected a single part, got %v", err)
+	}
+
+	result := <-results
+	runtime.ReadMemStats(&after)
+
+	if n != size {
+		t.Errorf("Expected %d file bytes, got %d", size, n)
+	}
+	if result.err != nil {
+		t.Errorf("Expected no write error, got %v", result.err)
+	}
+	if result.bytes <= size {
+		t.Errorf("Expected the body to count more than the file (%d), got %d", size, result.bytes)
+	}
+	if !file.closed {
+		t.Error("Expected the file to be closed")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+		t.Errorf("Expected streaming to allocate well under the file size, allocated %d bytes", allocated)
+	}
+}
+
+func TestStreamMultipartBodyReportsWriteError(t *testing.T) {
+	file := &syntheticFile{remaining: 1024, err: errors.New("disk read failed")}
+	items := []formItem{{key: "upload", isFile: true, file: file, filename: "bad.bin"}}
+
+	body, _, results := streamMultipartBody(items)
+	if _, err := io.Copy(io.Discard, body); err == nil || err.Error() != "disk read failed" {
+		t.Errorf("Expected the reader to see the write error, got %v", err)
+	}
+
+	result := <-results
+	if !bodyFailed(result.err, nil) {
+		t.Errorf("Expected a body failure, got %v", result.err)
+	}
+	if !file.closed {
+		t.Error("Expected the file to be closed")
+	}
+}
+
+func TestExecuteMultipartRequestStreamsLargeFile(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	const size = 8 << 20
+	useUploadLimits(t, 5, 2*size)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("upload")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		n, _ := io.Copy(io.Discard, file)
+		w.Write([]byte(strconv.FormatInt(n, 10) + " " + r.FormValue("title")))
+	}))
+	defer upstream.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("_request_meta", `{"method":"POST","url":"`+upstream.URL+`/upload"}`)
+	writer.WriteField("text_0_key", "title")
+	writer.WriteField("text_0_value", "report")
+	writer.WriteField("file_0_key", "upload")
+	part, _ := writer.CreateFormFile("file_0", "big.bin")
+	part.Write(bytes.Repeat([]byte("x"), size))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := executeMultipart(t, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Status       int    `json:"status"`
+		Body         string `json:"body"`
+		RequestBytes int64  `json:"request_bytes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Status != http.StatusOK {
+		t.Errorf("Expected upstream status 200, got %d (%s)", resp.Status, resp.Body)
+	}
+	if expected := strconv.Itoa(size) + " report"; resp.Body != expected {
+		t.Errorf("Expected body '%s', got '%s'", expected, resp.Body)
+	}
+	if resp.RequestBytes <= size {
+		t.Errorf("Expected request_bytes above %d, got %d", size, resp.RequestBytes)
+	}
+}
+
+func TestExecuteMultipartRequestBodyTransfer(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	useUploadLimits(t, 5, 1<<20)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("upload")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		n, _ := io.Copy(io.Discard, file)
+		fmt.Fprintf(w, "%s|%v|%d", r.Header.Get("Content-Length"), r.TransferEncoding, n)
+	}))
+	defer upstream.Close()
+
+	for transfer, want := range map[string]string{
+		"":               `^\|\[chunked\]\|1000$`,
+		"chunked":        `^\|\[chunked\]\|1000$`,
+		"content-length": `^\d+\|\[\]\|1000$`,
+	} {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("_request_meta", `{"method":"POST","url":"`+upstream.URL+`","body_transfer":"`+transfer+`"}`)
+		writer.WriteField("file_0_key", "upload")
+		part, _ := writer.CreateFormFile("file_0", "f.bin")
+		part.Write(bytes.Repeat([]byte("x"), 1000))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := executeMultipart(t, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d: %s", transfer, w.Code, w.Body.String())
+		}
+		var resp models.ExecuteResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if !regexp.MustCompile(want).MatchString(resp.Body) {
+			t.Errorf("%q: expected the upstream to see %s, got '%s'", transfer, want, resp.Body)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("_request_meta", `{"method":"POST","url":"`+upstream.URL+`","body_transfer":"gzip"}`)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if w := executeMultipart(t, req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown body_transfer to be rejected, got %d", w.Code)
+	}
+}
+
+func TestExecuteMultipartRequestRecordsFailure(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	useUploadLimits(t, 5, 100)
+	w := executeMultipart(t, multipartExecuteRequest(t, 1, 10))
+	if w.Code != http.StatusInternalServerError || decodeError(t, w).Error.Code != apierr.RequestFailed {
+		t.Fatalf("Expected 500 REQUEST_FAILED for an unreachable upstream, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var entry models.RequestHistory
+	if err := database.DB.Where("url = ?", "http://127.0.0.1:1/upload").First(&entry).Error; err != nil {
+		t.Fatalf("Expected the failed execution in history: %v", err)
+	}
+	if entry.Method != http.MethodPost || entry.Error == "" || entry.Status != 0 {
+		t.Errorf("Expected a POST entry with the error and no status, got %+v", entry)
+	}
+}
+
+func TestExecuteMultipartRequestRedirectToDeniedHost(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	user, team := createTestTeam(t, "uploader@example.com")
+	database.DB.Create(&models.TeamHostPolicy{TeamID: team.ID, Deny: "denied.test"})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://denied.test/upload", http.StatusFound)
+	}))
+	defer upstream.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("_request_meta", `{"method":"POST","url":"`+upstream.URL+`/upload"}`)
+	writer.WriteField("text_0_key", "title")
+	writer.WriteField("text_0_value", "report")
+	writer.Close()
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/requests/execute-multipart", ExecuteMultipartRequest)
+	req := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || decodeError(t, w).Error.Code != apierr.HostNotAllowed {
+		t.Errorf("Expected 403 HOST_NOT_ALLOWED for the redirect, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func validateRequest(body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/requests/validate", ValidateRequest)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/validate", strings.NewReader(body)))
+	return w
+}
+
+func TestValidateRequestJSONBody(t *testing.T) {
+	w := validateRequest(`{"method":"POST","url":"https://api.example.com/items","validate_json_body":true,
+		"headers":{"Content-Type":"application/json"},"body":"{\"name\": {{name}}}","inline_variables":{"name":"\"Ada\""}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the substituted body to validate, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = validateRequest(`{"method":"POST","url":"https://api.example.com/items","validate_json_body":true,
+		"headers":{"Content-Type":"application/json"},"body":"{\"name\": Ada}"}`)
+	if w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != apierr.InvalidJSONBody ||
+		!strings.Contains(w.Body.String(), `"column":10`) {
+		t.Errorf("Expected 400 INVALID_JSON_BODY at column 10, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExecuteRequestRedactsStoredHeaders(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	user, _ := createTestTeam(t, "redact@example.com")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cret"})
+		w.Header().Set("X-Request-Id", "42")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/requests/execute", func(c *gin.Context) { c.Set("user_id", user.ID) }, ExecuteRequest)
+	body, _ := json.Marshal(models.ExecuteRequest{Method: "GET", URL: upstream.URL,
+		Headers: map[string]string{"Authorization": "Bearer token", "Accept": "text/plain"}})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/execute", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The live response keeps the real values
+	var live models.ExecuteResponse
+	json.Unmarshal(w.Body.Bytes(), &live)
+	if !strings.Contains(live.Headers["Set-Cookie"], "s3cret") {
+		t.Errorf("Expected the live response to keep Set-Cookie, got %v", live.Headers)
+	}
+
+	var entry models.RequestHistory
+	if err := database.DB.Where("user_id = ?", user.ID).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.RequestHeaders["Authorization"] != services.RedactedValue || entry.RequestHeaders["Accept"] != "text/plain" {
+		t.Errorf("Expected Authorization redacted in the stored request headers, got %v", entry.RequestHeaders)
+	}
+	if entry.ResponseHeaders["Set-Cookie"] != services.RedactedValue || entry.ResponseHeaders["X-Request-Id"] != "42" {
+		t.Errorf("Expected Set-Cookie redacted in the stored response headers, got %v", entry.ResponseHeaders)
+	}
+}
+
+func TestDownloadResponseArtifact(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	user, _ := createTestTeam(t, "download@example.com")
+	other, _ := createTestTeam(t, "other@example.com")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Write([]byte("plain text"))
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", `attachment; filename="../chart.png"`)
+		w.Write(png)
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	asUser := func(id uint) gin.HandlerFunc { return func(c *gin.Context) { c.Set("user_id", id) } }
+	r.POST("/requests/execute", asUser(user.ID), ExecuteRequest)
+	r.GET("/requests/response/:artifact_id/download", asUser(user.ID), DownloadResponseArtifact)
+	r.GET("/other/:artifact_id/download", asUser(other.ID), DownloadResponseArtifact)
+	execute := func(path string) models.ExecuteResponse {
+		body, _ := json.Marshal(models.ExecuteRequest{Method: "GET", URL: upstream.URL + path})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/execute", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.ExecuteResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	if resp := execute("/text"); resp.ArtifactID != "" {
+		t.Errorf("Expected no artifact for a text body, got %q", resp.ArtifactID)
+	}
+	resp := execute("/chart")
+	if resp.ArtifactID == "" || resp.ArtifactExpiresAt == nil {
+		t.Fatalf("Expected an artifact for the PNG body, got %+v", resp)
+	}
+	if resp.Body != "" {
+		t.Errorf("Expected the PNG to be left out of the JSON, got %q", resp.Body)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/requests/response/"+resp.ArtifactID+"/download", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), png) {
+		t.Fatalf("Expected the PNG bytes, got %d: %q", w.Code, w.Body.Bytes())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected Content-Type image/png, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != "attachment; filename=chart.png" {
+		t.Errorf("Expected the upstream's base filename, got %q", cd)
+	}
+
+	// Artifacts belong to the user who ran the request
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other/"+resp.ArtifactID+"/download", nil))
+	if w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != apierr.ArtifactNotFound {
+		t.Errorf("Expected another user's download to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExecuteRequestWithoutTeamUsesMembership(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+	user, team := createTestTeam(t, "member@example.com")
+	database.DB.Create(&models.TeamHostPolicy{TeamID: team.ID, Deny: "127.0.0.1"})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/requests/execute", func(c *gin.Context) { c.Set("user_id", user.ID) }, ExecuteRequest)
+	execute := func(teamID *uint) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExecuteRequest{Method: "GET", URL: upstream.URL, TeamID: teamID})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/execute", bytes.NewReader(body)))
+		return w
+	}
+
+	// The user's only team applies, policy included
+	if w := execute(nil); w.Code != http.StatusForbidden || decodeError(t, w).Error.Code != apierr.HostNotAllowed {
+		t.Errorf("Expected the only team's policy to block the host, got %d: %s", w.Code, w.Body.String())
+	}
+
+	second := models.Team{Name: "Second"}
+	database.DB.Create(&second)
+	database.DB.Create(&models.TeamMember{TeamID: second.ID, UserID: user.ID, Role: "member"})
+	if w := execute(nil); w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != apierr.TeamRequired {
+		t.Errorf("Expected team_id to be required with two teams, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := execute(&second.ID); w.Code != http.StatusOK {
+		t.Errorf("Expected the second team, without a policy, to allow the host, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateRequestIgnoresAnotherTeamsEnvironment(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+	_, other := createTestTeam(t, "other@example.com")
+	own := models.Environment{Name: "mine", TeamID: &team.ID, Variables: models.Variables{"host": "mine.test"}}
+	theirs := models.Environment{Name: "theirs", TeamID: &other.ID, Variables: models.Variables{"host": "theirs.test"}}
+	database.DB.Create(&own)
+	database.DB.Create(&theirs)
+
+	r := teamRouter(team.ID, user.ID)
+	r.POST("/requests/validate", ValidateRequest)
+	for env, want := range map[uint]string{own.ID: "mine.test", theirs.ID: "{{host}}"} {
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"method":"GET","url":"https://api.test/items","headers":{"X-Host":"{{host}}"},"environment_id":%d}`, env)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/validate", strings.NewReader(body)))
+		var resolved models.ResolvedRequest
+		json.Unmarshal(w.Body.Bytes(), &resolved)
+		if w.Code != http.StatusOK || resolved.Headers["X-Host"] != want {
+			t.Errorf("Environment %d: expected X-Host '%s', got %d: %s", env, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestExecutionRejectsEnvironmentOfAnotherTeam(t *testing.T) {
+	useTestDB(t)
+	user, open := createTestTeam(t, "member@example.com")
+	strict := models.Team{Name: "Strict"}
+	database.DB.Create(&strict)
+	database.DB.Create(&models.TeamMember{TeamID: strict.ID, UserID: user.ID, Role: "member"})
+	database.DB.Create(&models.TeamHostPolicy{TeamID: strict.ID, Allow: "api.strict.test"})
+	env := models.Environment{Name: "strict", TeamID: &strict.ID, Variables: models.Variables{"key": "s3cret"}}
+	database.DB.Create(&env)
+
+	// The strict team's environment can't be used under the open team's policy
+	meta := fmt.Sprintf(`{"method":"GET","url":"https://elsewhere.test/?api_key={{key}}","team_id":%d,"environment_id":%d}`, open.ID, env.ID)
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("_request_meta", meta)
+	writer.Close()
+	multipartReq := httptest.NewRequest(http.MethodPost, "/requests/execute-multipart", &body)
+	multipartReq.Header.Set("Content-Type", writer.FormDataContentType())
+
+	r := teamRouter(open.ID, user.ID)
+	r.POST("/requests/execute", ExecuteRequest)
+	r.POST("/requests/validate", ValidateRequest)
+	r.POST("/requests/execute-multipart", ExecuteMultipartRequest)
+	for name, req := range map[string]*http.Request{
+		"execute":           httptest.NewRequest(http.MethodPost, "/requests/execute", strings.NewReader(meta)),
+		"validate":          httptest.NewRequest(http.MethodPost, "/requests/validate", strings.NewReader(meta)),
+		"execute-multipart": multipartReq,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound || decodeError(t, w).Error.Code != apierr.EnvironmentNotFound {
+			t.Errorf("%s: expected 404 ENVIRONMENT_NOT_FOUND, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	// Without team_id the environment's own team, and its policy, apply
+	w := httptest.NewRecorder()
+	derived := fmt.Sprintf(`{"method":"GET","url":"https://elsewhere.test/","environment_id":%d}`, env.ID)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/requests/execute", strings.NewReader(derived)))
+	if w.Code != http.StatusForbidden || decodeError(t, w).Error.Code != apierr.HostNotAllowed {
+		t.Errorf("Expected the environment's team policy to apply, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -3,35 +3,43 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
+	"postmanxodja/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TabRequest struct {
-	TabID       string            `json:"tab_id"`
-	Name        string            `json:"name"`
-	Method      string            `json:"method"`
-	URL         string            `json:"url"`
-	Headers     map[string]string `json:"headers"`
-	Body        string            `json:"body"`
-	QueryParams map[string]string `json:"query_params"`
-	IsActive    bool              `json:"is_active"`
-	SortOrder   int               `json:"sort_order"`
+	TabID       string             `json:"tab_id"`
+	Name        string             `json:"name"`
+	Method      string             `json:"method"`
+	URL         string             `json:"url"`
+	Headers     map[string]string  `json:"headers"`
+	Body        string             `json:"body"`
+	QueryParams map[string]string  `json:"query_params"`
+	BodyType    string             `json:"body_type"`
+	FormFields  []models.FormField `json:"form_fields"`
+	IsActive    bool               `json:"is_active"`
+	Pinned      bool               `json:"pinned"`
+	SortOrder   int                `json:"sort_order"`
 }
 
 type TabResponse struct {
-	ID          uint              `json:"id"`
-	TabID       string            `json:"tab_id"`
-	Name        string            `json:"name"`
-	Method      string            `json:"method"`
-	URL         string            `json:"url"`
-	Headers     map[string]string `json:"headers"`
-	Body        string            `json:"body"`
-	QueryParams map[string]string `json:"query_params"`
-	IsActive    bool              `json:"is_active"`
-	SortOrder   int               `json:"sort_order"`
+	ID          uint               `json:"id"`
+	TabID       string             `json:"tab_id"`
+	Name        string             `json:"name"`
+	Method      string             `json:"method"`
+	URL         string             `json:"url"`
+	Headers     map[string]string  `json:"headers"`
+	Body        string             `json:"body"`
+	QueryParams map[string]string  `json:"query_params"`
+	BodyType    string             `json:"body_type"`
+	FormFields  []models.FormField `json:"form_fields"`
+	IsActive    bool               `json:"is_active"`
+	Pinned      bool               `json:"pinned"`
+	SortOrder   int                `json:"sort_order"`
 }
 
 // GetSavedTabs returns all saved tabs for the current user
@@ -39,8 +47,8 @@ func GetSavedTabs(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var tabs []models.SavedTab
-	if err := database.DB.Where("user_id = ?", userID).Order("sort_order ASC").Find(&tabs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tabs"})
+	if err := database.DB.Where("user_id = ?", userID).Order("pinned DESC, sort_order ASC").Find(&tabs).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch tabs")
 		return
 	}
 
@@ -66,7 +74,10 @@ func GetSavedTabs(c *gin.Context) {
 			Headers:     headers,
 			Body:        tab.Body,
 			QueryParams: queryParams,
+			BodyType:    tab.BodyType,
+			FormFields:  services.DecodeFormFields(tab.FormFields),
 			IsActive:    tab.IsActive,
+			Pinned:      tab.Pinned,
 			SortOrder:   tab.SortOrder,
 		}
 	}
@@ -79,12 +90,12 @@ func SaveTabs(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Tabs         []TabRequest `json:"tabs"`
-		ActiveTabID  string       `json:"active_tab_id"`
+		Tabs        []TabRequest `json:"tabs"`
+		ActiveTabID string       `json:"active_tab_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
@@ -94,7 +105,7 @@ func SaveTabs(c *gin.Context) {
 	// Delete existing tabs for this user
 	if err := tx.Where("user_id = ?", userID).Delete(&models.SavedTab{}).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tabs"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save tabs")
 		return
 	}
 
@@ -112,13 +123,16 @@ func SaveTabs(c *gin.Context) {
 			Headers:     string(headersJSON),
 			Body:        tab.Body,
 			QueryParams: string(queryParamsJSON),
+			BodyType:    tab.BodyType,
+			FormFields:  services.EncodeFormFields(tab.FormFields),
 			IsActive:    tab.TabID == req.ActiveTabID,
+			Pinned:      tab.Pinned,
 			SortOrder:   i,
 		}
 
 		if err := tx.Create(&savedTab).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tabs"})
+			apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to save tabs")
 			return
 		}
 	}
@@ -126,3 +140,28 @@ func SaveTabs(c *gin.Context) {
 	tx.Commit()
 	c.JSON(http.StatusOK, gin.H{"message": "Tabs saved successfully"})
 }
+
+// PinTab pins one of the user's saved tabs
+func PinTab(c *gin.Context) {
+	setTabPinned(c, true)
+}
+
+// UnpinTab unpins one of the user's saved tabs
+func UnpinTab(c *gin.Context) {
+	setTabPinned(c, false)
+}
+
+func setTabPinned(c *gin.Context, pinned bool) {
+	result := database.DB.Model(&models.SavedTab{}).
+		Where("user_id = ? AND tab_id = ?", c.GetUint("user_id"), c.Param("tab_id")).
+		Update("pinned", pinned)
+	if result.Error != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update tab")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TabNotFound, "Tab not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tab_id": c.Param("tab_id"), "pinned": pinned})
+}
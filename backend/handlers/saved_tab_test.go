@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPinTabListsItFirst(t *testing.T) {
+	useTestDB(t)
+	user, team := createTestTeam(t, "owner@example.com")
+
+	r := teamRouter(team.ID, user.ID)
+	r.GET("/tabs", GetSavedTabs)
+	r.POST("/tabs", SaveTabs)
+	r.PUT("/tabs/:tab_id/pin", PinTab)
+	r.DELETE("/tabs/:tab_id/pin", UnpinTab)
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tabIDs := func() string {
+		var tabs []TabResponse
+		json.Unmarshal(call(http.MethodGet, "/tabs", "").Body.Bytes(), &tabs)
+		var ids []string
+		for _, tab := range tabs {
+			ids = append(ids, tab.TabID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	call(http.MethodPost, "/tabs", `{"tabs":[{"tab_id":"a"},{"tab_id":"b"},{"tab_id":"c"}]}`)
+	if w := call(http.MethodPut, "/tabs/c/pin", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the tab to be pinned, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := tabIDs(); got != "c,a,b" {
+		t.Errorf("Expected the pinned tab first, got %s", got)
+	}
+
+	// Saving the tabs again keeps the pin the client sends back
+	call(http.MethodPost, "/tabs", `{"tabs":[{"tab_id":"a"},{"tab_id":"b","pinned":true},{"tab_id":"c"}]}`)
+	if got := tabIDs(); got != "b,a,c" {
+		t.Errorf("Expected saved pins to be kept, got %s", got)
+	}
+
+	call(http.MethodDelete, "/tabs/b/pin", "")
+	if got := tabIDs(); got != "a,b,c" {
+		t.Errorf("Expected the saved order once unpinned, got %s", got)
+	}
+	if w := call(http.MethodPut, "/tabs/missing/pin", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown tab to be not found, got %d", w.Code)
+	}
+}
@@ -3,20 +3,23 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"postmanxodja/apierr"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetUserTeams(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	teams, err := services.GetUserTeams(userID)
+	teams, err := services.GetUserTeamsWithRoles(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get teams"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get teams")
 		return
 	}
 
@@ -28,13 +31,13 @@ func CreateTeam(c *gin.Context) {
 
 	var req models.CreateTeamRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	team, err := services.CreateTeamWithOwner(req.Name, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create team")
 		return
 	}
 
@@ -43,14 +46,29 @@ func CreateTeam(c *gin.Context) {
 
 func GetTeam(c *gin.Context) {
 	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
 
 	var team models.Team
 	if result := database.DB.Preload("Members.User").First(&team, teamID); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
 		return
 	}
 
-	c.JSON(http.StatusOK, team)
+	response := models.TeamResponse{
+		Team:        team,
+		YourRole:    services.GetUserRole(userID, teamID),
+		MemberCount: int64(len(team.Members)),
+	}
+	for _, member := range team.Members {
+		if member.Role == "owner" {
+			response.OwnerID = member.UserID
+			if member.User != nil {
+				response.OwnerName = member.User.Name
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func UpdateTeam(c *gin.Context) {
@@ -58,19 +76,19 @@ func UpdateTeam(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can update the team"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can update the team")
 		return
 	}
 
 	var req models.CreateTeamRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.RespondBindError(c, err)
 		return
 	}
 
 	var team models.Team
 	if result := database.DB.First(&team, teamID); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
 		return
 	}
 
@@ -85,35 +103,63 @@ func DeleteTeam(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can delete the team"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can delete the team")
+		return
+	}
+
+	// Soft delete: members are kept so the owner can still restore the team,
+	// everything else is purged once the restore window passes
+	if err := services.SoftDeleteTeam(teamID); err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to delete team")
 		return
 	}
 
-	tx := database.DB.Begin()
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Team deleted successfully",
+		"restore_until": time.Now().Add(services.TeamRestoreWindow()),
+	})
+}
+
+// RestoreTeam brings back a deleted team while it's still within the restore
+// window. It's registered outside TeamAccessMiddleware since that middleware
+// rejects deleted teams.
+func RestoreTeam(c *gin.Context) {
+	userID := c.GetUint("user_id")
 
-	// Delete team members
-	if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamMember{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team members"})
+	teamID, err := strconv.ParseUint(c.Param("team_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid team ID")
 		return
 	}
 
-	// Delete team invites
-	if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamInvite{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team invites"})
+	if !services.IsTeamOwner(userID, uint(teamID)) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can restore the team")
 		return
 	}
 
-	// Delete team
-	if err := tx.Delete(&models.Team{}, teamID).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+	var team models.Team
+	if result := database.DB.Unscoped().First(&team, teamID); result.Error != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.TeamNotFound, "Team not found")
+		return
+	}
+
+	if !team.DeletedAt.Valid {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.TeamNotDeleted, "Team is not deleted")
 		return
 	}
 
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+	if time.Since(team.DeletedAt.Time) > services.TeamRestoreWindow() {
+		apierr.RespondError(c, http.StatusGone, apierr.RestoreWindowExpired, "Restore window has expired")
+		return
+	}
+
+	if err := services.RestoreTeam(team.ID, team.DeletedAt.Time); err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to restore team")
+		return
+	}
+
+	team.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, team)
 }
 
 func GetTeamMembers(c *gin.Context) {
@@ -121,7 +167,7 @@ func GetTeamMembers(c *gin.Context) {
 
 	var members []models.TeamMember
 	if result := database.DB.Preload("User").Where("team_id = ?", teamID).Find(&members); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get team members"})
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to get team members")
 		return
 	}
 
@@ -133,47 +179,170 @@ func RemoveTeamMember(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	if !services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owner can remove members"})
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can remove members")
 		return
 	}
 
 	memberUserIDStr := c.Param("user_id")
 	memberUserID, err := strconv.ParseUint(memberUserIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid user ID")
 		return
 	}
 
 	// Can't remove the owner
 	if services.IsTeamOwner(uint(memberUserID), teamID) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove team owner"})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.OwnerRoleProtected, "Cannot remove team owner")
 		return
 	}
 
-	result := database.DB.Where("team_id = ? AND user_id = ?", teamID, memberUserID).Delete(&models.TeamMember{})
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	removed, err := services.RemoveMemberFromTeam(teamID, uint(memberUserID))
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to remove member")
+		return
+	}
+	if !removed {
+		apierr.RespondError(c, http.StatusNotFound, apierr.MemberNotFound, "Member not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
 }
 
+// UpdateMemberRole promotes or demotes a team member (owner only).
+// Ownership itself can't be granted or revoked here.
+func UpdateMemberRole(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can change member roles")
+		return
+	}
+
+	memberUserIDStr := c.Param("user_id")
+	memberUserID, err := strconv.ParseUint(memberUserIDStr, 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+
+	// A team has exactly one owner; ownership moves via transfer, not here
+	if req.Role == "owner" {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.OwnerRoleProtected, "Cannot assign owner role. Transfer ownership instead.")
+		return
+	}
+	if req.Role != "admin" && req.Role != "member" {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid role. Must be: admin or member")
+		return
+	}
+
+	var member models.TeamMember
+	if result := database.DB.Where("team_id = ? AND user_id = ?", teamID, memberUserID).First(&member); result.Error != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.MemberNotFound, "Member not found")
+		return
+	}
+
+	// The owner's role is only changed by transferring ownership
+	if member.Role == "owner" {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.OwnerRoleProtected, "Cannot change the team owner's role")
+		return
+	}
+
+	member.Role = req.Role
+	if err := database.DB.Save(&member).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update member role")
+		return
+	}
+
+	database.DB.Preload("User").First(&member, member.ID)
+	c.JSON(http.StatusOK, member)
+}
+
 func LeaveTeam(c *gin.Context) {
 	teamID := c.GetUint("team_id")
 	userID := c.GetUint("user_id")
 
 	// Owners can't leave their team, they must transfer ownership or delete the team
 	if services.IsTeamOwner(userID, teamID) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Team owner cannot leave. Transfer ownership or delete the team."})
+		apierr.RespondError(c, http.StatusBadRequest, apierr.OwnerRoleProtected, "Team owner cannot leave. Transfer ownership or delete the team.")
 		return
 	}
 
-	result := database.DB.Where("team_id = ? AND user_id = ?", teamID, userID).Delete(&models.TeamMember{})
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Membership not found"})
+	removed, err := services.RemoveMemberFromTeam(teamID, userID)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to leave team")
+		return
+	}
+	if !removed {
+		apierr.RespondError(c, http.StatusNotFound, apierr.MemberNotFound, "Membership not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Left team successfully"})
 }
+
+// ExportTeam returns a versioned backup of the team (owner only): members,
+// environments, collections and API key metadata, without any secrets
+func ExportTeam(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	userID := c.GetUint("user_id")
+
+	if !services.IsTeamOwner(userID, teamID) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can export the team")
+		return
+	}
+
+	export, err := services.BuildTeamExport(teamID)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to export team")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+sanitizeFilename(export.Team.Name)+".team_export.json\"")
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// ImportTeam creates a new team, owned by the caller, from an ExportTeam
+// bundle
+func ImportTeam(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var export models.TeamExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if err := services.ValidateTeamExport(&export); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, "Invalid team export: "+err.Error())
+		return
+	}
+
+	team, err := services.ImportTeam(&export, userID)
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to import team")
+		return
+	}
+
+	// The importer is the owner; everyone else has to be invited again
+	var toInvite []models.TeamExportMember
+	for _, member := range export.Members {
+		if member.Role != "owner" {
+			toInvite = append(toInvite, member)
+		}
+	}
+
+	c.JSON(http.StatusCreated, models.TeamImportResponse{
+		Team:                models.TeamResponse{Team: *team, YourRole: "owner", MemberCount: 1, OwnerID: userID},
+		CollectionsImported: len(export.Collections),
+		EnvironmentsCreated: len(export.Environments),
+		MembersToInvite:     toInvite,
+		APIKeysNotRestored:  len(export.APIKeys),
+	})
+}
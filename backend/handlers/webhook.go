@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateWebhookRequest checks the URL and that every event is known,
+// returning the events normalized for storage
+func validateWebhookRequest(req *models.WebhookRequest) (string, error) {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(req.Events) == 0 {
+		return "", fmt.Errorf("at least one event is required")
+	}
+
+	known := make(map[string]bool, len(models.WebhookEvents))
+	for _, event := range models.WebhookEvents {
+		known[event] = true
+	}
+	seen := make(map[string]bool, len(req.Events))
+	var events []string
+	for _, event := range req.Events {
+		if !known[event] {
+			return "", fmt.Errorf("unknown event %q (supported: %s)", event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return strings.Join(events, ","), nil
+}
+
+func toWebhookResponse(webhook *models.Webhook) models.WebhookResponse {
+	return models.WebhookResponse{
+		ID:        webhook.ID,
+		TeamID:    webhook.TeamID,
+		URL:       webhook.URL,
+		Events:    webhook.EventList(),
+		IsEnabled: webhook.IsEnabled,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+// requireWebhookOwner responds 403 unless the user owns the team
+func requireWebhookOwner(c *gin.Context) bool {
+	if !services.IsTeamOwner(c.GetUint("user_id"), c.GetUint("team_id")) {
+		apierr.RespondError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owners can manage webhooks")
+		return false
+	}
+	return true
+}
+
+// findWebhook loads one of the team's webhooks, responding with an error
+// itself when it can't
+func findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("webhook_id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid webhook ID")
+		return nil, false
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND team_id = ?", webhookID, c.GetUint("team_id")).First(&webhook).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.WebhookNotFound, "Webhook not found")
+		return nil, false
+	}
+	return &webhook, true
+}
+
+// GetWebhooks lists the team's webhooks (owner only)
+func GetWebhooks(c *gin.Context) {
+	if !requireWebhookOwner(c) {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.DB.Where("team_id = ?", c.GetUint("team_id")).Order("id").Find(&webhooks).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch webhooks")
+		return
+	}
+
+	response := make([]models.WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		response[i] = toWebhookResponse(&webhooks[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWebhook adds a webhook and returns its signing secret once
+func CreateWebhook(c *gin.Context) {
+	if !requireWebhookOwner(c) {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	events, err := validateWebhookRequest(&req)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	secret, err := services.GenerateWebhookSecret()
+	if err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to generate webhook secret")
+		return
+	}
+
+	webhook := models.Webhook{
+		TeamID:    c.GetUint("team_id"),
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		IsEnabled: req.IsEnabled == nil || *req.IsEnabled,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if err := database.DB.Create(&webhook).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create webhook")
+		return
+	}
+
+	response := toWebhookResponse(&webhook)
+	response.Secret = secret // Only returned on creation
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateWebhook changes a webhook's URL, events or enabled flag. The secret
+// stays the same.
+func UpdateWebhook(c *gin.Context) {
+	if !requireWebhookOwner(c) {
+		return
+	}
+	webhook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	events, err := validateWebhookRequest(&req)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = events
+	if req.IsEnabled != nil {
+		webhook.IsEnabled = *req.IsEnabled
+	}
+	if err := database.DB.Save(webhook).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update webhook")
+		return
+	}
+	c.JSON(http.StatusOK, toWebhookResponse(webhook))
+}
+
+// DeleteWebhook removes a webhook
+func DeleteWebhook(c *gin.Context) {
+	if !requireWebhookOwner(c) {
+		return
+	}
+	webhook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(webhook).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to delete webhook")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"postmanxodja/apierr"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateWorkflowRequest checks the workflow and that its environment and
+// collections belong to the team, responding with an error itself when not
+func validateWorkflowRequest(c *gin.Context, req *models.WorkflowRequest) bool {
+	teamID := c.GetUint("team_id")
+	if err := services.ValidateWorkflow(req); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return false
+	}
+
+	if req.EnvironmentID != nil {
+		var count int64
+		database.DB.Model(&models.Environment{}).Where("id = ? AND team_id = ?", *req.EnvironmentID, teamID).Count(&count)
+		if count == 0 {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+			return false
+		}
+	}
+
+	collectionIDs := make([]uint, 0, len(req.Steps))
+	seen := make(map[uint]bool, len(req.Steps))
+	for _, step := range req.Steps {
+		if !seen[step.CollectionID] {
+			seen[step.CollectionID] = true
+			collectionIDs = append(collectionIDs, step.CollectionID)
+		}
+	}
+	var found []uint
+	database.DB.Model(&models.Collection{}).Where("id IN ? AND team_id = ?", collectionIDs, teamID).Pluck("id", &found)
+	if len(found) != len(collectionIDs) {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.CollectionNotFound, "A step refers to a collection that doesn't exist in this team")
+		return false
+	}
+	return true
+}
+
+// findWorkflow loads one of the team's workflows, responding with an error
+// itself when it can't
+func findWorkflow(c *gin.Context) (*models.Workflow, bool) {
+	workflowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid workflow ID")
+		return nil, false
+	}
+
+	var workflow models.Workflow
+	if err := database.DB.Where("id = ? AND team_id = ?", workflowID, c.GetUint("team_id")).First(&workflow).Error; err != nil {
+		apierr.RespondError(c, http.StatusNotFound, apierr.WorkflowNotFound, "Workflow not found")
+		return nil, false
+	}
+	return &workflow, true
+}
+
+// GetWorkflows lists the team's workflows
+func GetWorkflows(c *gin.Context) {
+	var workflows []models.Workflow
+	if err := database.DB.Where("team_id = ?", c.GetUint("team_id")).Order("name").Find(&workflows).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to fetch workflows")
+		return
+	}
+	c.JSON(http.StatusOK, workflows)
+}
+
+// CreateWorkflow adds a workflow to the team
+func CreateWorkflow(c *gin.Context) {
+	var req models.WorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if !validateWorkflowRequest(c, &req) {
+		return
+	}
+
+	workflow := models.Workflow{
+		TeamID:            c.GetUint("team_id"),
+		Name:              req.Name,
+		Description:       req.Description,
+		Steps:             req.Steps,
+		EnvironmentID:     req.EnvironmentID,
+		ContinueOnFailure: req.ContinueOnFailure,
+		CreatedBy:         c.GetUint("user_id"),
+	}
+	if err := database.DB.Create(&workflow).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to create workflow")
+		return
+	}
+	c.JSON(http.StatusCreated, workflow)
+}
+
+// GetWorkflow returns one workflow
+func GetWorkflow(c *gin.Context) {
+	workflow, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, workflow)
+}
+
+// UpdateWorkflow replaces a workflow's contents
+func UpdateWorkflow(c *gin.Context) {
+	workflow, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req models.WorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.RespondBindError(c, err)
+		return
+	}
+	if !validateWorkflowRequest(c, &req) {
+		return
+	}
+
+	workflow.Name = req.Name
+	workflow.Description = req.Description
+	workflow.Steps = req.Steps
+	workflow.EnvironmentID = req.EnvironmentID
+	workflow.ContinueOnFailure = req.ContinueOnFailure
+	if err := database.DB.Save(workflow).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to update workflow")
+		return
+	}
+	c.JSON(http.StatusOK, workflow)
+}
+
+// DeleteWorkflow removes a workflow
+func DeleteWorkflow(c *gin.Context) {
+	workflow, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(workflow).Error; err != nil {
+		apierr.RespondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to delete workflow")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Workflow deleted successfully"})
+}
+
+// RunWorkflow executes a workflow's steps in order and returns each step's
+// result along with the final variables, or runs it once per data row and
+// returns each iteration. Like a single request, the run can be cancelled
+// through its execution ID.
+func RunWorkflow(c *gin.Context) {
+	teamID := c.GetUint("team_id")
+	workflow, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req models.RunWorkflowRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierr.RespondBindError(c, err)
+			return
+		}
+	}
+
+	if err := services.ValidateWorkflowDataRows(req.DataRows); err != nil {
+		apierr.RespondError(c, http.StatusBadRequest, apierr.InvalidRequest, err.Error())
+		return
+	}
+
+	environmentID := req.EnvironmentID
+	if environmentID == nil {
+		environmentID = workflow.EnvironmentID
+	}
+	var variables models.Variables
+	if environmentID != nil {
+		var env models.Environment
+		if err := database.DB.Where("id = ? AND team_id = ?", *environmentID, teamID).First(&env).Error; err != nil {
+			apierr.RespondError(c, http.StatusBadRequest, apierr.EnvironmentNotFound, "Environment not found")
+			return
+		}
+		variables = env.Variables
+	}
+
+	ctx, executionID, done, ok := beginExecution(c, req.ExecutionID, teamID)
+	if !ok {
+		return
+	}
+	defer done()
+
+	variables = services.MergeVariables(variables, req.InlineVariables)
+	var result *models.WorkflowRunResult
+	var err error
+	if len(req.DataRows) > 0 {
+		result, err = services.RunWorkflowDataRows(ctx, workflow, variables, req.DataRows)
+	} else {
+		result, err = services.RunWorkflow(ctx, workflow, variables)
+	}
+	if err != nil {
+		respondExecutionError(c, executionID, err)
+		return
+	}
+
+	result.ExecutionID = executionID
+	c.JSON(http.StatusOK, result)
+}
@@ -6,6 +6,8 @@ import (
 	"postmanxodja/database"
 	"postmanxodja/handlers"
 	"postmanxodja/middleware"
+	"postmanxodja/services"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,24 +23,42 @@ func main() {
 
 	// Load configuration
 	config.LoadConfig()
+	if err := config.AppConfig.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	// Initialize database
 	if err := database.InitDB(); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// Purge deleted teams once their restore window has passed
+	services.StartTeamPurger()
+
+	// Delete long-expired API keys and sessions
+	services.StartCredentialCleanup()
+
+	// Remind team owners of API keys about to expire
+	services.StartAPIKeyExpiryReminders()
+
+	// Deliver webhook events in the background
+	services.StartWebhookDispatcher()
+
 	// Initialize OAuth
 	handlers.InitOAuth()
 
 	// Create Gin router
 	r := gin.Default()
+	if err := r.SetTrustedProxies(config.AppConfig.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Configure CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000", "https://postbaby.uz", "https://www.postbaby.uz"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "x-api-key"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "x-api-key", "If-None-Match", "X-Confirm-Delete"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Retry-After", "ETag", "Last-Modified", "X-Total-Count", "Link"},
 		AllowCredentials: true,
 	}))
 
@@ -50,11 +70,14 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok", "service": "postmanxodja"})
 	})
 
-	// Public auth routes
+	// Public auth routes, throttled per client IP
+	limits := config.AppConfig
+	window := time.Duration(limits.RateLimitWindowSeconds) * time.Second
 	auth := r.Group("/api/auth")
+	auth.Use(middleware.RateLimit(limits.RateLimitAuth, window))
 	{
-		auth.POST("/register", handlers.Register)
-		auth.POST("/login", handlers.Login)
+		auth.POST("/register", middleware.RateLimit(limits.RateLimitRegister, window), handlers.Register)
+		auth.POST("/login", middleware.RateLimit(limits.RateLimitLogin, window), handlers.Login)
 		auth.POST("/refresh", handlers.RefreshToken)
 		// Google OAuth
 		auth.GET("/google", handlers.GoogleLogin)
@@ -64,7 +87,7 @@ func main() {
 	}
 
 	// Public invite route (to view invite details from email link)
-	r.GET("/api/invites/:token", handlers.GetInviteByToken)
+	r.GET("/api/invites/:token", middleware.RateLimit(limits.RateLimitInvite, window), handlers.GetInviteByToken)
 
 	// Protected routes
 	api := r.Group("/api")
@@ -72,11 +95,39 @@ func main() {
 	{
 		// Auth routes (protected)
 		api.GET("/auth/me", handlers.GetCurrentUser)
+		api.GET("/auth/bootstrap", handlers.Bootstrap)
 		api.POST("/auth/logout", handlers.Logout)
+		api.DELETE("/auth/sessions", handlers.RevokeOtherSessions)
+
+		// Maintenance routes for the accounts in ADMIN_EMAILS
+		admin := api.Group("/admin")
+		admin.Use(middleware.AdminMiddleware())
+		{
+			admin.POST("/test-email", middleware.RateLimit(limits.RateLimitTestEmail, window), handlers.SendTestEmail)
+			admin.POST("/reindex", handlers.Reindex)
+			admin.GET("/resource-owner", handlers.GetResourceOwner)
+			admin.GET("/executions", handlers.GetExecutionStats)
+		}
 
 		// Team routes
 		api.GET("/teams", handlers.GetUserTeams)
 		api.POST("/teams", handlers.CreateTeam)
+		api.POST("/teams/import", handlers.ImportTeam)
+		// Restoring a deleted team can't go through TeamAccessMiddleware
+		api.POST("/teams/:team_id/restore", handlers.RestoreTeam)
+
+		// Organization routes
+		api.GET("/organizations", handlers.GetUserOrganizations)
+		api.POST("/organizations", handlers.CreateOrganization)
+		orgApi := api.Group("/organizations/:org_id")
+		orgApi.Use(middleware.OrganizationAccessMiddleware())
+		{
+			orgApi.GET("/teams", handlers.GetOrganizationTeams)
+			orgApi.POST("/teams", handlers.AddOrganizationTeam)
+			orgApi.DELETE("/teams/:team_id", handlers.RemoveOrganizationTeam)
+			orgApi.POST("/members", handlers.AddOrganizationMember)
+			orgApi.DELETE("/members/:user_id", handlers.RemoveOrganizationMember)
+		}
 
 		// User's pending invites
 		api.GET("/invites", handlers.GetUserInvites)
@@ -86,10 +137,22 @@ func main() {
 		// Request execution (not team-scoped, uses environment_id in body)
 		api.POST("/requests/execute", handlers.ExecuteRequest)
 		api.POST("/requests/execute-multipart", handlers.ExecuteMultipartRequest)
+		api.POST("/requests/validate", handlers.ValidateRequest)
+		api.POST("/requests/extract", handlers.ExtractFromBody)
+		api.POST("/requests/oauth2/token", handlers.FetchOAuth2Token)
+		api.POST("/requests/graphql/introspect", handlers.IntrospectGraphQL)
+		api.POST("/requests/:execution_id/cancel", handlers.CancelExecution)
+		api.GET("/requests/response/:artifact_id/download", handlers.DownloadResponseArtifact)
+
+		// Request history (user-scoped)
+		api.GET("/requests/history", handlers.GetRequestHistory)
+		api.POST("/requests/history/clear", handlers.ClearRequestHistory)
 
 		// Saved tabs (user-scoped)
 		api.GET("/tabs", handlers.GetSavedTabs)
 		api.POST("/tabs", handlers.SaveTabs)
+		api.PUT("/tabs/:tab_id/pin", handlers.PinTab)
+		api.DELETE("/tabs/:tab_id/pin", handlers.UnpinTab)
 
 		// Team-specific routes (require team membership)
 		teamApi := api.Group("/teams/:team_id")
@@ -99,10 +162,12 @@ func main() {
 			teamApi.GET("", handlers.GetTeam)
 			teamApi.PUT("", handlers.UpdateTeam)
 			teamApi.DELETE("", handlers.DeleteTeam)
+			teamApi.GET("/export", handlers.ExportTeam)
 
 			// Team members
 			teamApi.GET("/members", handlers.GetTeamMembers)
 			teamApi.DELETE("/members/:user_id", handlers.RemoveTeamMember)
+			teamApi.PUT("/members/:user_id/role", handlers.UpdateMemberRole)
 			teamApi.POST("/leave", handlers.LeaveTeam)
 
 			// Team invites
@@ -113,35 +178,90 @@ func main() {
 			teamApi.GET("/collections", handlers.GetCollections)
 			teamApi.POST("/collections", handlers.CreateCollection)
 			teamApi.POST("/collections/import", handlers.ImportCollection)
+			teamApi.GET("/collections/export-all", handlers.ExportAllCollections)
 			teamApi.GET("/collections/:id", handlers.GetCollection)
 			teamApi.GET("/collections/:id/export", handlers.ExportCollection)
+			teamApi.GET("/collections/:id/variable-usage", handlers.GetVariableUsage)
+			teamApi.GET("/collections/:id/lint", handlers.LintCollection)
+			teamApi.GET("/collections/:id/preview", handlers.PreviewCollection)
 			teamApi.PUT("/collections/:id", handlers.UpdateCollection)
+			teamApi.PATCH("/collections/:id", handlers.UpdateCollection)
 			teamApi.PATCH("/collections/:id/environment", handlers.SetCollectionEnvironment)
+			teamApi.POST("/collections/:id/reorder", handlers.ReorderCollection)
+			teamApi.POST("/collections/:id/resync", handlers.ResyncCollection)
+			teamApi.PUT("/collections/:id/pin", handlers.PinCollection)
+			teamApi.DELETE("/collections/:id/pin", handlers.UnpinCollection)
 			teamApi.DELETE("/collections/:id", handlers.DeleteCollection)
+			teamApi.POST("/collections/:id/items/execute", handlers.ExecuteCollectionItem)
+			teamApi.GET("/collections/:id/items/timings", handlers.GetItemTimings)
 
 			// Team environments
 			teamApi.GET("/environments", handlers.GetEnvironments)
 			teamApi.POST("/environments", handlers.CreateEnvironment)
 			teamApi.PUT("/environments/:id", handlers.UpdateEnvironment)
+			teamApi.PATCH("/environments/:id/variables", handlers.PatchEnvironmentVariables)
+			teamApi.POST("/environments/:id/copy-from", handlers.CopyEnvironmentVariables)
 			teamApi.DELETE("/environments/:id", handlers.DeleteEnvironment)
 
+			// Team request templates
+			teamApi.GET("/request-templates", handlers.GetRequestTemplates)
+			teamApi.POST("/request-templates", handlers.CreateRequestTemplate)
+			teamApi.GET("/request-templates/:template_id", handlers.GetRequestTemplate)
+			teamApi.PUT("/request-templates/:template_id", handlers.UpdateRequestTemplate)
+			teamApi.DELETE("/request-templates/:template_id", handlers.DeleteRequestTemplate)
+			teamApi.POST("/request-templates/:template_id/apply", handlers.ApplyRequestTemplate)
+
+			// Team workflows
+			teamApi.GET("/workflows", handlers.GetWorkflows)
+			teamApi.POST("/workflows", handlers.CreateWorkflow)
+			teamApi.GET("/workflows/:id", handlers.GetWorkflow)
+			teamApi.PUT("/workflows/:id", handlers.UpdateWorkflow)
+			teamApi.DELETE("/workflows/:id", handlers.DeleteWorkflow)
+			teamApi.POST("/workflows/:id/run", handlers.RunWorkflow)
+
 			// Team API keys management
 			teamApi.GET("/api-keys", handlers.GetAPIKeys)
+			teamApi.GET("/api-key-settings", handlers.GetAPIKeySettings)
+			teamApi.PUT("/api-key-settings", handlers.UpdateAPIKeySettings)
 			teamApi.POST("/api-keys", handlers.CreateAPIKey)
+			teamApi.PATCH("/api-keys/:key_id", handlers.UpdateAPIKey)
 			teamApi.DELETE("/api-keys/:key_id", handlers.DeleteAPIKey)
 
+			// Host allow/deny policy for the executor (owner manages)
+			teamApi.GET("/host-policy", handlers.GetHostPolicy)
+			teamApi.PUT("/host-policy", handlers.UpdateHostPolicy)
+			teamApi.DELETE("/host-policy", handlers.DeleteHostPolicy)
+
+			// Headers added to every request the team executes (owner manages)
+			teamApi.GET("/request-defaults", handlers.GetRequestDefaults)
+			teamApi.PUT("/request-defaults", handlers.UpdateRequestDefaults)
+			teamApi.DELETE("/request-defaults", handlers.DeleteRequestDefaults)
+
+			// Team webhooks (owner only)
+			teamApi.GET("/webhooks", handlers.GetWebhooks)
+			teamApi.POST("/webhooks", handlers.CreateWebhook)
+			teamApi.PUT("/webhooks/:webhook_id", handlers.UpdateWebhook)
+			teamApi.DELETE("/webhooks/:webhook_id", handlers.DeleteWebhook)
+
 			// Team AI settings
 			teamApi.GET("/ai-settings", handlers.GetAISettings)
 			teamApi.PUT("/ai-settings", handlers.UpdateAISettings)
 			teamApi.DELETE("/ai-settings", handlers.DeleteAISettings)
+			teamApi.GET("/ai/models", handlers.GetAIModels)
 			teamApi.POST("/ai-analyze", handlers.AIAnalyzeDBML)
 		}
 	}
 
+	// Public API description (no API key needed to read it)
+	r.GET("/api/v1/openapi.json", handlers.PublicOpenAPISpec)
+
 	// Public API routes (authenticated via API key for third-party access)
 	publicApi := r.Group("/api/v1")
 	publicApi.Use(middleware.APIKeyMiddleware())
 	{
+		// The key itself, so integrations can check it at startup
+		publicApi.GET("/whoami", handlers.PublicWhoAmI)
+
 		// Collections - read endpoints
 		publicApi.GET("/collections", handlers.PublicGetCollections)
 		publicApi.GET("/collections/:id", handlers.PublicGetCollection)
@@ -149,7 +269,7 @@ func main() {
 
 		// Collections - write endpoints (require write permission)
 		writeApi := publicApi.Group("")
-		writeApi.Use(middleware.RequireWritePermission())
+		writeApi.Use(middleware.RequireWritePermission(), middleware.Idempotency())
 		{
 			writeApi.POST("/collections", handlers.PublicCreateCollection)
 			writeApi.PUT("/collections/:id", handlers.PublicUpdateCollection)
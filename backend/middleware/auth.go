@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"postmanxodja/apierr"
+	"postmanxodja/config"
 	"postmanxodja/database"
 	"postmanxodja/models"
 	"postmanxodja/services"
@@ -17,24 +19,35 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.Unauthorized, "Authorization header required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.Unauthorized, "Invalid authorization format")
 			return
 		}
 
 		claims, err := services.ValidateJWT(parts[1])
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.InvalidToken, "Invalid or expired token")
 			return
 		}
 
+		// Access tokens of revoked sessions stop working right away. Tokens
+		// issued before sessions were persisted carry no session ID.
+		if claims.SessionID != 0 && !services.IsSessionActive(claims.SessionID) {
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.InvalidToken, "Session has been revoked")
+			return
+		}
+
+		services.TouchUserActivity(claims.UserID)
+
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_claims", claims)
 		c.Next()
 	}
 }
@@ -45,18 +58,23 @@ func TeamAccessMiddleware() gin.HandlerFunc {
 		teamIDStr := c.Param("team_id")
 
 		if teamIDStr == "" {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Team ID required"})
+			apierr.AbortWithError(c, http.StatusBadRequest, apierr.InvalidID, "Team ID required")
 			return
 		}
 
 		teamID, err := strconv.ParseUint(teamIDStr, 10, 32)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+			apierr.AbortWithError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid team ID")
 			return
 		}
 
-		if !services.UserBelongsToTeam(userID, uint(teamID)) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied to this team"})
+		// A fresh token's team list saves the membership query
+		trusted := false
+		if claims, ok := c.Get("token_claims"); ok {
+			trusted = services.TrustTeamClaim(claims.(*services.JWTClaims), uint(teamID), time.Now())
+		}
+		if !trusted && !services.UserBelongsToTeam(userID, uint(teamID)) {
+			apierr.AbortWithError(c, http.StatusForbidden, apierr.PermissionDenied, "Access denied to this team")
 			return
 		}
 
@@ -71,7 +89,7 @@ func TeamOwnerMiddleware() gin.HandlerFunc {
 		teamID := c.GetUint("team_id")
 
 		if !services.IsTeamOwner(userID, teamID) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only team owner can perform this action"})
+			apierr.AbortWithError(c, http.StatusForbidden, apierr.PermissionDenied, "Only team owner can perform this action")
 			return
 		}
 
@@ -79,6 +97,40 @@ func TeamOwnerMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OrganizationAccessMiddleware admits members of the organization in
+// :org_id and sets "organization_id" and "organization_role"
+func OrganizationAccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, err := strconv.ParseUint(c.Param("org_id"), 10, 32)
+		if err != nil {
+			apierr.AbortWithError(c, http.StatusBadRequest, apierr.InvalidID, "Invalid organization ID")
+			return
+		}
+
+		role := services.GetOrganizationRole(c.GetUint("user_id"), uint(orgID))
+		if role == "" {
+			apierr.AbortWithError(c, http.StatusNotFound, apierr.OrganizationNotFound, "Organization not found")
+			return
+		}
+
+		c.Set("organization_id", uint(orgID))
+		c.Set("organization_role", role)
+		c.Next()
+	}
+}
+
+// AdminMiddleware lets through only the accounts listed in ADMIN_EMAILS. It
+// runs after AuthMiddleware, whose token carries the email.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if config.AppConfig == nil || !config.AppConfig.IsAdmin(c.GetString("email")) {
+			apierr.AbortWithError(c, http.StatusForbidden, apierr.PermissionDenied, "Admin access required")
+			return
+		}
+		c.Next()
+	}
+}
+
 // APIKeyMiddleware authenticates requests using API keys for third-party access
 func APIKeyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -92,20 +144,28 @@ func APIKeyMiddleware() gin.HandlerFunc {
 		}
 
 		if apiKey == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.APIKeyRequired, "API key required")
 			return
 		}
 
-		// Find the API key in database
+		// Find the API key in database (keys of deleted teams don't count)
 		var keyRecord models.TeamAPIKey
-		if err := database.GetDB().Where("key = ?", apiKey).First(&keyRecord).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		if err := database.GetDB().
+			Joins("JOIN teams ON teams.id = team_api_keys.team_id AND teams.deleted_at IS NULL").
+			Where("team_api_keys.key = ?", apiKey).
+			First(&keyRecord).Error; err != nil {
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.InvalidAPIKey, "Invalid API key")
 			return
 		}
 
 		// Check if key is expired
 		if keyRecord.ExpiresAt != nil && keyRecord.ExpiresAt.Before(time.Now()) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key has expired"})
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.APIKeyExpired, "API key has expired")
+			return
+		}
+
+		if !keyRecord.Enabled {
+			apierr.AbortWithError(c, http.StatusUnauthorized, apierr.APIKeyDisabled, "API key is disabled; a team owner can re-enable it")
 			return
 		}
 
@@ -126,7 +186,7 @@ func RequireWritePermission() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		permissions := c.GetString("api_key_permissions")
 		if permissions != "write" && permissions != "read_write" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Write permission required"})
+			apierr.AbortWithError(c, http.StatusForbidden, apierr.PermissionDenied, "Write permission required")
 			return
 		}
 		c.Next()
@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"postmanxodja/apierr"
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apierr.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode error body '%s': %v", w.Body.String(), err)
+	}
+	return body.Error.Code
+}
+
+func TestAuthMiddlewareErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, header := range []string{"", "Token abc", "Bearer"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization '%s': expected status 401, got %d", header, w.Code)
+		}
+		if code := errorCode(t, w); code != apierr.Unauthorized {
+			t.Errorf("Authorization '%s': expected code '%s', got '%s'", header, apierr.Unauthorized, code)
+		}
+	}
+}
+
+func TestRequireWritePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for permissions, want := range map[string]int{
+		"read":       http.StatusForbidden,
+		"write":      http.StatusOK,
+		"read_write": http.StatusOK,
+	} {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			c.Set("api_key_permissions", permissions)
+		}, RequireWritePermission(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != want {
+			t.Errorf("Permissions '%s': expected status %d, got %d", permissions, want, w.Code)
+		}
+		if want == http.StatusForbidden {
+			if code := errorCode(t, w); code != apierr.PermissionDenied {
+				t.Errorf("Expected code '%s', got '%s'", apierr.PermissionDenied, code)
+			}
+		}
+	}
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{AdminEmails: []string{"ops@example.com"}}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	for email, want := range map[string]int{
+		"ops@example.com":  http.StatusOK,
+		" OPS@Example.com": http.StatusOK,
+		"ada@example.com":  http.StatusForbidden,
+		"":                 http.StatusForbidden,
+	} {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			c.Set("email", email)
+		}, AdminMiddleware(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != want {
+			t.Errorf("Email '%s': expected status %d, got %d", email, want, w.Code)
+		}
+	}
+}
+
+func TestTeamAccessMiddlewareTeamClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	previousDB := database.DB
+	if err := database.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = previousDB
+	})
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", JWTExpirationHours: 1, RefreshExpirationDays: 1, JWTTeamClaims: true, JWTTeamClaimsGraceMinutes: 5}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	user := models.User{Email: "ada@example.com", Name: "Ada"}
+	database.DB.Create(&user)
+	team, _ := services.CreateTeamWithOwner("Acme", user.ID)
+	other := models.Team{Name: "Other"}
+	database.DB.Create(&other)
+	auth, err := services.GenerateTokenPair(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/teams/:team_id", AuthMiddleware(), TeamAccessMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func(teamID uint) int {
+		req := httptest.NewRequest(http.MethodGet, "/teams/"+strconv.Itoa(int(teamID)), nil)
+		req.Header.Set("Authorization", "Bearer "+auth.AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// With the membership gone from the database, only the token vouches for it
+	database.DB.Where("team_id = ?", team.ID).Delete(&models.TeamMember{})
+	if code := get(team.ID); code != http.StatusOK {
+		t.Errorf("Expected the token's team claim to be trusted, got %d", code)
+	}
+	if code := get(other.ID); code != http.StatusForbidden {
+		t.Errorf("Expected a team outside the token to be checked against the database, got %d", code)
+	}
+
+	services.MarkMembershipChanged(team.ID)
+	if code := get(team.ID); code != http.StatusForbidden {
+		t.Errorf("Expected a changed membership to fall back to the database, got %d", code)
+	}
+}
+
+func TestAPIKeyOfRemovedMemberStopsAuthenticating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	previousDB := database.DB
+	if err := database.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = previousDB
+	})
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner"}
+	member := models.User{Email: "member@example.com", Name: "Member"}
+	database.DB.Create(&owner)
+	database.DB.Create(&member)
+	team, _ := services.CreateTeamWithOwner("Acme", owner.ID)
+	database.DB.Create(&models.TeamMember{TeamID: team.ID, UserID: member.ID, Role: "member"})
+	key := models.TeamAPIKey{TeamID: team.ID, Name: "CI", Key: "pmx_member_key", KeyPrefix: "pmx_memb", CreatedBy: member.ID, Enabled: true}
+	database.DB.Create(&key)
+
+	r := gin.New()
+	r.GET("/", APIKeyMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusOK {
+		t.Fatalf("Expected the member's key to work while they're in the team, got %d", w.Code)
+	}
+	if _, err := services.RemoveMemberFromTeam(team.ID, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(); w.Code != http.StatusUnauthorized || errorCode(t, w) != apierr.APIKeyDisabled {
+		t.Errorf("Expected the removed member's key to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyOfDeletedTeamStopsAuthenticating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	previousDB := database.DB
+	if err := database.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = previousDB
+	})
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner"}
+	database.DB.Create(&owner)
+	deleted, _ := services.CreateTeamWithOwner("Deleted", owner.ID)
+	orphaned, _ := services.CreateTeamWithOwner("Orphaned", owner.ID)
+	for _, key := range []models.TeamAPIKey{
+		{TeamID: deleted.ID, Name: "CI", Key: "pmx_deleted_key", KeyPrefix: "pmx_dele", CreatedBy: owner.ID, Enabled: true},
+		{TeamID: orphaned.ID, Name: "CI", Key: "pmx_orphan_key", KeyPrefix: "pmx_orph", CreatedBy: owner.ID, Enabled: true},
+	} {
+		database.DB.Create(&key)
+	}
+	database.DB.Create(&models.TeamAISettings{TeamID: deleted.ID, APIKey: "secret"})
+
+	if err := services.SoftDeleteTeam(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	var keys, aiSettings int64
+	database.DB.Model(&models.TeamAPIKey{}).Where("team_id = ?", deleted.ID).Count(&keys)
+	database.DB.Model(&models.TeamAISettings{}).Where("team_id = ?", deleted.ID).Count(&aiSettings)
+	if keys != 0 || aiSettings != 0 {
+		t.Errorf("Expected the deleted team's credentials removed, got %d keys and %d AI settings", keys, aiSettings)
+	}
+	// A key left behind by a team deleted some other way still has to stop working
+	database.DB.Model(&models.Team{}).Where("id = ?", orphaned.ID).Update("deleted_at", time.Now())
+
+	r := gin.New()
+	r.GET("/", APIKeyMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, key := range []string{"pmx_deleted_key", "pmx_orphan_key"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized || errorCode(t, w) != apierr.InvalidAPIKey {
+			t.Errorf("%s: expected the key to be rejected, got %d: %s", key, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"postmanxodja/apierr"
+	"postmanxodja/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a write safe
+// to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// responseRecorder keeps a copy of the response body while writing it through
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request repeats an
+// Idempotency-Key the team already used. Keys are scoped per team (set by
+// APIKeyMiddleware) and must be reused with the same method, path and body.
+// Requests without the header pass through untouched.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apierr.AbortWithError(c, http.StatusBadRequest, apierr.InvalidRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apierr.AbortWithError(c, http.StatusBadRequest, apierr.InvalidRequest, "Failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		teamID := c.GetUint("team_id")
+		stored, err := services.BeginIdempotentRequest(teamID, key, fingerprint)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			apierr.AbortWithError(c, http.StatusUnprocessableEntity, apierr.IdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+			return
+		case errors.Is(err, services.ErrIdempotencyInProgress):
+			apierr.AbortWithError(c, http.StatusConflict, apierr.IdempotencyInProgress, "A request with this Idempotency-Key is still in progress")
+			return
+		case stored != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			// A panicking handler never stored its response; let the retry run
+			if recovered := recover(); recovered != nil {
+				services.ReleaseIdempotentRequest(teamID, key)
+				panic(recovered)
+			}
+		}()
+
+		c.Next()
+
+		services.CompleteIdempotentRequest(teamID, key, services.IdempotentResponse{
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
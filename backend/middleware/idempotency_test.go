@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"postmanxodja/apierr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotentRouter counts how often the create handler really runs
+func idempotentRouter(created *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/collections", func(c *gin.Context) {
+		c.Set("team_id", uint(1))
+		if c.GetHeader("X-Team") == "2" {
+			c.Set("team_id", uint(2))
+		}
+	}, Idempotency(), func(c *gin.Context) {
+		*created++
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"id": *created, "body": string(body)})
+	})
+	return r
+}
+
+func postWithKey(r *gin.Engine, key, body, team string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/collections", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if team != "" {
+		req.Header.Set("X-Team", team)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysSameKey(t *testing.T) {
+	created := 0
+	r := idempotentRouter(&created)
+
+	first := postWithKey(r, "replay-key", `{"name":"API"}`, "")
+	second := postWithKey(r, "replay-key", `{"name":"API"}`, "")
+
+	if created != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", created)
+	}
+	if second.Code != http.StatusCreated {
+		t.Errorf("Expected replayed status 201, got %d", second.Code)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed body '%s', got '%s'", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected the replay to be marked with Idempotent-Replayed")
+	}
+	if !strings.Contains(second.Body.String(), `"body":"{\"name\":\"API\"}"`) {
+		t.Errorf("Expected the handler to have seen the request body, got '%s'", second.Body.String())
+	}
+}
+
+func TestIdempotencyKeysAreScopedPerTeam(t *testing.T) {
+	created := 0
+	r := idempotentRouter(&created)
+
+	postWithKey(r, "team-key", `{}`, "")
+	w := postWithKey(r, "team-key", `{}`, "2")
+
+	if created != 2 {
+		t.Errorf("Expected the other team's request to run, handler ran %d times", created)
+	}
+	if w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Expected no replay across teams")
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	created := 0
+	r := idempotentRouter(&created)
+
+	postWithKey(r, "reused-key", `{"name":"A"}`, "")
+	w := postWithKey(r, "reused-key", `{"name":"B"}`, "")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	if code := errorCode(t, w); code != apierr.IdempotencyKeyReused {
+		t.Errorf("Expected code '%s', got '%s'", apierr.IdempotencyKeyReused, code)
+	}
+	if created != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", created)
+	}
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	created := 0
+	r := idempotentRouter(&created)
+
+	postWithKey(r, "", `{}`, "")
+	postWithKey(r, "", `{}`, "")
+
+	if created != 2 {
+		t.Errorf("Expected both requests to run without a key, ran %d times", created)
+	}
+}
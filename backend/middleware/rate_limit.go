@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"postmanxodja/apierr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter allows each key limit requests in any sliding window. It keeps
+// the request times per key, at most limit of them, in memory; like the login
+// guard it resets on restart and isn't shared between instances.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// allow records a request for key, or returns how long until it would be
+// allowed
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+	if now.Sub(l.lastSweep) > l.window {
+		// Forget keys with nothing left in the window
+		for k, times := range l.hits {
+			if !times[len(times)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	times := l.hits[key]
+	kept := 0
+	for kept < len(times) && !times[kept].After(cutoff) {
+		kept++
+	}
+	times = times[kept:]
+
+	if len(times) >= l.limit {
+		l.hits[key] = times
+		return false, times[0].Sub(cutoff)
+	}
+	l.hits[key] = append(times, now)
+	return true, 0
+}
+
+// RateLimit allows each client IP limit requests per sliding window to the
+// routes it's applied to, answering 429 with Retry-After beyond that. Every
+// call keeps its own counts, so each endpoint can have its own limit; a
+// limit of 0 or less turns it off. The client IP is gin's ClientIP, which
+// only believes X-Forwarded-For from the router's trusted proxies.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := newRateLimiter(limit, window)
+
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.allow(c.ClientIP())
+		if !allowed {
+			seconds := int(retryAfter / time.Second)
+			if retryAfter%time.Second != 0 {
+				seconds++
+			}
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			apierr.AbortWithError(c, http.StatusTooManyRequests, apierr.RateLimited, "Too many requests. Try again later.")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"postmanxodja/apierr"
+	"postmanxodja/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitPerEndpointAndIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.POST("/register", RateLimit(2, time.Minute), ok)
+	r.POST("/login", RateLimit(5, time.Minute), ok)
+
+	call := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := call("/register", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+	w := call("/register", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 past the limit, got %d", w.Code)
+	}
+	if code := errorCode(t, w); code != apierr.RateLimited {
+		t.Errorf("Expected code '%s', got '%s'", apierr.RateLimited, code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "60" {
+		t.Errorf("Expected Retry-After '60', got '%s'", retry)
+	}
+
+	if w := call("/register", "10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to have its own limit, got %d", w.Code)
+	}
+	if w := call("/login", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected login to have its own limit, got %d", w.Code)
+	}
+}
+
+func TestRateLimitIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	call := func(r *gin.Engine, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	router := func() *gin.Engine {
+		config.LoadConfig()
+		r := gin.New()
+		if err := r.SetTrustedProxies(config.AppConfig.TrustedProxies); err != nil {
+			t.Fatal(err)
+		}
+		r.POST("/login", RateLimit(1, time.Minute), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+
+	r := router()
+	if code := call(r, "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("Expected the first request through, got %d", code)
+	}
+	if code := call(r, "203.0.113.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a spoofed X-Forwarded-For not to reset the limit, got %d", code)
+	}
+
+	// Behind a trusted proxy each forwarded client has its own limit
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	r = router()
+	call(r, "203.0.113.1")
+	if code := call(r, "203.0.113.2"); code != http.StatusOK {
+		t.Errorf("Expected X-Forwarded-For from a trusted proxy to be used, got %d", code)
+	}
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("ip")
+	now = now.Add(30 * time.Second)
+	limiter.allow("ip")
+
+	allowed, retryAfter := limiter.allow("ip")
+	if allowed || retryAfter != 30*time.Second {
+		t.Fatalf("Expected a denial until the first request leaves the window, got %v after %v", allowed, retryAfter)
+	}
+
+	now = now.Add(31 * time.Second)
+	if allowed, _ := limiter.allow("ip"); !allowed {
+		t.Error("Expected a request once the first one left the window")
+	}
+	if allowed, _ := limiter.allow("ip"); allowed {
+		t.Error("Expected the second request to still count")
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", RateLimit(0, time.Minute), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected no limit, got %d", w.Code)
+		}
+	}
+}
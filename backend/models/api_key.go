@@ -4,25 +4,34 @@ import "time"
 
 // TeamAPIKey represents an API key for third-party access to team resources
 type TeamAPIKey struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	TeamID      uint      `json:"team_id" gorm:"not null;index"`
-	Name        string    `json:"name" gorm:"not null"` // e.g., "CI/CD Pipeline", "External Integration"
-	Key         string    `json:"-" gorm:"uniqueIndex;not null"`
-	KeyPrefix   string    `json:"key_prefix" gorm:"not null"` // First 8 chars for identification
-	Permissions string    `json:"permissions" gorm:"default:'read'"` // read, write, read_write
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TeamID      uint       `json:"team_id" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"not null"` // e.g., "CI/CD Pipeline", "External Integration"
+	Key         string     `json:"-" gorm:"uniqueIndex;not null"`
+	KeyPrefix   string     `json:"key_prefix" gorm:"not null"`           // First 8 chars for identification
+	Permissions string     `json:"permissions" gorm:"default:'read'"`    // read, write, read_write
+	Enabled     bool       `json:"enabled" gorm:"not null;default:true"` // disabled keys are rejected but kept
 	LastUsedAt  *time.Time `json:"last_used_at"`
-	ExpiresAt   *time.Time `json:"expires_at"` // nil means no expiration
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   uint      `json:"created_by" gorm:"not null"`
-	Team        *Team     `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"` // nil means no expiration
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   uint       `json:"created_by" gorm:"not null"`
+	Team        *Team      `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+
+	// When the team owner was emailed that the key expires soon; once per key
+	ExpiryReminderSentAt *time.Time `json:"-"`
 }
 
 type CreateAPIKeyRequest struct {
 	Name        string `json:"name" binding:"required"`
-	Permissions string `json:"permissions"` // read, write, read_write (default: read)
+	Permissions string `json:"permissions"` // read, write, read_write (default: the team's default permission)
 	ExpiresIn   int    `json:"expires_in"`  // Days until expiration, 0 = no expiration
 }
 
+// UpdateAPIKeyRequest disables or re-enables an API key
+type UpdateAPIKeyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
 type APIKeyResponse struct {
 	ID          uint       `json:"id"`
 	TeamID      uint       `json:"team_id"`
@@ -30,7 +39,48 @@ type APIKeyResponse struct {
 	Key         string     `json:"key,omitempty"` // Only returned on creation
 	KeyPrefix   string     `json:"key_prefix"`
 	Permissions string     `json:"permissions"`
+	Enabled     bool       `json:"enabled"`
 	LastUsedAt  *time.Time `json:"last_used_at"`
 	ExpiresAt   *time.Time `json:"expires_at"`
 	CreatedAt   time.Time  `json:"created_at"`
+	// Whole days left before ExpiresAt, negative once expired; nil for keys
+	// that never expire
+	DaysUntilExpiry *int `json:"days_until_expiry"`
+	// Within API_KEY_EXPIRY_WARNING_DAYS of expiring, and not expired yet
+	ExpiringSoon bool `json:"expiring_soon"`
+}
+
+// APIKeyWhoAmIResponse describes the key a public API request was made with,
+// without the key itself
+type APIKeyWhoAmIResponse struct {
+	KeyID       uint       `json:"key_id"`
+	TeamID      uint       `json:"team_id"`
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"key_prefix"`
+	Permissions string     `json:"permissions"`
+	CanWrite    bool       `json:"can_write"` // allowed on the write endpoints
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// TeamAPIKeySettings holds a team's API key preferences
+type TeamAPIKeySettings struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	TeamID            uint      `json:"team_id" gorm:"uniqueIndex;not null"`
+	DefaultPermission string    `json:"default_permission" gorm:"not null;default:'read'"` // for keys created without permissions
+	UpdatedBy         uint      `json:"updated_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// RequireDeleteConfirmation makes public API deletes fail with 428
+	// unless they send X-Confirm-Delete: true, so a CI job can't delete a
+	// collection by accident
+	RequireDeleteConfirmation bool `json:"require_delete_confirmation" gorm:"not null;default:false"`
+}
+
+// APIKeySettingsRequest replaces a team's API key settings.
+// RequireDeleteConfirmation is left as it is when absent.
+type APIKeySettingsRequest struct {
+	DefaultPermission         string `json:"default_permission" binding:"required"`
+	RequireDeleteConfirmation *bool  `json:"require_delete_confirmation"`
 }
@@ -1,16 +1,59 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
 
-// Collection represents a stored Postman collection in database
+	"gorm.io/gorm"
+)
+
+// Collection represents a stored Postman collection in database.
+// Pinned collections are listed first. Pins are shared by the team, like the
+// collection itself: they mark what the team works with most, and keeping
+// them on the row avoids a per-user table for what is only a listing hint.
 type Collection struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	RawJSON       string    `json:"raw_json" gorm:"type:text"`
-	EnvironmentID *uint     `json:"environment_id" gorm:"index"`
-	TeamID        *uint     `json:"team_id" gorm:"index"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	RawJSON       string         `json:"raw_json" gorm:"type:text"`
+	RequestCount  int            `json:"request_count" gorm:"not null;default:0"` // kept in step with RawJSON by BeforeSave
+	EnvironmentID *uint          `json:"environment_id" gorm:"index"`             // used by runs that don't pick an environment
+	TeamID        *uint          `json:"team_id" gorm:"index"`
+	Pinned        bool           `json:"pinned" gorm:"not null;default:false"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"` // behind the ETag of GET responses
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// BeforeSave recounts the requests whenever the collection is created or
+// saved, so listings can show the count without parsing RawJSON
+func (c *Collection) BeforeSave(tx *gorm.DB) error {
+	c.RequestCount = CountCollectionRequests(c.RawJSON)
+	return nil
+}
+
+// CountCollectionRequests returns how many requests a Postman collection JSON
+// holds, including those in nested folders. Unparseable JSON counts as 0.
+func CountCollectionRequests(rawJSON string) int {
+	if rawJSON == "" {
+		return 0
+	}
+	var collection PostmanCollection
+	if err := json.Unmarshal([]byte(rawJSON), &collection); err != nil {
+		return 0
+	}
+	return countRequests(collection.Item)
+}
+
+func countRequests(items []PostmanItem) int {
+	count := 0
+	for _, item := range items {
+		if item.Request != nil {
+			count++
+		}
+		count += countRequests(item.Item)
+	}
+	return count
 }
 
 // PostmanCollection represents Postman Collection v2.1 format
@@ -18,6 +61,7 @@ type PostmanCollection struct {
 	Info     PostmanInfo       `json:"info"`
 	Item     []PostmanItem     `json:"item"`
 	Variable []PostmanVariable `json:"variable,omitempty"`
+	Auth     *PostmanAuth      `json:"auth,omitempty"` // Default auth for every request
 }
 
 type PostmanInfo struct {
@@ -34,6 +78,7 @@ type PostmanItem struct {
 	Request  *PostmanRequest   `json:"request,omitempty"`
 	Response []PostmanResponse `json:"response,omitempty"` // Saved example responses
 	Item     []PostmanItem     `json:"item"`               // For folders
+	Auth     *PostmanAuth      `json:"auth,omitempty"`     // Folder auth, inherited by its requests
 }
 
 // PostmanResponse represents a saved example response (Postman collection v2.1 format)
@@ -122,3 +167,62 @@ type PostmanVariable struct {
 	Value string `json:"value"`
 	Type  string `json:"type,omitempty"`
 }
+
+// VariableUsage lists the requests in a collection that reference a variable
+type VariableUsage struct {
+	Name     string              `json:"name"`
+	Requests []VariableReference `json:"requests"`
+}
+
+// VariableReference is a request that uses a variable, and where
+type VariableReference struct {
+	ItemPath  []string `json:"item_path"` // see ExecuteCollectionItemRequest.ItemPath
+	Method    string   `json:"method"`
+	Locations []string `json:"locations"` // url, header, body, auth
+}
+
+// ReorderCollectionRequest sets the order of the items in one or more folders
+type ReorderCollectionRequest struct {
+	Folders []FolderOrder `json:"folders" binding:"required"`
+}
+
+// FolderOrder lists every item of a folder by name, in their new order. Path
+// is the folder names from the collection root, empty for the top level.
+type FolderOrder struct {
+	Path  []string `json:"path"`
+	Items []string `json:"items"`
+}
+
+// LintFinding is a likely mistake LintCollection found in a request
+type LintFinding struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`  // error, warning
+	ItemPath []string `json:"item_path"` // see ExecuteCollectionItemRequest.ItemPath
+	Message  string   `json:"message"`
+}
+
+// CollectionPreview is a collection with variables substituted into its
+// requests, as PreviewCollection builds it
+type CollectionPreview struct {
+	Items               []PreviewItem `json:"items"`
+	UnresolvedVariables []string      `json:"unresolved_variables"` // across all requests, sorted
+	MaskedVariables     []string      `json:"masked_variables"`     // secrets shown as "***", sorted
+}
+
+// PreviewItem is a folder (Item) or a request (Request) of a preview
+type PreviewItem struct {
+	Name    string          `json:"name"`
+	Request *PreviewRequest `json:"request,omitempty"`
+	Item    []PreviewItem   `json:"item,omitempty"`
+}
+
+// PreviewRequest is a request as it would be sent, auth included. Error is
+// set instead when the request can't be built.
+type PreviewRequest struct {
+	Method              string     `json:"method"`
+	URL                 string     `json:"url"`
+	Headers             []KeyValue `json:"headers"`
+	Body                string     `json:"body,omitempty"`
+	UnresolvedVariables []string   `json:"unresolved_variables,omitempty"`
+	Error               string     `json:"error,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCollectionBeforeSaveCountsRequests(t *testing.T) {
+	collection := PostmanCollection{
+		Info: PostmanInfo{Name: "Shop"},
+		Item: []PostmanItem{
+			{Name: "Ping", Request: &PostmanRequest{Method: "GET"}},
+			{Name: "Orders", Item: []PostmanItem{
+				{Name: "List", Request: &PostmanRequest{Method: "GET"}},
+			}},
+		},
+	}
+	raw, _ := json.Marshal(collection)
+	row := Collection{Name: "Shop", RawJSON: string(raw)}
+
+	row.BeforeSave(nil)
+	if row.RequestCount != 2 {
+		t.Fatalf("Expected 2 requests, got %d", row.RequestCount)
+	}
+
+	// Add a request inside the folder, as an item-level edit would
+	collection.Item[1].Item = append(collection.Item[1].Item, PostmanItem{Name: "Create", Request: &PostmanRequest{Method: "POST"}})
+	raw, _ = json.Marshal(collection)
+	row.RawJSON = string(raw)
+
+	row.BeforeSave(nil)
+	if row.RequestCount != 3 {
+		t.Errorf("Expected 3 requests after the edit, got %d", row.RequestCount)
+	}
+}
+
+func TestCountCollectionRequestsInvalidJSON(t *testing.T) {
+	if count := CountCollectionRequests("{not json"); count != 0 {
+		t.Errorf("Expected 0 for invalid JSON, got %d", count)
+	}
+}
@@ -4,15 +4,47 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // Environment represents an environment with variables
 type Environment struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name"`
-	Variables Variables `json:"variables" gorm:"type:jsonb"`
-	TeamID    *uint     `json:"team_id" gorm:"index"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name"`
+	Variables Variables      `json:"variables" gorm:"type:jsonb"`
+	TeamID    *uint          `json:"team_id" gorm:"index"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// PostmanEnvironment is an environment in Postman's export format
+type PostmanEnvironment struct {
+	Name   string                    `json:"name"`
+	Values []PostmanEnvironmentValue `json:"values"`
+	Scope  string                    `json:"_postman_variable_scope"` // always "environment"
+}
+
+type PostmanEnvironmentValue struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+// PatchVariablesRequest changes individual environment variables
+type PatchVariablesRequest struct {
+	Set   map[string]string `json:"set"`
+	Unset []string          `json:"unset"`
+}
+
+// CopyVariablesRequest copies variables from another environment of the
+// team. Without keys every variable of the source is copied.
+type CopyVariablesRequest struct {
+	SourceID  uint     `json:"source_id" binding:"required"`
+	Keys      []string `json:"keys"`
+	Overwrite bool     `json:"overwrite"` // replace variables the target already has
 }
 
 // Variables is a custom type for JSONB storage
@@ -24,13 +56,28 @@ func (v *Variables) Scan(value interface{}) error {
 		*v = make(Variables)
 		return nil
 	}
-	bytes, ok := value.([]byte)
-	if !ok {
+	var bytes []byte
+	switch data := value.(type) {
+	case []byte:
+		bytes = data
+	case string:
+		// SQLite hands text columns back as strings
+		bytes = []byte(data)
+	default:
 		return nil
 	}
 	return json.Unmarshal(bytes, v)
 }
 
+// GormDBDataType stores Variables as jsonb on Postgres and as plain JSON
+// text on other databases, e.g. the SQLite used in tests
+func (Variables) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "text"
+}
+
 // Value implements driver.Valuer interface
 func (v Variables) Value() (driver.Value, error) {
 	if v == nil {
@@ -38,3 +85,11 @@ func (v Variables) Value() (driver.Value, error) {
 	}
 	return json.Marshal(v)
 }
+
+// EnvironmentReference is something that stops working as configured when
+// its environment is deleted
+type EnvironmentReference struct {
+	Type string `json:"type"` // collection
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
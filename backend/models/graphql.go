@@ -0,0 +1,54 @@
+package models
+
+// GraphQLIntrospectRequest asks for the schema of a GraphQL endpoint. The
+// introspection query is POSTed to URL with Headers and Auth, after variables
+// from the environment and InlineVariables are substituted, as for
+// ExecuteRequest.
+type GraphQLIntrospectRequest struct {
+	URL             string            `json:"url" binding:"required"`
+	Headers         map[string]string `json:"headers"`
+	EnvironmentID   *uint             `json:"environment_id"`
+	TeamID          *uint             `json:"team_id,omitempty"`
+	InlineVariables map[string]string `json:"inline_variables,omitempty"`
+	Auth            *RequestAuth      `json:"auth,omitempty"`
+	ExecutionID     string            `json:"execution_id,omitempty"`
+}
+
+// GraphQLSchema is a simplified introspection result. Types leaves out the
+// introspection types themselves (__Schema, __Type, ...).
+type GraphQLSchema struct {
+	QueryType        string        `json:"query_type,omitempty"`
+	MutationType     string        `json:"mutation_type,omitempty"`
+	SubscriptionType string        `json:"subscription_type,omitempty"`
+	Types            []GraphQLType `json:"types"`
+}
+
+// GraphQLType is one named type of a schema. Which of Fields, InputFields,
+// EnumValues and PossibleTypes are set depends on Kind.
+type GraphQLType struct {
+	Name          string         `json:"name"`
+	Kind          string         `json:"kind"` // OBJECT, INTERFACE, UNION, ENUM, INPUT_OBJECT or SCALAR
+	Description   string         `json:"description,omitempty"`
+	Fields        []GraphQLField `json:"fields,omitempty"`
+	InputFields   []GraphQLInput `json:"input_fields,omitempty"`
+	EnumValues    []string       `json:"enum_values,omitempty"`
+	PossibleTypes []string       `json:"possible_types,omitempty"`
+}
+
+// GraphQLField is a field of an object or interface type. Type is written as
+// in GraphQL SDL, e.g. "[User!]!".
+type GraphQLField struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Type        string         `json:"type"`
+	Args        []GraphQLInput `json:"args,omitempty"`
+	Deprecated  bool           `json:"deprecated,omitempty"`
+}
+
+// GraphQLInput is an argument or an input object's field
+type GraphQLInput struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	Type         string  `json:"type"`
+	DefaultValue *string `json:"default_value,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// RequestHistory is one request a user executed. Entries belong to the user
+// who ran them; TeamID only records which team's environment or collection
+// was used. The composite indexes back the common history filters.
+type RequestHistory struct {
+	ID     uint   `json:"id" gorm:"primaryKey;index:idx_history_user_method,priority:3;index:idx_history_user_status,priority:3"`
+	UserID uint   `json:"user_id" gorm:"not null;index:idx_history_user_created,priority:1;index:idx_history_user_method,priority:1;index:idx_history_user_status,priority:1"`
+	TeamID *uint  `json:"team_id"`
+	Method string `json:"method" gorm:"size:16;index:idx_history_user_method,priority:2"`
+	URL    string `json:"url" gorm:"type:text"`
+	// 0 when the request failed without a response
+	Status    int       `json:"status" gorm:"index:idx_history_user_status,priority:2"`
+	Time      int64     `json:"time"` // milliseconds
+	Error     string    `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_history_user_created,priority:2"`
+
+	// Headers sent and received, with the values of sensitive ones (see
+	// REDACTED_HEADERS) replaced by ***
+	RequestHeaders  Variables `json:"request_headers,omitempty"`
+	ResponseHeaders Variables `json:"response_headers,omitempty"`
+}
+
+// HistoryFilter narrows a user's request history. Zero values don't filter.
+type HistoryFilter struct {
+	Methods   []string
+	StatusMin int
+	StatusMax int
+	URL       string // case-insensitive substring
+	From      *time.Time
+	To        *time.Time
+	// Only entries older than this ID, from the previous page's next_cursor
+	Cursor uint
+	Limit  int
+}
+
+// HistoryPage is one page of history, newest first
+type HistoryPage struct {
+	Entries []RequestHistory `json:"entries"`
+	// Entries matching the filter across all pages
+	Total      int64 `json:"total"`
+	NextCursor *uint `json:"next_cursor"` // nil on the last page
+}
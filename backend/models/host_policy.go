@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TeamHostPolicy restricts which hosts the executor may call on behalf of a
+// team. Patterns are globs matched against the request's host name, e.g.
+// "*.ourcompany.com". Deny patterns win over allow patterns; an empty allow
+// list allows every host that isn't denied.
+type TeamHostPolicy struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TeamID    uint      `json:"team_id" gorm:"uniqueIndex;not null"`
+	Allow     string    `json:"-" gorm:"type:text"` // comma-separated patterns
+	Deny      string    `json:"-" gorm:"type:text"` // comma-separated patterns
+	UpdatedBy uint      `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AllowList returns the allow patterns
+func (p *TeamHostPolicy) AllowList() []string {
+	return splitPatterns(p.Allow)
+}
+
+// DenyList returns the deny patterns
+func (p *TeamHostPolicy) DenyList() []string {
+	return splitPatterns(p.Deny)
+}
+
+func splitPatterns(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// HostPolicyRequest replaces a team's host policy
+type HostPolicyRequest struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// HostPolicyResponse is a team's host policy; both lists are empty when the
+// team has none
+type HostPolicyResponse struct {
+	TeamID    uint       `json:"team_id"`
+	Allow     []string   `json:"allow"`
+	Deny      []string   `json:"deny"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ReindexResult reports how many rows POST /admin/reindex corrected, per
+// denormalized field. Rows that were already right aren't counted.
+type ReindexResult struct {
+	CollectionRequestCounts int64 `json:"collection_request_counts"`
+	CollectionUpdatedAt     int64 `json:"collection_updated_at"` // collections saved before updated_at existed
+	Total                   int64 `json:"total"`
+}
+
+// ResourceOwner is what GET /admin/resource-owner reports about a collection
+// or environment: the team it belongs to and basic metadata, never its
+// content (requests, variable values). Soft-deleted resources and teams are
+// found too, with DeletedAt set.
+type ResourceOwner struct {
+	Type      string     `json:"type"` // collection or environment
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// RequestCount is set for collections, VariableCount for environments
+	RequestCount  *int `json:"request_count,omitempty"`
+	VariableCount *int `json:"variable_count,omitempty"`
+	// Team is nil for a resource without a team
+	Team *ResourceOwnerTeam `json:"team"`
+}
+
+// ResourceOwnerTeam describes the team owning a resource
+type ResourceOwnerTeam struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	IsPersonal     bool       `json:"is_personal"`
+	OrganizationID *uint      `json:"organization_id,omitempty"`
+	MemberCount    int64      `json:"member_count"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
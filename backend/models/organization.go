@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization groups teams for org-level billing and administration. Org
+// admins can see every team in the organization; teams outside any
+// organization work exactly as before.
+type Organization struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// OrganizationMember is a user's membership of an organization. It doesn't
+// grant access to the org's teams, only to the organization itself. A user
+// is a member of an organization at most once.
+type OrganizationMember struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_member,priority:1"`
+	UserID         uint      `json:"user_id" gorm:"not null;index;uniqueIndex:idx_organization_member,priority:2"`
+	Role           string    `json:"role" gorm:"not null;default:'member'"` // admin, member
+	JoinedAt       time.Time `json:"joined_at"`
+	User           *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// OrganizationResponse is an organization as seen by one of its members
+type OrganizationResponse struct {
+	Organization
+	YourRole  string `json:"your_role"`
+	TeamCount int64  `json:"team_count"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type AddOrganizationTeamRequest struct {
+	TeamID uint `json:"team_id" binding:"required"`
+}
+
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role"` // admin, member (default)
+}
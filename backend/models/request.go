@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ExecuteRequest represents a request to execute
 type ExecuteRequest struct {
 	Method        string            `json:"method"`
@@ -8,6 +13,120 @@ type ExecuteRequest struct {
 	Body          string            `json:"body"`
 	QueryParams   map[string]string `json:"query_params"`
 	EnvironmentID *uint             `json:"environment_id"`
+	// TeamID is the team the request runs for; its host policy applies.
+	// Defaults to the environment's team.
+	TeamID *uint `json:"team_id,omitempty"`
+	// QueryMergePolicy decides how query_params combine with a query string
+	// already in the URL: add (default), override or append
+	QueryMergePolicy string `json:"query_merge_policy"`
+	// HeaderList and QueryList are ordered alternatives to Headers and
+	// QueryParams (e.g. when replaying a collection item). When set they are
+	// sent in the given order, may repeat keys, and take the place of the maps.
+	HeaderList []KeyValue `json:"header_list,omitempty"`
+	QueryList  []KeyValue `json:"query_list,omitempty"`
+	// InlineVariables are one-off values for this request only. They override
+	// the environment's variables of the same name and are never saved.
+	InlineVariables map[string]string `json:"inline_variables,omitempty"`
+	// ExecutionID identifies the run for POST /requests/:execution_id/cancel.
+	// Generated by the server when empty; clients that want to be able to
+	// cancel pass their own random ID.
+	ExecutionID string `json:"execution_id,omitempty"`
+	// UseETag sends If-None-Match with the ETag the last response from the
+	// same URL carried (unless the request sets the header itself)
+	UseETag bool `json:"use_etag,omitempty"`
+	// BodyEncoding is "text" (default) or "base64", for binary bodies such as
+	// protobuf: the body is decoded before sending. Set the Content-Type
+	// header yourself.
+	BodyEncoding string `json:"body_encoding,omitempty"`
+	// FormData sends a multipart/form-data body built from these fields in
+	// place of Body. Only text fields can go this way; file fields have to
+	// be uploaded through /requests/execute-multipart.
+	FormData []FormField `json:"form_data,omitempty"`
+	// ValidateJSONBody checks that the body (after variable substitution)
+	// parses when Content-Type is JSON, failing with the error's line and
+	// column instead of sending it. JSONBodyFormat ("minify" or "pretty")
+	// also reformats such a body, and implies the check.
+	ValidateJSONBody bool   `json:"validate_json_body,omitempty"`
+	JSONBodyFormat   string `json:"json_body_format,omitempty"`
+	// Auth is auth the server performs itself, for schemes that can't be
+	// sent as a plain header (others are set in Headers by the client)
+	Auth *RequestAuth `json:"auth,omitempty"`
+	// IncludeTLSInfo adds the server's certificate chain and the negotiated
+	// TLS parameters to HTTPS responses
+	IncludeTLSInfo bool `json:"include_tls_info,omitempty"`
+	// PreviewBytes reads only the first N bytes of the (decoded) response
+	// body and then drops the connection, to look at a large download
+	// without waiting for all of it. 0 reads the whole body.
+	PreviewBytes int64 `json:"preview_bytes,omitempty"`
+	// NDJSON reads the body of a streaming endpoint as newline-delimited
+	// JSON, one value per line, into ExecuteResponse.Events
+	NDJSON *NDJSONOptions `json:"ndjson,omitempty"`
+	// CacheTTLMs caches the response of a GET or HEAD for this many
+	// milliseconds (at most an hour): the same request (method, URL and
+	// headers) made again within that time is answered from the cache.
+	// Only 2xx responses are cached, never those with Cache-Control: no-store.
+	CacheTTLMs int64 `json:"cache_ttl_ms,omitempty"`
+	// BodyTransfer forces how the body is framed: content-length or chunked
+	// (Transfer-Encoding). Empty sends a Content-Length.
+	BodyTransfer string `json:"body_transfer,omitempty"`
+	// Host overrides the Host the server sees (e.g. a virtual host behind an
+	// IP-addressed URL). A Host header does the same; this field wins.
+	Host string `json:"host,omitempty"`
+}
+
+// NDJSONOptions bounds how much of an NDJSON stream is read. Zero values
+// use the defaults (1000 lines, 30 seconds).
+type NDJSONOptions struct {
+	MaxLines       int `json:"max_lines,omitempty"`
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// RequestAuth selects a server-side auth scheme for an execution
+type RequestAuth struct {
+	Type   string        `json:"type"` // ntlm or oauth2
+	NTLM   *NTLMAuth     `json:"ntlm,omitempty"`
+	OAuth2 *OAuth2Config `json:"oauth2,omitempty"`
+}
+
+// NTLMAuth holds the credentials for an NTLM handshake
+type NTLMAuth struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Domain      string `json:"domain,omitempty"`
+	Workstation string `json:"workstation,omitempty"`
+}
+
+// ExtractRequest pulls a value out of a JSON body (typically a response the
+// client already has) with a GJSON or simple JSONPath expression
+type ExtractRequest struct {
+	Body string `json:"body"`
+	Path string `json:"path" binding:"required"`
+}
+
+// ExecuteCollectionItemRequest executes a request stored in a collection.
+// ItemPath lists folder names from the collection root, then the request name.
+type ExecuteCollectionItemRequest struct {
+	ItemPath      []string `json:"item_path" binding:"required"`
+	EnvironmentID *uint    `json:"environment_id"` // defaults to the collection's linked environment
+	ExecutionID   string   `json:"execution_id"`   // see ExecuteRequest.ExecutionID
+}
+
+// ResolvedRequest is what an ExecuteRequest would send, after environment
+// variables are substituted and the final URL is built
+type ResolvedRequest struct {
+	Method              string            `json:"method"`
+	URL                 string            `json:"url"`
+	Headers             map[string]string `json:"headers"`
+	Body                string            `json:"body"`
+	UnresolvedVariables []string          `json:"unresolved_variables"`
+	// Host is set when the request overrides it
+	Host string `json:"host,omitempty"`
+}
+
+// KeyValue is a single ordered name/value pair
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 // ExecuteResponse represents the response from executing a request
@@ -16,5 +135,98 @@ type ExecuteResponse struct {
 	StatusText string            `json:"status_text"`
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
-	Time       int64             `json:"time"` // milliseconds
+	// BodyPresent is false for bodiless responses such as HEAD, where
+	// Content-Length describes the body a GET would have returned
+	BodyPresent bool  `json:"body_present"`
+	Time        int64 `json:"time"` // milliseconds
+	// UnresolvedVariables lists {{placeholders}} that had no value and were
+	// sent as-is
+	UnresolvedVariables []string `json:"unresolved_variables"`
+	// RequestBytes and ResponseBytes are approximate wire sizes: the body plus
+	// the header lines. ResponseBytes counts the body as received, before
+	// decompression.
+	RequestBytes  int64  `json:"request_bytes"`
+	ResponseBytes int64  `json:"response_bytes"`
+	ExecutionID   string `json:"execution_id"`
+	// NotModified is true for a 304, i.e. a conditional request's cache hit
+	NotModified bool `json:"not_modified"`
+	// ETagSent is the stored ETag sent as If-None-Match because of UseETag
+	ETagSent string `json:"etag_sent,omitempty"`
+	// TLS is only set for HTTPS requests made with IncludeTLSInfo
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// Truncated is true when PreviewBytes cut the body short, or when an
+	// NDJSON stream was still going at max_lines, the timeout or an oversized
+	// line. ContentLength is the full body's size for preview requests, when
+	// the server sent it.
+	Truncated     bool   `json:"truncated,omitempty"`
+	ContentLength *int64 `json:"content_length,omitempty"`
+	// Charset is the charset the body was declared in (by Content-Type or an
+	// HTML meta tag), when recognized. Body is always UTF-8, transcoded from
+	// it if needed.
+	Charset string `json:"charset,omitempty"`
+	// Events are the parsed lines of an NDJSON execution, in order
+	Events []json.RawMessage `json:"events,omitempty"`
+	// FromCache is true when the response was answered from the cache (see
+	// ExecuteRequest.CacheTTLMs) instead of being fetched
+	FromCache bool `json:"from_cache"`
+	// ArtifactID is set for a binary body, which can be downloaded as a file
+	// from /requests/response/:artifact_id/download until ArtifactExpiresAt.
+	// Body is left empty then.
+	ArtifactID        string     `json:"artifact_id,omitempty"`
+	ArtifactExpiresAt *time.Time `json:"artifact_expires_at,omitempty"`
+}
+
+// TLSInfo describes the TLS connection a response came over
+type TLSInfo struct {
+	Version            string `json:"version"` // e.g. "TLS 1.3"
+	CipherSuite        string `json:"cipher_suite"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"` // ALPN, e.g. "h2"
+	ServerName         string `json:"server_name,omitempty"`         // SNI sent by the client
+	// Verified is false when verification was skipped (localhost targets)
+	Verified     bool             `json:"verified"`
+	Certificates []TLSCertificate `json:"certificates"` // as sent by the server, leaf first
+}
+
+// TLSCertificate summarizes one certificate of the server's chain
+type TLSCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	IPAddresses  []string  `json:"ip_addresses,omitempty"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	IsCA         bool      `json:"is_ca"`
+	SHA256       string    `json:"sha256_fingerprint"`
+}
+
+// OAuth2Config describes how to obtain an OAuth2 access token
+type OAuth2Config struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+	GrantType    string   `json:"grant_type,omitempty"`  // client_credentials (default)
+	ClientAuth   string   `json:"client_auth,omitempty"` // body (default) or header (HTTP Basic)
+}
+
+// OAuth2TokenRequest asks the server to fetch an OAuth2 token. Variables
+// from the environment and InlineVariables are substituted in the config,
+// and the token is cached for the environment until it expires.
+type OAuth2TokenRequest struct {
+	OAuth2Config
+	EnvironmentID   *uint             `json:"environment_id"`
+	InlineVariables map[string]string `json:"inline_variables,omitempty"`
+	// ForceRefresh skips the cache and fetches a new token
+	ForceRefresh bool `json:"force_refresh,omitempty"`
+}
+
+// OAuth2Token is an access token obtained from a token endpoint
+type OAuth2Token struct {
+	AccessToken string     `json:"access_token"`
+	TokenType   string     `json:"token_type"`
+	ExpiresIn   int64      `json:"expires_in,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Scope       string     `json:"scope,omitempty"`
+	Cached      bool       `json:"cached"` // served from the cache instead of fetched
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TeamRequestDefaults are headers the executor adds to every request run for
+// a team, e.g. an identifying header or a User-Agent a WAF accepts. Headers
+// the request sets itself win.
+type TeamRequestDefaults struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TeamID    uint      `json:"team_id" gorm:"uniqueIndex;not null"`
+	Headers   Variables `json:"headers" gorm:"type:jsonb"`
+	UpdatedBy uint      `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RequestDefaultsRequest replaces a team's default headers
+type RequestDefaultsRequest struct {
+	Headers map[string]string `json:"headers"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// RequestTemplate is a reusable request snippet shared within a team, e.g. a
+// standard auth header or pagination params. URL, header and body values may
+// contain {{placeholders}}; they're resolved when the request is executed.
+type RequestTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TeamID      uint      `json:"team_id" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"not null"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	Headers     Variables `json:"headers" gorm:"type:jsonb"`
+	QueryParams Variables `json:"query_params" gorm:"type:jsonb"`
+	Body        string    `json:"body" gorm:"type:text"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RequestTemplateRequest creates or replaces a template
+type RequestTemplateRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+	QueryParams map[string]string `json:"query_params"`
+	Body        string            `json:"body"`
+}
+
+// ApplyTemplateRequest optionally names the tab a template is applied to
+type ApplyTemplateRequest struct {
+	TabID string `json:"tab_id"`
+	Name  string `json:"name"`
+}
@@ -9,11 +9,24 @@ type SavedTab struct {
 	Name        string    `json:"name"`
 	Method      string    `json:"method"`
 	URL         string    `json:"url"`
-	Headers     string    `gorm:"type:text" json:"headers"`      // JSON string
+	Headers     string    `gorm:"type:text" json:"headers"` // JSON string
 	Body        string    `gorm:"type:text" json:"body"`
 	QueryParams string    `gorm:"type:text" json:"query_params"` // JSON string
+	BodyType    string    `json:"body_type"`                     // raw, form-data, ...
+	FormFields  string    `gorm:"type:text" json:"form_fields"`  // JSON []FormField for form-data bodies
 	IsActive    bool      `json:"is_active"`
+	Pinned      bool      `json:"pinned"` // listed before unpinned tabs
 	SortOrder   int       `json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// FormField is one saved form-data field. File fields keep their key and the
+// last file name only; the file itself has to be uploaded again.
+type FormField struct {
+	Key      string `json:"key"`
+	Value    string `json:"value,omitempty"`
+	Type     string `json:"type"` // text or file
+	FileName string `json:"file_name,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
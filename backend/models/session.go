@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session is a persisted login. The refresh token is only stored hashed.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+}
@@ -1,36 +1,77 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Team struct {
-	ID        uint         `json:"id" gorm:"primaryKey"`
-	Name      string       `json:"name" gorm:"not null"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
-	Members   []TeamMember `json:"members,omitempty" gorm:"foreignKey:TeamID"`
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null"`
+	// IsPersonal marks the workspace every user gets; nobody can be invited to it
+	IsPersonal bool           `json:"is_personal" gorm:"not null;default:false"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	Members    []TeamMember   `json:"members,omitempty" gorm:"foreignKey:TeamID"`
+
+	// OrganizationID is the organization the team belongs to, nil for the
+	// teams (all of them before organizations existed) that have none
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+}
+
+// TeamResponse is a team as seen by one of its members
+type TeamResponse struct {
+	Team
+	YourRole    string `json:"your_role"`
+	MemberCount int64  `json:"member_count"`
+	OwnerID     uint   `json:"owner_id"`
+	OwnerName   string `json:"owner_name"`
 }
 
 type TeamMember struct {
 	ID       uint      `json:"id" gorm:"primaryKey"`
 	TeamID   uint      `json:"team_id" gorm:"not null;index"`
 	UserID   uint      `json:"user_id" gorm:"not null;index"`
-	Role     string    `json:"role" gorm:"default:'member'"` // owner, member
+	Role     string    `json:"role" gorm:"default:'member'"` // owner, admin, member
 	JoinedAt time.Time `json:"joined_at"`
 	Team     *Team     `json:"team,omitempty" gorm:"foreignKey:TeamID"`
 	User     *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
 type TeamInvite struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	TeamID       uint      `json:"team_id" gorm:"not null;index"`
-	InviterID    uint      `json:"inviter_id" gorm:"not null"`
-	InviteeEmail string    `json:"invitee_email" gorm:"not null;index"`
-	Status       string    `json:"status" gorm:"default:'pending'"` // pending, accepted, declined
-	Token        string    `json:"token,omitempty" gorm:"uniqueIndex;not null"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	Team         *Team     `json:"team,omitempty" gorm:"foreignKey:TeamID"`
-	Inviter      *User     `json:"inviter,omitempty" gorm:"foreignKey:InviterID"`
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	TeamID       uint           `json:"team_id" gorm:"not null;index"`
+	InviterID    uint           `json:"inviter_id" gorm:"not null"`
+	InviteeEmail string         `json:"invitee_email" gorm:"not null;index"`
+	Status       string         `json:"status" gorm:"default:'pending'"` // pending, accepted, declined, cancelled
+	Token        string         `json:"token,omitempty" gorm:"uniqueIndex;not null"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	Team         *Team          `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+	Inviter      *User          `json:"inviter,omitempty" gorm:"foreignKey:InviterID"`
+	// InviteeUserID is the invited account, when the email has one. Filled
+	// in when listing a team's invites; invitees needn't have signed up yet.
+	InviteeUserID *uint `json:"invitee_user_id,omitempty" gorm:"-"`
+}
+
+// InviteFilter narrows a team's invites. Status is pending (the default,
+// expired ones included), accepted, declined, cancelled, expired or all.
+type InviteFilter struct {
+	Status string
+	// Only invites older than this ID, from the previous page's NextCursor
+	Cursor uint
+	Limit  int
+}
+
+// InvitePage is one page of a team's invites, newest first
+type InvitePage struct {
+	Invites []TeamInvite
+	// Invites matching the filter across all pages
+	Total      int64
+	NextCursor *uint // nil on the last page
 }
 
 type CreateTeamRequest struct {
@@ -38,5 +79,9 @@ type CreateTeamRequest struct {
 }
 
 type InviteRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email string `json:"email" binding:"required"` // normalized and checked by services.NormalizeEmail
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required"` // admin, member
 }
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// TeamExportVersion is bumped whenever the bundle format changes
+const TeamExportVersion = 1
+
+// TeamExport is a full team backup. It never carries secrets: no API key
+// values, AI provider keys or password hashes.
+type TeamExport struct {
+	Version      int                     `json:"version"`
+	ExportedAt   time.Time               `json:"exported_at"`
+	Team         TeamExportTeam          `json:"team"`
+	Members      []TeamExportMember      `json:"members"`
+	Environments []TeamExportEnvironment `json:"environments"`
+	Collections  []TeamExportCollection  `json:"collections"`
+	APIKeys      []TeamExportAPIKey      `json:"api_keys"`
+	AISettings   *TeamExportAISettings   `json:"ai_settings,omitempty"`
+}
+
+type TeamExportTeam struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TeamExportMember struct {
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// TeamExportEnvironment keeps its original ID so collections can refer to it
+type TeamExportEnvironment struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Variables Variables `json:"variables"`
+}
+
+type TeamExportCollection struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	RawJSON       string `json:"raw_json"`
+	EnvironmentID *uint  `json:"environment_id,omitempty"` // an environments[].id
+}
+
+type TeamExportAPIKey struct {
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"key_prefix"`
+	Permissions string     `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type TeamExportAISettings struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	IsEnabled bool   `json:"is_enabled"`
+}
+
+// TeamImportResponse describes what an import recreated. Members, API keys
+// and AI settings need secrets or consent, so they're reported, not restored.
+type TeamImportResponse struct {
+	Team                TeamResponse       `json:"team"`
+	CollectionsImported int                `json:"collections_imported"`
+	EnvironmentsCreated int                `json:"environments_created"`
+	MembersToInvite     []TeamExportMember `json:"members_to_invite"`
+	APIKeysNotRestored  int                `json:"api_keys_not_restored"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// CollectionItemTiming rolls up the response times of one collection item's
+// executions on one day (UTC). Percentiles come from Sketch, so no samples
+// are kept.
+type CollectionItemTiming struct {
+	ID           uint          `json:"-" gorm:"primaryKey"`
+	TeamID       uint          `json:"-" gorm:"not null;index"`
+	CollectionID uint          `json:"-" gorm:"not null;uniqueIndex:idx_item_timing_day,priority:1"`
+	ItemPath     string        `json:"-" gorm:"type:text;not null;uniqueIndex:idx_item_timing_day,priority:3"` // JSON array of names
+	Day          string        `json:"day" gorm:"size:10;not null;uniqueIndex:idx_item_timing_day,priority:2"` // YYYY-MM-DD
+	Count        int64         `json:"count"`
+	SumMs        int64         `json:"-"`
+	MinMs        int64         `json:"min_ms"`
+	MaxMs        int64         `json:"max_ms"`
+	Sketch       LatencySketch `json:"-"`
+	UpdatedAt    time.Time     `json:"-"`
+}
+
+// LatencySketch is a log-bucketed histogram of latencies in milliseconds:
+// bucket i counts values in (gamma^(i-1), gamma^i], and Zero those of 0 ms.
+// Quantiles read from it are within the sketch's relative accuracy, and
+// sketches of the same accuracy merge by adding their counts.
+type LatencySketch struct {
+	Zero    int64         `json:"zero,omitempty"`
+	Buckets map[int]int64 `json:"buckets"`
+}
+
+// Scan implements sql.Scanner interface
+func (s *LatencySketch) Scan(value interface{}) error {
+	var bytes []byte
+	switch data := value.(type) {
+	case nil:
+		*s = LatencySketch{}
+		return nil
+	case []byte:
+		bytes = data
+	case string:
+		bytes = []byte(data)
+	default:
+		return nil
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// Value implements driver.Valuer interface
+func (s LatencySketch) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// GormDBDataType matches Variables: jsonb on Postgres, JSON text elsewhere
+func (LatencySketch) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "text"
+}
+
+// ItemTimingDay is one day of an item's timings. P50Ms and P95Ms are
+// approximate.
+type ItemTimingDay struct {
+	Day    string  `json:"day"`
+	Count  int64   `json:"count"`
+	MinMs  int64   `json:"min_ms"`
+	MaxMs  int64   `json:"max_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+}
+
+// ItemTimings are the timings of one collection item: over the whole period
+// asked for, and per day (oldest first)
+type ItemTimings struct {
+	ItemPath []string        `json:"item_path"`
+	Count    int64           `json:"count"`
+	MinMs    int64           `json:"min_ms"`
+	MaxMs    int64           `json:"max_ms"`
+	MeanMs   float64         `json:"mean_ms"`
+	P50Ms    float64         `json:"p50_ms"`
+	P95Ms    float64         `json:"p95_ms"`
+	Days     []ItemTimingDay `json:"days"`
+}
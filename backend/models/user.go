@@ -3,14 +3,16 @@ package models
 import "time"
 
 type User struct {
-	ID             uint      `json:"id" gorm:"primaryKey"`
-	Email          string    `json:"email" gorm:"uniqueIndex;not null"`
-	PasswordHash   string    `json:"-"`
-	Name           string    `json:"name"`
-	GoogleID       *string   `json:"-" gorm:"index"`
-	ProfilePicture *string   `json:"profile_picture,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uint    `json:"id" gorm:"primaryKey"`
+	Email          string  `json:"email" gorm:"uniqueIndex;not null"`
+	PasswordHash   string  `json:"-"`
+	Name           string  `json:"name"`
+	GoogleID       *string `json:"-" gorm:"index"`
+	ProfilePicture *string `json:"profile_picture,omitempty"`
+	// Updated on authenticated requests, at most every few minutes
+	LastActiveAt *time.Time `json:"last_active_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // Password is a virtual field for setting password during registration
@@ -23,23 +25,35 @@ func (u *User) GetPassword() string {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required"` // normalized and checked by services.NormalizeEmail
 	Password string `json:"password" binding:"required,min=6"`
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Email    string `json:"email" binding:"required"`    // normalized and checked by services.NormalizeEmail
+	Password string `json:"password" binding:"required"` // strength checked by services.ValidatePasswordStrength
 	Name     string `json:"name" binding:"required"`
 }
 
+// AuthResponse is returned on sign-in. Login and Register also fill in the
+// bootstrap fields so the client can skip fetching them; refreshes leave them
+// out.
 type AuthResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
-	User         User   `json:"user"`
+	AccessToken        string         `json:"access_token"`
+	RefreshToken       string         `json:"refresh_token"`
+	ExpiresIn          int64          `json:"expires_in"`
+	User               User           `json:"user"`
+	Teams              []TeamResponse `json:"teams,omitempty"`
+	PendingInviteCount int64          `json:"pending_invite_count,omitempty"`
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
+
+// BootstrapResponse bundles everything the client needs on app load
+type BootstrapResponse struct {
+	User               User           `json:"user"`
+	Teams              []TeamResponse `json:"teams"`
+	PendingInviteCount int64          `json:"pending_invite_count"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook event types
+const (
+	EventCollectionUpdated = "collection.updated"
+	EventMemberJoined      = "member.joined"
+	EventAPIKeyCreated     = "api_key.created"
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{EventCollectionUpdated, EventMemberJoined, EventAPIKeyCreated}
+
+// Webhook receives a signed POST for each subscribed team event
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TeamID    uint      `json:"team_id" gorm:"not null;index"`
+	URL       string    `json:"url" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"` // HMAC key, only returned on creation
+	Events    string    `json:"-" gorm:"not null"` // comma-separated event types
+	IsEnabled bool      `json:"is_enabled" gorm:"not null"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EventList returns the subscribed events
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes reports whether the webhook wants event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookRequest struct {
+	URL       string   `json:"url" binding:"required"`
+	Events    []string `json:"events" binding:"required"`
+	IsEnabled *bool    `json:"is_enabled"` // defaults to true
+}
+
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	TeamID    uint      `json:"team_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned on creation
+	Events    []string  `json:"events"`
+	IsEnabled bool      `json:"is_enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook
+type WebhookPayload struct {
+	ID         string      `json:"id"` // unique per delivery, also sent as X-Webhook-Delivery
+	Event      string      `json:"event"`
+	TeamID     uint        `json:"team_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
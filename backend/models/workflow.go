@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Workflow is an ordered list of collection requests run one after the
+// other. Values extracted from a step's response become variables for the
+// steps after it.
+type Workflow struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	TeamID      uint          `json:"team_id" gorm:"not null;index"`
+	Name        string        `json:"name" gorm:"not null"`
+	Description string        `json:"description"`
+	Steps       WorkflowSteps `json:"steps" gorm:"type:jsonb"`
+	// EnvironmentID is the environment runs start from unless the run names one
+	EnvironmentID *uint `json:"environment_id"`
+	// ContinueOnFailure keeps running the remaining steps after a failed one
+	ContinueOnFailure bool      `json:"continue_on_failure" gorm:"not null;default:false"`
+	CreatedBy         uint      `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// WorkflowStep runs one request from one of the team's collections
+type WorkflowStep struct {
+	Name         string              `json:"name"`
+	CollectionID uint                `json:"collection_id"`
+	ItemPath     []string            `json:"item_path"` // see ExecuteCollectionItemRequest.ItemPath
+	Extract      []WorkflowExtractor `json:"extract,omitempty"`
+	Assertions   []WorkflowAssertion `json:"assertions,omitempty"`
+	// CacheTTLMs caches the step's response if it's a GET or HEAD, shared by
+	// the team's workflow runs; see ExecuteRequest.CacheTTLMs
+	CacheTTLMs int64 `json:"cache_ttl_ms,omitempty"`
+}
+
+// WorkflowExtractor stores part of a step's response in a variable
+type WorkflowExtractor struct {
+	Variable string `json:"variable"`
+	Source   string `json:"source"`         // body (default), header or status
+	Path     string `json:"path,omitempty"` // JSON path for body, header name for header
+}
+
+// WorkflowAssertion checks part of a step's response
+type WorkflowAssertion struct {
+	Source   string `json:"source"`         // status, header or body
+	Path     string `json:"path,omitempty"` // JSON path for body, header name for header
+	Operator string `json:"operator"`       // equals (default), not_equals, contains, exists, not_exists
+	Value    string `json:"value,omitempty"`
+}
+
+// WorkflowSteps is stored as JSON, like Variables
+type WorkflowSteps []WorkflowStep
+
+// Scan implements sql.Scanner interface
+func (s *WorkflowSteps) Scan(value interface{}) error {
+	var bytes []byte
+	switch data := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		bytes = data
+	case string:
+		bytes = []byte(data)
+	default:
+		return nil
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// Value implements driver.Valuer interface
+func (s WorkflowSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return json.Marshal([]WorkflowStep{})
+	}
+	return json.Marshal(s)
+}
+
+// GormDBDataType matches Variables: jsonb on Postgres, JSON text elsewhere
+func (WorkflowSteps) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "text"
+}
+
+// WorkflowRequest creates or replaces a workflow
+type WorkflowRequest struct {
+	Name              string         `json:"name" binding:"required"`
+	Description       string         `json:"description"`
+	Steps             []WorkflowStep `json:"steps"`
+	EnvironmentID     *uint          `json:"environment_id"`
+	ContinueOnFailure bool           `json:"continue_on_failure"`
+}
+
+// RunWorkflowRequest starts a workflow run
+type RunWorkflowRequest struct {
+	EnvironmentID   *uint             `json:"environment_id"`   // defaults to the workflow's environment
+	InlineVariables map[string]string `json:"inline_variables"` // override the environment for this run
+	ExecutionID     string            `json:"execution_id"`     // see ExecuteRequest.ExecutionID
+	// DataRows runs the workflow once per row, like a Postman data file, with
+	// the row's variables merged over the rest for that iteration
+	DataRows []map[string]string `json:"data_rows"`
+}
+
+// WorkflowAssertionResult is the outcome of one assertion
+type WorkflowAssertionResult struct {
+	WorkflowAssertion
+	Passed bool   `json:"passed"`
+	Actual string `json:"actual"`
+	Error  string `json:"error,omitempty"` // e.g. the body isn't JSON
+}
+
+// WorkflowStepResult is what happened in one step
+type WorkflowStepResult struct {
+	Index      int                       `json:"index"`
+	Name       string                    `json:"name"`
+	Passed     bool                      `json:"passed"`
+	Error      string                    `json:"error,omitempty"` // the request couldn't be made or a value couldn't be extracted
+	Response   *ExecuteResponse          `json:"response,omitempty"`
+	Assertions []WorkflowAssertionResult `json:"assertions"`
+	Extracted  map[string]string         `json:"extracted"`
+}
+
+// WorkflowRunResult is the outcome of a workflow run. Steps that didn't run
+// because an earlier one failed aren't listed. A run with data rows reports
+// each row under Iterations and leaves Steps empty and Variables unset.
+type WorkflowRunResult struct {
+	WorkflowID  uint                      `json:"workflow_id"`
+	ExecutionID string                    `json:"execution_id"`
+	Passed      bool                      `json:"passed"`
+	Steps       []WorkflowStepResult      `json:"steps"`
+	Variables   Variables                 `json:"variables"` // the variable scope after the last step
+	Iterations  []WorkflowIterationResult `json:"iterations,omitempty"`
+	Time        int64                     `json:"time"` // milliseconds
+}
+
+// WorkflowIterationResult is the run of the workflow for one data row
+type WorkflowIterationResult struct {
+	Row       int                  `json:"row"` // index into RunWorkflowRequest.DataRows
+	Passed    bool                 `json:"passed"`
+	Steps     []WorkflowStepResult `json:"steps"`
+	Variables Variables            `json:"variables"`
+	Time      int64                `json:"time"` // milliseconds
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"log"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"sync"
+	"time"
+)
+
+// activityWriteInterval is how often at most a user's LastActiveAt is written
+const activityWriteInterval = 5 * time.Minute
+
+var (
+	activityMu   sync.Mutex
+	lastActivity = make(map[uint]time.Time) // last LastActiveAt we stored, per user
+)
+
+// shouldRecordActivity reports whether activity at now needs a DB write for
+// the user, and if so remembers it as stored
+func shouldRecordActivity(userID uint, now time.Time) bool {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	if last, ok := lastActivity[userID]; ok && now.Sub(last) < activityWriteInterval {
+		return false
+	}
+	lastActivity[userID] = now
+	return true
+}
+
+// TouchUserActivity updates the user's LastActiveAt, skipping the write when
+// it was already updated within activityWriteInterval
+func TouchUserActivity(userID uint) {
+	now := time.Now()
+	if !shouldRecordActivity(userID, now) {
+		return
+	}
+	// UpdateColumn so updated_at keeps meaning "profile changed"
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("last_active_at", now).Error; err != nil {
+		log.Printf("Failed to update last activity for user %d: %v", userID, err)
+	}
+}
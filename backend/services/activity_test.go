@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldRecordActivityThrottles(t *testing.T) {
+	const userID = 4242
+	start := time.Now()
+
+	if !shouldRecordActivity(userID, start) {
+		t.Error("Expected the first activity to be recorded")
+	}
+	if shouldRecordActivity(userID, start.Add(time.Minute)) {
+		t.Error("Expected activity a minute later to be skipped")
+	}
+	if shouldRecordActivity(userID, start.Add(activityWriteInterval-time.Second)) {
+		t.Error("Expected activity just inside the interval to be skipped")
+	}
+	if !shouldRecordActivity(userID, start.Add(activityWriteInterval)) {
+		t.Error("Expected activity after the interval to be recorded")
+	}
+
+	// Other users aren't affected
+	if !shouldRecordActivity(userID+1, start.Add(time.Minute)) {
+		t.Error("Expected another user's first activity to be recorded")
+	}
+}
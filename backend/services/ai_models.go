@@ -0,0 +1,146 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOpenAIModels is used when no API key is configured or the provider's
+// model list can't be fetched
+var StaticOpenAIModels = []string{
+	"gpt-4o",
+	"gpt-4o-mini",
+	"gpt-4-turbo",
+	"gpt-3.5-turbo",
+	"o1",
+	"o1-mini",
+	"o3-mini",
+}
+
+// Where a model list came from
+const (
+	ModelSourceProvider = "provider" // fetched (or cached) from the provider
+	ModelSourceStatic   = "static"   // no API key configured
+	ModelSourceFallback = "fallback" // fetching failed, static list used
+)
+
+var (
+	openAIBaseURL = "https://api.openai.com/v1"
+	aiModelsTTL   = time.Hour
+	aiModelClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Fetched model lists are cached in memory per API key
+type aiModelsEntry struct {
+	models    []string
+	expiresAt time.Time
+}
+
+var (
+	aiModelsMu    sync.Mutex
+	aiModelsCache = make(map[string]aiModelsEntry)
+)
+
+// isChatModel reports whether an OpenAI model ID is usable with the chat
+// completions API. The models endpoint doesn't say, so it goes by name.
+func isChatModel(id string) bool {
+	if !strings.HasPrefix(id, "gpt-") && !strings.HasPrefix(id, "chatgpt-") &&
+		!strings.HasPrefix(id, "o1") && !strings.HasPrefix(id, "o3") && !strings.HasPrefix(id, "o4") {
+		return false
+	}
+	for _, excluded := range []string{"instruct", "embedding", "audio", "realtime", "tts", "transcribe", "image", "search"} {
+		if strings.Contains(id, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchOpenAIModels lists the chat models the API key can use
+func fetchOpenAIModels(apiKey string) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, openAIBaseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := aiModelClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models endpoint answered %s", resp.Status)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	models := []string{}
+	for _, model := range list.Data {
+		if isChatModel(model.ID) {
+			models = append(models, model.ID)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("models endpoint returned no chat models")
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// AvailableAIModels returns the chat models for apiKey and where the list
+// came from. Provider lists are cached for aiModelsTTL; when fetching fails
+// the static list is returned instead of an error.
+func AvailableAIModels(apiKey string) ([]string, string) {
+	if apiKey == "" {
+		return StaticOpenAIModels, ModelSourceStatic
+	}
+
+	sum := sha256.Sum256([]byte(apiKey))
+	key := hex.EncodeToString(sum[:])
+
+	aiModelsMu.Lock()
+	entry, ok := aiModelsCache[key]
+	aiModelsMu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.models, ModelSourceProvider
+	}
+
+	models, err := fetchOpenAIModels(apiKey)
+	if err != nil {
+		log.Printf("Failed to fetch OpenAI models, using the static list: %v", err)
+		return StaticOpenAIModels, ModelSourceFallback
+	}
+
+	aiModelsMu.Lock()
+	aiModelsCache[key] = aiModelsEntry{models: models, expiresAt: time.Now().Add(aiModelsTTL)}
+	aiModelsMu.Unlock()
+	return models, ModelSourceProvider
+}
+
+// IsAvailableAIModel reports whether model is in the list for apiKey,
+// returning the list too
+func IsAvailableAIModel(apiKey, model string) (bool, []string) {
+	models, _ := AvailableAIModels(apiKey)
+	for _, available := range models {
+		if available == model {
+			return true, models
+		}
+	}
+	return false, models
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// useModelsEndpoint points the model list at a mock OpenAI server
+func useModelsEndpoint(t *testing.T, handler http.HandlerFunc) *int32 {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	previous := openAIBaseURL
+	openAIBaseURL = server.URL + "/v1"
+	t.Cleanup(func() {
+		openAIBaseURL = previous
+		server.Close()
+	})
+	return &calls
+}
+
+func TestAvailableAIModelsFromProvider(t *testing.T) {
+	calls := useModelsEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-provider" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"gpt-5"},{"id":"text-embedding-3-small"},{"id":"gpt-4o"},{"id":"whisper-1"},{"id":"o4-mini"},{"id":"gpt-4o-realtime-preview"}]}`))
+	})
+
+	available, source := AvailableAIModels("sk-provider")
+	if source != ModelSourceProvider {
+		t.Errorf("Expected source '%s', got '%s'", ModelSourceProvider, source)
+	}
+	expected := []string{"gpt-4o", "gpt-5", "o4-mini"}
+	if len(available) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, available)
+	}
+	for i := range expected {
+		if available[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, available)
+		}
+	}
+
+	if ok, _ := IsAvailableAIModel("sk-provider", "gpt-5"); !ok {
+		t.Error("Expected a provider model to be accepted")
+	}
+	if ok, _ := IsAvailableAIModel("sk-provider", "whisper-1"); ok {
+		t.Error("Expected a non-chat model to be rejected")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("Expected the list to be fetched once and cached, got %d fetches", got)
+	}
+}
+
+func TestAvailableAIModelsFallback(t *testing.T) {
+	useModelsEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+
+	available, source := AvailableAIModels("sk-fallback")
+	if source != ModelSourceFallback || len(available) != len(StaticOpenAIModels) {
+		t.Errorf("Expected the static list as a fallback, got %v (%s)", available, source)
+	}
+	if ok, _ := IsAvailableAIModel("sk-fallback", "gpt-4o-mini"); !ok {
+		t.Error("Expected static models to be accepted on fallback")
+	}
+
+	if _, source := AvailableAIModels(""); source != ModelSourceStatic {
+		t.Errorf("Expected source '%s' without a key, got '%s'", ModelSourceStatic, source)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// ValidAPIKeyPermission reports whether permission is one an API key can have
+func ValidAPIKeyPermission(permission string) bool {
+	return permission == "read" || permission == "write" || permission == "read_write"
+}
+
+// DefaultAPIKeyPermission is the permission the team's keys get when they're
+// created without one: the team's setting, or read
+func DefaultAPIKeyPermission(teamID uint) string {
+	var settings models.TeamAPIKeySettings
+	if err := database.GetDB().Where("team_id = ?", teamID).First(&settings).Error; err != nil || !ValidAPIKeyPermission(settings.DefaultPermission) {
+		return "read"
+	}
+	return settings.DefaultPermission
+}
+
+// RequiresDeleteConfirmation reports whether the team's public API deletes
+// must be confirmed with X-Confirm-Delete (off unless the team turned it on)
+func RequiresDeleteConfirmation(teamID uint) bool {
+	var settings models.TeamAPIKeySettings
+	if err := database.GetDB().Where("team_id = ?", teamID).First(&settings).Error; err != nil {
+		return false
+	}
+	return settings.RequireDeleteConfirmation
+}
+
+// APIKeyNameTaken reports whether the team already has a key with this name,
+// ignoring case
+func APIKeyNameTaken(teamID uint, name string) bool {
+	var count int64
+	database.GetDB().Model(&models.TeamAPIKey{}).Where("team_id = ? AND LOWER(name) = LOWER(?)", teamID, name).Count(&count)
+	return count > 0
+}
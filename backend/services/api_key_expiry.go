@@ -0,0 +1,106 @@
+package services
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// APIKeyExpiryWarning is how close to expiring a key is flagged; 7 days when
+// unset
+func APIKeyExpiryWarning() time.Duration {
+	days := 7
+	if config.AppConfig != nil && config.AppConfig.APIKeyExpiryWarningDays > 0 {
+		days = config.AppConfig.APIKeyExpiryWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// APIKeyExpiry returns the whole days left before expiresAt (rounded down, so
+// negative once it has passed) and whether that's within warning. Keys
+// without an expiry get nil and false.
+func APIKeyExpiry(expiresAt *time.Time, now time.Time, warning time.Duration) (*int, bool) {
+	if expiresAt == nil {
+		return nil, false
+	}
+	remaining := expiresAt.Sub(now)
+	days := int(math.Floor(remaining.Hours() / 24))
+	return &days, remaining > 0 && remaining <= warning
+}
+
+// apiKeyReminder is a key due a reminder, with where to send it
+type apiKeyReminder struct {
+	ID         uint
+	Name       string
+	KeyPrefix  string
+	ExpiresAt  time.Time
+	TeamName   string
+	OwnerEmail string
+}
+
+// SendAPIKeyExpiryReminders emails each team owner about their enabled keys
+// expiring within the warning window, once per key, and returns how many
+// were sent. Nothing is sent (or marked) while email isn't configured.
+func SendAPIKeyExpiryReminders(now time.Time) (int, error) {
+	sender := NewEmailSender()
+	if !sender.IsConfigured() {
+		return 0, nil
+	}
+
+	var due []apiKeyReminder
+	err := database.GetDB().Table("team_api_keys").
+		Select("team_api_keys.id, team_api_keys.name, team_api_keys.key_prefix, team_api_keys.expires_at, "+
+			"teams.name AS team_name, users.email AS owner_email").
+		Joins("JOIN teams ON teams.id = team_api_keys.team_id AND teams.deleted_at IS NULL").
+		Joins("JOIN team_members ON team_members.team_id = teams.id AND team_members.role = ?", "owner").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_api_keys.expires_at > ? AND team_api_keys.expires_at <= ?", now, now.Add(APIKeyExpiryWarning())).
+		Where("team_api_keys.expiry_reminder_sent_at IS NULL AND team_api_keys.enabled = ?", true).
+		Scan(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, key := range due {
+		subject, body, err := renderAPIKeyExpiryEmail(key.TeamName, key.Name, key.KeyPrefix, key.ExpiresAt)
+		if err == nil {
+			err = sender.SendEmail(key.OwnerEmail, subject, body)
+		}
+		if err != nil {
+			log.Printf("Failed to send expiry reminder for API key %d: %v", key.ID, err)
+			continue
+		}
+		database.GetDB().Model(&models.TeamAPIKey{}).Where("id = ?", key.ID).UpdateColumn("expiry_reminder_sent_at", now)
+		sent++
+	}
+	return sent, nil
+}
+
+// StartAPIKeyExpiryReminders periodically sends SendAPIKeyExpiryReminders.
+// A non-positive API_KEY_REMINDER_INTERVAL_MINUTES disables it.
+func StartAPIKeyExpiryReminders() {
+	interval := time.Duration(config.AppConfig.APIKeyReminderIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sent, err := SendAPIKeyExpiryReminders(time.Now())
+			if err != nil {
+				log.Printf("Failed to send API key expiry reminders: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("Sent %d API key expiry reminders", sent)
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestAPIKeyExpiry(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	warning := 7 * 24 * time.Hour
+	day := 24 * time.Hour
+	tests := []struct {
+		name     string
+		expires  time.Duration
+		wantDays int
+		wantSoon bool
+	}{
+		{"well before the threshold", 30 * day, 30, false},
+		{"just outside the threshold", 7*day + time.Minute, 7, false},
+		{"exactly at the threshold", 7 * day, 7, true},
+		{"just inside the threshold", 7*day - time.Minute, 6, true},
+		{"expires in an hour", time.Hour, 0, true},
+		{"just expired", -time.Minute, -1, false},
+		{"long expired", -10 * day, -10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := now.Add(tt.expires)
+			days, soon := APIKeyExpiry(&expiresAt, now, warning)
+			if days == nil || *days != tt.wantDays || soon != tt.wantSoon {
+				t.Errorf("Expected %d days (soon=%v), got %v (soon=%v)", tt.wantDays, tt.wantSoon, days, soon)
+			}
+		})
+	}
+
+	if days, soon := APIKeyExpiry(nil, now, warning); days != nil || soon {
+		t.Errorf("Expected nothing for a key without expiry, got %v (soon=%v)", days, soon)
+	}
+}
+
+func TestSendAPIKeyExpiryReminders(t *testing.T) {
+	useTestDB(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{FrontendURL: "https://app.example.com", APIKeyExpiryWarningDays: 7}
+	t.Cleanup(func() { config.AppConfig = previous })
+	sender := &RecordingEmailSender{Configured: true}
+	previousSender := NewEmailSender
+	NewEmailSender = func() EmailSender { return sender }
+	t.Cleanup(func() { NewEmailSender = previousSender })
+
+	owner := createTestUser(t, "owner@example.com")
+	team, err := CreateTeamWithOwner("Acme", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	soon := now.Add(3 * 24 * time.Hour)
+	later := now.Add(30 * 24 * time.Hour)
+	expired := now.Add(-time.Hour)
+	for _, key := range []models.TeamAPIKey{
+		{Name: "CI", Key: "k1", ExpiresAt: &soon},
+		{Name: "Later", Key: "k2", ExpiresAt: &later},
+		{Name: "Gone", Key: "k3", ExpiresAt: &expired},
+		{Name: "Forever", Key: "k4"},
+		{Name: "Disabled", Key: "k5", ExpiresAt: &soon},
+	} {
+		key.TeamID, key.KeyPrefix, key.CreatedBy = team.ID, "pmx_"+key.Key, owner.ID
+		database.DB.Create(&key)
+	}
+	// A disabled key can't be used, so its expiry is nothing to warn about
+	database.DB.Model(&models.TeamAPIKey{}).Where("name = ?", "Disabled").Update("enabled", false)
+
+	sent, err := SendAPIKeyExpiryReminders(now)
+	if err != nil || sent != 1 {
+		t.Fatalf("Expected 1 reminder, got %d (%v)", sent, err)
+	}
+	emails := sender.Sent()
+	if len(emails) != 1 || emails[0].To != "owner@example.com" || emails[0].Subject != `API key "CI" for Acme expires soon` {
+		t.Errorf("Expected a reminder about CI to the owner, got %+v", emails)
+	}
+
+	if sent, _ := SendAPIKeyExpiryReminders(now.Add(time.Hour)); sent != 0 {
+		t.Errorf("Expected each key to be reminded about once, got %d more", sent)
+	}
+}
@@ -14,8 +14,12 @@ import (
 )
 
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID uint   `json:"sid,omitempty"`
+	// TeamIDs are the user's teams when the token was issued, with
+	// JWT_TEAM_CLAIMS on; see TrustTeamClaim
+	TeamIDs []uint `json:"teams,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -29,13 +33,19 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// GenerateTokenPair starts a new session for the user and returns its tokens
 func GenerateTokenPair(user *models.User) (*models.AuthResponse, error) {
-	accessToken, expiresIn, err := generateAccessToken(user)
+	refreshToken, err := generateRefreshToken()
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := generateRefreshToken()
+	session, err := createSession(user.ID, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresIn, err := generateAccessToken(user, session.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -48,13 +58,15 @@ func GenerateTokenPair(user *models.User) (*models.AuthResponse, error) {
 	}, nil
 }
 
-func generateAccessToken(user *models.User) (string, int64, error) {
+func generateAccessToken(user *models.User, sessionID uint) (string, int64, error) {
 	expirationTime := time.Now().Add(time.Duration(config.AppConfig.JWTExpirationHours) * time.Hour)
 	expiresIn := int64(config.AppConfig.JWTExpirationHours * 3600)
 
 	claims := &JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
+		TeamIDs:   tokenTeamIDs(user.ID),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"postmanxodja/config"
+)
+
+// IsBlockedHeader reports whether name is on the BLOCKED_HEADERS list
+// (DefaultBlockedHeaders without a config)
+func IsBlockedHeader(name string) bool {
+	blocked := config.DefaultBlockedHeaders
+	if config.AppConfig != nil && len(config.AppConfig.BlockedHeaders) > 0 {
+		blocked = config.AppConfig.BlockedHeaders
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, header := range blocked {
+		if name == header {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyHeaderPolicy strips the blocked (hop-by-hop) headers the user set on
+// httpReq, which net/http would otherwise send as given, and moves a Host
+// header to httpReq.Host, the only place net/http takes it from. host, the
+// request's own override field, wins over a Host header.
+func ApplyHeaderPolicy(httpReq *http.Request, host string) error {
+	if values := httpReq.Header.Values("Host"); len(values) > 0 && host == "" {
+		host = values[0]
+	}
+	httpReq.Header.Del("Host")
+	for name := range httpReq.Header {
+		if IsBlockedHeader(name) {
+			delete(httpReq.Header, name)
+		}
+	}
+
+	if host = strings.TrimSpace(host); host == "" {
+		return nil
+	}
+	if strings.ContainsAny(host, " \t\r\n/\\?#@") {
+		return fmt.Errorf("%w: %q is not a valid Host", ErrInvalidHeader, host)
+	}
+	httpReq.Host = host
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/config"
+	"postmanxodja/models"
+	"strings"
+	"testing"
+)
+
+// headerEcho answers with the Host it was sent and the named headers
+func headerEcho(t *testing.T, names ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host=%s", r.Host)
+		for _, name := range names {
+			fmt.Fprintf(w, " %s=%s", strings.ToLower(name), strings.Join(r.Header.Values(name), ","))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteStripsBlockedHeaders(t *testing.T) {
+	useLoopback(t)
+	server := headerEcho(t, "Upgrade", "X-Trace", "Content-Length")
+
+	// Connection is hop-by-hop: the server only sees the transport's own
+	var connection []string
+	connServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection = r.Header.Values("Connection")
+		w.Write([]byte(r.Header.Get("Keep-Alive")))
+	}))
+	defer connServer.Close()
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: connServer.URL,
+		Headers: map[string]string{"Connection": "close, X-Secret", "Keep-Alive": "timeout=600"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connection) != 0 || resp.Body != "" {
+		t.Errorf("Expected Connection and Keep-Alive stripped, server saw Connection %v and Keep-Alive %q", connection, resp.Body)
+	}
+
+	resp, err = ExecuteHTTPRequest(&models.ExecuteRequest{Method: "POST", URL: server.URL, Body: "hi",
+		HeaderList: []models.KeyValue{{Key: "upgrade", Value: "h2c"}, {Key: "Content-Length", Value: "100"}, {Key: "X-Trace", Value: "1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "upgrade= x-trace=1 content-length=2"; !strings.HasSuffix(resp.Body, want) {
+		t.Errorf("Expected the server to see '%s', got '%s'", want, resp.Body)
+	}
+
+	// The list is configurable; X-Trace is blocked once it's on it
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{BlockedHeaders: []string{"x-trace"}}
+	t.Cleanup(func() { config.AppConfig = previous })
+	resp, err = ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: server.URL,
+		Headers: map[string]string{"X-Trace": "1", "Upgrade": "h2c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Body, "upgrade=h2c x-trace= ") {
+		t.Errorf("Expected only X-Trace stripped, got '%s'", resp.Body)
+	}
+}
+
+func TestExecuteCustomHost(t *testing.T) {
+	useLoopback(t)
+	server := headerEcho(t)
+
+	tests := []struct {
+		name string
+		req  models.ExecuteRequest
+		want string
+	}{
+		{"field", models.ExecuteRequest{Host: "api.internal"}, "host=api.internal"},
+		{"header", models.ExecuteRequest{Headers: map[string]string{"Host": "vhost.example:8443"}}, "host=vhost.example:8443"},
+		{"field wins", models.ExecuteRequest{Host: "{{vhost}}", Headers: map[string]string{"host": "ignored"}}, "host=from-env"},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		req.Method, req.URL = "GET", server.URL
+		ReplaceInRequest(&req, models.Variables{"vhost": "from-env"})
+		resp, err := ExecuteHTTPRequest(&req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.Body != tt.want {
+			t.Errorf("%s: expected the server to see '%s', got '%s'", tt.name, tt.want, resp.Body)
+		}
+	}
+
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: server.URL, Host: "evil.com/path"}); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Expected an invalid Host to be rejected, got %v", err)
+	}
+}
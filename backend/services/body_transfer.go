@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Ways of framing an outgoing request body
+const (
+	BodyTransferAuto          = ""               // Content-Length when the size is known, chunked otherwise
+	BodyTransferContentLength = "content-length" // buffer a body of unknown size to send its length
+	BodyTransferChunked       = "chunked"        // Transfer-Encoding: chunked even for a known size
+)
+
+// IsValidBodyTransfer reports whether mode is a known body transfer mode
+func IsValidBodyTransfer(mode string) bool {
+	switch mode {
+	case BodyTransferAuto, BodyTransferContentLength, BodyTransferChunked:
+		return true
+	}
+	return false
+}
+
+// ApplyBodyTransfer frames httpReq's body as mode asks. For content-length a
+// streamed body (e.g. a multipart upload) is read into memory first, for
+// servers that reject chunked bodies. chunked only applies to requests with
+// a body; one without stays as it is. A read error is the body's own.
+func ApplyBodyTransfer(httpReq *http.Request, mode string) error {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil
+	}
+	switch mode {
+	case BodyTransferAuto:
+		return nil
+	case BodyTransferChunked:
+		// net/http leaves out Content-Length once chunked is set
+		httpReq.TransferEncoding = []string{"chunked"}
+		return nil
+	case BodyTransferContentLength:
+		if httpReq.ContentLength > 0 {
+			return nil
+		}
+		body, err := io.ReadAll(httpReq.Body)
+		httpReq.Body.Close()
+		if err != nil {
+			return err
+		}
+		httpReq.ContentLength = int64(len(body))
+		if len(body) == 0 {
+			// A zero length with a Body would mean "unknown" and go chunked
+			httpReq.Body, httpReq.GetBody = http.NoBody, nil
+			return nil
+		}
+		httpReq.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		httpReq.Body, _ = httpReq.GetBody()
+		return nil
+	}
+	return fmt.Errorf("invalid body_transfer %q", mode)
+}
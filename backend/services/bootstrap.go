@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// CountPendingInvites returns how many unexpired invites are waiting for the email
+func CountPendingInvites(email string) (int64, error) {
+	var count int64
+	result := database.DB.Model(&models.TeamInvite{}).
+		Where("invitee_email = ? AND status = ? AND expires_at > ?", strings.ToLower(email), "pending", time.Now()).
+		Count(&count)
+	return count, result.Error
+}
+
+// BuildBootstrap assembles the user, their teams (with roles and member
+// counts) and their pending invite count
+func BuildBootstrap(user *models.User) (*models.BootstrapResponse, error) {
+	teams, err := GetUserTeamsWithRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	inviteCount, err := CountPendingInvites(user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BootstrapResponse{
+		User:               *user,
+		Teams:              teams,
+		PendingInviteCount: inviteCount,
+	}, nil
+}
+
+// AttachBootstrap adds the user's teams and pending invite count to a fresh
+// auth response
+func AttachBootstrap(auth *models.AuthResponse) error {
+	bootstrap, err := BuildBootstrap(&auth.User)
+	if err != nil {
+		return err
+	}
+	auth.Teams = bootstrap.Teams
+	auth.PendingInviteCount = bootstrap.PendingInviteCount
+	return nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// metaCharsetPattern matches both <meta charset="..."> and the charset in
+// <meta http-equiv="Content-Type" content="text/html; charset=...">
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]*?charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+// metaSniffBytes is how far into an HTML body to look for a meta charset, as
+// browsers do
+const metaSniffBytes = 1024
+
+// DetectCharset returns the lower-cased charset label from contentType, or
+// for HTML without one, from a meta tag near the start of body. It returns ""
+// when neither names one.
+func DetectCharset(contentType string, body []byte) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if label := strings.TrimSpace(params["charset"]); label != "" {
+			return strings.ToLower(label)
+		}
+	}
+	if err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return ""
+	}
+
+	head := body[:min(len(body), metaSniffBytes)]
+	if match := metaCharsetPattern.FindSubmatch(head); match != nil {
+		return strings.ToLower(string(match[1]))
+	}
+	return ""
+}
+
+// DecodeBody transcodes body to UTF-8 from the charset DetectCharset finds,
+// returning the text and that charset. Bodies in UTF-8, with no charset or
+// with one that isn't recognized pass through unchanged; the charset is only
+// reported when body was decoded with it (or already was UTF-8).
+func DecodeBody(contentType string, body []byte) (string, string) {
+	label := DetectCharset(contentType, body)
+	if label == "" {
+		return string(body), ""
+	}
+	encoding, err := htmlindex.Get(label)
+	if err != nil {
+		return string(body), ""
+	}
+	if name, _ := htmlindex.Name(encoding); name == "utf-8" {
+		return string(body), label
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body), ""
+	}
+	return string(decoded), label
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+func TestExecuteHTTPRequestDecodesLatin1(t *testing.T) {
+	useLoopback(t)
+	// "Café Zürich £5" in Latin-1
+	latin1 := []byte{'C', 'a', 'f', 0xE9, ' ', 'Z', 0xFC, 'r', 'i', 'c', 'h', ' ', 0xA3, '5'}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+		w.Write(latin1)
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != "Café Zürich £5" {
+		t.Errorf("Expected the body transcoded to UTF-8, got %q", resp.Body)
+	}
+	if resp.Charset != "iso-8859-1" {
+		t.Errorf("Expected charset iso-8859-1, got %q", resp.Charset)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        string
+		charset     string
+	}{
+		{"utf-8 passes through", "application/json; charset=utf-8", []byte(`{"name":"Zoë"}`), `{"name":"Zoë"}`, "utf-8"},
+		{"no charset passes through", "application/octet-stream", []byte{0xE9}, "\xE9", ""},
+		{"unknown charset passes through", "text/plain; charset=x-made-up", []byte{0xE9}, "\xE9", ""},
+		{"windows-1251", "text/plain; charset=windows-1251", []byte{0xCF, 0xF0, 0xE8, 0xE2, 0xE5, 0xF2}, "Привет", "windows-1251"},
+		{"html meta charset", "text/html", []byte(`<html><head><meta charset="iso-8859-1"></head><body>` + "na\xEFve</body></html>"), `<html><head><meta charset="iso-8859-1"></head><body>naïve</body></html>`, "iso-8859-1"},
+		{"html http-equiv", "text/html", []byte(`<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1">` + "\xE9"), `<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1">é`, "iso-8859-1"},
+		{"header wins over meta", "text/html; charset=utf-8", []byte(`<meta charset="iso-8859-1">é`), `<meta charset="iso-8859-1">é`, "utf-8"},
+		{"meta ignored outside html", "text/plain", []byte(`<meta charset="iso-8859-1">` + "\xE9"), `<meta charset="iso-8859-1">` + "\xE9", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, charset := DecodeBody(tt.contentType, tt.body)
+			if body != tt.want || charset != tt.charset {
+				t.Errorf("Expected %q (%q), got %q (%q)", tt.want, tt.charset, body, charset)
+			}
+		})
+	}
+}
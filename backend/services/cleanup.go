@@ -0,0 +1,54 @@
+package services
+
+import (
+	"log"
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"time"
+)
+
+// CleanupExpiredCredentials deletes API keys that expired before cutoff and
+// sessions that expired or were revoked before it. Both queries are range
+// scans on the indexed expires_at / revoked_at columns.
+func CleanupExpiredCredentials(cutoff time.Time) (apiKeys int64, sessions int64, err error) {
+	result := database.DB.
+		Where("expires_at IS NOT NULL AND expires_at < ?", cutoff).
+		Delete(&models.TeamAPIKey{})
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	apiKeys = result.RowsAffected
+
+	result = database.DB.
+		Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).
+		Delete(&models.Session{})
+	if result.Error != nil {
+		return apiKeys, 0, result.Error
+	}
+	return apiKeys, result.RowsAffected, nil
+}
+
+// StartCredentialCleanup periodically removes API keys and sessions that
+// expired more than CLEANUP_GRACE_DAYS ago. It does nothing when
+// CLEANUP_ENABLED is false or the interval isn't positive.
+func StartCredentialCleanup() {
+	interval := time.Duration(config.AppConfig.CleanupIntervalMinutes) * time.Minute
+	if !config.AppConfig.CleanupEnabled || interval <= 0 {
+		return
+	}
+	grace := time.Duration(config.AppConfig.CleanupGraceDays) * 24 * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			apiKeys, sessions, err := CleanupExpiredCredentials(time.Now().Add(-grace))
+			if err != nil {
+				log.Printf("Failed to clean up expired credentials: %v", err)
+				continue
+			}
+			log.Printf("Credential cleanup removed %d expired API keys and %d sessions", apiKeys, sessions)
+		}
+	}()
+}
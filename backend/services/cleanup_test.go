@@ -0,0 +1,42 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestCleanupExpiredCredentials(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "user@example.com")
+	team, _ := CreateTeamWithOwner("Acme", user.ID)
+
+	now := time.Now()
+	longAgo := now.Add(-30 * 24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+	for i, expiresAt := range []*time.Time{&longAgo, &yesterday, nil} {
+		database.DB.Create(&models.TeamAPIKey{TeamID: team.ID, Name: "key", Key: "key-" + strconv.Itoa(i), KeyPrefix: "pmx_",
+			ExpiresAt: expiresAt, CreatedBy: user.ID})
+	}
+	database.DB.Create(&models.Session{UserID: user.ID, TokenHash: "expired", ExpiresAt: longAgo})
+	database.DB.Create(&models.Session{UserID: user.ID, TokenHash: "revoked", ExpiresAt: now.Add(time.Hour), RevokedAt: &longAgo})
+	database.DB.Create(&models.Session{UserID: user.ID, TokenHash: "recent", ExpiresAt: yesterday})
+	database.DB.Create(&models.Session{UserID: user.ID, TokenHash: "active", ExpiresAt: now.Add(time.Hour)})
+
+	apiKeys, sessions, err := CleanupExpiredCredentials(now.Add(-7 * 24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKeys != 1 || sessions != 2 {
+		t.Errorf("Expected 1 API key and 2 sessions removed, got %d and %d", apiKeys, sessions)
+	}
+
+	var remaining int64
+	database.DB.Model(&models.Session{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("Expected the recent and active sessions to stay, got %d", remaining)
+	}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"postmanxodja/models"
+	"strings"
+)
+
+// FindCollectionItem walks the collection by item names (folders first, the
+// request last) and returns the request item at the end of the path
+func FindCollectionItem(collection *models.PostmanCollection, path []string) (*models.PostmanItem, error) {
+	if len(path) == 0 {
+		return nil, errors.New("item_path is empty")
+	}
+
+	items := collection.Item
+	var found *models.PostmanItem
+	for _, name := range path {
+		found = nil
+		for i := range items {
+			if items[i].Name == name {
+				found = &items[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("item %q not found", name)
+		}
+		items = found.Item
+	}
+
+	if found.Request == nil {
+		return nil, fmt.Errorf("item %q is a folder, not a request", found.Name)
+	}
+	return found, nil
+}
+
+// inheritsAuth reports whether a request or folder takes its auth from its
+// parent: no auth block at all, or Postman's explicit "inherit" type
+func inheritsAuth(auth *models.PostmanAuth) bool {
+	return auth == nil || auth.Type == "inherit"
+}
+
+// EffectiveAuth returns the auth that applies to the item at path, walking
+// from the collection root through each folder down to the request. The
+// nearest non-inheriting level wins; "noauth" on a folder or request turns
+// auth off for everything below it.
+func EffectiveAuth(collection *models.PostmanCollection, path []string) *models.PostmanAuth {
+	auth := collection.Auth
+
+	items := collection.Item
+	for _, name := range path {
+		var found *models.PostmanItem
+		for i := range items {
+			if items[i].Name == name {
+				found = &items[i]
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+
+		if found.Request != nil {
+			if !inheritsAuth(found.Request.Auth) {
+				auth = found.Request.Auth
+			}
+		} else if !inheritsAuth(found.Auth) {
+			auth = found.Auth
+		}
+		items = found.Item
+	}
+
+	if inheritsAuth(auth) || auth.Type == "noauth" {
+		return nil
+	}
+	return auth
+}
+
+// ResolveRequestURL returns the URL of a stored request, which Postman saves
+// either as a plain string or as an object with a raw field
+func ResolveRequestURL(rawURL interface{}) string {
+	switch u := rawURL.(type) {
+	case string:
+		return u
+	case map[string]interface{}:
+		if raw, ok := u["raw"].(string); ok && raw != "" {
+			return raw
+		}
+
+		// No raw URL: rebuild it from the parts
+		var result string
+		if protocol, ok := u["protocol"].(string); ok && protocol != "" {
+			result = protocol + "://"
+		}
+		result += joinURLParts(u["host"], ".")
+		if path := joinURLParts(u["path"], "/"); path != "" {
+			result += "/" + path
+		}
+		if query, ok := u["query"].([]interface{}); ok {
+			var pairs []string
+			for _, q := range query {
+				param, ok := q.(map[string]interface{})
+				if !ok || param["disabled"] == true {
+					continue
+				}
+				pairs = append(pairs, fmt.Sprint(param["key"])+"="+stringValue(param["value"]))
+			}
+			if len(pairs) > 0 {
+				result += "?" + strings.Join(pairs, "&")
+			}
+		}
+		return result
+	}
+	return ""
+}
+
+func joinURLParts(parts interface{}, sep string) string {
+	switch p := parts.(type) {
+	case string:
+		return p
+	case []interface{}:
+		values := make([]string, 0, len(p))
+		for _, part := range p {
+			values = append(values, fmt.Sprint(part))
+		}
+		return strings.Join(values, sep)
+	}
+	return ""
+}
+
+// stringValue formats a Postman value (usually a string) for sending
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// authParam returns the value of key in a Postman auth parameter list
+func authParam(params []models.PostmanAuthParameter, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return stringValue(p.Value)
+		}
+	}
+	return ""
+}
+
+// applyAuth adds the headers / query params a Postman auth block describes
+func applyAuth(req *models.ExecuteRequest, auth *models.PostmanAuth) {
+	if auth == nil {
+		return
+	}
+
+	switch auth.Type {
+	case "bearer":
+		if token := authParam(auth.Bearer, "token"); token != "" {
+			req.HeaderList = append(req.HeaderList, models.KeyValue{Key: "Authorization", Value: "Bearer " + token})
+		}
+	case "basic":
+		credentials := authParam(auth.Basic, "username") + ":" + authParam(auth.Basic, "password")
+		req.HeaderList = append(req.HeaderList, models.KeyValue{
+			Key:   "Authorization",
+			Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+		})
+	case "apikey":
+		key := authParam(auth.Apikey, "key")
+		if key == "" {
+			return
+		}
+		pair := models.KeyValue{Key: key, Value: authParam(auth.Apikey, "value")}
+		if authParam(auth.Apikey, "in") == "query" {
+			req.QueryList = append(req.QueryList, pair)
+		} else {
+			req.HeaderList = append(req.HeaderList, pair)
+		}
+	case "oauth2":
+		if token := authParam(auth.OAuth2, "accessToken"); token != "" {
+			prefix := authParam(auth.OAuth2, "headerPrefix")
+			if prefix == "" {
+				prefix = "Bearer"
+			}
+			req.HeaderList = append(req.HeaderList, models.KeyValue{Key: "Authorization", Value: prefix + " " + token})
+		}
+	case "noauth", "":
+	default:
+		log.Printf("Unsupported auth type %q on stored request, sending without auth", auth.Type)
+	}
+}
+
+// rawContentTypes maps Postman's raw body language to a Content-Type
+var rawContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"javascript": "application/javascript",
+	"text":       "text/plain",
+}
+
+// ItemToExecuteRequest maps a stored collection request (method, URL,
+// headers and body) onto an ExecuteRequest, applying auth, which is usually
+// the item's EffectiveAuth. Variables are left as {{placeholders}} for
+// ReplaceInRequest.
+func ItemToExecuteRequest(item *models.PostmanItem, auth *models.PostmanAuth) (*models.ExecuteRequest, error) {
+	stored := item.Request
+	if stored == nil {
+		return nil, errors.New("item has no request")
+	}
+
+	req := &models.ExecuteRequest{
+		Method: strings.ToUpper(stored.Method),
+		URL:    ResolveRequestURL(stored.URL),
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	hasContentType := false
+	for _, h := range stored.Header {
+		if h.Disabled {
+			continue
+		}
+		if strings.EqualFold(h.Key, "Content-Type") {
+			hasContentType = true
+		}
+		req.HeaderList = append(req.HeaderList, models.KeyValue{Key: h.Key, Value: stringValue(h.Value)})
+	}
+
+	applyAuth(req, auth)
+
+	setContentType := func(contentType string) {
+		if !hasContentType {
+			req.HeaderList = append(req.HeaderList, models.KeyValue{Key: "Content-Type", Value: contentType})
+		}
+	}
+
+	if body := stored.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			req.Body = body.Raw
+			if body.Options != nil && body.Options.Raw != nil {
+				if contentType, ok := rawContentTypes[body.Options.Raw.Language]; ok && body.Raw != "" {
+					setContentType(contentType)
+				}
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, field := range body.Urlencoded {
+				if !field.Disabled {
+					form.Add(field.Key, field.Value)
+				}
+			}
+			req.Body = form.Encode()
+			setContentType("application/x-www-form-urlencoded")
+		case "formdata":
+			// Only text fields can be replayed; uploaded files aren't stored
+			var buf bytes.Buffer
+			writer := multipart.NewWriter(&buf)
+			for _, field := range body.FormData {
+				if field.Disabled {
+					continue
+				}
+				if field.Type == "file" {
+					log.Printf("Skipping file field %q: stored requests don't keep uploaded files", field.Key)
+					continue
+				}
+				writer.WriteField(field.Key, field.Value)
+			}
+			writer.Close()
+			req.Body = buf.String()
+			// The boundary has to match the body, so always set it
+			req.HeaderList = removeHeader(req.HeaderList, "Content-Type")
+			req.HeaderList = append(req.HeaderList, models.KeyValue{Key: "Content-Type", Value: writer.FormDataContentType()})
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported body mode %q", body.Mode)
+		}
+	}
+
+	return req, nil
+}
+
+func removeHeader(headers []models.KeyValue, name string) []models.KeyValue {
+	kept := headers[:0]
+	for _, h := range headers {
+		if !strings.EqualFold(h.Key, name) {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// CollectionVariables returns a collection's own variables
+func CollectionVariables(collection *models.PostmanCollection) models.Variables {
+	variables := make(models.Variables, len(collection.Variable))
+	for _, v := range collection.Variable {
+		variables[v.Key] = v.Value
+	}
+	return variables
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+const storedCollection = `{
+	"info": {"name": "Shop"},
+	"variable": [{"key": "base", "value": "http://api.test"}],
+	"item": [
+		{"name": "Users", "item": [
+			{"name": "Create user", "request": {
+				"method": "post",
+				"url": {"raw": "{{base}}/users?x=1", "host": ["{{base}}"], "path": ["users"]},
+				"header": [
+					{"key": "X-Trace", "value": "on"},
+					{"key": "X-Off", "value": "no", "disabled": true}
+				],
+				"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+				"body": {"mode": "raw", "raw": "{\"name\":\"a\"}", "options": {"raw": {"language": "json"}}}
+			}}
+		]},
+		{"name": "Login", "request": {
+			"method": "POST",
+			"url": "{{base}}/login",
+			"body": {"mode": "urlencoded", "urlencoded": [
+				{"key": "user", "value": "bob"},
+				{"key": "skip", "value": "x", "disabled": true}
+			]}
+		}},
+		{"name": "Upload", "request": {
+			"method": "POST",
+			"url": "{{base}}/upload",
+			"body": {"mode": "formdata", "formdata": [
+				{"key": "title", "value": "doc", "type": "text"},
+				{"key": "file", "type": "file"}
+			]}
+		}}
+	]
+}`
+
+func headerValue(headers []models.KeyValue, key string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, key) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func TestFindCollectionItem(t *testing.T) {
+	collection, err := ParsePostmanCollection(storedCollection)
+	if err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+
+	item, err := FindCollectionItem(collection, []string{"Users", "Create user"})
+	if err != nil {
+		t.Fatalf("Expected item, got error %v", err)
+	}
+	if item.Name != "Create user" {
+		t.Errorf("Expected 'Create user', got '%s'", item.Name)
+	}
+
+	if _, err := FindCollectionItem(collection, []string{"Users"}); err == nil {
+		t.Error("Expected an error for a folder path")
+	}
+	if _, err := FindCollectionItem(collection, []string{"Users", "Missing"}); err == nil {
+		t.Error("Expected an error for a missing item")
+	}
+}
+
+func TestItemToExecuteRequestRaw(t *testing.T) {
+	collection, _ := ParsePostmanCollection(storedCollection)
+	item, _ := FindCollectionItem(collection, []string{"Users", "Create user"})
+
+	req, err := ItemToExecuteRequest(item, item.Request.Auth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("Expected method POST, got '%s'", req.Method)
+	}
+	if req.URL != "{{base}}/users?x=1" {
+		t.Errorf("Expected raw URL, got '%s'", req.URL)
+	}
+	if headerValue(req.HeaderList, "X-Off") != "" {
+		t.Error("Expected disabled header to be skipped")
+	}
+	if got := headerValue(req.HeaderList, "Authorization"); got != "Bearer {{token}}" {
+		t.Errorf("Expected 'Bearer {{token}}', got '%s'", got)
+	}
+	if got := headerValue(req.HeaderList, "Content-Type"); got != "application/json" {
+		t.Errorf("Expected 'application/json', got '%s'", got)
+	}
+
+	unresolved := ReplaceInRequest(req, CollectionVariables(collection))
+	if req.URL != "http://api.test/users?x=1" {
+		t.Errorf("Expected collection variable in URL, got '%s'", req.URL)
+	}
+	if len(unresolved) != 1 || unresolved[0] != "token" {
+		t.Errorf("Expected [token] unresolved, got %v", unresolved)
+	}
+}
+
+func TestItemToExecuteRequestForms(t *testing.T) {
+	collection, _ := ParsePostmanCollection(storedCollection)
+
+	login, _ := FindCollectionItem(collection, []string{"Login"})
+	req, err := ItemToExecuteRequest(login, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Body != "user=bob" {
+		t.Errorf("Expected 'user=bob', got '%s'", req.Body)
+	}
+	if got := headerValue(req.HeaderList, "Content-Type"); got != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected urlencoded content type, got '%s'", got)
+	}
+
+	upload, _ := FindCollectionItem(collection, []string{"Upload"})
+	req, err = ItemToExecuteRequest(upload, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(headerValue(req.HeaderList, "Content-Type"), "multipart/form-data; boundary=") {
+		t.Errorf("Expected multipart content type, got '%s'", headerValue(req.HeaderList, "Content-Type"))
+	}
+	if !strings.Contains(req.Body, `name="title"`) || strings.Contains(req.Body, `name="file"`) {
+		t.Errorf("Expected only the text field in the body, got '%s'", req.Body)
+	}
+}
+
+func TestResolveRequestURLFromParts(t *testing.T) {
+	got := ResolveRequestURL(map[string]interface{}{
+		"protocol": "https",
+		"host":     []interface{}{"api", "example", "com"},
+		"path":     []interface{}{"v1", "items"},
+		"query": []interface{}{
+			map[string]interface{}{"key": "page", "value": "2"},
+			map[string]interface{}{"key": "off", "value": "1", "disabled": true},
+		},
+	})
+	if got != "https://api.example.com/v1/items?page=2" {
+		t.Errorf("Expected 'https://api.example.com/v1/items?page=2', got '%s'", got)
+	}
+}
+
+const inheritedAuthCollection = `{
+	"info": {"name": "Auth"},
+	"auth": {"type": "apikey", "apikey": [
+		{"key": "key", "value": "X-Api-Key"},
+		{"key": "value", "value": "root-key"}
+	]},
+	"item": [
+		{"name": "Plain", "request": {"method": "GET", "url": "http://api.test/plain"}},
+		{"name": "Admin", "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "folder-token"}]}, "item": [
+			{"name": "Inherit", "request": {"method": "GET", "url": "http://api.test/a", "auth": {"type": "inherit"}}},
+			{"name": "Own", "request": {"method": "GET", "url": "http://api.test/b",
+				"auth": {"type": "basic", "basic": [{"key": "username", "value": "u"}, {"key": "password", "value": "p"}]}}},
+			{"name": "Public", "auth": {"type": "noauth"}, "item": [
+				{"name": "Health", "request": {"method": "GET", "url": "http://api.test/health"}}
+			]}
+		]}
+	]
+}`
+
+func TestEffectiveAuthInheritance(t *testing.T) {
+	collection, err := ParsePostmanCollection(inheritedAuthCollection)
+	if err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+
+	tests := []struct {
+		path     []string
+		wantType string
+	}{
+		{[]string{"Plain"}, "apikey"},               // from the collection
+		{[]string{"Admin", "Inherit"}, "bearer"},    // from the folder
+		{[]string{"Admin", "Own"}, "basic"},         // request overrides the folder
+		{[]string{"Admin", "Public", "Health"}, ""}, // noauth folder turns it off
+	}
+
+	for _, tt := range tests {
+		auth := EffectiveAuth(collection, tt.path)
+		gotType := ""
+		if auth != nil {
+			gotType = auth.Type
+		}
+		if gotType != tt.wantType {
+			t.Errorf("Path %v: expected auth '%s', got '%s'", tt.path, tt.wantType, gotType)
+		}
+	}
+}
+
+func TestItemToExecuteRequestInheritedAuth(t *testing.T) {
+	collection, _ := ParsePostmanCollection(inheritedAuthCollection)
+
+	path := []string{"Admin", "Inherit"}
+	item, _ := FindCollectionItem(collection, path)
+	req, err := ItemToExecuteRequest(item, EffectiveAuth(collection, path))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := headerValue(req.HeaderList, "Authorization"); got != "Bearer folder-token" {
+		t.Errorf("Expected 'Bearer folder-token', got '%s'", got)
+	}
+
+	path = []string{"Plain"}
+	item, _ = FindCollectionItem(collection, path)
+	req, _ = ItemToExecuteRequest(item, EffectiveAuth(collection, path))
+	if got := headerValue(req.HeaderList, "X-Api-Key"); got != "root-key" {
+		t.Errorf("Expected collection API key header 'root-key', got '%s'", got)
+	}
+
+	path = []string{"Admin", "Own"}
+	item, _ = FindCollectionItem(collection, path)
+	req, _ = ItemToExecuteRequest(item, EffectiveAuth(collection, path))
+	if got := headerValue(req.HeaderList, "Authorization"); got != "Basic dTpw" {
+		t.Errorf("Expected 'Basic dTpw', got '%s'", got)
+	}
+}
+
+func TestReorderCollectionItems(t *testing.T) {
+	collection, err := ParsePostmanCollection(storedCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var original []string
+	for _, item := range collection.Item {
+		original = append(original, item.Name)
+	}
+	reversed := make([]string, len(original))
+	for i, name := range original {
+		reversed[len(original)-1-i] = name
+	}
+
+	rawJSON, err := ReorderCollectionItems(storedCollection, []models.FolderOrder{
+		{Items: reversed},
+		{Path: []string{"Users"}, Items: []string{"Create user"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected the reorder to succeed, got %v", err)
+	}
+	if collection, err = ParsePostmanCollection(rawJSON); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, item := range collection.Item {
+		got = append(got, item.Name)
+	}
+	if strings.Join(got, ",") != strings.Join(reversed, ",") {
+		t.Errorf("Expected order %v, got %v", reversed, got)
+	}
+	if _, err := FindCollectionItem(collection, []string{"Users", "Create user"}); err != nil {
+		t.Errorf("Expected folder contents to move with the folder, got %v", err)
+	}
+}
+
+func TestReorderCollectionItemsKeepsUnknownFields(t *testing.T) {
+	rawJSON, err := ReorderCollectionItems(`{"info":{"name":"Shop"},"x-owner":"qa","item":[
+		{"name":"A","request":{"method":"GET","url":"http://api.test/a"}},
+		{"name":"B","event":[{"listen":"test","script":{"exec":["pm.test()"]}}],"request":{"method":"GET","url":"http://api.test/b","x-extra":true}}
+	]}`, []models.FolderOrder{{Items: []string{"B", "A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root struct {
+		Owner string `json:"x-owner"`
+		Item  []struct {
+			Name    string            `json:"name"`
+			Event   []json.RawMessage `json:"event"`
+			Request map[string]any    `json:"request"`
+		} `json:"item"`
+	}
+	if err := json.Unmarshal([]byte(rawJSON), &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Owner != "qa" || len(root.Item) != 2 || root.Item[0].Name != "B" {
+		t.Fatalf("Expected B first with the collection's fields kept, got %s", rawJSON)
+	}
+	if len(root.Item[0].Event) != 1 || root.Item[0].Request["x-extra"] != true {
+		t.Errorf("Expected B's event and request fields to survive, got %s", rawJSON)
+	}
+}
+
+func TestReorderCollectionItemsRejectsMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		folders []models.FolderOrder
+	}{
+		{"missing item", []models.FolderOrder{{Path: []string{"Users"}, Items: []string{}}}},
+		{"unknown item", []models.FolderOrder{{Path: []string{"Users"}, Items: []string{"Delete user"}}}},
+		{"unknown folder", []models.FolderOrder{{Path: []string{"Orders"}, Items: []string{}}}},
+		{"request as folder", []models.FolderOrder{{Path: []string{"Login"}, Items: []string{}}}},
+		{"folder twice", []models.FolderOrder{
+			{Path: []string{"Users"}, Items: []string{"Create user"}},
+			{Path: []string{"Users"}, Items: []string{"Create user"}},
+		}},
+		{"nothing to do", nil},
+	}
+	for _, tt := range tests {
+		if _, err := ReorderCollectionItems(storedCollection, tt.folders); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestReorderCollectionItemsDuplicateNames(t *testing.T) {
+	stored := `{"item":[
+		{"name":"Ping","request":{"method":"GET"}},
+		{"name":"Ping","request":{"method":"POST"}},
+		{"name":"Health","request":{"method":"GET"}}
+	]}`
+	if _, err := ReorderCollectionItems(stored, []models.FolderOrder{{Items: []string{"Ping", "Ping", "Ping"}}}); err == nil {
+		t.Error("Expected a name listed more often than it exists to be rejected")
+	}
+	rawJSON, err := ReorderCollectionItems(stored, []models.FolderOrder{{Items: []string{"Health", "Ping", "Ping"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, _ := ParsePostmanCollection(rawJSON)
+	if collection.Item[0].Name != "Health" || collection.Item[1].Request.Method != "GET" || collection.Item[2].Request.Method != "POST" {
+		t.Errorf("Expected same-named items to keep their relative order, got %+v", collection.Item)
+	}
+}
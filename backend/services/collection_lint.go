@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"postmanxodja/models"
+)
+
+// Lint finding severities
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// lintTarget is one request of the collection as a lint rule sees it
+type lintTarget struct {
+	Path     []string
+	Request  *models.PostmanRequest
+	Siblings []models.PostmanItem // the items of the request's folder
+	Index    int                  // the request's position in Siblings
+	// Auth is the nearest auth block that doesn't inherit, nil when no
+	// level of the collection configures any
+	Auth *models.PostmanAuth
+}
+
+// lintRule checks a request and returns a message for each problem it finds
+type lintRule struct {
+	Name     string
+	Severity string
+	Check    func(target lintTarget) []string
+}
+
+// lintRules are run by LintCollection against every request; add a rule
+// here to have it reported
+var lintRules = []lintRule{
+	{Name: "missing-url", Severity: LintError, Check: lintMissingURL},
+	{Name: "hardcoded-credential", Severity: LintWarning, Check: lintHardcodedCredential},
+	{Name: "missing-auth", Severity: LintWarning, Check: lintMissingAuth},
+	{Name: "duplicate-name", Severity: LintWarning, Check: lintDuplicateName},
+}
+
+// credentialHeaders are headers whose values are secrets, so they should
+// come from a variable rather than be saved in the collection
+var credentialHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"api-key":             true,
+	"x-auth-token":        true,
+	"x-access-token":      true,
+	"cookie":              true,
+}
+
+// LintCollection runs lintRules against every request in the collection,
+// including nested folders, and returns the findings in collection order
+func LintCollection(collection *models.PostmanCollection) []models.LintFinding {
+	findings := []models.LintFinding{}
+	lintItems(collection.Item, nil, collection.Auth, &findings)
+	return findings
+}
+
+func lintItems(items []models.PostmanItem, parent []string, auth *models.PostmanAuth, findings *[]models.LintFinding) {
+	for i := range items {
+		item := &items[i]
+		path := append(append([]string{}, parent...), item.Name)
+
+		if item.Request == nil {
+			folderAuth := auth
+			if !inheritsAuth(item.Auth) {
+				folderAuth = item.Auth
+			}
+			lintItems(item.Item, path, folderAuth, findings)
+			continue
+		}
+
+		target := lintTarget{Path: path, Request: item.Request, Siblings: items, Index: i, Auth: auth}
+		if !inheritsAuth(item.Request.Auth) {
+			target.Auth = item.Request.Auth
+		}
+		for _, rule := range lintRules {
+			for _, message := range rule.Check(target) {
+				*findings = append(*findings, models.LintFinding{
+					Rule:     rule.Name,
+					Severity: rule.Severity,
+					ItemPath: path,
+					Message:  message,
+				})
+			}
+		}
+	}
+}
+
+func lintMissingURL(target lintTarget) []string {
+	if strings.TrimSpace(ResolveRequestURL(target.Request.URL)) == "" {
+		return []string{"Request has no URL"}
+	}
+	return nil
+}
+
+// lintHardcodedCredential flags credential headers with a literal value.
+// Disabled headers count too, since they're still saved in the collection.
+func lintHardcodedCredential(target lintTarget) []string {
+	var messages []string
+	for _, h := range target.Request.Header {
+		if !credentialHeaders[strings.ToLower(strings.TrimSpace(h.Key))] {
+			continue
+		}
+		value := strings.TrimSpace(stringValue(h.Value))
+		// Whatever is left once the variables are taken out is hardcoded;
+		// a scheme such as "Bearer " on its own is fine
+		literal := strings.TrimSpace(variablePattern.ReplaceAllString(value, ""))
+		if fields := strings.Fields(literal); len(fields) == 0 || (len(fields) == 1 && value != literal && isAuthScheme(fields[0])) {
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("Header %q has a hardcoded value; use a variable instead", h.Key))
+	}
+	return messages
+}
+
+func isAuthScheme(word string) bool {
+	switch strings.ToLower(word) {
+	case "bearer", "basic", "token", "digest", "apikey":
+		return true
+	}
+	return false
+}
+
+// lintMissingAuth flags requests that no level of the collection sets auth
+// for and that don't send credentials in a header either. An explicit
+// "noauth" is a deliberate choice and isn't reported.
+func lintMissingAuth(target lintTarget) []string {
+	if target.Auth != nil {
+		return nil
+	}
+	for _, h := range target.Request.Header {
+		if !h.Disabled && credentialHeaders[strings.ToLower(strings.TrimSpace(h.Key))] {
+			return nil
+		}
+	}
+	return []string{"Request has no auth and doesn't inherit any"}
+}
+
+// lintDuplicateName flags every request after the first with a name already
+// used by another request in the same folder, since item paths can only
+// reach the first one
+func lintDuplicateName(target lintTarget) []string {
+	name := target.Siblings[target.Index].Name
+	for _, sibling := range target.Siblings[:target.Index] {
+		if sibling.Request != nil && sibling.Name == name {
+			return []string{fmt.Sprintf("Another request in this folder is already named %q", name)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const lintFixture = `{
+	"info": {"name": "Lint"},
+	"item": [
+		{"name": "No URL", "request": {"method": "GET", "url": "", "auth": {"type": "noauth"}}},
+		{"name": "Hardcoded", "request": {
+			"method": "GET",
+			"url": "http://api.test/me",
+			"header": [
+				{"key": "Authorization", "value": "Bearer abc123"},
+				{"key": "X-API-Key", "value": "{{api_key}}"},
+				{"key": "Cookie", "value": "session=xyz", "disabled": true}
+			]
+		}},
+		{"name": "Templated", "request": {
+			"method": "GET",
+			"url": "http://api.test/me",
+			"header": [{"key": "Authorization", "value": "Bearer {{token}}"}]
+		}},
+		{"name": "Unauthenticated", "request": {"method": "GET", "url": "http://api.test/open"}},
+		{"name": "Secured", "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]}, "item": [
+			{"name": "Get", "request": {"method": "GET", "url": "http://api.test/a"}},
+			{"name": "Get", "request": {"method": "GET", "url": "http://api.test/b"}}
+		]}
+	]
+}`
+
+func TestLintCollection(t *testing.T) {
+	collection, err := ParsePostmanCollection(lintFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := LintCollection(collection)
+	got := make([]string, 0, len(findings))
+	for _, f := range findings {
+		got = append(got, f.Rule+" "+f.Severity+" "+strings.Join(f.ItemPath, "/")+": "+f.Message)
+	}
+	expected := []string{
+		`missing-url error No URL: Request has no URL`,
+		`hardcoded-credential warning Hardcoded: Header "Authorization" has a hardcoded value; use a variable instead`,
+		`hardcoded-credential warning Hardcoded: Header "Cookie" has a hardcoded value; use a variable instead`,
+		`missing-auth warning Unauthenticated: Request has no auth and doesn't inherit any`,
+		`duplicate-name warning Secured/Get: Another request in this folder is already named "Get"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected findings:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestLintCollectionClean(t *testing.T) {
+	collection, err := ParsePostmanCollection(storedCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range LintCollection(collection) {
+		if f.Rule == "missing-url" || f.Rule == "hardcoded-credential" || f.Rule == "duplicate-name" {
+			t.Errorf("Unexpected finding %+v", f)
+		}
+	}
+}
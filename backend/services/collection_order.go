@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"postmanxodja/models"
+	"strings"
+)
+
+// rawItem is a collection, folder or request kept as raw JSON fields, so
+// reordering leaves everything the models don't know about untouched
+type rawItem struct {
+	fields   map[string]json.RawMessage
+	name     string
+	request  bool
+	items    []*rawItem
+	hasItems bool
+}
+
+// ReorderCollectionItems reorders folders of the collection's raw JSON and
+// returns the result. Each FolderOrder has to name exactly the items its
+// folder already has; items sharing a name keep their relative order. Only
+// the item arrays are rewritten, every other field is kept as it is.
+func ReorderCollectionItems(rawJSON string, folders []models.FolderOrder) (string, error) {
+	if len(folders) == 0 {
+		return "", errors.New("folders is empty")
+	}
+	root, err := decodeRawItem(json.RawMessage(rawJSON))
+	if err != nil {
+		return "", fmt.Errorf("invalid collection: %w", err)
+	}
+
+	seen := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		key := strings.Join(folder.Path, "\x00")
+		if seen[key] {
+			return "", fmt.Errorf("folder %q is listed more than once", folderLabel(folder.Path))
+		}
+		seen[key] = true
+
+		target, err := folderItem(root, folder.Path)
+		if err != nil {
+			return "", err
+		}
+		reordered, err := reorderItems(target.items, folder.Items)
+		if err != nil {
+			return "", fmt.Errorf("folder %q: %w", folderLabel(folder.Path), err)
+		}
+		target.items = reordered
+	}
+
+	encoded, err := root.encode()
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// decodeRawItem splits raw into its fields, descending into the item array
+func decodeRawItem(raw json.RawMessage) (*rawItem, error) {
+	item := &rawItem{}
+	if err := json.Unmarshal(raw, &item.fields); err != nil {
+		return nil, err
+	}
+	if name, ok := item.fields["name"]; ok {
+		if err := json.Unmarshal(name, &item.name); err != nil {
+			return nil, fmt.Errorf("name: %w", err)
+		}
+	}
+	if request, ok := item.fields["request"]; ok && string(request) != "null" {
+		item.request = true
+	}
+
+	children, ok := item.fields["item"]
+	if !ok {
+		return item, nil
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(children, &raws); err != nil {
+		return nil, fmt.Errorf("item: %w", err)
+	}
+	item.hasItems = true
+	for _, child := range raws {
+		decoded, err := decodeRawItem(child)
+		if err != nil {
+			return nil, err
+		}
+		item.items = append(item.items, decoded)
+	}
+	return item, nil
+}
+
+// encode writes the item back, with its item array in the current order
+func (item *rawItem) encode() (json.RawMessage, error) {
+	if item.hasItems {
+		children := make([]json.RawMessage, 0, len(item.items))
+		for _, child := range item.items {
+			encoded, err := child.encode()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, encoded)
+		}
+		encoded, err := json.Marshal(children)
+		if err != nil {
+			return nil, err
+		}
+		item.fields["item"] = encoded
+	}
+	return json.Marshal(item.fields)
+}
+
+// folderItem returns the folder at path, the collection itself when path is
+// empty
+func folderItem(root *rawItem, path []string) (*rawItem, error) {
+	current := root
+	for i, name := range path {
+		var found *rawItem
+		for _, item := range current.items {
+			if item.name == name {
+				found = item
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("folder %q not found", folderLabel(path[:i+1]))
+		}
+		if found.request {
+			return nil, fmt.Errorf("item %q is a request, not a folder", folderLabel(path[:i+1]))
+		}
+		current = found
+	}
+	return current, nil
+}
+
+// reorderItems returns items in the order names lists them
+func reorderItems(items []*rawItem, names []string) ([]*rawItem, error) {
+	if len(names) != len(items) {
+		return nil, fmt.Errorf("expected %d items, got %d", len(items), len(names))
+	}
+
+	// Queue up the positions of each name so duplicates are taken in order
+	positions := make(map[string][]int, len(items))
+	for i, item := range items {
+		positions[item.name] = append(positions[item.name], i)
+	}
+
+	reordered := make([]*rawItem, 0, len(items))
+	for _, name := range names {
+		queue := positions[name]
+		if len(queue) == 0 {
+			if _, exists := positions[name]; exists {
+				return nil, fmt.Errorf("item %q is listed more times than it exists", name)
+			}
+			return nil, fmt.Errorf("item %q not found", name)
+		}
+		reordered = append(reordered, items[queue[0]])
+		positions[name] = queue[1:]
+	}
+	return reordered, nil
+}
+
+// folderLabel names a folder path in error messages
+func folderLabel(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, " / ")
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"postmanxodja/models"
+)
+
+// secretNameParts mark a variable as a secret when its name, lowercased and
+// without separators, contains one of them
+var secretNameParts = []string{
+	"secret", "password", "passwd", "token", "apikey", "accesskey", "privatekey", "credential", "cookie", "session",
+}
+
+// IsSecretVariableName reports whether a variable's name says it holds a
+// secret, e.g. api_key, clientSecret or AUTH-TOKEN
+func IsSecretVariableName(name string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(strings.ToLower(name))
+	for _, part := range secretNameParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// previewTarget is a request of the preview waiting for its variables.
+// authQuery is the query parameter its apikey auth goes in, if any.
+type previewTarget struct {
+	req       *models.ExecuteRequest
+	preview   *models.PreviewRequest
+	authQuery string
+}
+
+// PreviewCollection builds every request of the collection, auth included,
+// and substitutes variables into it, keeping the folder tree. Unless
+// revealSecrets is set the values of secret variables are shown as
+// RedactedValue: those typed "secret" in the collection, those whose name
+// looks like one (IsSecretVariableName) and those used in a redacted header
+// or an apikey auth query parameter anywhere in the collection. Those
+// headers and parameters are masked themselves too, so credentials written
+// into the collection don't show either (LintCollection flags them).
+func PreviewCollection(collection *models.PostmanCollection, variables models.Variables, revealSecrets bool) *models.CollectionPreview {
+	var targets []previewTarget
+	preview := &models.CollectionPreview{
+		Items:               previewItems(collection.Item, collection.Auth, &targets),
+		UnresolvedVariables: []string{},
+		MaskedVariables:     []string{},
+	}
+
+	secret := make(map[string]bool)
+	if !revealSecrets {
+		for _, v := range collection.Variable {
+			if v.Type == "secret" {
+				secret[v.Key] = true
+			}
+		}
+		for name := range variables {
+			if IsSecretVariableName(name) {
+				secret[name] = true
+			}
+		}
+		for _, target := range targets {
+			for _, h := range target.secretPairs() {
+				for _, match := range variablePattern.FindAllStringSubmatch(h.Value, -1) {
+					secret[strings.TrimPrefix(match[1], bodyVariablePrefix)] = true
+				}
+			}
+		}
+	}
+
+	shown := make(models.Variables, len(variables))
+	for name, value := range variables {
+		if secret[name] {
+			value = RedactedValue
+			preview.MaskedVariables = append(preview.MaskedVariables, name)
+		}
+		shown[name] = value
+	}
+	sort.Strings(preview.MaskedVariables)
+
+	unresolved := make(map[string]bool)
+	for _, target := range targets {
+		names := ReplaceInRequest(target.req, shown)
+		for _, name := range names {
+			unresolved[name] = true
+		}
+		if !revealSecrets {
+			for _, h := range target.secretPairs() {
+				h.Value = maskCredential(h.Value)
+			}
+		}
+		target.preview.URL = MergeQueryList(target.req.URL, target.req.QueryList, target.req.QueryMergePolicy)
+		target.preview.Headers = append([]models.KeyValue{}, target.req.HeaderList...)
+		target.preview.Body = target.req.Body
+		target.preview.UnresolvedVariables = names
+	}
+	for name := range unresolved {
+		preview.UnresolvedVariables = append(preview.UnresolvedVariables, name)
+	}
+	sort.Strings(preview.UnresolvedVariables)
+	return preview
+}
+
+// secretPairs returns the headers and query parameters of the target that
+// carry credentials: redacted headers and its apikey auth parameter
+func (target previewTarget) secretPairs() []*models.KeyValue {
+	var pairs []*models.KeyValue
+	for i := range target.req.HeaderList {
+		if IsRedactedHeader(target.req.HeaderList[i].Key) {
+			pairs = append(pairs, &target.req.HeaderList[i])
+		}
+	}
+	for i := range target.req.QueryList {
+		if target.authQuery != "" && target.req.QueryList[i].Key == target.authQuery {
+			pairs = append(pairs, &target.req.QueryList[i])
+		}
+	}
+	return pairs
+}
+
+// maskCredential replaces a credential with RedactedValue, keeping an auth
+// scheme in front of it ("Bearer ***") and placeholders nothing was
+// substituted into
+func maskCredential(value string) string {
+	scheme, credential, found := strings.Cut(value, " ")
+	if !found || strings.IndexFunc(scheme, func(r rune) bool { return !unicode.IsLetter(r) }) != -1 {
+		scheme, credential = "", value
+	}
+	if strings.TrimSpace(variablePattern.ReplaceAllString(credential, "")) != "" {
+		credential = RedactedValue
+	}
+	if scheme == "" {
+		return credential
+	}
+	return scheme + " " + credential
+}
+
+// previewItems mirrors items into the preview tree, adding each request that
+// could be built to targets. auth is the nearest non-inheriting auth above.
+func previewItems(items []models.PostmanItem, auth *models.PostmanAuth, targets *[]previewTarget) []models.PreviewItem {
+	previews := make([]models.PreviewItem, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		itemAuth := auth
+		if item.Request != nil {
+			if !inheritsAuth(item.Request.Auth) {
+				itemAuth = item.Request.Auth
+			}
+		} else if !inheritsAuth(item.Auth) {
+			itemAuth = item.Auth
+		}
+		if itemAuth != nil && itemAuth.Type == "noauth" {
+			itemAuth = nil
+		}
+
+		preview := models.PreviewItem{Name: item.Name}
+		if item.Request == nil {
+			preview.Item = previewItems(item.Item, itemAuth, targets)
+			previews = append(previews, preview)
+			continue
+		}
+
+		preview.Request = &models.PreviewRequest{Method: strings.ToUpper(item.Request.Method), Headers: []models.KeyValue{}}
+		req, err := ItemToExecuteRequest(item, itemAuth)
+		if err != nil {
+			preview.Request.Error = err.Error()
+		} else {
+			preview.Request.Method = req.Method
+			target := previewTarget{req: req, preview: preview.Request}
+			if itemAuth != nil && itemAuth.Type == "apikey" && authParam(itemAuth.Apikey, "in") == "query" {
+				target.authQuery = authParam(itemAuth.Apikey, "key")
+			}
+			*targets = append(*targets, target)
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+func TestIsSecretVariableName(t *testing.T) {
+	for _, name := range []string{"token", "api_key", "clientSecret", "AUTH-TOKEN", "db.password", "session_id"} {
+		if !IsSecretVariableName(name) {
+			t.Errorf("Expected %q to be a secret", name)
+		}
+	}
+	for _, name := range []string{"base", "user_id", "keyword", "monkey"} {
+		if IsSecretVariableName(name) {
+			t.Errorf("Expected %q not to be a secret", name)
+		}
+	}
+}
+
+func TestPreviewCollectionPartialEnvironment(t *testing.T) {
+	collection, err := ParsePostmanCollection(storedCollection)
+	if err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+
+	// The environment overrides base but has no token
+	variables := MergeVariables(CollectionVariables(collection), map[string]string{"base": "http://staging.test"})
+	preview := PreviewCollection(collection, variables, false)
+
+	if !reflect.DeepEqual(preview.UnresolvedVariables, []string{"token"}) {
+		t.Errorf("Expected only token unresolved, got %v", preview.UnresolvedVariables)
+	}
+	if len(preview.Items) != 3 || preview.Items[0].Request != nil || len(preview.Items[0].Item) != 1 {
+		t.Fatalf("Expected the folder tree to be kept, got %+v", preview.Items)
+	}
+
+	create := preview.Items[0].Item[0].Request
+	if create.Method != "POST" || create.URL != "http://staging.test/users?x=1" {
+		t.Errorf("Expected POST http://staging.test/users?x=1, got %s %s", create.Method, create.URL)
+	}
+	if got := headerValue(create.Headers, "Authorization"); got != "Bearer {{token}}" {
+		t.Errorf("Expected the unresolved token kept as a placeholder, got '%s'", got)
+	}
+	if !reflect.DeepEqual(create.UnresolvedVariables, []string{"token"}) {
+		t.Errorf("Expected token unresolved in Create user, got %v", create.UnresolvedVariables)
+	}
+	if login := preview.Items[1].Request; login.URL != "http://staging.test/login" || len(login.UnresolvedVariables) != 0 {
+		t.Errorf("Expected Login to resolve fully, got %+v", login)
+	}
+}
+
+func TestPreviewCollectionMasksSecrets(t *testing.T) {
+	collection, _ := ParsePostmanCollection(storedCollection)
+	variables := MergeVariables(CollectionVariables(collection), map[string]string{"token": "s3cr3t"})
+
+	preview := PreviewCollection(collection, variables, false)
+	create := preview.Items[0].Item[0].Request
+	if got := headerValue(create.Headers, "Authorization"); got != "Bearer "+RedactedValue {
+		t.Errorf("Expected the token to be masked, got '%s'", got)
+	}
+	if !reflect.DeepEqual(preview.MaskedVariables, []string{"token"}) {
+		t.Errorf("Expected token to be reported as masked, got %v", preview.MaskedVariables)
+	}
+
+	revealed := PreviewCollection(collection, variables, true)
+	if got := headerValue(revealed.Items[0].Item[0].Request.Headers, "Authorization"); got != "Bearer s3cr3t" {
+		t.Errorf("Expected the token with reveal_secrets, got '%s'", got)
+	}
+	if len(revealed.MaskedVariables) != 0 {
+		t.Errorf("Expected nothing masked, got %v", revealed.MaskedVariables)
+	}
+}
+
+func TestPreviewCollectionMasksRedactedHeaderVariables(t *testing.T) {
+	collection, err := ParsePostmanCollection(`{
+		"info": {"name": "Keys"},
+		"variable": [{"key": "pin", "value": "1234", "type": "secret"}],
+		"item": [
+			{"name": "Get", "request": {
+				"method": "GET",
+				"url": "http://api.test/{{who}}?pin={{pin}}",
+				"header": [{"key": "X-Api-Key", "value": "{{who}}"}]
+			}},
+			{"name": "Broken", "request": {"method": "POST", "url": "http://api.test", "body": {"mode": "file"}}}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+
+	preview := PreviewCollection(collection, models.Variables{"who": "alice", "pin": "1234"}, false)
+	get := preview.Items[0].Request
+	// who goes into a redacted header, so it's masked in the URL as well
+	if get.URL != "http://api.test/***?pin=***" || headerValue(get.Headers, "X-Api-Key") != RedactedValue {
+		t.Errorf("Expected who and pin masked everywhere, got %s %v", get.URL, get.Headers)
+	}
+	if !reflect.DeepEqual(preview.MaskedVariables, []string{"pin", "who"}) {
+		t.Errorf("Expected pin and who masked, got %v", preview.MaskedVariables)
+	}
+	if broken := preview.Items[1].Request; !strings.Contains(broken.Error, "unsupported body mode") {
+		t.Errorf("Expected an error for the unbuildable request, got %+v", broken)
+	}
+}
+
+func TestPreviewCollectionMasksLiteralCredentials(t *testing.T) {
+	collection, err := ParsePostmanCollection(`{
+		"info": {"name": "Keys"},
+		"item": [
+			{"name": "Literal", "request": {
+				"method": "GET",
+				"url": "http://api.test/me",
+				"header": [{"key": "Authorization", "value": "Bearer abc"}, {"key": "X-Trace", "value": "on"}]
+			}},
+			{"name": "Query key", "request": {
+				"method": "GET",
+				"url": "http://api.test/search?q=go",
+				"auth": {"type": "apikey", "apikey": [
+					{"key": "key", "value": "api_key"},
+					{"key": "value", "value": "{{search_key}}"},
+					{"key": "in", "value": "query"}
+				]}
+			}},
+			{"name": "Literal query key", "request": {
+				"method": "GET",
+				"url": "http://api.test/search",
+				"auth": {"type": "apikey", "apikey": [
+					{"key": "key", "value": "k"},
+					{"key": "value", "value": "hardcoded"},
+					{"key": "in", "value": "query"}
+				]}
+			}}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+
+	preview := PreviewCollection(collection, models.Variables{"search_key": "s3cr3t"}, false)
+	literal := preview.Items[0].Request
+	if headerValue(literal.Headers, "Authorization") != "Bearer "+RedactedValue || headerValue(literal.Headers, "X-Trace") != "on" {
+		t.Errorf("Expected only the literal bearer token masked, got %v", literal.Headers)
+	}
+	for i, want := range []map[string]string{
+		{"q": "go", "api_key": RedactedValue},
+		{"k": RedactedValue},
+	} {
+		request := preview.Items[i+1].Request
+		parsed, err := url.Parse(request.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range want {
+			if got := parsed.Query().Get(key); got != value {
+				t.Errorf("%s: expected %s=%s, got %s", request.URL, key, value, got)
+			}
+		}
+	}
+	if !reflect.DeepEqual(preview.MaskedVariables, []string{"search_key"}) {
+		t.Errorf("Expected search_key masked, got %v", preview.MaskedVariables)
+	}
+
+	revealed := PreviewCollection(collection, models.Variables{"search_key": "s3cr3t"}, true)
+	if got := revealed.Items[1].Request.URL; !strings.Contains(got, "api_key=s3cr3t") {
+		t.Errorf("Expected the key with reveal_secrets, got %s", got)
+	}
+	if got := headerValue(revealed.Items[0].Request.Headers, "Authorization"); got != "Bearer abc" {
+		t.Errorf("Expected the literal token with reveal_secrets, got '%s'", got)
+	}
+}
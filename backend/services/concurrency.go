@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"postmanxodja/config"
+)
+
+// ErrExecutionQueueTimeout is returned when no execution slot frees up within
+// the queue wait
+var ErrExecutionQueueTimeout = errors.New("too many requests are running right now, try again shortly")
+
+// ExecutionStats is a snapshot of outbound execution concurrency
+type ExecutionStats struct {
+	Active       int `json:"active"`
+	Queued       int `json:"queued"`
+	Limit        int `json:"limit"`          // 0 when unlimited
+	PerTeamLimit int `json:"per_team_limit"` // 0 when unlimited
+}
+
+// executionLimiter caps concurrent outbound executions overall and per team.
+// Callers past a cap wait in line for up to wait before giving up.
+type executionLimiter struct {
+	global       chan struct{} // nil when unlimited
+	perTeamLimit int
+	wait         time.Duration
+
+	mu     sync.Mutex
+	teams  map[uint]*teamSlots
+	active int
+	queued int
+}
+
+// teamSlots is one team's semaphore; users counts holders and waiters so the
+// entry can be dropped once the team goes idle
+type teamSlots struct {
+	slots chan struct{}
+	users int
+}
+
+func newExecutionLimiter(limit, perTeamLimit int, wait time.Duration) *executionLimiter {
+	l := &executionLimiter{
+		perTeamLimit: max(perTeamLimit, 0),
+		wait:         wait,
+		teams:        make(map[uint]*teamSlots),
+	}
+	if limit > 0 {
+		l.global = make(chan struct{}, limit)
+	}
+	return l
+}
+
+var (
+	executionLimiterOnce sync.Once
+	executionSlots       *executionLimiter
+)
+
+// defaultExecutionLimiter is built from the config on first use
+func defaultExecutionLimiter() *executionLimiter {
+	executionLimiterOnce.Do(func() {
+		var limit, perTeam, waitSeconds int
+		if cfg := config.AppConfig; cfg != nil {
+			limit, perTeam, waitSeconds = cfg.MaxConcurrentExecutions, cfg.MaxConcurrentExecutionsPerTeam, cfg.ExecutionQueueWaitSeconds
+		}
+		executionSlots = newExecutionLimiter(limit, perTeam, time.Duration(waitSeconds)*time.Second)
+	})
+	return executionSlots
+}
+
+// AcquireExecutionSlot waits for room to run one outbound execution for the
+// team (0 for requests outside any team, which only count towards the global
+// limit). The returned release must be called once the execution finishes.
+// It fails with ErrExecutionQueueTimeout when the wait runs out, or with the
+// context's error.
+func AcquireExecutionSlot(ctx context.Context, teamID uint) (func(), error) {
+	return defaultExecutionLimiter().acquire(ctx, teamID)
+}
+
+// CurrentExecutionStats reports the current outbound execution concurrency
+func CurrentExecutionStats() ExecutionStats {
+	return defaultExecutionLimiter().stats()
+}
+
+func (l *executionLimiter) acquire(ctx context.Context, teamID uint) (func(), error) {
+	// Without a wait, callers past a cap are turned away right away
+	timeout := closedTimeout
+	if l.wait > 0 {
+		timer := time.NewTimer(l.wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	team := l.joinTeam(teamID)
+	l.mu.Lock()
+	l.queued++
+	l.mu.Unlock()
+
+	// The team's own slot comes first so a team waiting on its cap doesn't
+	// hold one of the global slots meanwhile
+	var err error
+	heldTeam := false
+	if team != nil {
+		if heldTeam, err = take(ctx, team.slots, timeout); err != nil {
+			l.giveUp(teamID, team, false)
+			return nil, err
+		}
+	}
+	if l.global != nil {
+		if _, err = take(ctx, l.global, timeout); err != nil {
+			l.giveUp(teamID, team, heldTeam)
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	l.queued--
+	l.active++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.global != nil {
+				<-l.global
+			}
+			l.mu.Lock()
+			l.active--
+			l.mu.Unlock()
+			if team != nil {
+				<-team.slots
+				l.leaveTeam(teamID, team)
+			}
+		})
+	}, nil
+}
+
+// closedTimeout has already fired
+var closedTimeout = func() <-chan time.Time {
+	ch := make(chan time.Time)
+	close(ch)
+	return ch
+}()
+
+// take claims a slot from slots, giving up when ctx ends or timeout fires
+func take(ctx context.Context, slots chan struct{}, timeout <-chan time.Time) (bool, error) {
+	select {
+	case slots <- struct{}{}:
+		return true, nil
+	default:
+	}
+	select {
+	case slots <- struct{}{}:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timeout:
+		return false, ErrExecutionQueueTimeout
+	}
+}
+
+// giveUp undoes a queued acquire that didn't get all its slots
+func (l *executionLimiter) giveUp(teamID uint, team *teamSlots, heldTeam bool) {
+	l.mu.Lock()
+	l.queued--
+	l.mu.Unlock()
+	if team == nil {
+		return
+	}
+	if heldTeam {
+		<-team.slots
+	}
+	l.leaveTeam(teamID, team)
+}
+
+func (l *executionLimiter) joinTeam(teamID uint) *teamSlots {
+	if teamID == 0 || l.perTeamLimit == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	team, ok := l.teams[teamID]
+	if !ok {
+		team = &teamSlots{slots: make(chan struct{}, l.perTeamLimit)}
+		l.teams[teamID] = team
+	}
+	team.users++
+	return team
+}
+
+func (l *executionLimiter) leaveTeam(teamID uint, team *teamSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if team.users--; team.users == 0 {
+		delete(l.teams, teamID)
+	}
+}
+
+func (l *executionLimiter) stats() ExecutionStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ExecutionStats{
+		Active:       l.active,
+		Queued:       l.queued,
+		Limit:        cap(l.global),
+		PerTeamLimit: l.perTeamLimit,
+	}
+}
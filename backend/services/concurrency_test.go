@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecutionLimiterRespectsLimit(t *testing.T) {
+	limiter := newExecutionLimiter(2, 0, 5*time.Second)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.acquire(context.Background(), 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			now := running.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("Expected at most 2 executions at once (and the queue to fill them), peaked at %d", got)
+	}
+	if stats := limiter.stats(); stats.Active != 0 || stats.Queued != 0 {
+		t.Errorf("Expected nothing running afterwards, got %+v", stats)
+	}
+}
+
+func TestExecutionLimiterPerTeam(t *testing.T) {
+	limiter := newExecutionLimiter(0, 1, 0)
+	release, err := limiter.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.acquire(context.Background(), 1); !errors.Is(err, ErrExecutionQueueTimeout) {
+		t.Errorf("Expected the team's second execution to be turned away, got %v", err)
+	}
+	other, err := limiter.acquire(context.Background(), 2)
+	if err != nil {
+		t.Errorf("Expected another team to have its own slot, got %v", err)
+	} else {
+		other()
+	}
+
+	release()
+	release() // releasing twice frees only one slot
+	if len(limiter.teams) != 0 {
+		t.Errorf("Expected idle teams to be dropped, got %d", len(limiter.teams))
+	}
+}
+
+func TestExecutionLimiterQueueWait(t *testing.T) {
+	limiter := newExecutionLimiter(1, 0, 30*time.Millisecond)
+	release, err := limiter.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := limiter.acquire(context.Background(), 0); !errors.Is(err, ErrExecutionQueueTimeout) {
+		t.Errorf("Expected ErrExecutionQueueTimeout, got %v", err)
+	}
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("Expected to wait in the queue first, gave up after %v", waited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limiter.acquire(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled wait to return context.Canceled, got %v", err)
+	}
+
+	// A queued execution gets the slot once it's released
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	next, err := limiter.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Expected the freed slot, got %v", err)
+	}
+	next()
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dbmlTablePattern matches the start of a Table block once strings and
+// comments are blanked out. "TableGroup" and "TablePartial" don't match since
+// the keyword has to be followed by whitespace.
+var dbmlTablePattern = regexp.MustCompile(`(?i)(^|[\s}])table\s+[^{}\n]+\s*\{`)
+
+// ValidateDBML is a quick sanity check run before DBML is sent to the AI. It
+// only looks for at least one Table block and braces that balance outside of
+// strings and comments; anything else is left for the model to interpret, so
+// unusual but valid schemas aren't rejected.
+func ValidateDBML(dbml string) error {
+	stripped, err := stripDBMLLiterals(dbml)
+	if err != nil {
+		return err
+	}
+
+	var open []int
+	line := 1
+	for _, char := range stripped {
+		switch char {
+		case '\n':
+			line++
+		case '{':
+			open = append(open, line)
+		case '}':
+			if len(open) == 0 {
+				return fmt.Errorf("unexpected '}' on line %d", line)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("'{' on line %d is never closed", open[len(open)-1])
+	}
+
+	if !dbmlTablePattern.MatchString(stripped) {
+		return fmt.Errorf("no Table block found, expected something like: Table users { id integer }")
+	}
+	return nil
+}
+
+// stripDBMLLiterals blanks out comments and quoted text (names, defaults,
+// notes and `expressions`) so braces inside them aren't counted. Newlines are
+// kept so line numbers still match the input.
+func stripDBMLLiterals(dbml string) (string, error) {
+	var out strings.Builder
+	out.Grow(len(dbml))
+
+	line := 1
+	for i := 0; i < len(dbml); {
+		rest := dbml[i:]
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("comment starting on line %d is never closed", line)
+			}
+			line += blankLiteral(&out, rest[:end+4])
+			i += end + 4
+		case strings.HasPrefix(rest, "'''"):
+			end := strings.Index(rest[3:], "'''")
+			if end < 0 {
+				return "", fmt.Errorf("multi-line string starting on line %d is never closed", line)
+			}
+			line += blankLiteral(&out, rest[:end+6])
+			i += end + 6
+		case rest[0] == '\'' || rest[0] == '"' || rest[0] == '`':
+			end := closingQuote(rest)
+			if end < 0 {
+				return "", fmt.Errorf("%c-quoted text starting on line %d is never closed", rest[0], line)
+			}
+			out.WriteString(`""`)
+			i += end + 1
+		default:
+			if rest[0] == '\n' {
+				line++
+			}
+			out.WriteByte(rest[0])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// closingQuote returns the index of the quote that closes the one at s[0],
+// skipping backslash escapes. Single-line literals can't span lines.
+func closingQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// blankLiteral writes only the newlines of a skipped literal and returns how
+// many there were
+func blankLiteral(out *strings.Builder, literal string) int {
+	count := strings.Count(literal, "\n")
+	out.WriteString(strings.Repeat("\n", count))
+	return count
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDBMLAcceptsValidSchemas(t *testing.T) {
+	tests := map[string]string{
+		"simple": `Table users {
+  id integer [pk]
+  name varchar
+}`,
+		"brace on next line and lowercase keyword": `table users
+{
+  id integer
+}`,
+		"quoted names, settings and notes": `Project shop {
+  database_type: 'PostgreSQL'
+  Note: '''
+    Braces in notes { are fine
+  '''
+}
+
+Table "public"."orders" as O [headercolor: #3498DB] {
+  id integer [pk, increment]
+  status varchar [default: 'new}', note: "not a { brace"]
+  created_at timestamp [default: ` + "`now()`" + `]
+
+  indexes {
+    (id, status) [unique]
+  }
+}
+
+// A trailing } in a comment
+/* and { in a block comment */
+Ref: O.id < items.order_id
+
+Enum order_status {
+  new
+  paid
+}
+
+TableGroup sales {
+  O
+}`,
+	}
+
+	for name, dbml := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateDBML(dbml); err != nil {
+				t.Errorf("Expected valid DBML, got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestValidateDBMLRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		dbml string
+		want string
+	}{
+		{"plain text", "Please design me a database for a shop", "no Table block"},
+		{"json", `{"tables": [{"name": "users"}]}`, "no Table block"},
+		{"only a table group", "TableGroup g {\n  users\n}", "no Table block"},
+		{"unclosed table", "Table users {\n  id integer\n", "line 1 is never closed"},
+		{"extra closing brace", "Table users {\n  id integer\n}\n}", "unexpected '}' on line 4"},
+		{"unclosed string", "Table users {\n  name varchar [note: 'oops]\n}", "on line 2 is never closed"},
+		{"unclosed comment", "Table users {\n  id integer\n}\n/* note", "comment starting on line 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDBML(tt.dbml)
+			if err == nil {
+				t.Fatalf("Expected an error for %q", tt.dbml)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing '%s', got '%v'", tt.want, err)
+			}
+		})
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"html/template"
 	"net/smtp"
 	"strings"
+	"time"
 
 	"postmanxodja/config"
 )
 
+// EmailService sends email over SMTP
 type EmailService struct {
 	host     string
 	port     int
@@ -137,6 +139,15 @@ type InviteEmailData struct {
 }
 
 func (e *EmailService) SendTeamInviteEmail(to, inviterName, teamName, inviteToken string) error {
+	subject, body, err := renderTeamInviteEmail(inviterName, teamName, inviteToken)
+	if err != nil {
+		return err
+	}
+	return e.SendEmail(to, subject, body)
+}
+
+// renderTeamInviteEmail builds the subject and HTML body of a team invite
+func renderTeamInviteEmail(inviterName, teamName, inviteToken string) (string, string, error) {
 	inviteLink := fmt.Sprintf("%s/invite/%s", config.AppConfig.FrontendURL, inviteToken)
 
 	data := InviteEmailData{
@@ -149,11 +160,11 @@ func (e *EmailService) SendTeamInviteEmail(to, inviterName, teamName, inviteToke
 	tmpl := template.Must(template.New("invite").Parse(inviteEmailTemplate))
 	var body bytes.Buffer
 	if err := tmpl.Execute(&body, data); err != nil {
-		return err
+		return "", "", err
 	}
 
 	subject := fmt.Sprintf("%s invited you to join %s on PostmanXodja", inviterName, teamName)
-	return e.SendEmail(to, subject, body.String())
+	return subject, body.String(), nil
 }
 
 const inviteEmailTemplate = `
@@ -225,3 +236,155 @@ const inviteEmailTemplate = `
 </body>
 </html>
 `
+
+type SecurityAlertEmailData struct {
+	Event       string
+	IP          string
+	Time        string
+	FrontendURL string
+}
+
+// SendSecurityAlertEmail tells the account owner about a security event such
+// as a login lockout, including where and when it happened
+func (e *EmailService) SendSecurityAlertEmail(to, event, ip string, at time.Time) error {
+	subject, body, err := renderSecurityAlertEmail(event, ip, at)
+	if err != nil {
+		return err
+	}
+	return e.SendEmail(to, subject, body)
+}
+
+// renderSecurityAlertEmail builds the subject and HTML body of a security alert
+func renderSecurityAlertEmail(event, ip string, at time.Time) (string, string, error) {
+	data := SecurityAlertEmailData{
+		Event:       event,
+		IP:          ip,
+		Time:        at.UTC().Format("2006-01-02 15:04:05 MST"),
+		FrontendURL: config.AppConfig.FrontendURL,
+	}
+
+	tmpl := template.Must(template.New("security-alert").Parse(securityAlertEmailTemplate))
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+
+	return "Security alert for your PostmanXodja account", body.String(), nil
+}
+
+// SendTestEmail sends a short message to check the SMTP settings work
+func (e *EmailService) SendTestEmail(to string) error {
+	subject, body := renderTestEmail()
+	return e.SendEmail(to, subject, body)
+}
+
+// renderTestEmail builds the subject and HTML body of the settings check
+func renderTestEmail() (string, string) {
+	return "PostmanXodja test email", "<p>This is a test email from PostmanXodja. Your email settings are working.</p>"
+}
+
+const securityAlertEmailTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+</head>
+<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f3f4f6;">
+    <table role="presentation" style="width: 100%; border-collapse: collapse;">
+        <tr>
+            <td style="padding: 40px 20px;">
+                <table role="presentation" style="max-width: 600px; margin: 0 auto; background-color: #ffffff; border-radius: 12px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);">
+                    <tr>
+                        <td style="padding: 40px; text-align: center;">
+                            <h1 style="color: #2563eb; margin: 0 0 10px 0; font-size: 28px;">PostmanXodja</h1>
+                            <p style="color: #6b7280; margin: 0; font-size: 14px;">Security Alert</p>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 0 40px;">
+                            <hr style="border: none; border-top: 1px solid #e5e7eb; margin: 0;">
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 40px;">
+                            <h2 style="color: #111827; margin: 0 0 20px 0; font-size: 20px;">{{.Event}}</h2>
+                            <p style="color: #4b5563; font-size: 16px; line-height: 1.6; margin: 0 0 20px 0;">
+                                We noticed this on your account:
+                            </p>
+                            <p style="color: #4b5563; font-size: 16px; line-height: 1.6; margin: 0 0 20px 0;">
+                                <strong>IP address:</strong> {{.IP}}<br>
+                                <strong>Time:</strong> {{.Time}}
+                            </p>
+                            <p style="color: #4b5563; font-size: 16px; line-height: 1.6; margin: 0;">
+                                If this was you, you can ignore this email. Otherwise, consider changing your password.
+                            </p>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 0 40px;">
+                            <hr style="border: none; border-top: 1px solid #e5e7eb; margin: 0;">
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 30px 40px; text-align: center;">
+                            <p style="color: #9ca3af; font-size: 12px; margin: 0;">
+                                You're receiving this because of activity on your PostmanXodja account.
+                            </p>
+                        </td>
+                    </tr>
+                </table>
+            </td>
+        </tr>
+    </table>
+</body>
+</html>
+`
+
+type APIKeyExpiryEmailData struct {
+	TeamName    string
+	KeyName     string
+	KeyPrefix   string
+	ExpiresAt   string
+	FrontendURL string
+}
+
+// renderAPIKeyExpiryEmail builds the subject and HTML body of the reminder
+// that a team's API key expires soon
+func renderAPIKeyExpiryEmail(teamName, keyName, keyPrefix string, expiresAt time.Time) (string, string, error) {
+	data := APIKeyExpiryEmailData{
+		TeamName:    teamName,
+		KeyName:     keyName,
+		KeyPrefix:   keyPrefix,
+		ExpiresAt:   expiresAt.UTC().Format("2006-01-02 15:04 MST"),
+		FrontendURL: config.AppConfig.FrontendURL,
+	}
+
+	tmpl := template.Must(template.New("api-key-expiry").Parse(apiKeyExpiryEmailTemplate))
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+
+	subject := fmt.Sprintf("API key \"%s\" for %s expires soon", keyName, teamName)
+	return subject, body.String(), nil
+}
+
+const apiKeyExpiryEmailTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; color: #111827;">
+    <h2 style="font-size: 20px;">An API key for {{.TeamName}} expires soon</h2>
+    <p style="color: #4b5563; font-size: 16px; line-height: 1.6;">
+        <strong>{{.KeyName}}</strong> ({{.KeyPrefix}}…) expires on {{.ExpiresAt}}.
+        Anything still using it will stop working then.
+    </p>
+    <p style="color: #4b5563; font-size: 16px; line-height: 1.6;">
+        Create a replacement key in <a href="{{.FrontendURL}}" style="color: #2563eb;">PostmanXodja</a> and switch over before it expires.
+    </p>
+</body>
+</html>
+`
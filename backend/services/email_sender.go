@@ -0,0 +1,87 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"postmanxodja/config"
+)
+
+// EmailSender sends the app's emails. EmailService sends them over SMTP and
+// SendGridSender through SendGrid's API; tests swap in a RecordingEmailSender.
+type EmailSender interface {
+	IsConfigured() bool
+	SendEmail(to, subject, htmlBody string) error
+	SendTeamInviteEmail(to, inviterName, teamName, inviteToken string) error
+	SendSecurityAlertEmail(to, event, ip string, at time.Time) error
+	SendTestEmail(to string) error
+}
+
+// NewEmailSender returns the sender EMAIL_PROVIDER picks, SMTP unless it's
+// sendgrid. It's a variable so tests can replace it.
+var NewEmailSender = func() EmailSender {
+	if config.AppConfig.EmailProvider == "sendgrid" {
+		return NewSendGridSender()
+	}
+	return NewEmailService()
+}
+
+// SentEmail is one message a RecordingEmailSender was asked to send
+type SentEmail struct {
+	To       string
+	Subject  string
+	HTMLBody string
+}
+
+// RecordingEmailSender keeps the emails it's asked to send instead of sending
+// them. The zero value reports itself unconfigured, like an empty SMTP setup;
+// Err makes every send fail.
+type RecordingEmailSender struct {
+	Configured bool
+	Err        error
+
+	mu   sync.Mutex
+	sent []SentEmail
+}
+
+func (r *RecordingEmailSender) IsConfigured() bool {
+	return r.Configured
+}
+
+func (r *RecordingEmailSender) SendEmail(to, subject, htmlBody string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	r.sent = append(r.sent, SentEmail{To: to, Subject: subject, HTMLBody: htmlBody})
+	return nil
+}
+
+func (r *RecordingEmailSender) SendTeamInviteEmail(to, inviterName, teamName, inviteToken string) error {
+	subject, body, err := renderTeamInviteEmail(inviterName, teamName, inviteToken)
+	if err != nil {
+		return err
+	}
+	return r.SendEmail(to, subject, body)
+}
+
+func (r *RecordingEmailSender) SendSecurityAlertEmail(to, event, ip string, at time.Time) error {
+	subject, body, err := renderSecurityAlertEmail(event, ip, at)
+	if err != nil {
+		return err
+	}
+	return r.SendEmail(to, subject, body)
+}
+
+func (r *RecordingEmailSender) SendTestEmail(to string) error {
+	subject, body := renderTestEmail()
+	return r.SendEmail(to, subject, body)
+}
+
+// Sent returns the emails recorded so far
+func (r *RecordingEmailSender) Sent() []SentEmail {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SentEmail(nil), r.sent...)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP accepts mail on a loopback port, recording each message's
+// recipients and data
+type fakeSMTP struct {
+	addr string
+	mu   sync.Mutex
+	rcpt []string
+	data []string
+	// rejectAuth answers AUTH with this reply instead of accepting it
+	rejectAuth string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{addr: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(command, "AUTH"):
+			if s.rejectAuth != "" {
+				reply(s.rejectAuth)
+				continue
+			}
+			reply("235 Authentication successful")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = append(s.data, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// emailServiceFor points an EmailService at the fake server
+func emailServiceFor(t *testing.T, server *fakeSMTP) *EmailService {
+	t.Helper()
+	host, port, err := net.SplitHostPort(server.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	return &EmailService{host: host, port: portNumber, username: "mailer", password: "s3cret", from: "PostmanXodja <noreply@example.com>"}
+}
+
+func TestSendTestEmail(t *testing.T) {
+	server := startFakeSMTP(t)
+	if err := emailServiceFor(t, server).SendTestEmail("ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.rcpt) != 1 || server.rcpt[0] != "ada@example.com" {
+		t.Errorf("Expected one message to ada@example.com, got %v", server.rcpt)
+	}
+	if len(server.data) != 1 || !strings.Contains(server.data[0], "Subject: PostmanXodja test email") {
+		t.Errorf("Expected the test email's subject in the message, got %v", server.data)
+	}
+}
+
+func TestSendTestEmailReportsSMTPError(t *testing.T) {
+	server := startFakeSMTP(t)
+	server.rejectAuth = "535 Authentication credentials invalid"
+
+	err := emailServiceFor(t, server).SendTestEmail("ada@example.com")
+	if err == nil || !strings.Contains(err.Error(), "535") {
+		t.Fatalf("Expected the server's 535 reply, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Expected the error not to include the password, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"sort"
+	"strings"
+)
+
+// maxVariableKeyLength bounds environment variable names
+const maxVariableKeyLength = 255
+
+// ValidateVariableKey checks that key can be used as a {{placeholder}}: not
+// empty, no surrounding whitespace, no braces and not overly long
+func ValidateVariableKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("variable name is empty")
+	case strings.TrimSpace(key) != key:
+		return fmt.Errorf("variable %q has leading or trailing whitespace", key)
+	case strings.ContainsAny(key, "{}"):
+		return fmt.Errorf("variable %q contains a brace", key)
+	case len(key) > maxVariableKeyLength:
+		return fmt.Errorf("variable %q is longer than %d characters", key, maxVariableKeyLength)
+	}
+	return nil
+}
+
+// VariablePatchError lists the keys that made a variable patch invalid
+type VariablePatchError struct {
+	Conflicting []string          // both set and unset
+	Invalid     map[string]string // key -> reason
+}
+
+func (e *VariablePatchError) Error() string {
+	if len(e.Conflicting) > 0 {
+		return "Variables can't be both set and unset: " + strings.Join(e.Conflicting, ", ")
+	}
+	return "Invalid variable names"
+}
+
+// ApplyVariablePatch returns a copy of variables with set merged in and unset
+// removed. Unsetting a missing key is a no-op. Returns a *VariablePatchError
+// when a key is in both set and unset or isn't a valid name.
+func ApplyVariablePatch(variables models.Variables, set map[string]string, unset []string) (models.Variables, error) {
+	patchErr := &VariablePatchError{Invalid: make(map[string]string)}
+
+	for key := range set {
+		if err := ValidateVariableKey(key); err != nil {
+			patchErr.Invalid[key] = err.Error()
+		}
+	}
+	for _, key := range unset {
+		if _, ok := set[key]; ok {
+			patchErr.Conflicting = append(patchErr.Conflicting, key)
+		}
+	}
+	if len(patchErr.Conflicting) > 0 || len(patchErr.Invalid) > 0 {
+		sort.Strings(patchErr.Conflicting)
+		return nil, patchErr
+	}
+
+	patched := make(models.Variables, len(variables)+len(set))
+	for key, value := range variables {
+		patched[key] = value
+	}
+	for key, value := range set {
+		patched[key] = value
+	}
+	for _, key := range unset {
+		delete(patched, key)
+	}
+	return patched, nil
+}
+
+// MissingVariablesError lists the requested keys a copy source doesn't have
+type MissingVariablesError struct {
+	Keys []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "Source environment has no variables named: " + strings.Join(e.Keys, ", ")
+}
+
+// CopyVariables returns a copy of target with the listed keys of source (all
+// of them when keys is empty) added. Keys target already has are only
+// replaced when overwrite is set. Returns a *MissingVariablesError when a
+// listed key isn't in source.
+func CopyVariables(target, source models.Variables, keys []string, overwrite bool) (models.Variables, error) {
+	if len(keys) == 0 {
+		for key := range source {
+			keys = append(keys, key)
+		}
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := source[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, &MissingVariablesError{Keys: missing}
+	}
+
+	copied := make(models.Variables, len(target)+len(keys))
+	for key, value := range target {
+		copied[key] = value
+	}
+	for _, key := range keys {
+		if _, exists := copied[key]; exists && !overwrite {
+			continue
+		}
+		copied[key] = source[key]
+	}
+	return copied, nil
+}
+
+// EnvironmentReferences lists what in the team depends on an environment.
+// Today that's the collections using it as their default environment, which
+// collection runs and item execution fall back to. Saved tabs don't record an
+// environment, so there's nothing to report for them.
+func EnvironmentReferences(teamID, environmentID uint) ([]models.EnvironmentReference, error) {
+	var collections []models.Collection
+	if err := database.DB.Select("id", "name").
+		Where("team_id = ? AND environment_id = ?", teamID, environmentID).
+		Order("id").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+
+	references := make([]models.EnvironmentReference, 0, len(collections))
+	for _, collection := range collections {
+		references = append(references, models.EnvironmentReference{
+			Type: "collection",
+			ID:   collection.ID,
+			Name: collection.Name,
+		})
+	}
+	return references, nil
+}
+
+// ToPostmanEnvironment converts env to Postman's environment format, with
+// the variables sorted by key so exports are stable
+func ToPostmanEnvironment(env *models.Environment) models.PostmanEnvironment {
+	keys := make([]string, 0, len(env.Variables))
+	for key := range env.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	exported := models.PostmanEnvironment{
+		Name:   env.Name,
+		Values: make([]models.PostmanEnvironmentValue, 0, len(keys)),
+		Scope:  "environment",
+	}
+	for _, key := range keys {
+		exported.Values = append(exported.Values, models.PostmanEnvironmentValue{
+			Key:     key,
+			Value:   env.Variables[key],
+			Type:    "default",
+			Enabled: true,
+		})
+	}
+	return exported
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"testing"
+)
+
+func TestApplyVariablePatchSet(t *testing.T) {
+	vars := models.Variables{"host": "localhost", "token": "old"}
+
+	patched, err := ApplyVariablePatch(vars, map[string]string{"token": "new", "port": "8080"}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if patched["token"] != "new" || patched["port"] != "8080" || patched["host"] != "localhost" {
+		t.Errorf("Expected merged variables, got %v", patched)
+	}
+	if vars["token"] != "old" {
+		t.Error("Expected the original variables to be left alone")
+	}
+}
+
+func TestApplyVariablePatchUnset(t *testing.T) {
+	vars := models.Variables{"host": "localhost", "token": "old"}
+
+	patched, err := ApplyVariablePatch(vars, nil, []string{"token", "missing"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := patched["token"]; ok {
+		t.Error("Expected token to be removed")
+	}
+	if len(patched) != 1 || patched["host"] != "localhost" {
+		t.Errorf("Expected only host to remain, got %v", patched)
+	}
+}
+
+func TestApplyVariablePatchConflict(t *testing.T) {
+	_, err := ApplyVariablePatch(models.Variables{}, map[string]string{"token": "x"}, []string{"token"})
+
+	var patchErr *VariablePatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("Expected a VariablePatchError, got %v", err)
+	}
+	if len(patchErr.Conflicting) != 1 || patchErr.Conflicting[0] != "token" {
+		t.Errorf("Expected 'token' to conflict, got %v", patchErr.Conflicting)
+	}
+}
+
+func TestApplyVariablePatchInvalidKey(t *testing.T) {
+	_, err := ApplyVariablePatch(models.Variables{}, map[string]string{"{{bad}}": "x", " padded": "y"}, nil)
+
+	var patchErr *VariablePatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("Expected a VariablePatchError, got %v", err)
+	}
+	if len(patchErr.Invalid) != 2 {
+		t.Errorf("Expected 2 invalid keys, got %v", patchErr.Invalid)
+	}
+}
+
+func TestCopyVariablesSelected(t *testing.T) {
+	target := models.Variables{"host": "localhost", "token": "old"}
+	source := models.Variables{"host": "api.test", "token": "new", "port": "443"}
+
+	copied, err := CopyVariables(target, source, []string{"token", "port"}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(copied) != 3 || copied["host"] != "localhost" || copied["token"] != "old" || copied["port"] != "443" {
+		t.Errorf("Expected only port to be added, got %v", copied)
+	}
+
+	copied, err = CopyVariables(target, source, []string{"token", "port"}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(copied) != 3 || copied["host"] != "localhost" || copied["token"] != "new" || copied["port"] != "443" {
+		t.Errorf("Expected token to be overwritten and host left alone, got %v", copied)
+	}
+	if target["token"] != "old" || len(target) != 2 {
+		t.Error("Expected the original variables to be left alone")
+	}
+}
+
+func TestCopyVariablesAll(t *testing.T) {
+	copied, err := CopyVariables(models.Variables{"host": "localhost"}, models.Variables{"host": "api.test", "port": "443"}, nil, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(copied) != 2 || copied["host"] != "localhost" || copied["port"] != "443" {
+		t.Errorf("Expected every missing variable to be copied, got %v", copied)
+	}
+}
+
+func TestCopyVariablesMissingKey(t *testing.T) {
+	_, err := CopyVariables(models.Variables{}, models.Variables{"host": "api.test"}, []string{"nope", "host", "gone"}, false)
+
+	var missingErr *MissingVariablesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("Expected a MissingVariablesError, got %v", err)
+	}
+	if len(missingErr.Keys) != 2 || missingErr.Keys[0] != "gone" || missingErr.Keys[1] != "nope" {
+		t.Errorf("Expected gone and nope to be missing, got %v", missingErr.Keys)
+	}
+}
+
+func TestEnvironmentReferences(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+	team, _ := CreateTeamWithOwner("Acme", owner.ID)
+	other, _ := CreateTeamWithOwner("Other", owner.ID)
+
+	env := models.Environment{Name: "dev", TeamID: &team.ID}
+	database.DB.Create(&env)
+	database.DB.Create(&models.Collection{Name: "Orders", TeamID: &team.ID, EnvironmentID: &env.ID})
+	database.DB.Create(&models.Collection{Name: "Unlinked", TeamID: &team.ID})
+	database.DB.Create(&models.Collection{Name: "Elsewhere", TeamID: &other.ID, EnvironmentID: &env.ID})
+
+	references, err := EnvironmentReferences(team.ID, env.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(references) != 1 || references[0].Type != "collection" || references[0].Name != "Orders" {
+		t.Errorf("Expected only the team's linked collection, got %+v", references)
+	}
+
+	unused := models.Environment{Name: "unused", TeamID: &team.ID}
+	database.DB.Create(&unused)
+	if references, _ := EnvironmentReferences(team.ID, unused.ID); len(references) != 0 {
+		t.Errorf("Expected no references, got %+v", references)
+	}
+}
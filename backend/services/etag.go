@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"postmanxodja/models"
+	"strings"
+	"sync"
+)
+
+// ETags captured from executed requests, per user and URL, for UseETag. Like
+// the other execution state they live in memory only.
+const maxETagsPerUser = 500
+
+var (
+	etagMu    sync.Mutex
+	etagStore = make(map[uint]map[string]string) // user -> URL -> ETag
+)
+
+// LookupETag returns the ETag last seen for url by the user, if any
+func LookupETag(userID uint, url string) string {
+	etagMu.Lock()
+	defer etagMu.Unlock()
+	return etagStore[userID][url]
+}
+
+// RememberETag stores the ETag of a response from url
+func RememberETag(userID uint, url, etag string) {
+	etagMu.Lock()
+	defer etagMu.Unlock()
+
+	urls, ok := etagStore[userID]
+	if !ok {
+		urls = make(map[string]string)
+		etagStore[userID] = urls
+	}
+	if _, exists := urls[url]; !exists && len(urls) >= maxETagsPerUser {
+		// Make room by dropping an arbitrary entry
+		for stale := range urls {
+			delete(urls, stale)
+			break
+		}
+	}
+	urls[url] = etag
+}
+
+// hasHeader reports whether the request sets name in either header form
+func hasHeader(req *models.ExecuteRequest, name string) bool {
+	for _, h := range req.HeaderList {
+		if strings.EqualFold(h.Key, name) {
+			return true
+		}
+	}
+	for key := range req.Headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteWithETags runs the request like ExecuteHTTPRequestContext and
+// remembers the response's ETag for the user. With req.UseETag set it first
+// adds If-None-Match from the ETag stored for the same URL. With req.CacheTTLMs
+// set the response is cached for the user and a live cached one is returned
+// instead of sending the request.
+func ExecuteWithETags(ctx context.Context, req *models.ExecuteRequest, userID uint) (*models.ExecuteResponse, error) {
+	httpReq, err := BuildHTTPRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	url := httpReq.URL.String()
+
+	// The cache is looked up with the request as given, before If-None-Match
+	// is added from a stored ETag
+	return executeCached(fmt.Sprintf("user:%d", userID), req, httpReq, func() (*models.ExecuteResponse, error) {
+		return executeWithETag(ctx, req, userID, url)
+	})
+}
+
+// executeWithETag is ExecuteWithETags without the response cache
+func executeWithETag(ctx context.Context, req *models.ExecuteRequest, userID uint, url string) (*models.ExecuteResponse, error) {
+	var sent string
+	if req.UseETag && !hasHeader(req, "If-None-Match") {
+		if sent = LookupETag(userID, url); sent != "" {
+			addHeader(req, "If-None-Match", sent)
+		}
+	}
+
+	response, err := ExecuteHTTPRequestContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if etag := response.Headers[http.CanonicalHeaderKey("ETag")]; etag != "" {
+		RememberETag(userID, url, etag)
+	}
+	response.ETagSent = sent
+	return response, nil
+}
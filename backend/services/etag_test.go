@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/models"
+	"testing"
+)
+
+// etagServer serves a resource with ETag "v1" and answers a matching
+// If-None-Match with 304; it records the If-None-Match it last received
+func etagServer(t *testing.T, received *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r.Header.Get("If-None-Match")
+		w.Header().Set("ETag", `"v1"`)
+		if *received == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("fresh"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteWithETagsCacheHit(t *testing.T) {
+	useLoopback(t)
+	var received string
+	server := etagServer(t, &received)
+
+	const userID = 9001
+	req := func() *models.ExecuteRequest {
+		return &models.ExecuteRequest{Method: "GET", URL: server.URL + "/item", UseETag: true}
+	}
+
+	first, err := ExecuteWithETags(context.Background(), req(), userID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first.NotModified || first.Status != http.StatusOK || received != "" {
+		t.Errorf("Expected a miss without If-None-Match, got status %d, sent '%s'", first.Status, received)
+	}
+
+	second, err := ExecuteWithETags(context.Background(), req(), userID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received != `"v1"` {
+		t.Errorf("Expected If-None-Match '\"v1\"', got '%s'", received)
+	}
+	if !second.NotModified || second.Status != http.StatusNotModified {
+		t.Errorf("Expected a 304 cache hit, got status %d", second.Status)
+	}
+	if second.ETagSent != `"v1"` {
+		t.Errorf("Expected etag_sent '\"v1\"', got '%s'", second.ETagSent)
+	}
+}
+
+func TestExecuteWithETagsOptIn(t *testing.T) {
+	useLoopback(t)
+	var received string
+	server := etagServer(t, &received)
+
+	const userID = 9002
+	url := server.URL + "/item"
+	RememberETag(userID, url, `"v1"`)
+
+	resp, err := ExecuteWithETags(context.Background(), &models.ExecuteRequest{Method: "GET", URL: url}, userID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received != "" || resp.NotModified {
+		t.Errorf("Expected no conditional header without use_etag, sent '%s'", received)
+	}
+
+	// Another user's ETag isn't used
+	resp, err = ExecuteWithETags(context.Background(), &models.ExecuteRequest{Method: "GET", URL: url, UseETag: true}, userID+1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received != "" || resp.NotModified {
+		t.Errorf("Expected a miss for a user without a stored ETag, sent '%s'", received)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+var ErrExecutionIDInUse = errors.New("execution_id is already in use")
+
+// runningExecution is an in-flight request that can be cancelled by the user
+// who started it
+type runningExecution struct {
+	userID uint
+	cancel context.CancelFunc
+}
+
+var (
+	executionsMu sync.Mutex
+	executions   = make(map[string]runningExecution)
+)
+
+// NewExecutionID returns a random ID for an execution
+func NewExecutionID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// StartExecution registers a cancelable execution derived from parent. Call
+// the returned done func when the execution finishes to release it.
+func StartExecution(parent context.Context, executionID string, userID uint) (context.Context, func(), error) {
+	executionsMu.Lock()
+	if _, exists := executions[executionID]; exists {
+		executionsMu.Unlock()
+		return nil, nil, ErrExecutionIDInUse
+	}
+	ctx, cancel := context.WithCancel(parent)
+	executions[executionID] = runningExecution{userID: userID, cancel: cancel}
+	executionsMu.Unlock()
+
+	return ctx, func() {
+		executionsMu.Lock()
+		delete(executions, executionID)
+		executionsMu.Unlock()
+		cancel()
+	}, nil
+}
+
+// CancelExecution cancels the user's running execution. It returns false if
+// there's no such execution (already finished, or started by someone else).
+func CancelExecution(executionID string, userID uint) bool {
+	executionsMu.Lock()
+	execution, ok := executions[executionID]
+	executionsMu.Unlock()
+
+	if !ok || execution.userID != userID {
+		return false
+	}
+	execution.cancel()
+	return true
+}
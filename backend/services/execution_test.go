@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"postmanxodja/models"
+)
+
+func TestCancelExecution(t *testing.T) {
+	useLoopback(t)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	id := NewExecutionID()
+	ctx, done, err := StartExecution(context.Background(), id, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, _, err := StartExecution(context.Background(), id, 2); !errors.Is(err, ErrExecutionIDInUse) {
+		t.Errorf("Expected ErrExecutionIDInUse, got %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := ExecuteHTTPRequestContext(ctx, &models.ExecuteRequest{Method: http.MethodGet, URL: server.URL})
+		result <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if CancelExecution(id, 2) {
+		t.Error("Expected another user's cancel to be refused")
+	}
+	if !CancelExecution(id, 1) {
+		t.Fatal("Expected cancel to succeed")
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the request to stop after cancel")
+	}
+
+	done()
+	if CancelExecution(id, 1) {
+		t.Error("Expected the execution to be removed from the registry when done")
+	}
+}
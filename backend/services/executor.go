@@ -1,19 +1,29 @@
 package services
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"postmanxodja/models"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// requestTimeout bounds every outgoing request made on behalf of a user
+var requestTimeout = 30 * time.Second
+
 // rewriteLocalhostURL rewrites localhost / 127.0.0.1 URLs so that requests
 // made from inside a Docker container reach the host machine.
 // When DOCKER_HOST_OVERRIDE is set (e.g. "host.docker.internal") the host
@@ -68,117 +78,457 @@ func isLocalhostURL(rawURL string) bool {
 func HttpClientFor(targetURL string) *http.Client {
 	if isLocalhostURL(targetURL) {
 		return &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: requestTimeout,
 			Transport: &http.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
 		}
 	}
 	return &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: requestTimeout,
 	}
 }
 
-// ExecuteHTTPRequest executes an HTTP request and returns the response
-func ExecuteHTTPRequest(req *models.ExecuteRequest) (*models.ExecuteResponse, error) {
-	// Validate URL
-	if req.URL == "" {
-		return nil, errors.New("URL is required")
+// Query merge policies for params that are also present in the URL
+const (
+	QueryMergeAdd      = "add"      // keep the URL's value, only add missing keys
+	QueryMergeOverride = "override" // replace the URL's value
+	QueryMergeAppend   = "append"   // send both values
+)
+
+// IsValidQueryMergePolicy reports whether policy is a known merge policy.
+// An empty policy means QueryMergeAdd.
+func IsValidQueryMergePolicy(policy string) bool {
+	switch policy {
+	case "", QueryMergeAdd, QueryMergeOverride, QueryMergeAppend:
+		return true
 	}
+	return false
+}
 
-	startTime := time.Now()
+// MergeQueryParams combines params with the query string already in rawURL
+// according to policy. Map params are added in key order so the resulting
+// URL is deterministic.
+func MergeQueryParams(rawURL string, params map[string]string, policy string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	// Build URL with query parameters
-	fullURL := req.URL
-	if len(req.QueryParams) > 0 {
-		// Parse existing URL to handle query params properly
-		parsedURL, err := url.Parse(fullURL)
-		if err == nil {
-			existingParams := parsedURL.Query()
-			for key, value := range req.QueryParams {
-				// Only add if not already in URL
-				if existingParams.Get(key) == "" {
-					existingParams.Add(key, value)
-				}
-			}
-			parsedURL.RawQuery = existingParams.Encode()
-			fullURL = parsedURL.String()
-		} else {
-			// Fallback to simple concatenation if URL parsing fails
-			params := url.Values{}
-			for key, value := range req.QueryParams {
-				params.Add(key, value)
+	list := make([]models.KeyValue, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, models.KeyValue{Key: key, Value: params[key]})
+	}
+	return MergeQueryList(rawURL, list, policy)
+}
+
+// MergeQueryList combines ordered params with the query string already in
+// rawURL according to policy. The URL's own query pairs are kept byte-for-byte
+// and in place, and params are appended in the order given, which matters for
+// APIs that sign the query string.
+func MergeQueryList(rawURL string, params []models.KeyValue, policy string) string {
+	if len(params) == 0 {
+		return rawURL
+	}
+
+	rest, fragment := rawURL, ""
+	if idx := strings.Index(rest, "#"); idx != -1 {
+		rest, fragment = rest[:idx], rest[idx:]
+	}
+	base, rawQuery, _ := strings.Cut(rest, "?")
+
+	// Keys already present in the URL
+	existing := make(map[string]bool)
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		existing[key] = true
+		pairs = append(pairs, pair)
+	}
+
+	if policy == QueryMergeOverride {
+		overridden := make(map[string]bool, len(params))
+		for _, param := range params {
+			overridden[param.Key] = true
+		}
+		kept := pairs[:0]
+		for _, pair := range pairs {
+			rawKey, _, _ := strings.Cut(pair, "=")
+			key, err := url.QueryUnescape(rawKey)
+			if err != nil {
+				key = rawKey
 			}
-			if strings.Contains(fullURL, "?") {
-				fullURL += "&" + params.Encode()
-			} else {
-				fullURL += "?" + params.Encode()
+			if !overridden[key] {
+				kept = append(kept, pair)
 			}
 		}
+		pairs = kept
+	}
+
+	for _, param := range params {
+		// Only add if not already in URL
+		if (policy == "" || policy == QueryMergeAdd) && existing[param.Key] {
+			continue
+		}
+		pairs = append(pairs, url.QueryEscape(param.Key)+"="+url.QueryEscape(param.Value))
+	}
+
+	if len(pairs) == 0 {
+		return base + fragment
+	}
+	return base + "?" + strings.Join(pairs, "&") + fragment
+}
+
+// ErrInvalidBody is returned (wrapped) for a body that doesn't match its
+// BodyEncoding
+var ErrInvalidBody = errors.New("invalid request body")
+
+// RequestBody returns the bytes to send for req, decoding base64 bodies
+func RequestBody(req *models.ExecuteRequest) ([]byte, error) {
+	switch strings.ToLower(req.BodyEncoding) {
+	case "", "text":
+		return []byte(req.Body), nil
+	case "base64":
+		// Tolerate line breaks from tools that wrap base64 output
+		encoded := strings.Join(strings.Fields(req.Body), "")
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: body is not valid base64: %v", ErrInvalidBody, err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported body_encoding %q (use text or base64)", ErrInvalidBody, req.BodyEncoding)
+	}
+}
+
+// ErrInvalidHeader is returned (wrapped) for a header whose name or value
+// contains a line break
+var ErrInvalidHeader = errors.New("invalid header")
+
+// ValidateHeader rejects a header that could inject further headers (or a
+// body) when written to the wire. net/http refuses some of these and quietly
+// rewrites others, so catch them all up front with a clear error.
+func ValidateHeader(key, value string) error {
+	if strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("%w: header name %q contains a line break", ErrInvalidHeader, key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: value of header %q contains a line break", ErrInvalidHeader, key)
+	}
+	return nil
+}
+
+// ValidateRequestHeaders runs ValidateHeader over both header forms of req
+func ValidateRequestHeaders(req *models.ExecuteRequest) error {
+	for _, header := range req.HeaderList {
+		if err := ValidateHeader(header.Key, header.Value); err != nil {
+			return err
+		}
+	}
+	for key, value := range req.Headers {
+		if err := ValidateHeader(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildHTTPRequest turns an ExecuteRequest (after variable substitution)
+// into the *http.Request that ExecuteHTTPRequest sends
+func BuildHTTPRequest(req *models.ExecuteRequest) (*http.Request, error) {
+	requestURL, err := NormalizeRequestURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsValidQueryMergePolicy(req.QueryMergePolicy) {
+		return nil, errors.New("invalid query_merge_policy")
+	}
+	if !IsValidBodyTransfer(req.BodyTransfer) {
+		return nil, errors.New("invalid body_transfer")
+	}
+
+	// Build URL with query parameters
+	var fullURL string
+	if len(req.QueryList) > 0 {
+		fullURL = MergeQueryList(requestURL, req.QueryList, req.QueryMergePolicy)
+	} else {
+		fullURL = MergeQueryParams(requestURL, req.QueryParams, req.QueryMergePolicy)
 	}
 
 	// Rewrite localhost URLs when running inside Docker
 	fullURL = RewriteLocalhostURL(fullURL)
 
-	// Create request
+	body, err := RequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRequestHeaders(req); err != nil {
+		return nil, err
+	}
+
+	// Create request. A body goes out with its Content-Length, not chunked
+	// (unless body_transfer asks for it), since some servers (and proxies)
+	// reject chunked PATCH / PUT bodies. Without a body, POST, PUT and PATCH
+	// still send "Content-Length: 0", which servers expecting content
+	// require (411 otherwise); other methods send no Content-Length at all.
 	var bodyReader io.Reader
-	if req.Body != "" {
-		bodyReader = strings.NewReader(req.Body)
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
 	}
 
-	httpReq, err := http.NewRequest(req.Method, fullURL, bodyReader)
+	httpReq, err := http.NewRequest(CanonicalMethod(req.Method), fullURL, bodyReader)
 	if err != nil {
 		return nil, err
 	}
+	if err := ApplyBodyTransfer(httpReq, req.BodyTransfer); err != nil {
+		return nil, err
+	}
 
-	// Add headers
-	for key, value := range req.Headers {
-		httpReq.Header.Set(key, value)
+	// Add headers. The ordered list keeps repeated headers' values in order;
+	// net/http itself always writes header names sorted.
+	if len(req.HeaderList) > 0 {
+		for _, header := range req.HeaderList {
+			httpReq.Header.Add(header.Key, header.Value)
+		}
+	} else {
+		for key, value := range req.Headers {
+			httpReq.Header.Set(key, value)
+		}
+	}
+	if err := ApplyHeaderPolicy(httpReq, req.Host); err != nil {
+		return nil, err
 	}
+	SetDefaultUserAgent(httpReq)
 
-	// Use a client appropriate for the target (relaxed TLS for localhost)
-	client := HttpClientFor(fullURL)
-	resp, err := client.Do(httpReq)
+	return httpReq, nil
+}
+
+// standardMethods are the methods whose names are sent upper-cased however
+// they were typed
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// CanonicalMethod upper-cases a standard method name ("patch" becomes
+// "PATCH") and defaults an empty one to GET. Method names are case-sensitive,
+// so a lower-case "patch" would otherwise reach the server as an unknown
+// method, and without the Content-Length handling above. Custom methods are
+// left as typed.
+func CanonicalMethod(method string) string {
+	method = strings.TrimSpace(method)
+	if method == "" {
+		return http.MethodGet
+	}
+	for _, standard := range standardMethods {
+		if strings.EqualFold(method, standard) {
+			return standard
+		}
+	}
+	return method
+}
+
+// ExecuteHTTPRequest executes an HTTP request and returns the response
+func ExecuteHTTPRequest(req *models.ExecuteRequest) (*models.ExecuteResponse, error) {
+	return ExecuteHTTPRequestContext(context.Background(), req)
+}
+
+// ExecuteHTTPRequestContext is ExecuteHTTPRequest with a context; cancelling
+// it aborts the request (returning context.Canceled)
+func ExecuteHTTPRequestContext(ctx context.Context, req *models.ExecuteRequest) (*models.ExecuteResponse, error) {
+	startTime := time.Now()
+
+	if req.PreviewBytes < 0 {
+		return nil, fmt.Errorf("preview_bytes must not be negative")
+	}
+	var ndjsonMaxLines, ndjsonTimeout int
+	if err := ValidateNDJSON(req); err != nil {
+		return nil, err
+	}
+	if req.NDJSON != nil {
+		ndjsonMaxLines, ndjsonTimeout, _ = ndjsonLimits(req.NDJSON)
+	}
+	httpReq, err := BuildHTTPRequest(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Decompress body if the server sent it compressed.
-	// Go's transport only auto-decompresses when it added Accept-Encoding itself;
-	// when the caller explicitly sets Accept-Encoding: gzip the raw bytes come through.
-	var respBodyReader io.Reader = resp.Body
-	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-		gr, err := gzip.NewReader(resp.Body)
-		if err != nil {
+	// An NDJSON read is cut off by cancelling the request once its time is up
+	var stopStream context.CancelFunc
+	if req.NDJSON != nil {
+		ctx, stopStream = context.WithCancel(ctx)
+		defer stopStream()
+	}
+	httpReq = httpReq.WithContext(ctx)
+
+	if err := ValidateRequestAuth(req.Auth); err != nil {
+		return nil, err
+	}
+	if req.Auth != nil && req.Auth.Type == AuthTypeOAuth2 {
+		if err := applyOAuth2(ctx, httpReq, req.Auth.OAuth2, req.EnvironmentID); err != nil {
 			return nil, err
 		}
-		defer gr.Close()
-		respBodyReader = gr
 	}
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(respBodyReader)
+	requestBytes := HeaderBytes(httpReq.Header) + httpReq.ContentLength
+
+	// Use a client appropriate for the target (relaxed TLS for localhost)
+	client := HttpClientFor(httpReq.URL.String())
+	if req.Auth != nil && req.Auth.Type == AuthTypeNTLM {
+		client = ntlmClient(httpReq, req.Auth.NTLM)
+		defer client.CloseIdleConnections()
+	}
+	if req.NDJSON != nil {
+		// The stream gets its own timeout on top of waiting for the headers
+		client.Timeout += time.Duration(ndjsonTimeout) * time.Second
+	}
+	if client, err = HostPolicyClient(ctx, client); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	// Count the body bytes as they come off the wire
+	received := &countingReader{r: resp.Body}
+
+	var streamTimedOut atomic.Bool
+	if req.NDJSON != nil {
+		timer := time.AfterFunc(time.Duration(ndjsonTimeout)*time.Second, func() {
+			streamTimedOut.Store(true)
+			stopStream()
+		})
+		defer timer.Stop()
+	}
+
+	// HEAD responses never carry a body even when Content-Length /
+	// Content-Encoding describe one, so don't try to read (or gunzip) it
+	var bodyBytes []byte
+	var events []json.RawMessage
+	truncated := false
+	if httpReq.Method != http.MethodHead {
+		// Decompress body if the server sent it compressed.
+		// Go's transport only auto-decompresses when it added Accept-Encoding itself;
+		// when the caller explicitly sets Accept-Encoding: gzip the raw bytes come through.
+		var respBodyReader io.Reader = received
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			gr, err := gzip.NewReader(received)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if err == nil {
+				defer gr.Close()
+				respBodyReader = gr
+			}
+		}
+
+		// A preview reads one byte past the limit to tell whether there was
+		// more; closing the unread body then drops the connection
+		if req.PreviewBytes > 0 {
+			respBodyReader = io.LimitReader(respBodyReader, req.PreviewBytes+1)
+		}
+
+		// Read response body
+		if req.NDJSON != nil {
+			stream, err := readNDJSON(respBodyReader, ndjsonMaxLines, maxNDJSONLineBytes(), streamTimedOut.Load)
+			if err != nil {
+				return nil, err
+			}
+			bodyBytes, events, truncated = stream.raw, stream.events, stream.truncated
+		} else if bodyBytes, err = io.ReadAll(respBodyReader); err != nil {
+			return nil, err
+		}
+		if req.PreviewBytes > 0 && int64(len(bodyBytes)) > req.PreviewBytes {
+			bodyBytes = bodyBytes[:req.PreviewBytes]
+			truncated = true
+		}
+	}
 
 	// Calculate elapsed time
 	elapsed := time.Since(startTime).Milliseconds()
 
 	// Build response headers map (strip Content-Encoding since we decoded the body)
-	respHeaders := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 && !strings.EqualFold(key, "Content-Encoding") {
-			respHeaders[key] = values[0]
+	respHeaders := FlattenHeaders(resp.Header)
+	if httpReq.Method != http.MethodHead {
+		delete(respHeaders, "Content-Encoding")
+	}
+
+	body, charset := DecodeBody(resp.Header.Get("Content-Type"), bodyBytes)
+
+	response := &models.ExecuteResponse{
+		Status:        resp.StatusCode,
+		StatusText:    resp.Status,
+		Headers:       respHeaders,
+		Body:          body,
+		Charset:       charset,
+		BodyPresent:   len(bodyBytes) > 0,
+		Time:          elapsed,
+		RequestBytes:  requestBytes,
+		ResponseBytes: HeaderBytes(resp.Header) + received.n,
+		NotModified:   resp.StatusCode == http.StatusNotModified,
+		Truncated:     truncated,
+		Events:        events,
+	}
+	if req.PreviewBytes > 0 && resp.ContentLength >= 0 {
+		contentLength := resp.ContentLength
+		response.ContentLength = &contentLength
+	}
+	if req.IncludeTLSInfo {
+		response.TLS = TLSInfoFrom(resp.TLS)
+	}
+	return response, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// HeaderBytes approximates the wire size of header as "Key: value\r\n" lines
+func HeaderBytes(header http.Header) int64 {
+	var size int64
+	for key, values := range header {
+		for _, value := range values {
+			size += int64(len(key) + len(": ") + len(value) + len("\r\n"))
 		}
 	}
+	return size
+}
 
-	return &models.ExecuteResponse{
-		Status:     resp.StatusCode,
-		StatusText: resp.Status,
-		Headers:    respHeaders,
-		Body:       string(bodyBytes),
-		Time:       elapsed,
-	}, nil
+// FlattenHeaders converts response headers into a single-valued map, joining
+// repeated headers (e.g. several Allow or Access-Control-Allow-* lines on an
+// OPTIONS response) with ", " instead of keeping only the first value.
+// Set-Cookie lines are joined with "\n" instead, as cookie expiry dates
+// contain commas.
+func FlattenHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for key, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+		separator := ", "
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			separator = "\n"
+		}
+		flat[key] = strings.Join(values, separator)
+	}
+	return flat
 }
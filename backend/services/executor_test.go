@@ -0,0 +1,722 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"postmanxodja/models"
+)
+
+// useLoopback keeps RewriteLocalhostURL pointing at the test server even when
+// the tests themselves run inside a container.
+func useLoopback(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+}
+
+func TestExecuteHTTPRequestHead(t *testing.T) {
+	useLoopback(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected method HEAD, got '%s'", r.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", "1234")
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodHead,
+		URL:    server.URL + "/resource",
+	})
+	if err != nil {
+		t.Fatalf("HEAD request failed: %v", err)
+	}
+
+	if resp.Status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.Status)
+	}
+	if resp.BodyPresent {
+		t.Error("Expected body_present to be false for HEAD")
+	}
+	if resp.Body != "" {
+		t.Errorf("Expected empty body, got '%s'", resp.Body)
+	}
+	if resp.Headers["Content-Length"] != "1234" {
+		t.Errorf("Expected Content-Length '1234', got '%s'", resp.Headers["Content-Length"])
+	}
+	if resp.Headers["X-Request-Id"] != "abc" {
+		t.Errorf("Expected X-Request-Id 'abc', got '%s'", resp.Headers["X-Request-Id"])
+	}
+}
+
+func TestExecuteHTTPRequestOptions(t *testing.T) {
+	useLoopback(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Allow", "GET")
+		w.Header().Add("Allow", "POST")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodOptions,
+		URL:    server.URL,
+	})
+	if err != nil {
+		t.Fatalf("OPTIONS request failed: %v", err)
+	}
+
+	if resp.BodyPresent {
+		t.Error("Expected body_present to be false for 204")
+	}
+	if resp.Headers["Allow"] != "GET, POST" {
+		t.Errorf("Expected Allow 'GET, POST', got '%s'", resp.Headers["Allow"])
+	}
+	if resp.Headers["Access-Control-Allow-Methods"] != "GET, POST, OPTIONS" {
+		t.Errorf("Expected Access-Control-Allow-Methods header, got '%s'", resp.Headers["Access-Control-Allow-Methods"])
+	}
+}
+
+func TestFlattenHeadersKeepsCookiesApart(t *testing.T) {
+	header := http.Header{}
+	header.Add("Set-Cookie", "sid=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT")
+	header.Add("Set-Cookie", "theme=dark; Path=/")
+	header.Add("Vary", "Accept")
+	header.Add("Vary", "Origin")
+
+	flat := FlattenHeaders(header)
+	if expected := "sid=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT\ntheme=dark; Path=/"; flat["Set-Cookie"] != expected {
+		t.Errorf("Expected Set-Cookie '%s', got '%s'", expected, flat["Set-Cookie"])
+	}
+	if flat["Vary"] != "Accept, Origin" {
+		t.Errorf("Expected Vary 'Accept, Origin', got '%s'", flat["Vary"])
+	}
+}
+
+func TestExecuteHTTPRequestGetHasBody(t *testing.T) {
+	useLoopback(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodGet,
+		URL:    server.URL,
+	})
+	if err != nil {
+		t.Fatalf("GET request failed: %v", err)
+	}
+
+	if !resp.BodyPresent {
+		t.Error("Expected body_present to be true")
+	}
+	if resp.Body != `{"ok":true}` {
+		t.Errorf("Expected body '{\"ok\":true}', got '%s'", resp.Body)
+	}
+}
+
+func TestExecuteHTTPRequestQueryParams(t *testing.T) {
+	useLoopback(t)
+
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+	}))
+	defer server.Close()
+
+	_, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodGet,
+		URL:    server.URL + "/search?page=1&q=existing",
+		QueryParams: map[string]string{
+			"q":     "new",
+			"limit": "20",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	// Params already in the URL win; new ones are appended
+	if gotQuery.Get("q") != "existing" {
+		t.Errorf("Expected q 'existing', got '%s'", gotQuery.Get("q"))
+	}
+	if len(gotQuery["q"]) != 1 {
+		t.Errorf("Expected a single q value, got %v", gotQuery["q"])
+	}
+	if gotQuery.Get("page") != "1" {
+		t.Errorf("Expected page '1', got '%s'", gotQuery.Get("page"))
+	}
+	if gotQuery.Get("limit") != "20" {
+		t.Errorf("Expected limit '20', got '%s'", gotQuery.Get("limit"))
+	}
+}
+
+func TestExecuteHTTPRequestHeadersAndBody(t *testing.T) {
+	useLoopback(t)
+
+	var gotMethod, gotAuth, gotContentType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodPost,
+		URL:    server.URL + "/items",
+		Headers: map[string]string{
+			"Authorization": "Bearer secret",
+			"Content-Type":  "application/json",
+		},
+		Body: `{"name":"item"}`,
+	})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Status != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.Status)
+	}
+	if gotMethod != http.MethodPost {
+		t.Errorf("Expected method POST, got '%s'", gotMethod)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Expected Authorization 'Bearer secret', got '%s'", gotAuth)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", gotContentType)
+	}
+	if gotBody != `{"name":"item"}` {
+		t.Errorf("Expected body '{\"name\":\"item\"}', got '%s'", gotBody)
+	}
+}
+
+func TestExecuteHTTPRequestRequiresURL(t *testing.T) {
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet}); err == nil {
+		t.Error("Expected an error for an empty URL")
+	}
+}
+
+func TestExecuteHTTPRequestTimeout(t *testing.T) {
+	useLoopback(t)
+
+	previous := requestTimeout
+	requestTimeout = 50 * time.Millisecond
+	defer func() { requestTimeout = previous }()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodGet,
+		URL:    server.URL,
+	})
+	if err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the timeout to fire quickly, took %v", elapsed)
+	}
+}
+
+func TestExecuteHTTPRequestLocalhostTLS(t *testing.T) {
+	useLoopback(t)
+
+	// The test server uses a self-signed certificate, which is only accepted
+	// because loopback targets get relaxed TLS verification
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: http.MethodGet,
+		URL:    server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Request to localhost TLS server failed: %v", err)
+	}
+	if resp.Body != "secure" {
+		t.Errorf("Expected body 'secure', got '%s'", resp.Body)
+	}
+}
+
+func TestHttpClientFor(t *testing.T) {
+	local := HttpClientFor("https://localhost:8443/api")
+	transport, ok := local.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil || !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("Expected relaxed TLS for localhost targets")
+	}
+
+	public := HttpClientFor("https://api.example.com/v1")
+	if public.Transport != nil {
+		t.Error("Expected the default transport for public targets")
+	}
+}
+
+func TestRewriteLocalhostURL(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "host.docker.internal")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"http://localhost:3000/api?x=1", "http://host.docker.internal:3000/api?x=1"},
+		{"http://127.0.0.1/health", "http://host.docker.internal/health"},
+		{"https://api.example.com/v1/users", "https://api.example.com/v1/users"},
+		{"http://192.168.1.10:8080/", "http://192.168.1.10:8080/"},
+	}
+
+	for _, tt := range tests {
+		if got := RewriteLocalhostURL(tt.input); got != tt.expected {
+			t.Errorf("RewriteLocalhostURL(%q): expected '%s', got '%s'", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMergeQueryParams(t *testing.T) {
+	base := "https://api.example.com/items?tag=a&page=1"
+	params := map[string]string{"tag": "b", "limit": "10"}
+
+	tests := []struct {
+		policy   string
+		expected url.Values
+	}{
+		{"", url.Values{"tag": {"a"}, "page": {"1"}, "limit": {"10"}}},
+		{QueryMergeAdd, url.Values{"tag": {"a"}, "page": {"1"}, "limit": {"10"}}},
+		{QueryMergeOverride, url.Values{"tag": {"b"}, "page": {"1"}, "limit": {"10"}}},
+		{QueryMergeAppend, url.Values{"tag": {"a", "b"}, "page": {"1"}, "limit": {"10"}}},
+	}
+
+	for _, tt := range tests {
+		merged, err := url.Parse(MergeQueryParams(base, params, tt.policy))
+		if err != nil {
+			t.Fatalf("Policy %q produced an invalid URL: %v", tt.policy, err)
+		}
+		got := merged.Query()
+		for key, want := range tt.expected {
+			if strings.Join(got[key], ",") != strings.Join(want, ",") {
+				t.Errorf("Policy %q: expected %s=%v, got %v", tt.policy, key, want, got[key])
+			}
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("Policy %q: expected %d keys, got %d", tt.policy, len(tt.expected), len(got))
+		}
+	}
+}
+
+func TestExecuteHTTPRequestInvalidMergePolicy(t *testing.T) {
+	_, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method:           http.MethodGet,
+		URL:              "http://localhost/",
+		QueryMergePolicy: "replace-all",
+	})
+	if err == nil {
+		t.Error("Expected an error for an unknown merge policy")
+	}
+}
+
+func TestExecuteHTTPRequestPreservesOrder(t *testing.T) {
+	useLoopback(t)
+
+	var gotRawQuery string
+	var gotAccept []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRawQuery = r.URL.RawQuery
+		gotAccept = r.Header.Values("Accept")
+	}))
+	defer server.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+			Method: http.MethodGet,
+			URL:    server.URL + "/sign?z=1&a=2",
+			QueryList: []models.KeyValue{
+				{Key: "timestamp", Value: "1700000000"},
+				{Key: "nonce", Value: "abc"},
+				{Key: "b", Value: "x y"},
+				{Key: "b", Value: "second"},
+			},
+			HeaderList: []models.KeyValue{
+				{Key: "Accept", Value: "application/json"},
+				{Key: "Accept", Value: "text/plain"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		expected := "z=1&a=2&timestamp=1700000000&nonce=abc&b=x+y&b=second"
+		if gotRawQuery != expected {
+			t.Fatalf("Expected query '%s', got '%s'", expected, gotRawQuery)
+		}
+		if strings.Join(gotAccept, ",") != "application/json,text/plain" {
+			t.Fatalf("Expected Accept values in order, got %v", gotAccept)
+		}
+	}
+}
+
+func TestMergeQueryListKeepsFragment(t *testing.T) {
+	got := MergeQueryList("https://example.com/a?x=1#section", []models.KeyValue{{Key: "y", Value: "2"}}, "")
+	if got != "https://example.com/a?x=1&y=2#section" {
+		t.Errorf("Expected fragment to stay at the end, got '%s'", got)
+	}
+}
+
+func TestBuildHTTPRequest(t *testing.T) {
+	t.Setenv("DOCKER_HOST_OVERRIDE", "127.0.0.1")
+
+	httpReq, err := BuildHTTPRequest(&models.ExecuteRequest{
+		Method:      http.MethodPut,
+		URL:         "http://localhost:9000/users/1",
+		Headers:     map[string]string{"X-Trace": "t1"},
+		QueryParams: map[string]string{"notify": "true"},
+		Body:        "{}",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+
+	if httpReq.URL.String() != "http://127.0.0.1:9000/users/1?notify=true" {
+		t.Errorf("Unexpected resolved URL '%s'", httpReq.URL.String())
+	}
+	if httpReq.Header.Get("X-Trace") != "t1" {
+		t.Errorf("Expected X-Trace 't1', got '%s'", httpReq.Header.Get("X-Trace"))
+	}
+}
+
+func TestExecuteHTTPRequestByteCounts(t *testing.T) {
+	useLoopback(t)
+
+	const payload = `{"name":"widget"}`
+	const reply = "0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(reply))
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    payload,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// "Content-Type: application/json\r\n", the default User-Agent and the body
+	wantRequest := int64(len("Content-Type: application/json\r\n") + len("User-Agent: "+DefaultUserAgent()+"\r\n") + len(payload))
+	if resp.RequestBytes != wantRequest {
+		t.Errorf("Expected RequestBytes %d, got %d", wantRequest, resp.RequestBytes)
+	}
+
+	header := http.Header{}
+	for key, value := range resp.Headers {
+		header.Set(key, value)
+	}
+	wantResponse := HeaderBytes(header) + int64(len(reply))
+	if resp.ResponseBytes != wantResponse {
+		t.Errorf("Expected ResponseBytes %d, got %d", wantResponse, resp.ResponseBytes)
+	}
+}
+
+func TestExecuteHTTPRequestByteCountsCompressed(t *testing.T) {
+	useLoopback(t)
+
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	gw.Write([]byte(strings.Repeat("a", 1000)))
+	gw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Headers: map[string]string{"Accept-Encoding": "gzip"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resp.Body) != 1000 {
+		t.Errorf("Expected decoded body of 1000 bytes, got %d", len(resp.Body))
+	}
+	// The received size reflects the compressed body, not the decoded one
+	if resp.ResponseBytes >= 1000 || resp.ResponseBytes < int64(compressed.Len()) {
+		t.Errorf("Expected ResponseBytes between %d and 1000, got %d", compressed.Len(), resp.ResponseBytes)
+	}
+}
+
+func TestHeaderBytes(t *testing.T) {
+	header := http.Header{}
+	header.Add("X-A", "1")
+	header.Add("X-A", "22")
+
+	// "X-A: 1\r\n" + "X-A: 22\r\n"
+	if got := HeaderBytes(header); got != 17 {
+		t.Errorf("Expected 17, got %d", got)
+	}
+}
+
+func TestExecuteHTTPRequestBase64Body(t *testing.T) {
+	useLoopback(t)
+
+	// A gRPC-Web style frame: flag byte, big-endian length, protobuf message
+	frame := []byte{0x00, 0x00, 0x00, 0x00, 0x03, 0x08, 0x96, 0x01}
+
+	var gotBody []byte
+	var gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method:       http.MethodPost,
+		URL:          server.URL + "/pkg.Service/Method",
+		Headers:      map[string]string{"Content-Type": "application/grpc-web+proto"},
+		Body:         "AAAAAAMIlgE=",
+		BodyEncoding: "base64",
+	})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if !bytes.Equal(gotBody, frame) {
+		t.Errorf("Expected decoded body %v, got %v", frame, gotBody)
+	}
+	if gotContentType != "application/grpc-web+proto" {
+		t.Errorf("Expected Content-Type 'application/grpc-web+proto', got '%s'", gotContentType)
+	}
+	if expected := HeaderBytes(http.Header{"Content-Type": {"application/grpc-web+proto"}, "User-Agent": {DefaultUserAgent()}}) + int64(len(frame)); resp.RequestBytes != expected {
+		t.Errorf("Expected request_bytes %d (decoded size), got %d", expected, resp.RequestBytes)
+	}
+}
+
+func TestRequestBodyInvalidBase64(t *testing.T) {
+	for _, req := range []models.ExecuteRequest{
+		{Body: "not base64!", BodyEncoding: "base64"},
+		{Body: "abc", BodyEncoding: "hex"},
+	} {
+		if _, err := RequestBody(&req); !errors.Is(err, ErrInvalidBody) {
+			t.Errorf("Expected ErrInvalidBody for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestExecuteHTTPRequestPreviewBytes(t *testing.T) {
+	useLoopback(t)
+
+	const total = 8 << 20
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(total))
+		for written := 0; written < total; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return // the client hung up after its preview
+			}
+		}
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, PreviewBytes: 1024})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if len(resp.Body) != 1024 || resp.Body != string(chunk[:1024]) {
+		t.Errorf("Expected the first 1024 bytes, got %d bytes", len(resp.Body))
+	}
+	if !resp.Truncated {
+		t.Error("Expected truncated to be set")
+	}
+	if resp.ContentLength == nil || *resp.ContentLength != total {
+		t.Errorf("Expected content_length %d, got %v", total, resp.ContentLength)
+	}
+	if resp.ResponseBytes >= total {
+		t.Errorf("Expected the download to stop early, received %d bytes", resp.ResponseBytes)
+	}
+
+	// A body that fits in the preview isn't truncated
+	small := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("short"))
+	}))
+	defer small.Close()
+	resp, err = ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: small.URL, PreviewBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Truncated || resp.Body != "short" {
+		t.Errorf("Expected the whole body untruncated, got '%s' (truncated %v)", resp.Body, resp.Truncated)
+	}
+
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: small.URL, PreviewBytes: -1}); err == nil {
+		t.Error("Expected a negative preview_bytes to be rejected")
+	}
+}
+
+func TestExecuteHTTPRequestRejectsHeaderInjection(t *testing.T) {
+	useLoopback(t)
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	for _, req := range []models.ExecuteRequest{
+		{Headers: map[string]string{"X-Note": "hello\r\nX-Injected: 1"}},
+		{Headers: map[string]string{"X-Note": "hello\nworld"}},
+		{HeaderList: []models.KeyValue{{Key: "X-Note", Value: "ok"}, {Key: "X-Other", Value: "a\rb"}}},
+		{HeaderList: []models.KeyValue{{Key: "X-Bad\r\nX-Injected", Value: "1"}}},
+	} {
+		req.Method = http.MethodGet
+		req.URL = server.URL
+		if _, err := ExecuteHTTPRequest(&req); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("Expected ErrInvalidHeader for %v %v, got %v", req.Headers, req.HeaderList, err)
+		}
+	}
+	if hits != 0 {
+		t.Errorf("Expected no request to be sent, got %d", hits)
+	}
+
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, Headers: map[string]string{"X-Note": "hello world"}}); err != nil {
+		t.Errorf("Expected a clean header to be sent, got %v", err)
+	}
+}
+
+func TestExecuteHTTPRequestContentLength(t *testing.T) {
+	useLoopback(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		contentLength, sent := r.Header["Content-Length"]
+		fmt.Fprintf(w, "%s|%v|%s|%v|%s", r.Method, sent, strings.Join(contentLength, ","), r.TransferEncoding, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		body   string
+		want   string
+	}{
+		{"PATCH", `{"name":"ada"}`, `PATCH|true|14|[]|{"name":"ada"}`},
+		{"PUT", `{"name":"ada"}`, `PUT|true|14|[]|{"name":"ada"}`},
+		{"PATCH", "", "PATCH|true|0|[]|"},
+		{"PUT", "", "PUT|true|0|[]|"},
+		{"patch", "x", "PATCH|true|1|[]|x"},
+		{"GET", "", "GET|false||[]|"},
+		{"DELETE", "", "DELETE|false||[]|"},
+		{"", "", "GET|false||[]|"},
+	}
+	for _, tt := range tests {
+		resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: tt.method, URL: server.URL, Body: tt.body})
+		if err != nil {
+			t.Fatalf("%s %q: request failed: %v", tt.method, tt.body, err)
+		}
+		if resp.Body != tt.want {
+			t.Errorf("%s %q: expected the server to see '%s', got '%s'", tt.method, tt.body, tt.want, resp.Body)
+		}
+	}
+}
+
+func TestExecuteHTTPRequestBodyTransfer(t *testing.T) {
+	useLoopback(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%v|%s", r.Header.Get("Content-Length"), r.TransferEncoding, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		transfer string
+		body     string
+		want     string
+	}{
+		{"", "hello", "5|[]|hello"},
+		{"content-length", "hello", "5|[]|hello"},
+		{"chunked", "hello", "|[chunked]|hello"},
+		// Nothing to chunk: a bodiless POST keeps its "Content-Length: 0"
+		{"chunked", "", "0|[]|"},
+	}
+	for _, tt := range tests {
+		resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "POST", URL: server.URL, Body: tt.body, BodyTransfer: tt.transfer})
+		if err != nil {
+			t.Fatalf("%q: request failed: %v", tt.transfer, err)
+		}
+		if resp.Body != tt.want {
+			t.Errorf("%q %q: expected the server to see '%s', got '%s'", tt.transfer, tt.body, tt.want, resp.Body)
+		}
+	}
+
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "POST", URL: server.URL, BodyTransfer: "gzip"}); err == nil {
+		t.Error("Expected an unknown body_transfer to be rejected")
+	}
+}
+
+func TestApplyBodyTransferBuffersStreamedBody(t *testing.T) {
+	for _, body := range []string{"streamed", ""} {
+		httpReq, _ := http.NewRequest(http.MethodPost, "http://example.com", io.MultiReader(strings.NewReader(body)))
+		if httpReq.ContentLength != 0 || httpReq.Body == nil {
+			t.Fatalf("Expected a body of unknown length, got %d", httpReq.ContentLength)
+		}
+		if err := ApplyBodyTransfer(httpReq, BodyTransferContentLength); err != nil {
+			t.Fatal(err)
+		}
+		if httpReq.ContentLength != int64(len(body)) {
+			t.Errorf("Expected Content-Length %d, got %d", len(body), httpReq.ContentLength)
+		}
+		if sent, _ := io.ReadAll(httpReq.Body); string(sent) != body {
+			t.Errorf("Expected body %q, got %q", body, sent)
+		}
+	}
+}
+
+func TestCanonicalMethod(t *testing.T) {
+	for method, want := range map[string]string{
+		"patch":    "PATCH",
+		" Put ":    "PUT",
+		"GET":      "GET",
+		"":         "GET",
+		"PROPFIND": "PROPFIND",
+		"purge":    "purge",
+	} {
+		if got := CanonicalMethod(method); got != want {
+			t.Errorf("CanonicalMethod(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// ErrBodyNotJSON is returned when extracting from a body that isn't JSON
+	ErrBodyNotJSON = errors.New("response body is not valid JSON")
+	// ErrInvalidPath is returned (wrapped) for a malformed path expression
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// ExtractResult is what a path matched in a JSON body
+type ExtractResult struct {
+	Path  string          `json:"path"`  // the GJSON path that was evaluated
+	Found bool            `json:"found"` // false when nothing matched
+	Type  string          `json:"type"`  // string, number, boolean, null, object or array
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+var (
+	jsonPathIndex  = regexp.MustCompile(`\[(\d+)\]`)
+	jsonPathQuoted = regexp.MustCompile(`\[['"]([^'"\]]+)['"]\]`)
+)
+
+// ToGJSONPath accepts either GJSON syntax (data.items.0.id, items.#.id) or
+// simple JSONPath ($.data.items[0].id, $.items[*].id, $['key']) and returns
+// the GJSON path
+func ToGJSONPath(path string) string {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return path
+	}
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[*]", ".#")
+	path = jsonPathQuoted.ReplaceAllStringFunc(path, func(m string) string {
+		key := jsonPathQuoted.FindStringSubmatch(m)[1]
+		return "." + strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`).Replace(key)
+	})
+	path = jsonPathIndex.ReplaceAllString(path, ".$1")
+	return strings.TrimPrefix(path, ".")
+}
+
+// validatePath catches the malformed paths GJSON would silently treat as
+// "no match": empty, unbalanced brackets / parentheses and dangling
+// separators
+func validatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("%w: path is empty", ErrInvalidPath)
+	}
+
+	depth := map[rune]int{}
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	escaped := false
+	for _, r := range path {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '(' || r == '[' || r == '{':
+			depth[r]++
+		case r == ')' || r == ']' || r == '}':
+			depth[pairs[r]]--
+			if depth[pairs[r]] < 0 {
+				return fmt.Errorf("%w: unexpected %q", ErrInvalidPath, r)
+			}
+		}
+	}
+	for open, n := range depth {
+		if n != 0 {
+			return fmt.Errorf("%w: unclosed %q", ErrInvalidPath, open)
+		}
+	}
+
+	if strings.HasSuffix(path, ".") && !strings.HasSuffix(path, `\.`) || strings.HasSuffix(path, "|") ||
+		strings.HasPrefix(path, ".") || strings.HasPrefix(path, "|") || strings.Contains(path, "..") && !strings.HasPrefix(path, "..") {
+		return fmt.Errorf("%w: misplaced separator in %q", ErrInvalidPath, path)
+	}
+	return nil
+}
+
+// ExtractJSONPath evaluates path (GJSON or simple JSONPath, see ToGJSONPath)
+// against a JSON body
+func ExtractJSONPath(body, path string) (*ExtractResult, error) {
+	gpath := ToGJSONPath(path)
+	if err := validatePath(gpath); err != nil {
+		return nil, err
+	}
+	if !gjson.Valid(body) {
+		return nil, ErrBodyNotJSON
+	}
+
+	match := gjson.Get(body, gpath)
+	result := &ExtractResult{Path: gpath, Found: match.Exists()}
+	if !result.Found {
+		return result, nil
+	}
+
+	switch {
+	case match.IsArray():
+		result.Type = "array"
+	case match.IsObject():
+		result.Type = "object"
+	case match.Type == gjson.String:
+		result.Type = "string"
+	case match.Type == gjson.Number:
+		result.Type = "number"
+	case match.Type == gjson.True, match.Type == gjson.False:
+		result.Type = "boolean"
+	default:
+		result.Type = "null"
+	}
+	result.Value = json.RawMessage(match.Raw)
+	return result, nil
+}
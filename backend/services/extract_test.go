@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+const extractBody = `{
+	"data": {
+		"user": {"name": "Ada", "roles": ["admin", "dev"]},
+		"items": [
+			{"id": 1, "price": 9.5, "active": true},
+			{"id": 2, "price": 12, "active": false}
+		]
+	},
+	"next": null
+}`
+
+func TestExtractJSONPathNested(t *testing.T) {
+	cases := map[string]struct {
+		value string
+		kind  string
+	}{
+		"data.user.name":      {`"Ada"`, "string"},
+		"$.data.user.name":    {`"Ada"`, "string"},
+		"$['data']['user']":   {`{"name": "Ada", "roles": ["admin", "dev"]}`, "object"},
+		"data.items.1.active": {`false`, "boolean"},
+		"next":                {`null`, "null"},
+	}
+	for path, expected := range cases {
+		result, err := ExtractJSONPath(extractBody, path)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", path, err)
+		}
+		if !result.Found {
+			t.Errorf("%s: expected a match", path)
+			continue
+		}
+		if string(result.Value) != expected.value || result.Type != expected.kind {
+			t.Errorf("%s: expected %s (%s), got %s (%s)", path, expected.value, expected.kind, result.Value, result.Type)
+		}
+	}
+}
+
+func TestExtractJSONPathArrays(t *testing.T) {
+	cases := map[string]string{
+		"data.items.#.id":     `[1,2]`,
+		"$.data.items[*].id":  `[1,2]`,
+		"$.data.items[0]":     `{"id": 1, "price": 9.5, "active": true}`,
+		"data.user.roles.1":   `"dev"`,
+		"data.items.#":        `2`,
+		"data.items.#(id==2)": `{"id": 2, "price": 12, "active": false}`,
+	}
+	for path, expected := range cases {
+		result, err := ExtractJSONPath(extractBody, path)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", path, err)
+		}
+		if string(result.Value) != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, result.Value)
+		}
+	}
+}
+
+func TestExtractJSONPathNoMatch(t *testing.T) {
+	result, err := ExtractJSONPath(extractBody, "data.missing")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Found || result.Value != nil {
+		t.Errorf("Expected no match, got %+v", result)
+	}
+}
+
+func TestExtractJSONPathErrors(t *testing.T) {
+	for _, path := range []string{"", "data.items.#(id==2", "data.", ".data", "data..user"} {
+		if _, err := ExtractJSONPath(extractBody, path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Path '%s': expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+	if _, err := ExtractJSONPath("<html></html>", "data"); !errors.Is(err, ErrBodyNotJSON) {
+		t.Errorf("Expected ErrBodyNotJSON, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
+
+	"postmanxodja/models"
+)
+
+// ErrFormFileNeedsUpload is returned (wrapped) for a file field in the
+// form_data of a JSON execution; files have to be uploaded through
+// /requests/execute-multipart
+var ErrFormFileNeedsUpload = errors.New("file fields need the multipart upload")
+
+// ApplyFormData turns the text fields of req.FormData into a multipart body,
+// which replaces req.Body, with a Content-Type carrying its boundary. Disabled
+// fields are skipped. It runs once, after variables are substituted, so every
+// later build of the request sends the same body and boundary.
+func ApplyFormData(req *models.ExecuteRequest) error {
+	if len(req.FormData) == 0 {
+		return nil
+	}
+	if req.Body != "" {
+		return fmt.Errorf("%w: set either body or form_data, not both", ErrInvalidBody)
+	}
+	if req.BodyEncoding != "" && !strings.EqualFold(req.BodyEncoding, "text") {
+		return fmt.Errorf("%w: body_encoding doesn't apply to form_data", ErrInvalidBody)
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, field := range req.FormData {
+		if field.Disabled {
+			continue
+		}
+		switch field.Type {
+		case "", "text":
+			if err := writer.WriteField(field.Key, field.Value); err != nil {
+				return err
+			}
+		case "file":
+			return fmt.Errorf("%w: form_data field %q is a file; send it to /requests/execute-multipart", ErrFormFileNeedsUpload, field.Key)
+		default:
+			return fmt.Errorf("%w: form_data field %q has unknown type %q (use text or file)", ErrInvalidBody, field.Key, field.Type)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req.Body = buf.String()
+	req.FormData = nil
+	// The boundary has to match the body, so any Content-Type given is replaced
+	for key := range req.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			delete(req.Headers, key)
+		}
+	}
+	if len(req.HeaderList) > 0 {
+		req.HeaderList = removeHeader(req.HeaderList, "Content-Type")
+		req.HeaderList = append(req.HeaderList, models.KeyValue{Key: "Content-Type", Value: writer.FormDataContentType()})
+		return nil
+	}
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	req.Headers["Content-Type"] = writer.FormDataContentType()
+	return nil
+}
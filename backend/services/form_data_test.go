@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/models"
+	"strings"
+	"testing"
+)
+
+func TestTextOnlyFormDataRequest(t *testing.T) {
+	useLoopback(t)
+	var fields map[string][]string
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fields = r.MultipartForm.Value
+	}))
+	defer server.Close()
+
+	req := &models.ExecuteRequest{
+		Method: "POST",
+		URL:    server.URL,
+		// A stale Content-Type (e.g. from team defaults) can't carry the boundary
+		Headers: map[string]string{"content-type": "application/json"},
+		FormData: []models.FormField{
+			{Key: "name", Value: "{{name}}", Type: "text"},
+			{Key: "tag", Value: "a"},
+			{Key: "tag", Value: "b", Type: "text"},
+			{Key: "skipped", Value: "x", Type: "text", Disabled: true},
+		},
+	}
+	ReplaceInRequest(req, models.Variables{"name": "Ada"})
+	if err := ApplyFormData(req); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ExecuteHTTPRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.Status, resp.Body)
+	}
+	if !strings.HasPrefix(contentType, "multipart/form-data; boundary=") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if got := fields["name"]; len(got) != 1 || got[0] != "Ada" {
+		t.Errorf("name = %v, want [Ada]", got)
+	}
+	if got := fields["tag"]; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("tag = %v, want [a b]", got)
+	}
+	if _, ok := fields["skipped"]; ok {
+		t.Error("disabled field was sent")
+	}
+}
+
+func TestApplyFormDataReplacesListedContentType(t *testing.T) {
+	req := &models.ExecuteRequest{
+		HeaderList: []models.KeyValue{{Key: "Content-Type", Value: "text/plain"}, {Key: "X-Trace", Value: "1"}},
+		FormData:   []models.FormField{{Key: "a", Value: "1", Type: "text"}},
+	}
+	if err := ApplyFormData(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.HeaderList) != 2 || req.HeaderList[0].Key != "X-Trace" ||
+		!strings.HasPrefix(req.HeaderList[1].Value, "multipart/form-data; boundary=") {
+		t.Errorf("HeaderList = %v", req.HeaderList)
+	}
+	if !strings.Contains(req.Body, `name="a"`) || req.FormData != nil {
+		t.Errorf("body %q, form_data %v", req.Body, req.FormData)
+	}
+}
+
+func TestApplyFormDataRejects(t *testing.T) {
+	for name, tc := range map[string]struct {
+		req  models.ExecuteRequest
+		want error
+	}{
+		"file field":    {models.ExecuteRequest{FormData: []models.FormField{{Key: "upload", Type: "file", FileName: "a.png"}}}, ErrFormFileNeedsUpload},
+		"unknown type":  {models.ExecuteRequest{FormData: []models.FormField{{Key: "a", Type: "blob"}}}, ErrInvalidBody},
+		"body and form": {models.ExecuteRequest{Body: "raw", FormData: []models.FormField{{Key: "a", Type: "text"}}}, ErrInvalidBody},
+	} {
+		if err := ApplyFormData(&tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// A disabled file field is skipped like any disabled field
+	req := &models.ExecuteRequest{FormData: []models.FormField{{Key: "upload", Type: "file", Disabled: true}, {Key: "a", Value: "1"}}}
+	if err := ApplyFormData(req); err != nil {
+		t.Errorf("disabled file field: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"postmanxodja/models"
+)
+
+var (
+	// ErrIntrospectionDisabled is returned (wrapped) when the endpoint
+	// refuses introspection queries, as many production servers do
+	ErrIntrospectionDisabled = errors.New("introspection is disabled on this endpoint")
+	// ErrGraphQLResponse is returned (wrapped) when the endpoint's answer
+	// isn't an introspection result
+	ErrGraphQLResponse = errors.New("unexpected GraphQL response")
+)
+
+// introspectionQuery is the standard introspection query, less directives
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  fields(includeDeprecated: true) {
+    name
+    description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+  }
+  inputFields { ...InputValue }
+  enumValues(includeDeprecated: true) { name }
+  possibleTypes { name }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+    ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+type introspectionInput struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionNamed struct {
+	Name string `json:"name"`
+}
+
+type introspectionResponse struct {
+	Data *struct {
+		Schema *struct {
+			QueryType        *introspectionNamed `json:"queryType"`
+			MutationType     *introspectionNamed `json:"mutationType"`
+			SubscriptionType *introspectionNamed `json:"subscriptionType"`
+			Types            []struct {
+				Kind        string `json:"kind"`
+				Name        string `json:"name"`
+				Description string `json:"description"`
+				Fields      []struct {
+					Name         string               `json:"name"`
+					Description  string               `json:"description"`
+					Args         []introspectionInput `json:"args"`
+					Type         introspectionTypeRef `json:"type"`
+					IsDeprecated bool                 `json:"isDeprecated"`
+				} `json:"fields"`
+				InputFields   []introspectionInput `json:"inputFields"`
+				EnumValues    []introspectionNamed `json:"enumValues"`
+				PossibleTypes []introspectionNamed `json:"possibleTypes"`
+			} `json:"types"`
+		} `json:"__schema"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// IntrospectGraphQL POSTs the introspection query to req's URL, with req's
+// headers and auth (variables already substituted), and simplifies the
+// schema it gets back. Method and body of req are replaced.
+func IntrospectGraphQL(ctx context.Context, req *models.ExecuteRequest) (*models.GraphQLSchema, *models.ExecuteResponse, error) {
+	body, _ := json.Marshal(map[string]string{"query": introspectionQuery, "operationName": "IntrospectionQuery"})
+	req.Method = http.MethodPost
+	req.Body = string(body)
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	for key := range req.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			delete(req.Headers, key)
+		}
+	}
+	req.Headers["Content-Type"] = "application/json"
+	if !hasHeader(req, "Accept") {
+		req.Headers["Accept"] = "application/json"
+	}
+
+	response, err := ExecuteHTTPRequestContext(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := parseIntrospection(response)
+	return schema, response, err
+}
+
+// parseIntrospection reads an introspection result, telling a server that
+// refuses introspection apart from one that isn't GraphQL at all
+func parseIntrospection(response *models.ExecuteResponse) (*models.GraphQLSchema, error) {
+	var parsed introspectionResponse
+	if err := json.Unmarshal([]byte(response.Body), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: status %d with a body that isn't JSON", ErrGraphQLResponse, response.Status)
+	}
+
+	if parsed.Data == nil || parsed.Data.Schema == nil {
+		messages := make([]string, 0, len(parsed.Errors))
+		for _, gqlErr := range parsed.Errors {
+			messages = append(messages, gqlErr.Message)
+		}
+		message := strings.Join(messages, "; ")
+		lower := strings.ToLower(message)
+		if strings.Contains(lower, "introspection") || strings.Contains(lower, "__schema") {
+			return nil, fmt.Errorf("%w: %s", ErrIntrospectionDisabled, message)
+		}
+		if message == "" {
+			return nil, fmt.Errorf("%w: status %d without a schema", ErrGraphQLResponse, response.Status)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrGraphQLResponse, message)
+	}
+
+	raw := parsed.Data.Schema
+	schema := &models.GraphQLSchema{Types: []models.GraphQLType{}}
+	if raw.QueryType != nil {
+		schema.QueryType = raw.QueryType.Name
+	}
+	if raw.MutationType != nil {
+		schema.MutationType = raw.MutationType.Name
+	}
+	if raw.SubscriptionType != nil {
+		schema.SubscriptionType = raw.SubscriptionType.Name
+	}
+
+	for _, rawType := range raw.Types {
+		if strings.HasPrefix(rawType.Name, "__") {
+			continue
+		}
+		gqlType := models.GraphQLType{Name: rawType.Name, Kind: rawType.Kind, Description: rawType.Description}
+		for _, rawField := range rawType.Fields {
+			gqlType.Fields = append(gqlType.Fields, models.GraphQLField{
+				Name:        rawField.Name,
+				Description: rawField.Description,
+				Type:        rawField.Type.String(),
+				Args:        simplifyInputs(rawField.Args),
+				Deprecated:  rawField.IsDeprecated,
+			})
+		}
+		gqlType.InputFields = simplifyInputs(rawType.InputFields)
+		for _, value := range rawType.EnumValues {
+			gqlType.EnumValues = append(gqlType.EnumValues, value.Name)
+		}
+		for _, possible := range rawType.PossibleTypes {
+			gqlType.PossibleTypes = append(gqlType.PossibleTypes, possible.Name)
+		}
+		schema.Types = append(schema.Types, gqlType)
+	}
+	return schema, nil
+}
+
+func simplifyInputs(inputs []introspectionInput) []models.GraphQLInput {
+	var simplified []models.GraphQLInput
+	for _, input := range inputs {
+		simplified = append(simplified, models.GraphQLInput{
+			Name:         input.Name,
+			Description:  input.Description,
+			Type:         input.Type.String(),
+			DefaultValue: input.DefaultValue,
+		})
+	}
+	return simplified
+}
+
+// String writes the type reference as in SDL: "[String!]" etc.
+func (t introspectionTypeRef) String() string {
+	switch {
+	case t.Kind == "NON_NULL" && t.OfType != nil:
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST" && t.OfType != nil:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/models"
+	"strings"
+	"testing"
+)
+
+// minimalSchema is an introspection result for
+//
+//	type Query { user(id: ID!, limit: Int = 10): User }
+//	type User { id: ID! tags: [String!]! role: Role }
+//	enum Role { ADMIN MEMBER }
+const minimalSchema = `{"data":{"__schema":{
+  "queryType":{"name":"Query"},"mutationType":null,"subscriptionType":null,
+  "types":[
+    {"kind":"OBJECT","name":"Query","fields":[{"name":"user","args":[
+      {"name":"id","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}},"defaultValue":null},
+      {"name":"limit","type":{"kind":"SCALAR","name":"Int","ofType":null},"defaultValue":"10"}],
+      "type":{"kind":"OBJECT","name":"User","ofType":null},"isDeprecated":false}]},
+    {"kind":"OBJECT","name":"User","description":"A person","fields":[
+      {"name":"id","args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}},"isDeprecated":false},
+      {"name":"tags","args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":
+        {"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"String","ofType":null}}}},"isDeprecated":false},
+      {"name":"role","args":[],"type":{"kind":"ENUM","name":"Role","ofType":null},"isDeprecated":true}]},
+    {"kind":"ENUM","name":"Role","fields":null,"enumValues":[{"name":"ADMIN"},{"name":"MEMBER"}]},
+    {"kind":"SCALAR","name":"ID","fields":null},
+    {"kind":"OBJECT","name":"__Schema","fields":[]}
+  ]}}}`
+
+// graphqlServer answers introspection queries with body and status, and
+// records the Authorization header it last received
+func graphqlServer(t *testing.T, status int, body string, authorization *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&payload) != nil ||
+			!strings.Contains(payload.Query, "__schema") {
+			http.Error(w, "expected an introspection query", http.StatusBadRequest)
+			return
+		}
+		*authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIntrospectGraphQL(t *testing.T) {
+	useLoopback(t)
+	var authorization string
+	server := graphqlServer(t, http.StatusOK, minimalSchema, &authorization)
+
+	req := &models.ExecuteRequest{URL: server.URL + "/graphql", Headers: map[string]string{"Authorization": "Bearer {{token}}"}}
+	ReplaceInRequest(req, models.Variables{"token": "secret"})
+	schema, _, err := IntrospectGraphQL(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the substituted header", authorization)
+	}
+
+	if schema.QueryType != "Query" || schema.MutationType != "" {
+		t.Errorf("root types: query %q, mutation %q", schema.QueryType, schema.MutationType)
+	}
+	types := map[string]models.GraphQLType{}
+	for _, gqlType := range schema.Types {
+		types[gqlType.Name] = gqlType
+	}
+	if _, ok := types["__Schema"]; ok || len(types) != 4 {
+		t.Errorf("types = %v, want Query, User, Role and ID only", schema.Types)
+	}
+
+	user := types["Query"].Fields[0]
+	if user.Type != "User" || len(user.Args) != 2 || user.Args[0].Type != "ID!" ||
+		user.Args[1].DefaultValue == nil || *user.Args[1].DefaultValue != "10" {
+		t.Errorf("Query.user = %+v", user)
+	}
+	fields := types["User"].Fields
+	if types["User"].Description != "A person" || len(fields) != 3 ||
+		fields[1].Type != "[String!]!" || !fields[2].Deprecated {
+		t.Errorf("User = %+v", types["User"])
+	}
+	if enum := types["Role"].EnumValues; len(enum) != 2 || enum[0] != "ADMIN" {
+		t.Errorf("Role values = %v", enum)
+	}
+}
+
+func TestIntrospectGraphQLDisabled(t *testing.T) {
+	useLoopback(t)
+	var authorization string
+	server := graphqlServer(t, http.StatusBadRequest,
+		`{"errors":[{"message":"GraphQL introspection is not allowed by Apollo Server, but the query contained __schema or __type."}]}`,
+		&authorization)
+
+	_, response, err := IntrospectGraphQL(context.Background(), &models.ExecuteRequest{URL: server.URL})
+	if !errors.Is(err, ErrIntrospectionDisabled) || response == nil || response.Status != http.StatusBadRequest {
+		t.Errorf("got %v (response %v), want ErrIntrospectionDisabled", err, response)
+	}
+
+	other := graphqlServer(t, http.StatusOK, `{"errors":[{"message":"Unauthorized"}]}`, &authorization)
+	if _, _, err := IntrospectGraphQL(context.Background(), &models.ExecuteRequest{URL: other.URL}); !errors.Is(err, ErrGraphQLResponse) ||
+		!strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("got %v, want ErrGraphQLResponse with the server's message", err)
+	}
+
+	notGraphQL := graphqlServer(t, http.StatusOK, `<html>hello</html>`, &authorization)
+	if _, _, err := IntrospectGraphQL(context.Background(), &models.ExecuteRequest{URL: notGraphQL.URL}); !errors.Is(err, ErrGraphQLResponse) {
+		t.Errorf("got %v, want ErrGraphQLResponse", err)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"strings"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// RecordRequestHistory saves an executed request to the user's history
+func RecordRequestHistory(entry *models.RequestHistory) error {
+	return database.GetDB().Create(entry).Error
+}
+
+// ListRequestHistory returns a page of the user's history matching filter,
+// newest first. Pages are keyed by ID rather than offset, so entries recorded
+// while paging don't shift what the next page returns.
+func ListRequestHistory(userID uint, filter models.HistoryFilter) (*models.HistoryPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	limit = min(limit, maxHistoryLimit)
+
+	query := historyQuery(userID, filter)
+	page := &models.HistoryPage{Entries: []models.RequestHistory{}}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	// One extra row tells whether there's another page
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&page.Entries).Error; err != nil {
+		return nil, err
+	}
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		next := page.Entries[limit-1].ID
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+// historyQuery applies every filter except the cursor
+func historyQuery(userID uint, filter models.HistoryFilter) *gorm.DB {
+	query := database.GetDB().Model(&models.RequestHistory{}).Where("user_id = ?", userID)
+	if len(filter.Methods) > 0 {
+		methods := make([]string, len(filter.Methods))
+		for i, method := range filter.Methods {
+			methods[i] = strings.ToUpper(strings.TrimSpace(method))
+		}
+		query = query.Where("method IN ?", methods)
+	}
+	if filter.StatusMin > 0 {
+		query = query.Where("status >= ?", filter.StatusMin)
+	}
+	if filter.StatusMax > 0 {
+		query = query.Where("status <= ?", filter.StatusMax)
+	}
+	if filter.URL != "" {
+		query = query.Where("LOWER(url) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.URL))+"%")
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// escapeLike makes % and _ in s match literally in a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ClearRequestHistory deletes all of the user's history and returns how many
+// entries went
+func ClearRequestHistory(userID uint) (int64, error) {
+	result := database.GetDB().Where("user_id = ?", userID).Delete(&models.RequestHistory{})
+	return result.RowsAffected, result.Error
+}
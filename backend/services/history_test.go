@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// seedHistory records entries for user a day apart, oldest first, starting
+// at start
+func seedHistory(t *testing.T, userID uint, start time.Time, entries []models.RequestHistory) {
+	t.Helper()
+	for i := range entries {
+		entries[i].UserID = userID
+		entries[i].CreatedAt = start.AddDate(0, 0, i)
+		if err := RecordRequestHistory(&entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListRequestHistoryFilters(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "ada@example.com")
+	other := createTestUser(t, "bob@example.com")
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	seedHistory(t, user.ID, start, []models.RequestHistory{
+		{Method: "GET", URL: "https://api.example.com/orders", Status: 200},
+		{Method: "POST", URL: "https://api.example.com/orders", Status: 201},
+		{Method: "GET", URL: "https://api.example.com/users/7", Status: 404},
+		{Method: "DELETE", URL: "https://API.example.com/Orders/1", Status: 500},
+		{Method: "GET", URL: "https://api.example.com/100%_off", Status: 0, Error: "connection refused"},
+	})
+	seedHistory(t, other.ID, start, []models.RequestHistory{{Method: "GET", URL: "https://api.example.com/orders", Status: 200}})
+
+	from := start.AddDate(0, 0, 1)
+	to := start.AddDate(0, 0, 3)
+	tests := []struct {
+		name   string
+		filter models.HistoryFilter
+		want   int64
+	}{
+		{"no filter", models.HistoryFilter{}, 5},
+		{"method", models.HistoryFilter{Methods: []string{"get"}}, 3},
+		{"methods", models.HistoryFilter{Methods: []string{"POST", " delete"}}, 2},
+		{"status range", models.HistoryFilter{StatusMin: 400, StatusMax: 599}, 2},
+		{"status min", models.HistoryFilter{StatusMin: 201}, 3},
+		{"url substring", models.HistoryFilter{URL: "orders"}, 3},
+		{"url wildcards are literal", models.HistoryFilter{URL: "%_off"}, 1},
+		{"date range", models.HistoryFilter{From: &from, To: &to}, 2},
+		{"combined", models.HistoryFilter{Methods: []string{"GET"}, URL: "example", StatusMin: 200, StatusMax: 299}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := ListRequestHistory(user.ID, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != tt.want || int64(len(page.Entries)) != tt.want {
+				t.Errorf("Expected %d entries, got total %d with %d entries", tt.want, page.Total, len(page.Entries))
+			}
+			for _, entry := range page.Entries {
+				if entry.UserID != user.ID {
+					t.Errorf("Expected only the user's own history, got an entry of user %d", entry.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestListRequestHistoryCursor(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "ada@example.com")
+	entries := make([]models.RequestHistory, 5)
+	for i := range entries {
+		entries[i] = models.RequestHistory{Method: "GET", URL: "https://api.example.com", Status: 200}
+	}
+	seedHistory(t, user.ID, time.Now().AddDate(0, 0, -10), entries)
+
+	var seen []uint
+	filter := models.HistoryFilter{Limit: 2}
+	for pages := 0; pages < 5; pages++ {
+		page, err := ListRequestHistory(user.ID, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 {
+			t.Errorf("Expected the total to cover every page, got %d", page.Total)
+		}
+		for _, entry := range page.Entries {
+			seen = append(seen, entry.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		filter.Cursor = *page.NextCursor
+	}
+
+	if len(seen) != 5 {
+		t.Fatalf("Expected 5 entries over all pages, got %v", seen)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i] >= seen[i-1] {
+			t.Fatalf("Expected newest first without repeats, got %v", seen)
+		}
+	}
+}
+
+func TestClearRequestHistory(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "ada@example.com")
+	other := createTestUser(t, "bob@example.com")
+	seedHistory(t, user.ID, time.Now(), []models.RequestHistory{{Method: "GET"}, {Method: "POST"}})
+	seedHistory(t, other.ID, time.Now(), []models.RequestHistory{{Method: "GET"}})
+
+	deleted, err := ClearRequestHistory(user.ID)
+	if err != nil || deleted != 2 {
+		t.Fatalf("Expected 2 entries deleted, got %d (%v)", deleted, err)
+	}
+	var remaining int64
+	database.DB.Model(&models.RequestHistory{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected the other user's history to stay, got %d entries", remaining)
+	}
+}
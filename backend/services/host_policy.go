@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"github.com/Azure/go-ntlmssp"
+)
+
+// ErrHostNotAllowed is returned when a team's host policy blocks a request
+var ErrHostNotAllowed = errors.New("host not allowed by team policy")
+
+// NormalizeHostPatterns trims and lowercases host patterns, dropping blanks
+// and duplicates, and rejects malformed globs. A CIDR range such as
+// "10.0.0.0/8" is a pattern too, matching the addresses in it.
+func NormalizeHostPatterns(patterns []string) ([]string, error) {
+	seen := make(map[string]bool, len(patterns))
+	normalized := []string{}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if _, network, err := net.ParseCIDR(pattern); err == nil {
+			pattern = network.String()
+		} else if strings.ContainsAny(pattern, ",/:") {
+			return nil, fmt.Errorf("invalid host pattern %q: use a host name only, without scheme, port or path", pattern)
+		}
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %v", pattern, err)
+		}
+		seen[pattern] = true
+		normalized = append(normalized, pattern)
+	}
+	return normalized, nil
+}
+
+// MatchHostPattern reports whether host matches a glob pattern. "*" matches
+// any run of characters, dots included, so "*.example.com" covers every
+// subdomain of example.com (but not example.com itself). A CIDR pattern
+// matches IP addresses in its range.
+func MatchHostPattern(pattern, host string) bool {
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(strings.Trim(host, "[]"))
+		return ip != nil && network.Contains(ip)
+	}
+	matched, err := path.Match(pattern, strings.ToLower(host))
+	return err == nil && matched
+}
+
+// HostAllowed applies a policy to a host name
+func HostAllowed(policy *models.TeamHostPolicy, host string) bool {
+	if policy == nil {
+		return true
+	}
+	for _, pattern := range policy.DenyList() {
+		if MatchHostPattern(pattern, host) {
+			return false
+		}
+	}
+	allow := policy.AllowList()
+	if len(allow) == 0 {
+		return true
+	}
+	for _, pattern := range allow {
+		if MatchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestHost returns the host name a request URL targets, lowercased and
+// without a trailing dot
+func RequestHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+// loadHostPolicy returns the team's host policy, nil when it has none
+func loadHostPolicy(teamID uint) (*models.TeamHostPolicy, error) {
+	if teamID == 0 {
+		return nil, nil
+	}
+	var policy models.TeamHostPolicy
+	if err := database.GetDB().Where("team_id = ?", teamID).Limit(1).Find(&policy).Error; err != nil {
+		return nil, err
+	}
+	if policy.ID == 0 {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// CheckHostPolicy returns ErrHostNotAllowed when the team's host policy
+// blocks rawURL. Teams without a policy allow everything.
+func CheckHostPolicy(teamID uint, rawURL string) error {
+	policy, err := loadHostPolicy(teamID)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	host := RequestHost(rawURL)
+	if !HostAllowed(policy, host) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+type hostPolicyKey struct{}
+
+// WithHostPolicy returns a context whose requests HostPolicyClient holds to
+// teamID's host policy
+func WithHostPolicy(ctx context.Context, teamID uint) context.Context {
+	return context.WithValue(ctx, hostPolicyKey{}, teamID)
+}
+
+// maxRedirects matches net/http's default limit
+const maxRedirects = 10
+
+// HostPolicyClient returns client with the host policy of ctx's team (see
+// WithHostPolicy) applied past the first URL: every redirect is checked
+// again, and the addresses a host name resolves to are checked against the
+// deny patterns before dialing, so a name can't lead to a denied IP or
+// range. The client is returned as it is when no policy applies.
+func HostPolicyClient(ctx context.Context, client *http.Client) (*http.Client, error) {
+	teamID, _ := ctx.Value(hostPolicyKey{}).(uint)
+	policy, err := loadHostPolicy(teamID)
+	if err != nil || policy == nil {
+		return client, err
+	}
+
+	guarded := *client
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if host := RequestHost(req.URL.String()); !HostAllowed(policy, host) {
+			return fmt.Errorf("%w: redirect to %s", ErrHostNotAllowed, host)
+		}
+		return nil
+	}
+	guarded.Transport = hostPolicyTransport(client.Transport, policy)
+	return &guarded, nil
+}
+
+// hostPolicyTransport returns a copy of transport that only dials addresses
+// the policy doesn't deny
+func hostPolicyTransport(transport http.RoundTripper, policy *models.TeamHostPolicy) http.RoundTripper {
+	switch t := transport.(type) {
+	case nil:
+		return hostPolicyTransport(http.DefaultTransport, policy)
+	case ntlmssp.Negotiator:
+		t.RoundTripper = hostPolicyTransport(t.RoundTripper, policy)
+		return t
+	case *http.Transport:
+		guarded := t.Clone()
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		guarded.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			// Dial the checked address itself, so a second lookup can't
+			// return a different one
+			var lastErr error = fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+			for _, addr := range addrs {
+				if ipDenied(policy, addr.IP) {
+					continue
+				}
+				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+				if err == nil {
+					return conn, nil
+				}
+				lastErr = err
+			}
+			return nil, lastErr
+		}
+		return guarded
+	}
+	return transport
+}
+
+// ipDenied reports whether any deny pattern matches ip. Allow patterns are
+// about host names, so an address needn't match one.
+func ipDenied(policy *models.TeamHostPolicy, ip net.IP) bool {
+	for _, pattern := range policy.DenyList() {
+		if MatchHostPattern(pattern, ip.String()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestMatchHostPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"*.ourcompany.com", "api.ourcompany.com", true},
+		{"*.ourcompany.com", "a.b.ourcompany.com", true},
+		{"*.ourcompany.com", "ourcompany.com", false},
+		{"*.ourcompany.com", "evilourcompany.com", false},
+		{"*.ourcompany.com", "API.OurCompany.com", true},
+		{"api-?.example.com", "api-1.example.com", true},
+		{"example.com", "example.com", true},
+		{"example.com", "example.org", false},
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/8", "11.1.2.3", false},
+		{"10.0.0.0/8", "ten.example.com", false},
+		{"fd00::/8", "fd12::1", true},
+	}
+	for _, tc := range cases {
+		if got := MatchHostPattern(tc.pattern, tc.host); got != tc.want {
+			t.Errorf("MatchHostPattern(%q, %q) = %v, expected %v", tc.pattern, tc.host, got, tc.want)
+		}
+	}
+}
+
+func TestHostAllowedAllowList(t *testing.T) {
+	policy := &models.TeamHostPolicy{Allow: "*.ourcompany.com,localhost"}
+
+	if !HostAllowed(policy, "api.ourcompany.com") {
+		t.Error("Expected an allowed subdomain to pass")
+	}
+	if !HostAllowed(policy, "localhost") {
+		t.Error("Expected localhost to pass")
+	}
+	if HostAllowed(policy, "example.com") {
+		t.Error("Expected a host outside the allow list to be blocked")
+	}
+}
+
+func TestHostAllowedDenyWins(t *testing.T) {
+	policy := &models.TeamHostPolicy{Allow: "*.ourcompany.com", Deny: "admin.ourcompany.com"}
+
+	if HostAllowed(policy, "admin.ourcompany.com") {
+		t.Error("Expected a denied host to be blocked even though it's allowed")
+	}
+	if !HostAllowed(policy, "api.ourcompany.com") {
+		t.Error("Expected other allowed hosts to pass")
+	}
+}
+
+func TestHostAllowedDenyOnly(t *testing.T) {
+	policy := &models.TeamHostPolicy{Deny: "*.internal"}
+
+	if HostAllowed(policy, "db.internal") {
+		t.Error("Expected a denied host to be blocked")
+	}
+	if !HostAllowed(policy, "example.com") {
+		t.Error("Expected hosts that aren't denied to pass without an allow list")
+	}
+	if !HostAllowed(nil, "db.internal") {
+		t.Error("Expected every host to pass without a policy")
+	}
+}
+
+func TestNormalizeHostPatterns(t *testing.T) {
+	patterns, err := NormalizeHostPatterns([]string{" *.Example.com ", "", "*.example.com", "api.test"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(patterns) != 2 || patterns[0] != "*.example.com" || patterns[1] != "api.test" {
+		t.Errorf("Expected [*.example.com api.test], got %v", patterns)
+	}
+
+	if patterns, err := NormalizeHostPatterns([]string{"10.1.0.0/8", "fd00::/8"}); err != nil || len(patterns) != 2 || patterns[0] != "10.0.0.0/8" {
+		t.Errorf("Expected CIDR ranges to be kept in canonical form, got %v (%v)", patterns, err)
+	}
+
+	for _, bad := range []string{"https://example.com", "example.com:8080", "[a-", "a,b", "10.0.0.0/33"} {
+		if _, err := NormalizeHostPatterns([]string{bad}); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRequestHost(t *testing.T) {
+	if host := RequestHost("https://API.Example.com.:8443/v1?x=1"); host != "api.example.com" {
+		t.Errorf("Expected 'api.example.com', got '%s'", host)
+	}
+}
+
+// useHostPolicy saves a host policy for team 1 and returns a context that
+// executes on its behalf
+func useHostPolicy(t *testing.T, deny string) context.Context {
+	t.Helper()
+	useTestDB(t)
+	if err := database.DB.Create(&models.TeamHostPolicy{TeamID: 1, Deny: deny}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return WithHostPolicy(context.Background(), 1)
+}
+
+func TestHostPolicyRechecksRedirects(t *testing.T) {
+	useLoopback(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+targetURL.Port()+"/", http.StatusFound)
+	}))
+	defer redirector.Close()
+	ctx := useHostPolicy(t, "localhost")
+
+	// The first hop, 127.0.0.1, is allowed; the redirect to localhost isn't
+	_, err := ExecuteHTTPRequestContext(ctx, &models.ExecuteRequest{Method: "GET", URL: redirector.URL})
+	if !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("Expected the redirect to be blocked, got %v", err)
+	}
+
+	resp, err := ExecuteHTTPRequestContext(context.Background(), &models.ExecuteRequest{Method: "GET", URL: redirector.URL})
+	if err != nil || resp.Body != "internal" {
+		t.Errorf("Expected the redirect to be followed without a policy, got %v, %v", resp, err)
+	}
+}
+
+func TestHostPolicyChecksResolvedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	ctx := useHostPolicy(t, "127.0.0.0/8,::1/128")
+
+	// The name passes the deny list, the loopback address it resolves to
+	// doesn't. The client is used directly so localhost isn't rewritten.
+	client, err := HostPolicyClient(ctx, &http.Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get("http://localhost:" + serverURL.Port() + "/"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("Expected the resolved address to be blocked, got %v", err)
+	}
+
+	open, _ := HostPolicyClient(context.Background(), &http.Client{})
+	if resp, err := open.Get("http://localhost:" + serverURL.Port() + "/"); err != nil {
+		t.Errorf("Expected localhost to be reachable without a policy, got %v", err)
+	} else {
+		resp.Body.Close()
+	}
+}
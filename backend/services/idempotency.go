@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"postmanxodja/config"
+	"sync"
+	"time"
+)
+
+// Idempotency keys let public API clients retry writes safely: the first
+// response for a (team, key) pair is stored and replayed for retries until it
+// expires. State is in memory, like the login guard, so it resets on restart
+// and isn't shared between instances.
+
+var (
+	// ErrIdempotencyInProgress means a request with the same key hasn't
+	// finished yet
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrIdempotencyKeyReused means the key was already used for a different
+	// request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// IdempotentResponse is a stored response to replay
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	response    *IdempotentResponse // nil while the first request is running
+	expiresAt   time.Time
+}
+
+var (
+	idempotencyMu    sync.Mutex
+	idempotencyStore = make(map[string]*idempotencyEntry)
+)
+
+func idempotencyStoreKey(teamID uint, key string) string {
+	return fmt.Sprintf("%d:%s", teamID, key)
+}
+
+func idempotencyTTL() time.Duration {
+	hours := 24
+	if config.AppConfig != nil && config.AppConfig.IdempotencyTTLHours > 0 {
+		hours = config.AppConfig.IdempotencyTTLHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// BeginIdempotentRequest claims key for the team. It returns the stored
+// response when the key was already used for the same request (fingerprint),
+// or nil when the caller should go ahead and then call
+// CompleteIdempotentRequest or ReleaseIdempotentRequest.
+func BeginIdempotentRequest(teamID uint, key, fingerprint string) (*IdempotentResponse, error) {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	now := time.Now()
+	for k, entry := range idempotencyStore {
+		if now.After(entry.expiresAt) {
+			delete(idempotencyStore, k)
+		}
+	}
+
+	storeKey := idempotencyStoreKey(teamID, key)
+	if entry, ok := idempotencyStore[storeKey]; ok {
+		if entry.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if entry.response == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		return entry.response, nil
+	}
+
+	idempotencyStore[storeKey] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(idempotencyTTL()),
+	}
+	return nil, nil
+}
+
+// CompleteIdempotentRequest stores the response for replay. Server errors
+// aren't stored, so the client can retry them.
+func CompleteIdempotentRequest(teamID uint, key string, response IdempotentResponse) {
+	if response.Status >= http.StatusInternalServerError {
+		ReleaseIdempotentRequest(teamID, key)
+		return
+	}
+
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	if entry, ok := idempotencyStore[idempotencyStoreKey(teamID, key)]; ok {
+		entry.response = &response
+		entry.expiresAt = time.Now().Add(idempotencyTTL())
+	}
+}
+
+// ReleaseIdempotentRequest forgets a claimed key without storing a response
+func ReleaseIdempotentRequest(teamID uint, key string) {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	delete(idempotencyStore, idempotencyStoreKey(teamID, key))
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+// Statuses a team's invites are listed by. Expired isn't stored: it's a
+// pending invite past its expiry, which pending includes as it always has.
+const (
+	InviteStatusPending   = "pending"
+	InviteStatusAccepted  = "accepted"
+	InviteStatusDeclined  = "declined"
+	InviteStatusCancelled = "cancelled"
+	InviteStatusExpired   = "expired"
+	InviteStatusAll       = "all"
+)
+
+const (
+	defaultInviteLimit = 50
+	maxInviteLimit     = 200
+)
+
+// IsValidInviteStatus reports whether status is one invites can be listed
+// by. An empty status means InviteStatusPending.
+func IsValidInviteStatus(status string) bool {
+	switch status {
+	case "", InviteStatusPending, InviteStatusAccepted, InviteStatusDeclined, InviteStatusCancelled,
+		InviteStatusExpired, InviteStatusAll:
+		return true
+	}
+	return false
+}
+
+// ListTeamInvites returns a page of the team's invites matching filter,
+// newest first, with their inviter and (if they have an account) the
+// invitee's user ID
+func ListTeamInvites(teamID uint, filter models.InviteFilter, now time.Time) (*models.InvitePage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultInviteLimit
+	}
+	limit = min(limit, maxInviteLimit)
+
+	query := database.GetDB().Model(&models.TeamInvite{}).Where("team_id = ?", teamID)
+	switch filter.Status {
+	case "", InviteStatusPending:
+		query = query.Where("status = ?", InviteStatusPending)
+	case InviteStatusExpired:
+		query = query.Where("status = ? AND expires_at <= ?", InviteStatusPending, now)
+	case InviteStatusAll:
+	default:
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	page := &models.InvitePage{Invites: []models.TeamInvite{}}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	// One extra row tells whether there's another page
+	if err := query.Preload("Inviter").Order("id DESC").Limit(limit + 1).Find(&page.Invites).Error; err != nil {
+		return nil, err
+	}
+	if len(page.Invites) > limit {
+		page.Invites = page.Invites[:limit]
+		next := page.Invites[limit-1].ID
+		page.NextCursor = &next
+	}
+
+	emails := make([]string, 0, len(page.Invites))
+	for _, invite := range page.Invites {
+		emails = append(emails, invite.InviteeEmail)
+	}
+	var invitees []struct {
+		ID    uint
+		Email string
+	}
+	if len(emails) > 0 {
+		if err := database.GetDB().Model(&models.User{}).Select("id, email").Where("email IN ?", emails).Find(&invitees).Error; err != nil {
+			return nil, err
+		}
+	}
+	byEmail := make(map[string]uint, len(invitees))
+	for _, invitee := range invitees {
+		byEmail[invitee.Email] = invitee.ID
+	}
+	for i := range page.Invites {
+		if id, ok := byEmail[page.Invites[i].InviteeEmail]; ok {
+			page.Invites[i].InviteeUserID = &id
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"testing"
+	"time"
+)
+
+func TestListTeamInvitesByStatus(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	owner := models.User{Email: "owner@example.com", Name: "Owner"}
+	invitee := models.User{Email: "ada@example.com", Name: "Ada"}
+	database.DB.Create(&owner)
+	database.DB.Create(&invitee)
+	team := models.Team{Name: "Team"}
+	database.DB.Create(&team)
+
+	week := now.Add(7 * 24 * time.Hour)
+	for i, invite := range []models.TeamInvite{
+		{InviteeEmail: "ada@example.com", Status: "pending", ExpiresAt: week},
+		{InviteeEmail: "bob@example.com", Status: "pending", ExpiresAt: now.Add(-time.Hour)},
+		{InviteeEmail: "cy@example.com", Status: "accepted", ExpiresAt: week},
+		{InviteeEmail: "di@example.com", Status: "declined", ExpiresAt: week},
+		{InviteeEmail: "ed@example.com", Status: "pending", ExpiresAt: week},
+		{InviteeEmail: "fay@example.com", Status: "cancelled", ExpiresAt: week},
+	} {
+		invite.TeamID, invite.InviterID, invite.Token = team.ID, owner.ID, "token-"+string(rune('a'+i))
+		database.DB.Create(&invite)
+	}
+	// Another team's invite is never listed
+	database.DB.Create(&models.TeamInvite{TeamID: team.ID + 1, InviterID: owner.ID, InviteeEmail: "x@example.com", Status: "pending", Token: "other", ExpiresAt: week})
+
+	emails := func(status string) []string {
+		t.Helper()
+		page, err := ListTeamInvites(team.ID, models.InviteFilter{Status: status}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != int64(len(page.Invites)) {
+			t.Errorf("%s: total %d for %d invites", status, page.Total, len(page.Invites))
+		}
+		var got []string
+		for _, invite := range page.Invites {
+			got = append(got, invite.InviteeEmail+":"+invite.Status)
+		}
+		return got
+	}
+	for status, want := range map[string][]string{
+		"":          {"ed@example.com:pending", "bob@example.com:pending", "ada@example.com:pending"},
+		"pending":   {"ed@example.com:pending", "bob@example.com:pending", "ada@example.com:pending"},
+		"expired":   {"bob@example.com:pending"},
+		"accepted":  {"cy@example.com:accepted"},
+		"declined":  {"di@example.com:declined"},
+		"cancelled": {"fay@example.com:cancelled"},
+		"all": {"fay@example.com:cancelled", "ed@example.com:pending", "di@example.com:declined",
+			"cy@example.com:accepted", "bob@example.com:pending", "ada@example.com:pending"},
+	} {
+		got := emails(status)
+		if len(got) != len(want) {
+			t.Errorf("%q: got %v, want %v", status, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%q: got %v, want %v", status, got, want)
+				break
+			}
+		}
+	}
+
+	page, err := ListTeamInvites(team.ID, models.InviteFilter{Status: "pending"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada := page.Invites[2]
+	if ada.Inviter == nil || ada.Inviter.Email != "owner@example.com" || ada.InviteeUserID == nil || *ada.InviteeUserID != invitee.ID {
+		t.Errorf("Expected the inviter and the invitee's account, got %+v / %v", ada.Inviter, ada.InviteeUserID)
+	}
+	if page.Invites[0].InviteeUserID != nil {
+		t.Errorf("Expected no account for an invitee who hasn't signed up, got %v", *page.Invites[0].InviteeUserID)
+	}
+}
+
+func TestListTeamInvitesPages(t *testing.T) {
+	useTestDB(t)
+	owner := models.User{Email: "owner@example.com", Name: "Owner"}
+	database.DB.Create(&owner)
+	team := models.Team{Name: "Team"}
+	database.DB.Create(&team)
+	week := time.Now().Add(7 * 24 * time.Hour)
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		invite := models.TeamInvite{TeamID: team.ID, InviterID: owner.ID, InviteeEmail: "p@example.com",
+			Status: "accepted", Token: "page-" + string(rune('a'+i)), ExpiresAt: week}
+		if err := database.DB.Create(&invite).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, invite.ID)
+	}
+
+	var seen []uint
+	filter := models.InviteFilter{Status: "all", Limit: 2}
+	for pages := 0; pages < 5; pages++ {
+		page, err := ListTeamInvites(team.ID, filter, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 {
+			t.Errorf("total = %d, want 5", page.Total)
+		}
+		for _, invite := range page.Invites {
+			seen = append(seen, invite.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		filter.Cursor = *page.NextCursor
+	}
+	if len(seen) != 5 || seen[0] != ids[4] || seen[4] != ids[0] {
+		t.Errorf("Expected invites %v newest first across pages, got %v", ids, seen)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Latency sketches estimate quantiles to within 2% of the true value
+const latencySketchAccuracy = 0.02
+
+const (
+	defaultTimingDays = 30
+	maxTimingDays     = 365
+)
+
+var (
+	latencyGamma    = (1 + latencySketchAccuracy) / (1 - latencySketchAccuracy)
+	latencyLogGamma = math.Log(latencyGamma)
+)
+
+// addLatency counts one latency of ms milliseconds in sketch
+func addLatency(sketch *models.LatencySketch, ms int64) {
+	if ms <= 0 {
+		sketch.Zero++
+		return
+	}
+	if sketch.Buckets == nil {
+		sketch.Buckets = make(map[int]int64)
+	}
+	sketch.Buckets[int(math.Ceil(math.Log(float64(ms))/latencyLogGamma))]++
+}
+
+// mergeSketch adds the counts of from to into
+func mergeSketch(into *models.LatencySketch, from models.LatencySketch) {
+	into.Zero += from.Zero
+	if into.Buckets == nil {
+		into.Buckets = make(map[int]int64, len(from.Buckets))
+	}
+	for bucket, count := range from.Buckets {
+		into.Buckets[bucket] += count
+	}
+}
+
+// sketchQuantile estimates the q-quantile (0 <= q <= 1) of the latencies in
+// sketch, or returns 0 for an empty one
+func sketchQuantile(sketch models.LatencySketch, q float64) float64 {
+	total := sketch.Zero
+	buckets := make([]int, 0, len(sketch.Buckets))
+	for bucket, count := range sketch.Buckets {
+		total += count
+		buckets = append(buckets, bucket)
+	}
+	if total == 0 {
+		return 0
+	}
+	sort.Ints(buckets)
+
+	rank := q * float64(total-1)
+	seen := sketch.Zero
+	if float64(seen) > rank {
+		return 0
+	}
+	for _, bucket := range buckets {
+		seen += sketch.Buckets[bucket]
+		if float64(seen) > rank {
+			// The value with the same relative error to both bucket bounds
+			return 2 * math.Pow(latencyGamma, float64(bucket)) / (latencyGamma + 1)
+		}
+	}
+	return 2 * math.Pow(latencyGamma, float64(buckets[len(buckets)-1])) / (latencyGamma + 1)
+}
+
+// RecordItemTiming adds an execution of the collection item at itemPath,
+// which took ms milliseconds, to the rollup for the day of at (UTC)
+func RecordItemTiming(teamID, collectionID uint, itemPath []string, ms int64, at time.Time) error {
+	path, err := json.Marshal(itemPath)
+	if err != nil {
+		return err
+	}
+	day := at.UTC().Format("2006-01-02")
+
+	record := func() error {
+		return database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var timing models.CollectionItemTiming
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("collection_id = ? AND day = ? AND item_path = ?", collectionID, day, string(path)).
+				Limit(1).Find(&timing).Error; err != nil {
+				return err
+			}
+			if timing.ID == 0 {
+				timing = models.CollectionItemTiming{TeamID: teamID, CollectionID: collectionID, ItemPath: string(path), Day: day, MinMs: ms}
+			}
+			timing.Count++
+			timing.SumMs += ms
+			timing.MinMs = min(timing.MinMs, ms)
+			timing.MaxMs = max(timing.MaxMs, ms)
+			addLatency(&timing.Sketch, ms)
+			return tx.Save(&timing).Error
+		})
+	}
+	if err := record(); err != nil {
+		// Another execution may have created the day's row first; now it's there
+		return record()
+	}
+	return nil
+}
+
+// ItemTimings returns the rollups of a collection's items for the last days
+// days (UTC, today included), one entry per item in path order. With
+// itemPath set only that item's timings are returned.
+func ItemTimings(collectionID uint, itemPath []string, days int, now time.Time) ([]models.ItemTimings, error) {
+	if days <= 0 {
+		days = defaultTimingDays
+	}
+	days = min(days, maxTimingDays)
+	since := now.UTC().AddDate(0, 0, 1-days).Format("2006-01-02")
+
+	query := database.GetDB().Where("collection_id = ? AND day >= ?", collectionID, since)
+	if len(itemPath) > 0 {
+		path, _ := json.Marshal(itemPath)
+		query = query.Where("item_path = ?", string(path))
+	}
+	var rows []models.CollectionItemTiming
+	if err := query.Order("item_path, day").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := []models.ItemTimings{}
+	var current *models.ItemTimings
+	var currentPath string
+	var sketch models.LatencySketch
+	var sum int64
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.MeanMs = float64(sum) / float64(current.Count)
+		current.P50Ms = sketchQuantile(sketch, 0.5)
+		current.P95Ms = sketchQuantile(sketch, 0.95)
+		result = append(result, *current)
+	}
+
+	for _, row := range rows {
+		if current == nil || row.ItemPath != currentPath {
+			finish()
+			current = &models.ItemTimings{MinMs: row.MinMs, Days: []models.ItemTimingDay{}}
+			json.Unmarshal([]byte(row.ItemPath), &current.ItemPath)
+			currentPath = row.ItemPath
+			sketch = models.LatencySketch{}
+			sum = 0
+		}
+		current.Count += row.Count
+		current.MinMs = min(current.MinMs, row.MinMs)
+		current.MaxMs = max(current.MaxMs, row.MaxMs)
+		sum += row.SumMs
+		mergeSketch(&sketch, row.Sketch)
+		current.Days = append(current.Days, models.ItemTimingDay{
+			Day:    row.Day,
+			Count:  row.Count,
+			MinMs:  row.MinMs,
+			MaxMs:  row.MaxMs,
+			MeanMs: float64(row.SumMs) / float64(row.Count),
+			P50Ms:  sketchQuantile(row.Sketch, 0.5),
+			P95Ms:  sketchQuantile(row.Sketch, 0.95),
+		})
+	}
+	finish()
+	return result, nil
+}
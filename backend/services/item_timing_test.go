@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"postmanxodja/models"
+	"testing"
+	"time"
+)
+
+// within reports whether got is within tolerance (relative) of want
+func within(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= want*tolerance
+}
+
+func TestSketchQuantiles(t *testing.T) {
+	var sketch models.LatencySketch
+	latencies := rand.New(rand.NewSource(1)).Perm(1000)
+	for _, ms := range latencies {
+		addLatency(&sketch, int64(ms+1)) // 1..1000 ms in random order
+	}
+	if p50 := sketchQuantile(sketch, 0.5); !within(p50, 500, 0.03) {
+		t.Errorf("p50 = %.1f, want about 500", p50)
+	}
+	if p95 := sketchQuantile(sketch, 0.95); !within(p95, 950, 0.03) {
+		t.Errorf("p95 = %.1f, want about 950", p95)
+	}
+
+	// A mostly fast endpoint with a slow tail
+	var skewed models.LatencySketch
+	for i := 0; i < 90; i++ {
+		addLatency(&skewed, 10)
+	}
+	for i := 0; i < 10; i++ {
+		addLatency(&skewed, 2000)
+	}
+	if p50 := sketchQuantile(skewed, 0.5); !within(p50, 10, 0.03) {
+		t.Errorf("skewed p50 = %.1f, want about 10", p50)
+	}
+	if p95 := sketchQuantile(skewed, 0.95); !within(p95, 2000, 0.03) {
+		t.Errorf("skewed p95 = %.1f, want about 2000", p95)
+	}
+
+	if q := sketchQuantile(models.LatencySketch{}, 0.5); q != 0 {
+		t.Errorf("empty sketch p50 = %v", q)
+	}
+	zeros := models.LatencySketch{}
+	addLatency(&zeros, 0)
+	if q := sketchQuantile(zeros, 0.95); q != 0 {
+		t.Errorf("0 ms sketch p95 = %v", q)
+	}
+}
+
+func TestItemTimingsRollup(t *testing.T) {
+	useTestDB(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	list := []string{"Users", "List"}
+	for ms := int64(1); ms <= 100; ms++ {
+		if err := RecordItemTiming(1, 7, list, ms, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	RecordItemTiming(1, 7, list, 400, now.AddDate(0, 0, -1))
+	RecordItemTiming(1, 7, list, 999, now.AddDate(0, 0, -40)) // outside the default 30 days
+	RecordItemTiming(1, 7, []string{"Users", "Create"}, 50, now)
+	RecordItemTiming(1, 8, list, 5, now) // another collection
+
+	timings, err := ItemTimings(7, nil, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timings) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(timings), timings)
+	}
+	listTimings := timings[1]
+	if listTimings.ItemPath[1] != "List" {
+		listTimings = timings[0]
+	}
+	if listTimings.Count != 101 || listTimings.MinMs != 1 || listTimings.MaxMs != 400 || len(listTimings.Days) != 2 {
+		t.Errorf("List over 30 days = %+v", listTimings)
+	}
+	today := listTimings.Days[1]
+	if today.Day != "2026-03-10" || today.Count != 100 || today.MeanMs != 50.5 ||
+		!within(today.P50Ms, 50, 0.05) || !within(today.P95Ms, 95, 0.05) {
+		t.Errorf("today = %+v", today)
+	}
+
+	// One item, one day
+	timings, err = ItemTimings(7, list, 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timings) != 1 || timings[0].Count != 100 || len(timings[0].Days) != 1 {
+		t.Errorf("List today only = %+v", timings)
+	}
+}
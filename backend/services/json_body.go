@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"postmanxodja/models"
+)
+
+// JSON body formats for ExecuteRequest.JSONBodyFormat
+const (
+	JSONBodyMinify = "minify"
+	JSONBodyPretty = "pretty"
+)
+
+// JSONBodyError is a JSON body that doesn't parse. Line and Column (both
+// 1-based) point at the byte where parsing failed.
+type JSONBodyError struct {
+	Line   int
+	Column int
+	Offset int64
+	Err    error
+}
+
+func (e *JSONBodyError) Error() string {
+	return fmt.Sprintf("body is not valid JSON at line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *JSONBodyError) Unwrap() error { return e.Err }
+
+// isJSONMediaType reports whether a Content-Type value is JSON, including
+// the +json types such as application/problem+json
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// requestContentType returns the Content-Type req will be sent with, from
+// HeaderList when it's set (it takes the place of Headers) and Headers
+// otherwise
+func requestContentType(req *models.ExecuteRequest) string {
+	if len(req.HeaderList) > 0 {
+		for _, header := range req.HeaderList {
+			if strings.EqualFold(header.Key, "Content-Type") {
+				return header.Value
+			}
+		}
+		return ""
+	}
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			return value
+		}
+	}
+	return ""
+}
+
+// PrepareJSONBody checks and reformats a JSON body as asked by
+// ValidateJSONBody and JSONBodyFormat. It runs after variables are
+// substituted, and only when the request's Content-Type is JSON and the body
+// is text; other bodies are left alone. Formatting implies validation.
+func PrepareJSONBody(req *models.ExecuteRequest) error {
+	switch req.JSONBodyFormat {
+	case "", JSONBodyMinify, JSONBodyPretty:
+	default:
+		return fmt.Errorf("%w: json_body_format must be minify or pretty", ErrInvalidBody)
+	}
+	if !req.ValidateJSONBody && req.JSONBodyFormat == "" {
+		return nil
+	}
+	if strings.TrimSpace(req.Body) == "" || strings.EqualFold(req.BodyEncoding, "base64") ||
+		!isJSONMediaType(requestContentType(req)) {
+		return nil
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(req.Body), &parsed); err != nil {
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			return &JSONBodyError{Line: 1, Column: 1, Err: err}
+		}
+		line, column := lineAndColumn(req.Body, syntaxErr.Offset)
+		return &JSONBodyError{Line: line, Column: column, Offset: syntaxErr.Offset, Err: err}
+	}
+
+	var formatted bytes.Buffer
+	switch req.JSONBodyFormat {
+	case JSONBodyMinify:
+		json.Compact(&formatted, []byte(req.Body))
+		req.Body = formatted.String()
+	case JSONBodyPretty:
+		json.Indent(&formatted, []byte(req.Body), "", "  ")
+		req.Body = formatted.String()
+	}
+	return nil
+}
+
+// lineAndColumn converts the offset a json.SyntaxError reports (the number
+// of bytes read, so the failing byte is the last of them) to a line and
+// column
+func lineAndColumn(body string, offset int64) (int, int) {
+	if offset > int64(len(body)) {
+		offset = int64(len(body))
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	read := body[:offset-1]
+	line := strings.Count(read, "\n") + 1
+	column := len(read) - strings.LastIndex(read, "\n")
+	return line, column
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"postmanxodja/models"
+	"testing"
+)
+
+func jsonRequest(body string) *models.ExecuteRequest {
+	return &models.ExecuteRequest{
+		Method: "POST", Body: body, ValidateJSONBody: true,
+		Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"},
+	}
+}
+
+func TestPrepareJSONBodyValid(t *testing.T) {
+	body := `{"name": "Ada", "tags": ["a", "b"]}`
+	req := jsonRequest(body)
+	if err := PrepareJSONBody(req); err != nil {
+		t.Fatalf("valid body rejected: %v", err)
+	}
+	if req.Body != body {
+		t.Errorf("validation alone changed the body to %q", req.Body)
+	}
+}
+
+func TestPrepareJSONBodyInvalid(t *testing.T) {
+	for body, want := range map[string][2]int{
+		`{"name": "Ada",}`:                {1, 16},
+		"{\n  \"a\": 1,\n  \"b\": tru\n}": {3, 11},
+		`{"a": 1} trailing`:               {1, 10},
+	} {
+		err := PrepareJSONBody(jsonRequest(body))
+		var bodyErr *JSONBodyError
+		if !errors.As(err, &bodyErr) {
+			t.Errorf("%q: got %v, want a JSONBodyError", body, err)
+			continue
+		}
+		if bodyErr.Line != want[0] || bodyErr.Column != want[1] {
+			t.Errorf("%q: error at line %d, column %d; want line %d, column %d (%v)",
+				body, bodyErr.Line, bodyErr.Column, want[0], want[1], err)
+		}
+	}
+}
+
+func TestPrepareJSONBodySkipsOtherBodies(t *testing.T) {
+	// Not JSON by Content-Type
+	req := jsonRequest("{not json")
+	req.Headers = map[string]string{"Content-Type": "text/plain"}
+	if err := PrepareJSONBody(req); err != nil {
+		t.Errorf("text/plain body validated: %v", err)
+	}
+
+	// HeaderList takes the place of Headers
+	req = jsonRequest("{not json")
+	req.HeaderList = []models.KeyValue{{Key: "content-type", Value: "application/vnd.api+json"}}
+	if err := PrepareJSONBody(req); err == nil {
+		t.Error("+json body from HeaderList was not validated")
+	}
+
+	// Not asked for
+	req = jsonRequest("{not json")
+	req.ValidateJSONBody = false
+	if err := PrepareJSONBody(req); err != nil {
+		t.Errorf("validated without the flag: %v", err)
+	}
+}
+
+func TestPrepareJSONBodyFormats(t *testing.T) {
+	req := jsonRequest("{\n  \"a\": [1, 2],\n  \"b\": {}\n}")
+	req.ValidateJSONBody = false
+	req.JSONBodyFormat = JSONBodyMinify
+	if err := PrepareJSONBody(req); err != nil || req.Body != `{"a":[1,2],"b":{}}` {
+		t.Errorf("minify: body %q, err %v", req.Body, err)
+	}
+
+	req.JSONBodyFormat = JSONBodyPretty
+	if err := PrepareJSONBody(req); err != nil || req.Body != "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}" {
+		t.Errorf("pretty: body %q, err %v", req.Body, err)
+	}
+
+	// Formatting implies validation
+	req = jsonRequest(`{"a":`)
+	req.ValidateJSONBody = false
+	req.JSONBodyFormat = JSONBodyMinify
+	var bodyErr *JSONBodyError
+	if err := PrepareJSONBody(req); !errors.As(err, &bodyErr) {
+		t.Errorf("minify of invalid body: got %v", err)
+	}
+
+	req = jsonRequest(`{}`)
+	req.JSONBodyFormat = "compact"
+	if err := PrepareJSONBody(req); !errors.Is(err, ErrInvalidBody) {
+		t.Errorf("unknown format: got %v", err)
+	}
+}
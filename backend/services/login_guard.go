@@ -0,0 +1,151 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Brute-force protection for password logins: after maxFailedLogins failures
+// within failedLoginWindow the account is locked for loginLockoutDuration.
+// State is in memory, so it resets on restart and isn't shared between
+// instances.
+const (
+	maxFailedLogins      = 5
+	failedLoginWindow    = 15 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+	// At most one lockout alert email per account per securityAlertCooldown
+	securityAlertCooldown = time.Hour
+	// Accounts tracked at once; past it the longest idle one is forgotten,
+	// so a spray of made-up emails can't grow the map without bound
+	maxLoginGuardEntries = 10000
+)
+
+type loginAttempts struct {
+	failures    []time.Time
+	lockedUntil time.Time
+	lastAlertAt time.Time
+}
+
+// lastActive is the latest time anything was recorded for the account
+func (a *loginAttempts) lastActive() time.Time {
+	last := a.lastAlertAt
+	if n := len(a.failures); n > 0 && a.failures[n-1].After(last) {
+		last = a.failures[n-1]
+	}
+	if unlocked := a.lockedUntil.Add(-loginLockoutDuration); unlocked.After(last) {
+		last = unlocked
+	}
+	return last
+}
+
+// expired reports whether forgetting the account changes nothing: no
+// failure left in the window, no lock and no alert throttle running
+func (a *loginAttempts) expired(now time.Time) bool {
+	if n := len(a.failures); n > 0 && now.Sub(a.failures[n-1]) < failedLoginWindow {
+		return false
+	}
+	return !now.Before(a.lockedUntil) && now.Sub(a.lastAlertAt) >= securityAlertCooldown
+}
+
+var (
+	loginGuardMu        sync.Mutex
+	loginGuard          = make(map[string]*loginAttempts)
+	loginGuardLastSweep time.Time
+	loginGuardNow       = time.Now
+)
+
+// pruneLoginGuard forgets expired accounts, at most once per
+// failedLoginWindow unless the map is full, and then makes room for one more
+// by dropping the longest idle account. Call with loginGuardMu held.
+func pruneLoginGuard(now time.Time) {
+	full := len(loginGuard) >= maxLoginGuardEntries
+	if full || now.Sub(loginGuardLastSweep) > failedLoginWindow {
+		for key, attempts := range loginGuard {
+			if attempts.expired(now) {
+				delete(loginGuard, key)
+			}
+		}
+		loginGuardLastSweep = now
+	}
+
+	for len(loginGuard) >= maxLoginGuardEntries {
+		var oldestKey string
+		var oldest time.Time
+		first := true
+		for key, attempts := range loginGuard {
+			if last := attempts.lastActive(); first || last.Before(oldest) {
+				oldestKey, oldest, first = key, last, false
+			}
+		}
+		delete(loginGuard, oldestKey)
+	}
+}
+
+func loginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginLockedFor returns how long the account stays locked, or 0
+func LoginLockedFor(email string) time.Duration {
+	loginGuardMu.Lock()
+	defer loginGuardMu.Unlock()
+
+	attempts, ok := loginGuard[loginKey(email)]
+	if !ok {
+		return 0
+	}
+	if remaining := attempts.lockedUntil.Sub(loginGuardNow()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// RecordFailedLogin counts a failed login and reports whether it locked the
+// account, and if so whether an alert email should go out (throttled to one
+// per securityAlertCooldown)
+func RecordFailedLogin(email string) (locked bool, sendAlert bool) {
+	loginGuardMu.Lock()
+	defer loginGuardMu.Unlock()
+
+	now := loginGuardNow()
+	key := loginKey(email)
+	attempts, ok := loginGuard[key]
+	if !ok {
+		pruneLoginGuard(now)
+		attempts = &loginAttempts{}
+		loginGuard[key] = attempts
+	}
+
+	recent := attempts.failures[:0]
+	for _, at := range attempts.failures {
+		if now.Sub(at) < failedLoginWindow {
+			recent = append(recent, at)
+		}
+	}
+	attempts.failures = append(recent, now)
+
+	if len(attempts.failures) < maxFailedLogins {
+		return false, false
+	}
+
+	attempts.failures = nil
+	attempts.lockedUntil = now.Add(loginLockoutDuration)
+	if now.Sub(attempts.lastAlertAt) >= securityAlertCooldown {
+		attempts.lastAlertAt = now
+		return true, true
+	}
+	return true, false
+}
+
+// ResetFailedLogins clears the failure count after a successful login. The
+// alert throttle is kept so an ongoing attack doesn't re-trigger emails.
+func ResetFailedLogins(email string) {
+	loginGuardMu.Lock()
+	defer loginGuardMu.Unlock()
+
+	if attempts, ok := loginGuard[loginKey(email)]; ok {
+		attempts.failures = nil
+		attempts.lockedUntil = time.Time{}
+	}
+}
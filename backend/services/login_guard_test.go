@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRecordFailedLoginLocksAndThrottlesAlerts(t *testing.T) {
+	email := "Guard-Test@Example.com"
+	t.Cleanup(func() {
+		loginGuardMu.Lock()
+		delete(loginGuard, loginKey(email))
+		loginGuardMu.Unlock()
+	})
+
+	for i := 1; i < maxFailedLogins; i++ {
+		if locked, _ := RecordFailedLogin(email); locked {
+			t.Fatalf("Expected no lockout after %d failures", i)
+		}
+	}
+	locked, sendAlert := RecordFailedLogin("guard-test@example.com")
+	if !locked || !sendAlert {
+		t.Fatalf("Expected lockout with alert, got locked=%v sendAlert=%v", locked, sendAlert)
+	}
+	if LoginLockedFor(email) <= 0 {
+		t.Error("Expected the account to be locked")
+	}
+
+	// A second lockout within the cooldown doesn't send another alert
+	ResetFailedLogins(email)
+	if LoginLockedFor(email) != 0 {
+		t.Error("Expected reset to clear the lock")
+	}
+	for i := 0; i < maxFailedLogins; i++ {
+		locked, sendAlert = RecordFailedLogin(email)
+	}
+	if !locked || sendAlert {
+		t.Errorf("Expected lockout without alert, got locked=%v sendAlert=%v", locked, sendAlert)
+	}
+}
+
+// useLoginGuard gives the test an empty login guard with its own clock
+func useLoginGuard(t *testing.T, now *time.Time) {
+	t.Helper()
+	loginGuardMu.Lock()
+	saved, savedSweep := loginGuard, loginGuardLastSweep
+	loginGuard, loginGuardLastSweep = make(map[string]*loginAttempts), time.Time{}
+	loginGuardNow = func() time.Time { return *now }
+	loginGuardMu.Unlock()
+	t.Cleanup(func() {
+		loginGuardMu.Lock()
+		loginGuard, loginGuardLastSweep, loginGuardNow = saved, savedSweep, time.Now
+		loginGuardMu.Unlock()
+	})
+}
+
+func TestLoginGuardForgetsExpiredAccounts(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	useLoginGuard(t, &now)
+
+	RecordFailedLogin("once@example.com")
+	for i := 0; i < maxFailedLogins; i++ {
+		RecordFailedLogin("locked@example.com")
+	}
+
+	// Past the window the single failure is forgotten, while the lockout's
+	// alert throttle keeps the locked account around
+	now = now.Add(failedLoginWindow + time.Minute)
+	RecordFailedLogin("new@example.com")
+	loginGuardMu.Lock()
+	_, once := loginGuard["once@example.com"]
+	_, locked := loginGuard["locked@example.com"]
+	loginGuardMu.Unlock()
+	if once || !locked {
+		t.Errorf("Expected only the expired account forgotten, got once=%v locked=%v", once, locked)
+	}
+}
+
+func TestLoginGuardIsCapped(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	useLoginGuard(t, &now)
+
+	for i := 0; i < maxLoginGuardEntries+10; i++ {
+		now = now.Add(time.Millisecond)
+		RecordFailedLogin("spray-" + strconv.Itoa(i) + "@example.com")
+	}
+	loginGuardMu.Lock()
+	size := len(loginGuard)
+	_, first := loginGuard["spray-0@example.com"]
+	_, last := loginGuard["spray-"+strconv.Itoa(maxLoginGuardEntries+9)+"@example.com"]
+	loginGuardMu.Unlock()
+	if size != maxLoginGuardEntries || first || !last {
+		t.Errorf("Expected %d accounts with the longest idle dropped, got %d (first kept=%v, last kept=%v)", maxLoginGuardEntries, size, first, last)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"postmanxodja/config"
+	"postmanxodja/models"
+)
+
+const (
+	defaultNDJSONMaxLines       = 1000
+	defaultNDJSONTimeoutSeconds = 30
+	maxNDJSONTimeoutSeconds     = 300
+)
+
+// ValidateNDJSON checks a request's ndjson options, if it has any
+func ValidateNDJSON(req *models.ExecuteRequest) error {
+	if req.NDJSON == nil {
+		return nil
+	}
+	if req.PreviewBytes > 0 {
+		return errors.New("preview_bytes can't be combined with ndjson")
+	}
+	_, _, err := ndjsonLimits(req.NDJSON)
+	return err
+}
+
+// ndjsonLimits returns the line count and read timeout for opts, with the
+// defaults filled in
+func ndjsonLimits(opts *models.NDJSONOptions) (int, int, error) {
+	if opts.MaxLines < 0 || opts.TimeoutSeconds < 0 {
+		return 0, 0, errors.New("ndjson max_lines and timeout_seconds must not be negative")
+	}
+	maxLines, timeout := opts.MaxLines, opts.TimeoutSeconds
+	if maxLines == 0 {
+		maxLines = defaultNDJSONMaxLines
+	}
+	if timeout == 0 {
+		timeout = defaultNDJSONTimeoutSeconds
+	}
+	if timeout > maxNDJSONTimeoutSeconds {
+		return 0, 0, fmt.Errorf("ndjson timeout_seconds must be at most %d", maxNDJSONTimeoutSeconds)
+	}
+	return maxLines, timeout, nil
+}
+
+// maxNDJSONLineBytes is the configured line cap, 1 MiB without a config
+func maxNDJSONLineBytes() int {
+	if cfg := config.AppConfig; cfg != nil && cfg.MaxNDJSONLineBytes > 0 {
+		return cfg.MaxNDJSONLineBytes
+	}
+	return 1 << 20
+}
+
+// ndjsonResult is what readNDJSON got out of a stream
+type ndjsonResult struct {
+	events    []json.RawMessage
+	raw       []byte // the lines read, for ExecuteResponse.Body
+	truncated bool
+}
+
+// readNDJSON parses r line by line until it ends or maxLines values were
+// read; a line after those marks the result truncated. Blank lines are
+// skipped. A line longer than maxLineBytes stops the
+// read and marks the result truncated, as does a read error once stopped()
+// reports the time ran out; the partial line at that point is dropped.
+func readNDJSON(r io.Reader, maxLines, maxLineBytes int, stopped func() bool) (*ndjsonResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxLineBytes, 64<<10)), maxLineBytes)
+
+	result := &ndjsonResult{events: []json.RawMessage{}}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(result.events) == maxLines {
+			result.truncated = true
+			return result, nil
+		}
+		if !json.Valid(text) {
+			return nil, fmt.Errorf("ndjson line %d is not valid JSON", line)
+		}
+		result.events = append(result.events, json.RawMessage(append([]byte(nil), text...)))
+		result.raw = append(append(result.raw, text...), '\n')
+	}
+
+	err := scanner.Err()
+	switch {
+	case err == nil:
+		return result, nil
+	case errors.Is(err, bufio.ErrTooLong):
+		result.truncated = true
+		return result, nil
+	case stopped():
+		result.truncated = true
+		return result, nil
+	}
+	return nil, err
+}
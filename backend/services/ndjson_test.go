@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/models"
+)
+
+// ndjsonServer writes lines one at a time, flushing each, then keeps the
+// stream open for hold
+func ndjsonServer(t *testing.T, lines []string, hold time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(hold):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteNDJSON(t *testing.T) {
+	useLoopback(t)
+	server := ndjsonServer(t, []string{`{"id":1}`, "", `{"id":2,"tags":["a"]}`, `  "done"  `}, 0)
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, NDJSON: &models.NDJSONOptions{}})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var got []string
+	for _, event := range resp.Events {
+		got = append(got, string(event))
+	}
+	if want := `{"id":1}|{"id":2,"tags":["a"]}|"done"`; strings.Join(got, "|") != want {
+		t.Errorf("Expected events %s, got %s", want, strings.Join(got, "|"))
+	}
+	if resp.Truncated {
+		t.Error("Expected a stream that ended by itself not to be truncated")
+	}
+	if resp.Body != "{\"id\":1}\n{\"id\":2,\"tags\":[\"a\"]}\n\"done\"\n" {
+		t.Errorf("Unexpected body %q", resp.Body)
+	}
+}
+
+func TestExecuteNDJSONMaxLines(t *testing.T) {
+	useLoopback(t)
+	server := ndjsonServer(t, []string{`1`, `2`, `3`, `4`}, time.Minute)
+
+	start := time.Now()
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, NDJSON: &models.NDJSONOptions{MaxLines: 2}})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if len(resp.Events) != 2 || !resp.Truncated {
+		t.Errorf("Expected 2 events and truncated, got %d events, truncated %v", len(resp.Events), resp.Truncated)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to stop at max_lines, took %v", elapsed)
+	}
+}
+
+func TestExecuteNDJSONTimeout(t *testing.T) {
+	useLoopback(t)
+	server := ndjsonServer(t, []string{`{"tick":1}`}, time.Minute)
+
+	start := time.Now()
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, NDJSON: &models.NDJSONOptions{TimeoutSeconds: 1}})
+	if err != nil {
+		t.Fatalf("Expected the timeout to end the stream without an error, got %v", err)
+	}
+	if len(resp.Events) != 1 || !resp.Truncated {
+		t.Errorf("Expected 1 event and truncated, got %d events, truncated %v", len(resp.Events), resp.Truncated)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected to read for the whole timeout, stopped after %v", elapsed)
+	}
+}
+
+func TestExecuteNDJSONLineCap(t *testing.T) {
+	useLoopback(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{MaxNDJSONLineBytes: 16}
+	t.Cleanup(func() { config.AppConfig = previous })
+	server := ndjsonServer(t, []string{`{"ok":true}`, `{"padding":"` + strings.Repeat("x", 64) + `"}`, `{"never":1}`}, 0)
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, NDJSON: &models.NDJSONOptions{}})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if len(resp.Events) != 1 || !resp.Truncated {
+		t.Errorf("Expected the oversized line to end the stream, got %d events, truncated %v", len(resp.Events), resp.Truncated)
+	}
+}
+
+func TestExecuteNDJSONInvalid(t *testing.T) {
+	useLoopback(t)
+	server := ndjsonServer(t, []string{`{"id":1}`, `data: {"id":2}`}, 0)
+
+	_, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, NDJSON: &models.NDJSONOptions{}})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+}
+
+func TestValidateNDJSON(t *testing.T) {
+	for _, req := range []*models.ExecuteRequest{
+		{NDJSON: &models.NDJSONOptions{MaxLines: -1}},
+		{NDJSON: &models.NDJSONOptions{TimeoutSeconds: maxNDJSONTimeoutSeconds + 1}},
+		{NDJSON: &models.NDJSONOptions{}, PreviewBytes: 10},
+	} {
+		if err := ValidateNDJSON(req); err == nil {
+			t.Errorf("Expected %+v to be rejected", req.NDJSON)
+		}
+	}
+	if err := ValidateNDJSON(&models.ExecuteRequest{}); err != nil {
+		t.Errorf("Expected requests without ndjson to pass, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// NormalizeEmail trims and lowercases an email address, so "Alice@X.com "
+// and "alice@x.com" are the same account, and checks it's a bare address
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", fmt.Errorf("email is required")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", fmt.Errorf("%q is not a valid email address", email)
+	}
+	return email, nil
+}
+
+// NormalizeName trims a display name and collapses runs of whitespace inside
+// it to single spaces
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
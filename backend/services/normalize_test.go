@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	for input, want := range map[string]string{
+		"alice@x.com":        "alice@x.com",
+		" Alice@X.com ":      "alice@x.com",
+		"\tBOB.Smith@Ex.org": "bob.smith@ex.org",
+	} {
+		got, err := NormalizeEmail(input)
+		if err != nil || got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "   ", "not-an-email", "Alice <alice@x.com>", "a@b.com, c@d.com"} {
+		if _, err := NormalizeEmail(input); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	for input, want := range map[string]string{
+		"Ada Lovelace":         "Ada Lovelace",
+		"  Ada   Lovelace \n":  "Ada Lovelace",
+		"Ada\t\tKing Lovelace": "Ada King Lovelace",
+		"   ":                  "",
+	} {
+		if got := NormalizeName(input); got != want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"postmanxodja/models"
+)
+
+// OAuth2 grant types supported by FetchOAuth2Token
+const (
+	GrantClientCredentials = "client_credentials"
+)
+
+var (
+	// ErrInvalidOAuth2Config is returned for an unusable token request
+	ErrInvalidOAuth2Config = errors.New("invalid oauth2 config")
+	// ErrOAuth2TokenFailed is returned when the token endpoint doesn't hand
+	// out a token
+	ErrOAuth2TokenFailed = errors.New("oauth2 token request failed")
+)
+
+// Tokens are cached in memory per environment and credentials until shortly
+// before they expire. Like the other execution state the cache resets on
+// restart and isn't shared between instances.
+const (
+	oauth2ExpirySkew     = 30 * time.Second
+	maxCachedOAuth2Token = 1000
+)
+
+var (
+	oauth2Mu    sync.Mutex
+	oauth2Cache = make(map[string]models.OAuth2Token)
+)
+
+// ValidateOAuth2Config checks a token request and fills in defaults
+func ValidateOAuth2Config(cfg *models.OAuth2Config) error {
+	if cfg.GrantType == "" {
+		cfg.GrantType = GrantClientCredentials
+	}
+	if cfg.GrantType != GrantClientCredentials {
+		return fmt.Errorf("%w: unsupported grant_type %q (supported: client_credentials)", ErrInvalidOAuth2Config, cfg.GrantType)
+	}
+	if cfg.ClientAuth == "" {
+		cfg.ClientAuth = "body"
+	}
+	if cfg.ClientAuth != "body" && cfg.ClientAuth != "header" {
+		return fmt.Errorf("%w: client_auth must be body or header", ErrInvalidOAuth2Config)
+	}
+	parsed, err := url.Parse(cfg.TokenURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: token_url must be an absolute http or https URL", ErrInvalidOAuth2Config)
+	}
+	if cfg.ClientID == "" {
+		return fmt.Errorf("%w: client_id is required", ErrInvalidOAuth2Config)
+	}
+	return nil
+}
+
+// ReplaceInOAuth2Config substitutes variables in every field of cfg
+func ReplaceInOAuth2Config(cfg *models.OAuth2Config, replacer *VariableReplacer) {
+	cfg.TokenURL = replacer.Replace(cfg.TokenURL)
+	cfg.ClientID = replacer.Replace(cfg.ClientID)
+	cfg.ClientSecret = replacer.Replace(cfg.ClientSecret)
+	for i := range cfg.Scopes {
+		cfg.Scopes[i] = replacer.Replace(cfg.Scopes[i])
+	}
+}
+
+func oauth2CacheKey(environmentID uint, cfg *models.OAuth2Config) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.FormatUint(uint64(environmentID), 10),
+		cfg.TokenURL, cfg.GrantType, cfg.ClientAuth, cfg.ClientID, cfg.ClientSecret,
+		strings.Join(cfg.Scopes, " "),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func cachedOAuth2Token(key string, now time.Time) (models.OAuth2Token, bool) {
+	oauth2Mu.Lock()
+	defer oauth2Mu.Unlock()
+
+	token, ok := oauth2Cache[key]
+	if !ok || !now.Before(token.ExpiresAt.Add(-oauth2ExpirySkew)) {
+		return models.OAuth2Token{}, false
+	}
+	return token, true
+}
+
+func cacheOAuth2Token(key string, token models.OAuth2Token, now time.Time) {
+	oauth2Mu.Lock()
+	defer oauth2Mu.Unlock()
+
+	if len(oauth2Cache) >= maxCachedOAuth2Token {
+		for stale, cached := range oauth2Cache {
+			if !now.Before(*cached.ExpiresAt) {
+				delete(oauth2Cache, stale)
+			}
+		}
+		if len(oauth2Cache) >= maxCachedOAuth2Token {
+			// Still full of live tokens: drop an arbitrary one
+			for stale := range oauth2Cache {
+				delete(oauth2Cache, stale)
+				break
+			}
+		}
+	}
+	oauth2Cache[key] = token
+}
+
+// FetchOAuth2Token returns an access token for cfg (which must have passed
+// ValidateOAuth2Config), from the environment's cache when a live one is
+// there. Tokens without an expires_in are never cached.
+func FetchOAuth2Token(ctx context.Context, cfg *models.OAuth2Config, environmentID uint, forceRefresh bool) (*models.OAuth2Token, error) {
+	key := oauth2CacheKey(environmentID, cfg)
+	if !forceRefresh {
+		if token, ok := cachedOAuth2Token(key, time.Now()); ok {
+			token.Cached = true
+			token.ExpiresIn = int64(time.Until(*token.ExpiresAt).Seconds())
+			return &token, nil
+		}
+	}
+
+	token, err := requestOAuth2Token(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if token.ExpiresIn > 0 {
+		now := time.Now()
+		expiresAt := now.Add(time.Duration(token.ExpiresIn) * time.Second)
+		token.ExpiresAt = &expiresAt
+		cacheOAuth2Token(key, *token, now)
+	}
+	return token, nil
+}
+
+// requestOAuth2Token performs the token request itself
+func requestOAuth2Token(ctx context.Context, cfg *models.OAuth2Config) (*models.OAuth2Token, error) {
+	form := url.Values{"grant_type": {cfg.GrantType}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	if cfg.ClientAuth == "body" {
+		form.Set("client_id", cfg.ClientID)
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+
+	tokenURL := RewriteLocalhostURL(cfg.TokenURL)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if cfg.ClientAuth == "header" {
+		httpReq.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	client, err := HostPolicyClient(ctx, HttpClientFor(tokenURL))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuth2TokenFailed, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuth2TokenFailed, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: token endpoint answered %s: %s", ErrOAuth2TokenFailed, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	token, err := parseOAuth2Token(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuth2TokenFailed, err)
+	}
+	return token, nil
+}
+
+// parseOAuth2Token reads a token response. Some providers answer with a
+// form-encoded body instead of JSON.
+func parseOAuth2Token(contentType string, body []byte) (*models.OAuth2Token, error) {
+	var raw struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+		Scope       string      `json:"scope"`
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("unreadable token response: %v", err)
+		}
+		raw.AccessToken = values.Get("access_token")
+		raw.TokenType = values.Get("token_type")
+		raw.ExpiresIn = json.Number(values.Get("expires_in"))
+		raw.Scope = values.Get("scope")
+	} else if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("unreadable token response: %v", err)
+	}
+
+	if raw.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+	token := &models.OAuth2Token{
+		AccessToken: raw.AccessToken,
+		TokenType:   raw.TokenType,
+		Scope:       raw.Scope,
+	}
+	if token.TokenType == "" {
+		token.TokenType = "Bearer"
+	}
+	if raw.ExpiresIn != "" {
+		if seconds, err := raw.ExpiresIn.Int64(); err == nil && seconds > 0 {
+			token.ExpiresIn = seconds
+		}
+	}
+	return token, nil
+}
+
+// authorizationScheme capitalizes "bearer" token types the way most APIs
+// expect the Authorization header to look
+func authorizationScheme(tokenType string) string {
+	if strings.EqualFold(tokenType, "bearer") {
+		return "Bearer"
+	}
+	return tokenType
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+// tokenServer is a client_credentials token endpoint that hands out
+// "token-N" for the N-th request
+func tokenServer(t *testing.T, expiresIn string) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+		clientID, secret, ok := r.BasicAuth()
+		if !ok {
+			clientID, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+		}
+		if clientID != "app" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token-` + strconv.Itoa(int(n)) + `","token_type":"bearer","scope":"` +
+			r.FormValue("scope") + `","expires_in":` + expiresIn + `}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestFetchOAuth2TokenCachesPerEnvironment(t *testing.T) {
+	useLoopback(t)
+	server, issued := tokenServer(t, "3600")
+	cfg := &models.OAuth2Config{TokenURL: server.URL + "/token", ClientID: "app", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+	if err := ValidateOAuth2Config(cfg); err != nil {
+		t.Fatalf("Expected a valid config, got %v", err)
+	}
+
+	first, err := FetchOAuth2Token(context.Background(), cfg, 41, false)
+	if err != nil {
+		t.Fatalf("Expected a token, got %v", err)
+	}
+	if first.AccessToken != "token-1" || first.Cached || first.Scope != "read write" || first.ExpiresAt == nil {
+		t.Errorf("Expected a fresh token-1 with scope and expiry, got %+v", first)
+	}
+
+	second, err := FetchOAuth2Token(context.Background(), cfg, 41, false)
+	if err != nil || second.AccessToken != "token-1" || !second.Cached {
+		t.Errorf("Expected the cached token-1, got %+v (%v)", second, err)
+	}
+
+	other, _ := FetchOAuth2Token(context.Background(), cfg, 42, false)
+	if other == nil || other.AccessToken != "token-2" {
+		t.Errorf("Expected another environment to fetch its own token, got %+v", other)
+	}
+
+	refreshed, _ := FetchOAuth2Token(context.Background(), cfg, 41, true)
+	if refreshed == nil || refreshed.AccessToken != "token-3" || refreshed.Cached {
+		t.Errorf("Expected force_refresh to fetch token-3, got %+v", refreshed)
+	}
+	if got := atomic.LoadInt32(issued); got != 3 {
+		t.Errorf("Expected 3 token requests, got %d", got)
+	}
+}
+
+func TestFetchOAuth2TokenHeaderClientAuth(t *testing.T) {
+	useLoopback(t)
+	server, _ := tokenServer(t, "0")
+	cfg := &models.OAuth2Config{TokenURL: server.URL, ClientID: "app", ClientSecret: "s3cret", ClientAuth: "header"}
+	ValidateOAuth2Config(cfg)
+
+	token, err := FetchOAuth2Token(context.Background(), cfg, 7, false)
+	if err != nil {
+		t.Fatalf("Expected a token, got %v", err)
+	}
+	if token.ExpiresAt != nil {
+		t.Errorf("Expected no expiry without expires_in, got %v", token.ExpiresAt)
+	}
+	// Not cached without an expiry
+	again, _ := FetchOAuth2Token(context.Background(), cfg, 7, false)
+	if again == nil || again.Cached || again.AccessToken == token.AccessToken {
+		t.Errorf("Expected a new token, got %+v", again)
+	}
+}
+
+func TestFetchOAuth2TokenFailure(t *testing.T) {
+	useLoopback(t)
+	server, _ := tokenServer(t, "60")
+	cfg := &models.OAuth2Config{TokenURL: server.URL, ClientID: "app", ClientSecret: "wrong"}
+	ValidateOAuth2Config(cfg)
+
+	if _, err := FetchOAuth2Token(context.Background(), cfg, 0, false); !errors.Is(err, ErrOAuth2TokenFailed) {
+		t.Errorf("Expected ErrOAuth2TokenFailed, got %v", err)
+	}
+}
+
+func TestValidateOAuth2Config(t *testing.T) {
+	cfg := &models.OAuth2Config{TokenURL: "https://auth.example.com/token", ClientID: "app"}
+	if err := ValidateOAuth2Config(cfg); err != nil {
+		t.Fatalf("Expected a valid config, got %v", err)
+	}
+	if cfg.GrantType != GrantClientCredentials || cfg.ClientAuth != "body" {
+		t.Errorf("Expected defaults to be filled in, got %+v", cfg)
+	}
+
+	for _, bad := range []models.OAuth2Config{
+		{TokenURL: "https://auth.example.com/token", ClientID: "app", GrantType: "password"},
+		{TokenURL: "auth.example.com/token", ClientID: "app"},
+		{TokenURL: "https://auth.example.com/token"},
+	} {
+		if err := ValidateOAuth2Config(&bad); !errors.Is(err, ErrInvalidOAuth2Config) {
+			t.Errorf("Expected %+v to be rejected, got %v", bad, err)
+		}
+	}
+}
+
+func TestExecuteWithOAuth2Auth(t *testing.T) {
+	useLoopback(t)
+	tokens, _ := tokenServer(t, "600")
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer api.Close()
+
+	envID := uint(99)
+	req := &models.ExecuteRequest{
+		Method:        "GET",
+		URL:           api.URL + "/me",
+		EnvironmentID: &envID,
+		Auth: &models.RequestAuth{
+			Type:   AuthTypeOAuth2,
+			OAuth2: &models.OAuth2Config{TokenURL: "{{auth_url}}", ClientID: "app", ClientSecret: "{{secret}}"},
+		},
+	}
+	ReplaceInRequest(req, models.Variables{"auth_url": tokens.URL, "secret": "s3cret"})
+
+	response, err := ExecuteHTTPRequest(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Body != "Bearer token-1" {
+		t.Errorf("Expected the fetched token to be sent, got '%s'", response.Body)
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+// Organization roles
+const (
+	OrganizationAdmin  = "admin"
+	OrganizationMember = "member"
+)
+
+var (
+	// ErrTeamInOtherOrganization is returned when adding a team that already
+	// belongs to a different organization
+	ErrTeamInOtherOrganization = errors.New("team already belongs to another organization")
+	// ErrPersonalTeam is returned when adding a personal team to an organization
+	ErrPersonalTeam = errors.New("personal teams can't join an organization")
+	// ErrLastOrganizationAdmin is returned when removing the only admin an
+	// organization has left
+	ErrLastOrganizationAdmin = errors.New("an organization needs at least one admin")
+)
+
+// CreateOrganizationWithAdmin creates an organization with the user as its
+// first admin
+func CreateOrganizationWithAdmin(name string, userID uint) (*models.Organization, error) {
+	org := &models.Organization{Name: name}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         userID,
+			Role:           OrganizationAdmin,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// GetOrganizationRole returns the user's role in the organization, "" when
+// they aren't a member or it has been deleted
+func GetOrganizationRole(userID, orgID uint) string {
+	var member models.OrganizationMember
+	result := database.DB.
+		Joins("JOIN organizations ON organizations.id = organization_members.organization_id AND organizations.deleted_at IS NULL").
+		Where("organization_members.user_id = ? AND organization_members.organization_id = ?", userID, orgID).
+		First(&member)
+	if result.Error != nil {
+		return ""
+	}
+	return member.Role
+}
+
+func IsOrganizationAdmin(userID, orgID uint) bool {
+	return GetOrganizationRole(userID, orgID) == OrganizationAdmin
+}
+
+// GetUserOrganizations returns the organizations the user belongs to, with
+// their role and the number of teams in each
+func GetUserOrganizations(userID uint) ([]models.OrganizationResponse, error) {
+	var orgs []models.OrganizationResponse
+	result := database.DB.Model(&models.Organization{}).
+		Select("organizations.*, organization_members.role AS your_role, "+
+			"(SELECT COUNT(*) FROM teams WHERE teams.organization_id = organizations.id AND teams.deleted_at IS NULL) AS team_count").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.id").
+		Scan(&orgs)
+	return orgs, result.Error
+}
+
+// GetOrganizationTeams lists the organization's teams with their member
+// count and owner. YourRole is the user's role in each team, empty for the
+// teams an org admin sees without being in them. Unless all is set, only
+// the teams the user belongs to are returned.
+func GetOrganizationTeams(orgID, userID uint, all bool) ([]models.TeamResponse, error) {
+	var teams []models.TeamResponse
+	query := database.DB.Model(&models.Team{}).
+		Select("teams.*, COALESCE(mine.role, '') AS your_role, "+
+			"(SELECT COUNT(*) FROM team_members AS tm WHERE tm.team_id = teams.id) AS member_count, "+
+			"owners.user_id AS owner_id, owner_users.name AS owner_name").
+		Joins("LEFT JOIN team_members AS mine ON mine.team_id = teams.id AND mine.user_id = ?", userID).
+		Joins("LEFT JOIN team_members AS owners ON owners.team_id = teams.id AND owners.role = ?", "owner").
+		Joins("LEFT JOIN users AS owner_users ON owner_users.id = owners.user_id").
+		Where("teams.organization_id = ?", orgID)
+	if !all {
+		query = query.Where("mine.id IS NOT NULL")
+	}
+	result := query.Order("teams.id").Scan(&teams)
+	return teams, result.Error
+}
+
+// AddTeamToOrganization moves a team into the organization. Adding a team
+// that's already there is a no-op.
+func AddTeamToOrganization(orgID uint, team *models.Team) error {
+	if team.IsPersonal {
+		return ErrPersonalTeam
+	}
+	if team.OrganizationID != nil {
+		if *team.OrganizationID == orgID {
+			return nil
+		}
+		return ErrTeamInOtherOrganization
+	}
+	// Only claim the team if nobody else did in the meantime
+	result := database.DB.Model(&models.Team{}).
+		Where("id = ? AND organization_id IS NULL", team.ID).
+		Update("organization_id", orgID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTeamInOtherOrganization
+	}
+	team.OrganizationID = &orgID
+	return nil
+}
+
+// RemoveTeamFromOrganization takes a team out of the organization, reporting
+// false when it wasn't in it
+func RemoveTeamFromOrganization(orgID, teamID uint) (bool, error) {
+	result := database.DB.Model(&models.Team{}).
+		Where("id = ? AND organization_id = ?", teamID, orgID).
+		Update("organization_id", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RemoveOrganizationMember takes a user out of the organization, reporting
+// false when they weren't in it. The last admin can't be removed, so the
+// organization always has someone to manage it.
+func RemoveOrganizationMember(orgID, userID uint) (bool, error) {
+	removed := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.OrganizationMember
+		if err := tx.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if member.Role == OrganizationAdmin {
+			var admins int64
+			if err := tx.Model(&models.OrganizationMember{}).
+				Where("organization_id = ? AND role = ?", orgID, OrganizationAdmin).
+				Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return ErrLastOrganizationAdmin
+			}
+		}
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		removed = true
+		return nil
+	})
+	return removed, err
+}
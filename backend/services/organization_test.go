@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestGetOrganizationTeams(t *testing.T) {
+	useTestDB(t)
+	admin := createTestUser(t, "admin@example.com")
+	member := createTestUser(t, "member@example.com")
+
+	org, err := CreateOrganizationWithAdmin("Acme", admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: member.ID, Role: OrganizationMember}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	backend, _ := CreateTeamWithOwner("Backend", admin.ID)
+	frontend, _ := CreateTeamWithOwner("Frontend", admin.ID)
+	addTestMember(t, frontend.ID, member.ID, "member")
+	outside, _ := CreateTeamWithOwner("Outside", admin.ID)
+	for _, team := range []*models.Team{backend, frontend} {
+		if err := AddTeamToOrganization(org.ID, team); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := GetOrganizationTeams(org.ID, admin.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != backend.ID || all[1].ID != frontend.ID {
+		t.Fatalf("Expected the two organization teams, got %+v", all)
+	}
+	if all[1].MemberCount != 2 || all[1].OwnerID != admin.ID {
+		t.Errorf("Expected member count and owner to be filled in, got %+v", all[1])
+	}
+
+	mine, err := GetOrganizationTeams(org.ID, member.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].ID != frontend.ID || mine[0].YourRole != "member" {
+		t.Fatalf("Expected only the member's team, got %+v", mine)
+	}
+
+	orgs, err := GetUserOrganizations(member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != 1 || orgs[0].TeamCount != 2 || orgs[0].YourRole != OrganizationMember {
+		t.Fatalf("Unexpected organizations: %+v", orgs)
+	}
+
+	if removed, err := RemoveTeamFromOrganization(org.ID, outside.ID); err != nil || removed {
+		t.Errorf("Expected removing a team outside the organization to report false, got %v, %v", removed, err)
+	}
+	if removed, err := RemoveTeamFromOrganization(org.ID, backend.ID); err != nil || !removed {
+		t.Errorf("Expected the team to be removed, got %v, %v", removed, err)
+	}
+}
+
+func TestAddTeamToOrganization(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "owner@example.com")
+
+	first, _ := CreateOrganizationWithAdmin("First", user.ID)
+	second, _ := CreateOrganizationWithAdmin("Second", user.ID)
+	team, _ := CreateTeamWithOwner("Acme", user.ID)
+
+	if err := AddTeamToOrganization(first.ID, team); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTeamToOrganization(first.ID, team); err != nil {
+		t.Errorf("Expected adding the team again to be a no-op, got %v", err)
+	}
+	if err := AddTeamToOrganization(second.ID, team); !errors.Is(err, ErrTeamInOtherOrganization) {
+		t.Errorf("Expected ErrTeamInOtherOrganization, got %v", err)
+	}
+
+	// A stale copy of the team must not steal it from the first organization
+	stale := &models.Team{ID: team.ID, Name: team.Name}
+	if err := AddTeamToOrganization(second.ID, stale); !errors.Is(err, ErrTeamInOtherOrganization) {
+		t.Errorf("Expected ErrTeamInOtherOrganization for a stale team, got %v", err)
+	}
+
+	personal, err := CreatePersonalTeam(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTeamToOrganization(first.ID, personal); !errors.Is(err, ErrPersonalTeam) {
+		t.Errorf("Expected ErrPersonalTeam, got %v", err)
+	}
+}
+
+func TestOrganizationMembershipIsUnique(t *testing.T) {
+	useTestDB(t)
+	admin := createTestUser(t, "admin@example.com")
+	org, err := CreateOrganizationWithAdmin("Acme", admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.DB.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: admin.ID, Role: OrganizationMember}).Error; err == nil {
+		t.Error("Expected a second membership of the same user to be refused")
+	}
+	if _, err := RemoveOrganizationMember(org.ID, admin.ID); !errors.Is(err, ErrLastOrganizationAdmin) {
+		t.Errorf("Expected ErrLastOrganizationAdmin, got %v", err)
+	}
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+
 	"postmanxodja/models"
 )
 
@@ -14,11 +17,143 @@ func ParsePostmanCollection(jsonData string) (*models.PostmanCollection, error)
 	return &collection, nil
 }
 
+// ValidateCollectionHeaders rejects a collection with a request header that
+// ValidateHeader wouldn't let the executor send, naming the request it's in,
+// so an untrusted import fails up front rather than on every run
+func ValidateCollectionHeaders(collection *models.PostmanCollection) error {
+	return validateItemHeaders(collection.Item, nil)
+}
+
+func validateItemHeaders(items []models.PostmanItem, parent []string) error {
+	for _, item := range items {
+		path := append(append([]string{}, parent...), item.Name)
+		if item.Request != nil {
+			for _, header := range item.Request.Header {
+				value, _ := header.Value.(string)
+				if err := ValidateHeader(header.Key, value); err != nil {
+					return fmt.Errorf("%s: %w", strings.Join(path, " / "), err)
+				}
+			}
+		}
+		if err := validateItemHeaders(item.Item, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParsePostmanCollectionLenient parses a collection that may contain //
+// and /* */ comments or trailing commas. Strict JSON is parsed as is. It
+// returns the JSON that was parsed, which is what should be stored, and the
+// strict parser's error when the cleaned-up input doesn't parse either.
+func ParsePostmanCollectionLenient(jsonData string) (*models.PostmanCollection, string, error) {
+	collection, err := ParsePostmanCollection(jsonData)
+	if err == nil {
+		return collection, jsonData, nil
+	}
+	cleaned := StripJSONExtensions(jsonData)
+	if lenient, lenientErr := ParsePostmanCollection(cleaned); lenientErr == nil {
+		return lenient, cleaned, nil
+	}
+	return nil, "", err
+}
+
+// StripJSONExtensions removes comments and trailing commas, leaving string
+// contents alone. Comments become spaces (newlines are kept) so line numbers
+// in later errors still match the input.
+func StripJSONExtensions(data string) string {
+	out := make([]byte, 0, len(data))
+	// Index in out of a comma that may turn out to be trailing
+	pendingComma := -1
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case ch == '"':
+			pendingComma = -1
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			end := min(i+1, len(data))
+			out = append(out, data[start:end]...)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for ; i < len(data) && data[i] != '\n'; i++ {
+				out = append(out, ' ')
+			}
+			i--
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			out = append(out, ' ', ' ')
+			for i += 2; i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/'); i++ {
+				out = append(out, blankOut(data[i]))
+			}
+			if i < len(data) {
+				out = append(out, ' ', ' ')
+				i++
+			}
+		case ch == ',':
+			pendingComma = len(out)
+			out = append(out, ch)
+		case ch == ']' || ch == '}':
+			if pendingComma >= 0 {
+				out[pendingComma] = ' '
+				pendingComma = -1
+			}
+			out = append(out, ch)
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			out = append(out, ch)
+		default:
+			pendingComma = -1
+			out = append(out, ch)
+		}
+	}
+	return string(out)
+}
+
+func blankOut(ch byte) byte {
+	if ch == '\n' {
+		return ch
+	}
+	return ' '
+}
+
 // ExtractCollectionInfo extracts name and description from collection
 func ExtractCollectionInfo(collection *models.PostmanCollection) (string, string) {
 	return collection.Info.Name, collection.Info.Description
 }
 
+// ApplyCollectionInfo sets the name, description and request count columns
+// of collection from parsed, its RawJSON, and returns the JSON names of the
+// columns that changed
+func ApplyCollectionInfo(collection *models.Collection, parsed *models.PostmanCollection) []string {
+	changed := []string{}
+	name, description := ExtractCollectionInfo(parsed)
+	if collection.Name != name {
+		collection.Name = name
+		changed = append(changed, "name")
+	}
+	if collection.Description != description {
+		collection.Description = description
+		changed = append(changed, "description")
+	}
+	if count := models.CountCollectionRequests(collection.RawJSON); collection.RequestCount != count {
+		collection.RequestCount = count
+		changed = append(changed, "request_count")
+	}
+	return changed
+}
+
+// SyncCollectionInfo re-parses collection's RawJSON (e.g. after it was
+// edited outside the app) and applies it as ApplyCollectionInfo does
+func SyncCollectionInfo(collection *models.Collection) ([]string, error) {
+	parsed, err := ParsePostmanCollection(collection.RawJSON)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyCollectionInfo(collection, parsed), nil
+}
+
 // CreateEmptyCollection creates an empty Postman collection JSON
 func CreateEmptyCollection(name, description string) string {
 	collection := models.PostmanCollection{
@@ -36,11 +171,40 @@ func CreateEmptyCollection(name, description string) string {
 
 // UpdateCollectionName updates the name in a collection's raw JSON
 func UpdateCollectionName(rawJSON string, newName string) (string, error) {
-	var collection models.PostmanCollection
+	return UpdateCollectionInfo(rawJSON, &newName, nil)
+}
+
+// UpdateCollectionInfo sets the name and/or description in a collection's
+// info block. Nil fields are left as they are, and so is everything else in
+// the JSON, fields the models don't know about included.
+func UpdateCollectionInfo(rawJSON string, name, description *string) (string, error) {
+	var collection map[string]json.RawMessage
 	if err := json.Unmarshal([]byte(rawJSON), &collection); err != nil {
 		return "", err
 	}
-	collection.Info.Name = newName
+	info := map[string]json.RawMessage{}
+	if raw, ok := collection["info"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &info); err != nil {
+			return "", err
+		}
+	}
+
+	for key, value := range map[string]*string{"name": name, "description": description} {
+		if value == nil {
+			continue
+		}
+		encoded, err := json.Marshal(*value)
+		if err != nil {
+			return "", err
+		}
+		info[key] = encoded
+	}
+
+	encodedInfo, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	collection["info"] = encodedInfo
 	updatedJSON, err := json.Marshal(collection)
 	if err != nil {
 		return "", err
@@ -2,7 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -134,3 +136,139 @@ func TestParsePostmanCollection(t *testing.T) {
 
 	t.Log("✓ All tests passed! Postman collection v2.1 format is fully supported")
 }
+
+func TestUpdateCollectionInfoDescriptionOnly(t *testing.T) {
+	raw := CreateEmptyCollection("Orders API", "old description")
+	description := "Endpoints for the orders service"
+
+	updated, err := UpdateCollectionInfo(raw, nil, &description)
+	if err != nil {
+		t.Fatalf("Failed to update collection info: %v", err)
+	}
+
+	parsed, err := ParsePostmanCollection(updated)
+	if err != nil {
+		t.Fatalf("Failed to parse updated collection: %v", err)
+	}
+	if parsed.Info.Description != description {
+		t.Errorf("Expected description '%s', got '%s'", description, parsed.Info.Description)
+	}
+	if parsed.Info.Name != "Orders API" {
+		t.Errorf("Expected name to stay 'Orders API', got '%s'", parsed.Info.Name)
+	}
+	if parsed.Info.Schema == "" {
+		t.Error("Expected the schema to be kept")
+	}
+}
+
+func TestUpdateCollectionInfoKeepsUnknownFields(t *testing.T) {
+	raw := `{"info":{"name":"Orders","_postman_id":"abc","x-team":"qa"},"event":[{"listen":"prerequest"}],"item":[{"name":"List","event":[{"listen":"test"}],"request":{"method":"GET","url":"http://api.test"}}]}`
+	name := "Orders v2"
+
+	updated, err := UpdateCollectionInfo(raw, &name, nil)
+	if err != nil {
+		t.Fatalf("Failed to update collection info: %v", err)
+	}
+
+	var parsed struct {
+		Info  map[string]string `json:"info"`
+		Event []json.RawMessage `json:"event"`
+		Item  []struct {
+			Event []json.RawMessage `json:"event"`
+		} `json:"item"`
+	}
+	if err := json.Unmarshal([]byte(updated), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Info["name"] != name || parsed.Info["_postman_id"] != "abc" || parsed.Info["x-team"] != "qa" {
+		t.Errorf("Expected only the name to change in info, got %v", parsed.Info)
+	}
+	if _, ok := parsed.Info["description"]; ok {
+		t.Error("Expected no description to be added")
+	}
+	if len(parsed.Event) != 1 || len(parsed.Item) != 1 || len(parsed.Item[0].Event) != 1 {
+		t.Errorf("Expected the events to survive, got %s", updated)
+	}
+}
+
+func TestParsePostmanCollectionLenientComments(t *testing.T) {
+	input := `{
+		// exported by hand
+		"info": {"name": "Orders /* not a comment */", "schema": "https://schema.getpostman.com"},
+		/* requests
+		   go here */
+		"item": [{"name": "List", "request": {"method": "GET", "url": "https://api.example.com//orders"}}]
+	}`
+
+	if _, err := ParsePostmanCollection(input); err == nil {
+		t.Fatal("Expected the strict parser to reject comments")
+	}
+	collection, cleaned, err := ParsePostmanCollectionLenient(input)
+	if err != nil {
+		t.Fatalf("Expected lenient parsing to succeed, got %v", err)
+	}
+	if collection.Info.Name != "Orders /* not a comment */" {
+		t.Errorf("Expected comment-like text in strings to be kept, got '%s'", collection.Info.Name)
+	}
+	if url := collection.Item[0].Request.URL; url != "https://api.example.com//orders" {
+		t.Errorf("Expected '//' in strings to be kept, got '%v'", url)
+	}
+	if !json.Valid([]byte(cleaned)) {
+		t.Errorf("Expected the cleaned JSON to be valid, got '%s'", cleaned)
+	}
+	if strings.Count(cleaned, "\n") != strings.Count(input, "\n") {
+		t.Error("Expected line breaks to be kept")
+	}
+}
+
+func TestParsePostmanCollectionLenientTrailingCommas(t *testing.T) {
+	input := `{"info": {"name": "Orders", "description": "a, b",}, "item": [
+		{"name": "List", "request": {"method": "GET", "url": "https://api.example.com"},},
+	],}`
+
+	collection, _, err := ParsePostmanCollectionLenient(input)
+	if err != nil {
+		t.Fatalf("Expected lenient parsing to succeed, got %v", err)
+	}
+	if collection.Info.Description != "a, b" || len(collection.Item) != 1 {
+		t.Errorf("Expected the collection to parse intact, got %+v", collection)
+	}
+}
+
+func TestParsePostmanCollectionLenientKeepsStrictError(t *testing.T) {
+	input := `{"info": {"name": "Orders"} // missing the rest`
+	_, strictErr := ParsePostmanCollection(input)
+	_, _, err := ParsePostmanCollectionLenient(input)
+	if err == nil || err.Error() != strictErr.Error() {
+		t.Errorf("Expected the strict error '%v', got '%v'", strictErr, err)
+	}
+
+	strict := `{"info":{"name":"Orders"},"item":[]}`
+	if _, cleaned, err := ParsePostmanCollectionLenient(strict); err != nil || cleaned != strict {
+		t.Errorf("Expected strict JSON to be returned unchanged, got '%s' (%v)", cleaned, err)
+	}
+}
+
+func TestValidateCollectionHeaders(t *testing.T) {
+	collection, err := ParsePostmanCollection(`{
+		"info": {"name": "Imported"},
+		"item": [
+			{"name": "Ping", "request": {"method": "GET", "url": "https://example.com", "header": [{"key": "Accept", "value": "*/*"}]}},
+			{"name": "Admin", "item": [
+				{"name": "Delete", "request": {"method": "DELETE", "url": "https://example.com", "header": [{"key": "X-Note", "value": "bye\r\nX-Admin: true"}]}}
+			]}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateCollectionHeaders(collection)
+	if !errors.Is(err, ErrInvalidHeader) || !strings.Contains(err.Error(), "Admin / Delete") {
+		t.Errorf("Expected an invalid header in Admin / Delete, got %v", err)
+	}
+
+	collection.Item = collection.Item[:1]
+	if err := ValidateCollectionHeaders(collection); err != nil {
+		t.Errorf("Expected clean headers to pass, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"postmanxodja/config"
+)
+
+// commonPasswords are rejected regardless of the policy (compared lowercased)
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "abc123": true,
+	"111111": true, "000000": true, "iloveyou": true, "admin": true,
+	"admin123": true, "welcome": true, "welcome1": true, "letmein": true,
+	"monkey": true, "dragon": true, "football": true, "baseball": true,
+	"sunshine": true, "princess": true, "master": true, "changeme": true,
+}
+
+// PasswordStrengthError lists the password criteria that weren't met
+type PasswordStrengthError struct {
+	Unmet []string
+}
+
+func (e *PasswordStrengthError) Error() string {
+	return "Password must have " + strings.Join(e.Unmet, ", ")
+}
+
+// ValidatePasswordStrength checks a new password against the configured
+// policy: minimum length, a letter plus a digit or symbol, optionally mixed
+// case, and not a common password. Returns a *PasswordStrengthError.
+func ValidatePasswordStrength(password string) error {
+	minLength := config.AppConfig.PasswordMinLength
+	var unmet []string
+
+	if len([]rune(password)) < minLength {
+		unmet = append(unmet, fmt.Sprintf("at least %d characters", minLength))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if !hasLower && !hasUpper {
+		unmet = append(unmet, "a letter")
+	}
+	if !hasDigit && !hasSymbol {
+		unmet = append(unmet, "a digit or symbol")
+	}
+	if config.AppConfig.PasswordRequireMixedCase && (!hasLower || !hasUpper) {
+		unmet = append(unmet, "both upper and lower case letters")
+	}
+	if commonPasswords[strings.ToLower(password)] {
+		unmet = append(unmet, "to not be a commonly used password")
+	}
+
+	if len(unmet) > 0 {
+		return &PasswordStrengthError{Unmet: unmet}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"postmanxodja/config"
+)
+
+func usePasswordPolicy(t *testing.T, minLength int, mixedCase bool) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{PasswordMinLength: minLength, PasswordRequireMixedCase: mixedCase}
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestValidatePasswordStrengthWeak(t *testing.T) {
+	usePasswordPolicy(t, 8, false)
+
+	tests := map[string]int{ // password -> number of unmet criteria
+		"123456":    3, // too short, no letter, common
+		"abcdefgh":  1, // no digit or symbol
+		"Password1": 1, // common (case-insensitive)
+		"ab1":       1, // too short
+		"        ":  2, // spaces only: no letter, no digit or symbol
+		"":          3,
+	}
+
+	for password, wantUnmet := range tests {
+		err := ValidatePasswordStrength(password)
+		var weak *PasswordStrengthError
+		if !errors.As(err, &weak) {
+			t.Errorf("Password '%s': expected a PasswordStrengthError, got %v", password, err)
+			continue
+		}
+		if len(weak.Unmet) != wantUnmet {
+			t.Errorf("Password '%s': expected %d unmet criteria, got %v", password, wantUnmet, weak.Unmet)
+		}
+	}
+}
+
+func TestValidatePasswordStrengthStrong(t *testing.T) {
+	usePasswordPolicy(t, 8, false)
+
+	for _, password := range []string{"correct-horse", "tr0ub4dor", "Пароль2024"} {
+		if err := ValidatePasswordStrength(password); err != nil {
+			t.Errorf("Password '%s': expected no error, got %v", password, err)
+		}
+	}
+}
+
+func TestValidatePasswordStrengthMixedCase(t *testing.T) {
+	usePasswordPolicy(t, 10, true)
+
+	err := ValidatePasswordStrength("lowercase-only")
+	if err == nil {
+		t.Fatal("Expected mixed case to be required")
+	}
+	if err.Error() != "Password must have both upper and lower case letters" {
+		t.Errorf("Unexpected message '%s'", err.Error())
+	}
+
+	if err := ValidatePasswordStrength("MixedCase-ok"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := ValidatePasswordStrength("Short-1"); err == nil {
+		t.Error("Expected the configured minimum length to apply")
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+
+	"postmanxodja/config"
+	"postmanxodja/models"
+)
+
+// RedactedValue replaces the value of a sensitive header in stored and
+// logged copies of requests and responses
+const RedactedValue = "***"
+
+// IsRedactedHeader reports whether name is on the REDACTED_HEADERS list
+// (DefaultRedactedHeaders without a config)
+func IsRedactedHeader(name string) bool {
+	redacted := config.DefaultRedactedHeaders
+	if config.AppConfig != nil && len(config.AppConfig.RedactedHeaders) > 0 {
+		redacted = config.AppConfig.RedactedHeaders
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, header := range redacted {
+		if name == header {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of headers with the values of sensitive
+// headers replaced by RedactedValue; headers itself is left untouched so the
+// live response keeps them
+func RedactHeaders(headers map[string]string) models.Variables {
+	if len(headers) == 0 {
+		return nil
+	}
+	redacted := make(models.Variables, len(headers))
+	for name, value := range headers {
+		if IsRedactedHeader(name) {
+			value = RedactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// RequestHeaders returns the headers req sends, from HeaderList when it's
+// set (repeated headers joined with ", ") and Headers otherwise
+func RequestHeaders(req *models.ExecuteRequest) map[string]string {
+	if len(req.HeaderList) == 0 {
+		return req.Headers
+	}
+	headers := make(map[string]string, len(req.HeaderList))
+	for _, header := range req.HeaderList {
+		if existing, ok := headers[header.Key]; ok {
+			headers[header.Key] = existing + ", " + header.Value
+			continue
+		}
+		headers[header.Key] = header.Value
+	}
+	return headers
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"postmanxodja/config"
+	"postmanxodja/models"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string]string{"Authorization": "Bearer secret", "set-cookie": "sid=1", "Content-Type": "application/json"}
+	redacted := RedactHeaders(headers)
+	if redacted["Authorization"] != RedactedValue || redacted["set-cookie"] != RedactedValue ||
+		redacted["Content-Type"] != "application/json" {
+		t.Errorf("redacted = %v", redacted)
+	}
+	if headers["Authorization"] != "Bearer secret" {
+		t.Error("RedactHeaders changed the original headers")
+	}
+
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{RedactedHeaders: []string{"x-session"}}
+	t.Cleanup(func() { config.AppConfig = previous })
+	redacted = RedactHeaders(map[string]string{"X-Session": "abc", "Authorization": "Bearer secret"})
+	if redacted["X-Session"] != RedactedValue || redacted["Authorization"] != "Bearer secret" {
+		t.Errorf("with REDACTED_HEADERS=x-session: %v", redacted)
+	}
+}
+
+func TestRequestHeadersFromList(t *testing.T) {
+	req := &models.ExecuteRequest{
+		Headers:    map[string]string{"Ignored": "yes"},
+		HeaderList: []models.KeyValue{{Key: "Accept", Value: "a"}, {Key: "Accept", Value: "b"}, {Key: "X-One", Value: "1"}},
+	}
+	headers := RequestHeaders(req)
+	if len(headers) != 2 || headers["Accept"] != "a, b" || headers["X-One"] != "1" {
+		t.Errorf("headers = %v", headers)
+	}
+}
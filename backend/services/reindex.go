@@ -0,0 +1,58 @@
+package services
+
+import (
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+// Reindex recomputes the denormalized fields the app keeps next to the data
+// they're derived from, after a bug let them drift. It only writes rows whose
+// value is wrong, so running it again right away changes nothing. Soft-deleted
+// rows are included since restoring them brings the fields back into use.
+func Reindex() (*models.ReindexResult, error) {
+	result := &models.ReindexResult{}
+
+	var err error
+	if result.CollectionRequestCounts, err = reindexRequestCounts(); err != nil {
+		return nil, err
+	}
+
+	// Collections saved before updated_at existed; created_at is the best
+	// guess for when they last changed. UpdateColumn keeps the hooks (and
+	// the timestamp itself) out of it.
+	update := database.GetDB().Unscoped().Model(&models.Collection{}).Where("updated_at IS NULL").
+		UpdateColumn("updated_at", gorm.Expr("created_at"))
+	if update.Error != nil {
+		return nil, update.Error
+	}
+	result.CollectionUpdatedAt = update.RowsAffected
+
+	result.Total = result.CollectionRequestCounts + result.CollectionUpdatedAt
+	return result, nil
+}
+
+// reindexRequestCounts recounts the requests of every collection, fixing the
+// request_count of those that disagree with their RawJSON
+func reindexRequestCounts() (int64, error) {
+	db := database.GetDB()
+	var fixed int64
+	var collections []models.Collection
+	err := db.Unscoped().Select("id", "raw_json", "request_count").
+		FindInBatches(&collections, 100, func(tx *gorm.DB, batch int) error {
+			for _, collection := range collections {
+				count := models.CountCollectionRequests(collection.RawJSON)
+				if count == collection.RequestCount {
+					continue
+				}
+				if err := db.Unscoped().Model(&models.Collection{}).Where("id = ?", collection.ID).
+					UpdateColumn("request_count", count).Error; err != nil {
+					return err
+				}
+				fixed++
+			}
+			return nil
+		}).Error
+	return fixed, err
+}
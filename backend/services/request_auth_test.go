@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+// ntlmChallenge builds a minimal NTLM type 2 (challenge) message
+func ntlmChallenge() string {
+	msg := make([]byte, 48)
+	copy(msg, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[16:], 48)         // empty target name at the end
+	binary.LittleEndian.PutUint32(msg[20:], 0x00000201) // unicode, NTLM
+	copy(msg[24:32], []byte{1, 2, 3, 4, 5, 6, 7, 8})    // server challenge
+	binary.LittleEndian.PutUint32(msg[44:], 48)         // empty target info
+	return base64.StdEncoding.EncodeToString(msg)
+}
+
+// ntlmMessageType decodes the type of the NTLM message in an Authorization
+// header, or 0 when there is none
+func ntlmMessageType(header string) uint32 {
+	token, ok := strings.CutPrefix(header, "NTLM ")
+	if !ok {
+		return 0
+	}
+	msg, err := base64.StdEncoding.DecodeString(token)
+	if err != nil || len(msg) < 12 || string(msg[:8]) != "NTLMSSP\x00" {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(msg[8:])
+}
+
+func TestExecuteNTLMHandshake(t *testing.T) {
+	useLoopback(t)
+
+	var mu sync.Mutex
+	var steps []uint32
+	connections := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step := ntlmMessageType(r.Header.Get("Authorization"))
+		mu.Lock()
+		steps = append(steps, step)
+		connections[r.RemoteAddr] = true
+		mu.Unlock()
+
+		switch step {
+		case 1:
+			w.Header().Set("WWW-Authenticate", "NTLM "+ntlmChallenge())
+			w.WriteHeader(http.StatusUnauthorized)
+		case 3:
+			w.Write([]byte("authenticated"))
+		default:
+			w.Header().Set("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	response, err := ExecuteHTTPRequest(&models.ExecuteRequest{
+		Method: "GET",
+		URL:    server.URL + "/legacy",
+		Auth: &models.RequestAuth{
+			Type: AuthTypeNTLM,
+			NTLM: &models.NTLMAuth{Username: "alice", Password: "secret", Domain: "CORP"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.Status != http.StatusOK || response.Body != "authenticated" {
+		t.Errorf("Expected the authenticated response, got %d '%s'", response.Status, response.Body)
+	}
+	if len(steps) != 3 || steps[0] != 0 || steps[1] != 1 || steps[2] != 3 {
+		t.Errorf("Expected anonymous, negotiate, authenticate requests, got %v", steps)
+	}
+	if len(connections) != 1 {
+		t.Errorf("Expected the handshake on a single connection, got %d", len(connections))
+	}
+}
+
+func TestValidateRequestAuth(t *testing.T) {
+	if err := ValidateRequestAuth(nil); err != nil {
+		t.Errorf("Expected no auth to be valid, got %v", err)
+	}
+	if err := ValidateRequestAuth(&models.RequestAuth{Type: AuthTypeNTLM}); !errors.Is(err, ErrInvalidAuth) {
+		t.Errorf("Expected ErrInvalidAuth without credentials, got %v", err)
+	}
+	if err := ValidateRequestAuth(&models.RequestAuth{Type: "kerberos"}); !errors.Is(err, ErrInvalidAuth) {
+		t.Errorf("Expected ErrInvalidAuth for an unknown type, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// fallbackUserAgent is sent when DEFAULT_USER_AGENT isn't configured, instead
+// of Go's "Go-http-client/1.1" that some WAFs block
+const fallbackUserAgent = "PostmanXodja/1.0"
+
+// DefaultUserAgent is the User-Agent for requests that don't set one
+func DefaultUserAgent() string {
+	if config.AppConfig != nil && config.AppConfig.DefaultUserAgent != "" {
+		return config.AppConfig.DefaultUserAgent
+	}
+	return fallbackUserAgent
+}
+
+// SetDefaultUserAgent sets the default User-Agent unless the request has one
+func SetDefaultUserAgent(httpReq *http.Request) {
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", DefaultUserAgent())
+	}
+}
+
+// ValidateDefaultHeaders rejects header names that can't be sent
+func ValidateDefaultHeaders(headers map[string]string) error {
+	for name := range headers {
+		if name == "" || strings.TrimSpace(name) != name || strings.ContainsAny(name, ": \t") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+// TeamDefaultHeaders returns the team's default headers, or nil when it has
+// none (or no team applies)
+func TeamDefaultHeaders(teamID uint) (map[string]string, error) {
+	if teamID == 0 {
+		return nil, nil
+	}
+	var defaults models.TeamRequestDefaults
+	if err := database.GetDB().Where("team_id = ?", teamID).Limit(1).Find(&defaults).Error; err != nil {
+		return nil, err
+	}
+	return defaults.Headers, nil
+}
+
+// ApplyDefaultHeaders adds each default header the request doesn't set
+// itself (names compare case-insensitively), so request headers win
+func ApplyDefaultHeaders(req *models.ExecuteRequest, defaults map[string]string) {
+	for name, value := range defaults {
+		if !hasHeader(req, name) {
+			addHeader(req, name, value)
+		}
+	}
+}
+
+// MergeDefaultHeaders is ApplyDefaultHeaders for a plain header map
+func MergeDefaultHeaders(headers, defaults map[string]string) map[string]string {
+	req := &models.ExecuteRequest{Headers: headers}
+	ApplyDefaultHeaders(req, defaults)
+	return req.Headers
+}
+
+// addHeader adds a header to whichever of HeaderList and Headers the request
+// is sent from
+func addHeader(req *models.ExecuteRequest, name, value string) {
+	if len(req.HeaderList) > 0 {
+		req.HeaderList = append(req.HeaderList, models.KeyValue{Key: name, Value: value})
+		return
+	}
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	req.Headers[name] = value
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestApplyDefaultHeaders(t *testing.T) {
+	defaults := map[string]string{"User-Agent": "TeamAgent/2.0", "X-Tenant": "acme"}
+
+	req := &models.ExecuteRequest{Headers: map[string]string{"x-tenant": "other"}}
+	ApplyDefaultHeaders(req, defaults)
+	if req.Headers["x-tenant"] != "other" || req.Headers["X-Tenant"] != "" {
+		t.Errorf("Expected the request's own header to win case-insensitively, got %v", req.Headers)
+	}
+	if req.Headers["User-Agent"] != "TeamAgent/2.0" {
+		t.Errorf("Expected the default User-Agent to be added, got %v", req.Headers)
+	}
+
+	listed := &models.ExecuteRequest{HeaderList: []models.KeyValue{{Key: "Accept", Value: "*/*"}}}
+	ApplyDefaultHeaders(listed, defaults)
+	if len(listed.HeaderList) != 3 || listed.Headers != nil {
+		t.Errorf("Expected defaults to be appended to the header list, got %+v", listed)
+	}
+
+	if merged := MergeDefaultHeaders(nil, defaults); merged["X-Tenant"] != "acme" {
+		t.Errorf("Expected defaults in a nil map, got %v", merged)
+	}
+}
+
+func TestExecuteHTTPRequestUserAgent(t *testing.T) {
+	useLoopback(t)
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{DefaultUserAgent: "Acme-Tester/3"}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	var gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if gotAgent != "Acme-Tester/3" {
+		t.Errorf("Expected the configured User-Agent, got '%s'", gotAgent)
+	}
+
+	req := &models.ExecuteRequest{Method: http.MethodGet, URL: server.URL, Headers: map[string]string{"user-agent": "curl/8"}}
+	ApplyDefaultHeaders(req, map[string]string{"User-Agent": "TeamAgent/2.0"})
+	if _, err := ExecuteHTTPRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if gotAgent != "curl/8" {
+		t.Errorf("Expected the request's User-Agent to win, got '%s'", gotAgent)
+	}
+
+	config.AppConfig = nil
+	if _, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: http.MethodGet, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if gotAgent != fallbackUserAgent {
+		t.Errorf("Expected the fallback User-Agent, got '%s'", gotAgent)
+	}
+}
+
+func TestTeamDefaultHeaders(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "owner@example.com")
+	team, err := CreateTeamWithOwner("Acme", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers, err := TeamDefaultHeaders(team.ID); err != nil || headers != nil {
+		t.Fatalf("Expected no defaults yet, got %v (%v)", headers, err)
+	}
+	database.DB.Create(&models.TeamRequestDefaults{TeamID: team.ID, Headers: models.Variables{"X-Tenant": "acme"}})
+	if headers, err := TeamDefaultHeaders(team.ID); err != nil || headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected the team's defaults, got %v (%v)", headers, err)
+	}
+	if headers, _ := TeamDefaultHeaders(0); headers != nil {
+		t.Errorf("Expected no defaults without a team, got %v", headers)
+	}
+}
+
+func TestValidateDefaultHeaders(t *testing.T) {
+	if err := ValidateDefaultHeaders(map[string]string{"X-Tenant": "acme"}); err != nil {
+		t.Errorf("Expected a valid header, got %v", err)
+	}
+	for _, name := range []string{"", " X-Pad", "X:Colon", "X Space"} {
+		if err := ValidateDefaultHeaders(map[string]string{name: "v"}); err == nil {
+			t.Errorf("Expected header name %q to be rejected", name)
+		}
+	}
+}
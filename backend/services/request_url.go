@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"postmanxodja/config"
+)
+
+// ErrInvalidURL is returned (wrapped) for a request URL the executor can't
+// send to
+var ErrInvalidURL = errors.New("invalid URL")
+
+// NormalizeRequestURL checks that rawURL is an http or https URL with a host,
+// failing with a clear error rather than whatever http.NewRequest would say.
+// A URL typed without a scheme ("example.com/users", "localhost:8080") gets
+// http:// in front unless AUTO_PREPEND_URL_SCHEME is off.
+func NormalizeRequestURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: URL is required", ErrInvalidURL)
+	}
+	if !hasURLScheme(rawURL) {
+		if !autoPrependURLScheme() {
+			return "", fmt.Errorf("%w: %q has no scheme, start it with http:// or https://", ErrInvalidURL, rawURL)
+		}
+		rawURL = "http://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("%w: unsupported scheme %q, only http and https are supported", ErrInvalidURL, parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("%w: %q has no host", ErrInvalidURL, rawURL)
+	}
+	return rawURL, nil
+}
+
+// hasURLScheme reports whether rawURL starts with "scheme:". url.Parse reads
+// "localhost:8080" as scheme "localhost", so a colon followed by a port
+// number is taken as a host and port instead.
+func hasURLScheme(rawURL string) bool {
+	colon := strings.IndexByte(rawURL, ':')
+	if colon <= 0 {
+		return false
+	}
+	for i, ch := range rawURL[:colon] {
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z':
+		case i > 0 && ('0' <= ch && ch <= '9' || ch == '+' || ch == '-' || ch == '.'):
+		default:
+			return false
+		}
+	}
+	rest := rawURL[colon+1:]
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+func autoPrependURLScheme() bool {
+	return config.AppConfig == nil || config.AppConfig.AutoPrependURLScheme
+}
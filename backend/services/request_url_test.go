@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"postmanxodja/config"
+)
+
+func TestNormalizeRequestURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://api.example.com/users?page=2", "https://api.example.com/users?page=2"},
+		{"HTTP://example.com", "HTTP://example.com"},
+		{"  http://example.com  ", "http://example.com"},
+		{"example.com/users", "http://example.com/users"},
+		{"localhost:8080/health", "http://localhost:8080/health"},
+		{"127.0.0.1:3000", "http://127.0.0.1:3000"},
+		{"[::1]:8080/", "http://[::1]:8080/"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeRequestURL(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeRequestURL(%q) = %q, %v; expected %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRequestURLRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"htp://foo",
+		"file:///etc/passwd",
+		"ftp://files.example.com/a.txt",
+		"mailto:ops@example.com",
+		"http://",
+		"http:///path-only",
+		"http:/example.com",
+		"http://exa mple.com",
+	} {
+		if got, err := NormalizeRequestURL(in); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Expected ErrInvalidURL for %q, got %q, %v", in, got, err)
+		}
+	}
+}
+
+func TestNormalizeRequestURLWithoutPrepending(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{AutoPrependURLScheme: false}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	if _, err := NormalizeRequestURL("example.com/users"); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Expected a URL without a scheme to be rejected, got %v", err)
+	}
+	if got, err := NormalizeRequestURL("https://example.com"); err != nil || got != "https://example.com" {
+		t.Errorf("Expected a full URL to pass, got %q, %v", got, err)
+	}
+}
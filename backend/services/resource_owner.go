@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownResourceType is returned by LookupResourceOwner for a type other
+// than collection or environment
+var ErrUnknownResourceType = errors.New("type must be collection or environment")
+
+// LookupResourceOwner finds the collection or environment with id, deleted
+// or not, and the team it belongs to. It returns gorm.ErrRecordNotFound when
+// there's no such resource.
+func LookupResourceOwner(resourceType string, id uint) (*models.ResourceOwner, error) {
+	db := database.GetDB().Unscoped()
+	owner := &models.ResourceOwner{Type: resourceType, ID: id}
+	var teamID *uint
+
+	switch resourceType {
+	case "collection":
+		var collection models.Collection
+		if err := db.Select("id", "name", "request_count", "team_id", "created_at", "deleted_at").
+			First(&collection, id).Error; err != nil {
+			return nil, err
+		}
+		owner.Name, owner.CreatedAt = collection.Name, collection.CreatedAt
+		owner.DeletedAt = deletedAt(collection.DeletedAt)
+		owner.RequestCount = &collection.RequestCount
+		teamID = collection.TeamID
+	case "environment":
+		var environment models.Environment
+		if err := db.First(&environment, id).Error; err != nil {
+			return nil, err
+		}
+		owner.Name, owner.CreatedAt = environment.Name, environment.CreatedAt
+		owner.DeletedAt = deletedAt(environment.DeletedAt)
+		count := len(environment.Variables)
+		owner.VariableCount = &count
+		teamID = environment.TeamID
+	default:
+		return nil, ErrUnknownResourceType
+	}
+
+	if teamID == nil {
+		return owner, nil
+	}
+	var team models.Team
+	if err := db.First(&team, *teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Left behind by a purged team
+			return owner, nil
+		}
+		return nil, err
+	}
+	owner.Team = &models.ResourceOwnerTeam{
+		ID:             team.ID,
+		Name:           team.Name,
+		IsPersonal:     team.IsPersonal,
+		OrganizationID: team.OrganizationID,
+		DeletedAt:      deletedAt(team.DeletedAt),
+	}
+	if err := database.GetDB().Model(&models.TeamMember{}).Where("team_id = ?", team.ID).
+		Count(&owner.Team.MemberCount).Error; err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+func deletedAt(deleted gorm.DeletedAt) *time.Time {
+	if !deleted.Valid {
+		return nil
+	}
+	return &deleted.Time
+}
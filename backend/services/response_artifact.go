@@ -0,0 +1,181 @@
+package services
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"postmanxodja/models"
+)
+
+// Binary response bodies are kept in memory for a while so they can be
+// downloaded as files instead of read out of the JSON response. Like the
+// response cache the artifacts reset on restart and aren't shared between
+// instances.
+const (
+	ResponseArtifactTTL       = 15 * time.Minute
+	maxResponseArtifactBytes  = 32 << 20  // a larger body gets no artifact
+	maxResponseArtifactsBytes = 256 << 20 // all artifacts together; oldest go first
+)
+
+// ResponseArtifact is a stored response body and what's needed to serve it
+type ResponseArtifact struct {
+	ID          string
+	UserID      uint
+	ContentType string
+	Filename    string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+var (
+	responseArtifactsMu    sync.Mutex
+	responseArtifactsOrder = list.New() // oldest first, so also first to expire
+	responseArtifacts      = make(map[string]*list.Element)
+	responseArtifactsBytes int64
+)
+
+// binaryMediaTypes are non-text types whose bodies may still happen to be
+// valid UTF-8
+var binaryMediaTypes = []string{
+	"application/octet-stream", "application/pdf", "application/zip", "application/gzip",
+	"application/x-protobuf", "application/protobuf", "application/msword",
+}
+
+// IsBinaryBody reports whether a response body is binary rather than text:
+// it isn't valid UTF-8, or its Content-Type is an image, audio, video, font
+// or other known binary type (SVG aside)
+func IsBinaryBody(contentType string, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if !utf8.Valid(body) {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	for _, binary := range binaryMediaTypes {
+		if mediaType == binary {
+			return true
+		}
+	}
+	return false
+}
+
+// artifactFilename is the filename of Content-Disposition, or "response"
+func artifactFilename(contentDisposition string) string {
+	if _, params, err := mime.ParseMediaType(contentDisposition); err == nil {
+		// Only the base name, whatever path the server put in it
+		name := params["filename"]
+		if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+			name = name[i+1:]
+		}
+		if name != "" && name != "." && name != ".." {
+			return name
+		}
+	}
+	return "response"
+}
+
+// StoreResponseArtifact keeps body, a response with the given (flattened)
+// headers, for userID to download until the TTL runs out. ok is false for a
+// body too large to keep.
+func StoreResponseArtifact(userID uint, headers map[string]string, body []byte, now time.Time) (*ResponseArtifact, bool) {
+	if len(body) > maxResponseArtifactBytes {
+		return nil, false
+	}
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	artifact := &ResponseArtifact{
+		ID:          hex.EncodeToString(idBytes),
+		UserID:      userID,
+		ContentType: flatHeader(headers, "Content-Type"),
+		Filename:    artifactFilename(flatHeader(headers, "Content-Disposition")),
+		Body:        body,
+		ExpiresAt:   now.Add(ResponseArtifactTTL),
+	}
+	if artifact.ContentType == "" {
+		artifact.ContentType = "application/octet-stream"
+	}
+
+	responseArtifactsMu.Lock()
+	defer responseArtifactsMu.Unlock()
+	for front := responseArtifactsOrder.Front(); front != nil; front = responseArtifactsOrder.Front() {
+		oldest := front.Value.(*ResponseArtifact)
+		if oldest.ExpiresAt.After(now) && responseArtifactsBytes+int64(len(body)) <= maxResponseArtifactsBytes {
+			break
+		}
+		removeResponseArtifact(front)
+	}
+	responseArtifacts[artifact.ID] = responseArtifactsOrder.PushBack(artifact)
+	responseArtifactsBytes += int64(len(body))
+	return artifact, true
+}
+
+// AttachResponseArtifact stores response's body for userID when it's binary
+// and sets ArtifactID and ArtifactExpiresAt, clearing Body: the bytes would
+// only be mangled by the JSON response, the download has them intact.
+// Transcoded bodies (with a Charset) are text, and previews cut short aren't
+// the whole file.
+func AttachResponseArtifact(userID uint, response *models.ExecuteResponse, now time.Time) {
+	if response.Charset != "" || response.Truncated || !IsBinaryBody(flatHeader(response.Headers, "Content-Type"), []byte(response.Body)) {
+		return
+	}
+	if artifact, ok := StoreResponseArtifact(userID, response.Headers, []byte(response.Body), now); ok {
+		response.ArtifactID = artifact.ID
+		response.ArtifactExpiresAt = &artifact.ExpiresAt
+		response.Body = ""
+	}
+}
+
+// GetResponseArtifact returns userID's artifact with the given ID, unless
+// it has expired
+func GetResponseArtifact(id string, userID uint, now time.Time) (*ResponseArtifact, bool) {
+	responseArtifactsMu.Lock()
+	defer responseArtifactsMu.Unlock()
+	element, ok := responseArtifacts[id]
+	if !ok {
+		return nil, false
+	}
+	artifact := element.Value.(*ResponseArtifact)
+	if !artifact.ExpiresAt.After(now) {
+		removeResponseArtifact(element)
+		return nil, false
+	}
+	if artifact.UserID != userID {
+		return nil, false
+	}
+	return artifact, true
+}
+
+// removeResponseArtifact drops element; responseArtifactsMu must be held
+func removeResponseArtifact(element *list.Element) {
+	artifact := responseArtifactsOrder.Remove(element).(*ResponseArtifact)
+	delete(responseArtifacts, artifact.ID)
+	responseArtifactsBytes -= int64(len(artifact.Body))
+}
+
+// flatHeader looks name up in flattened headers, ignoring case
+func flatHeader(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"postmanxodja/models"
+	"testing"
+	"time"
+)
+
+func TestIsBinaryBody(t *testing.T) {
+	for _, tt := range []struct {
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"application/json", `{"a":1}`, false},
+		{"", "plain", false},
+		{"", "\xff\xfe\x00", true},
+		{"image/png", "PNG", true},
+		{"image/svg+xml", "<svg/>", false},
+		{"application/pdf; charset=binary", "%PDF-1.7", true},
+		{"image/png", "", false},
+	} {
+		if got := IsBinaryBody(tt.contentType, []byte(tt.body)); got != tt.want {
+			t.Errorf("IsBinaryBody(%q, %q) = %v, want %v", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestResponseArtifactExpires(t *testing.T) {
+	now := time.Now()
+	response := &models.ExecuteResponse{Headers: map[string]string{"Content-Type": "application/octet-stream"}, Body: "\x00\x01"}
+	AttachResponseArtifact(7, response, now)
+	if response.ArtifactID == "" {
+		t.Fatal("Expected an artifact")
+	}
+	if response.Body != "" {
+		t.Errorf("Expected the body left out of the response, got %q", response.Body)
+	}
+
+	artifact, ok := GetResponseArtifact(response.ArtifactID, 7, now.Add(ResponseArtifactTTL-time.Second))
+	if !ok || artifact.Filename != "response" || string(artifact.Body) != "\x00\x01" {
+		t.Errorf("Expected the artifact within its TTL, got %+v", artifact)
+	}
+	if _, ok := GetResponseArtifact(response.ArtifactID, 7, now.Add(ResponseArtifactTTL)); ok {
+		t.Error("Expected the artifact to expire after its TTL")
+	}
+	if _, ok := GetResponseArtifact(response.ArtifactID, 7, now); ok {
+		t.Error("Expected an expired artifact to be dropped")
+	}
+
+	// A cut-short preview isn't the whole file
+	preview := &models.ExecuteResponse{Body: "\xff\xfe", Truncated: true}
+	AttachResponseArtifact(7, preview, now)
+	if preview.ArtifactID != "" {
+		t.Error("Expected no artifact for a truncated body")
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"postmanxodja/models"
+)
+
+// Responses of GET and HEAD requests that opt in with CacheTTLMs are kept in
+// an in-memory LRU for the TTL. Like the other execution state the cache
+// resets on restart and isn't shared between instances.
+const (
+	maxCachedResponses     = 1000
+	maxCachedResponseBytes = 1 << 20
+	maxResponseCacheTTL    = time.Hour
+)
+
+type cachedResponseEntry struct {
+	key       string
+	response  models.ExecuteResponse
+	expiresAt time.Time
+}
+
+var (
+	responseCacheMu    sync.Mutex
+	responseCacheOrder = list.New() // most recently used first
+	responseCache      = make(map[string]*list.Element)
+)
+
+// ValidateCacheTTL checks cache_ttl_ms: not negative and at most an hour
+func ValidateCacheTTL(ttlMs int64) error {
+	if ttlMs < 0 {
+		return fmt.Errorf("cache_ttl_ms must not be negative")
+	}
+	if time.Duration(ttlMs)*time.Millisecond > maxResponseCacheTTL {
+		return fmt.Errorf("cache_ttl_ms must be at most %d", maxResponseCacheTTL.Milliseconds())
+	}
+	return nil
+}
+
+// cacheableMethod reports whether responses to method may be cached
+func cacheableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// responseCacheKey identifies what httpReq (built from req) would fetch for
+// scope: the method, URL and every header sent, plus the options that change
+// the response the executor returns
+func responseCacheKey(scope string, req *models.ExecuteRequest, httpReq *http.Request) string {
+	headers := make([]string, 0, len(httpReq.Header))
+	for name, values := range httpReq.Header {
+		headers = append(headers, strings.ToLower(name)+": "+strings.Join(values, ", "))
+	}
+	sort.Strings(headers)
+
+	auth, _ := json.Marshal(req.Auth)
+	ndjson, _ := json.Marshal(req.NDJSON)
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		scope, httpReq.Method, httpReq.URL.String(), httpReq.Host, strings.Join(headers, "\n"), req.Body,
+		string(auth), string(ndjson),
+		strconv.FormatInt(req.PreviewBytes, 10), strconv.FormatBool(req.IncludeTLSInfo),
+		strconv.FormatBool(req.UseETag), req.BodyTransfer,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// noStore reports whether the response asked not to be stored
+func noStore(response *models.ExecuteResponse) bool {
+	for name, value := range response.Headers {
+		if !strings.EqualFold(name, "Cache-Control") {
+			continue
+		}
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cachedResponse returns a copy of the live response stored under key,
+// marking it as the most recently used
+func cachedResponse(key string, now time.Time) (*models.ExecuteResponse, bool) {
+	responseCacheMu.Lock()
+	defer responseCacheMu.Unlock()
+
+	element, ok := responseCache[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cachedResponseEntry)
+	if !now.Before(entry.expiresAt) {
+		responseCacheOrder.Remove(element)
+		delete(responseCache, key)
+		return nil, false
+	}
+	responseCacheOrder.MoveToFront(element)
+
+	response := entry.response
+	response.Headers = make(map[string]string, len(entry.response.Headers))
+	for name, value := range entry.response.Headers {
+		response.Headers[name] = value
+	}
+	response.FromCache = true
+	return &response, true
+}
+
+// cacheResponse stores response under key for ttl, evicting the least
+// recently used entry when the cache is full. Only 2xx responses are kept,
+// and not those marked no-store or with large bodies: a 304 in particular
+// only means something next to the ETag it answered.
+func cacheResponse(key string, response *models.ExecuteResponse, ttl time.Duration, now time.Time) {
+	if response.Status < 200 || response.Status >= 300 || noStore(response) || len(response.Body) > maxCachedResponseBytes {
+		return
+	}
+
+	responseCacheMu.Lock()
+	defer responseCacheMu.Unlock()
+
+	entry := &cachedResponseEntry{key: key, response: *response, expiresAt: now.Add(ttl)}
+	entry.response.ETagSent = ""
+	if element, ok := responseCache[key]; ok {
+		element.Value = entry
+		responseCacheOrder.MoveToFront(element)
+		return
+	}
+	for responseCacheOrder.Len() >= maxCachedResponses {
+		oldest := responseCacheOrder.Back()
+		responseCacheOrder.Remove(oldest)
+		delete(responseCache, oldest.Value.(*cachedResponseEntry).key)
+	}
+	responseCache[key] = responseCacheOrder.PushFront(entry)
+}
+
+// executeCached serves req from the cache when it opted in with CacheTTLMs
+// and a live response for the same request is stored under scope; otherwise
+// it runs execute and stores the result
+func executeCached(scope string, req *models.ExecuteRequest, httpReq *http.Request,
+	execute func() (*models.ExecuteResponse, error)) (*models.ExecuteResponse, error) {
+	if req.CacheTTLMs <= 0 || !cacheableMethod(httpReq.Method) {
+		return execute()
+	}
+
+	key := responseCacheKey(scope, req, httpReq)
+	if response, ok := cachedResponse(key, time.Now()); ok {
+		return response, nil
+	}
+	response, err := execute()
+	if err != nil {
+		return nil, err
+	}
+	cacheResponse(key, response, time.Duration(req.CacheTTLMs)*time.Millisecond, time.Now())
+	return response, nil
+}
+
+// ExecuteCached runs the request like ExecuteHTTPRequestContext, caching the
+// response for scope (e.g. a team) as described on CacheTTLMs
+func ExecuteCached(ctx context.Context, scope string, req *models.ExecuteRequest) (*models.ExecuteResponse, error) {
+	httpReq, err := BuildHTTPRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return executeCached(scope, req, httpReq, func() (*models.ExecuteResponse, error) {
+		return ExecuteHTTPRequestContext(ctx, req)
+	})
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/models"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers every request with "hello" and the given
+// Cache-Control, counting the requests it received
+func countingServer(t *testing.T, cacheControl string) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestExecuteCacheHitWithinTTL(t *testing.T) {
+	useLoopback(t)
+	server, hits := countingServer(t, "")
+	req := func(token string) *models.ExecuteRequest {
+		return &models.ExecuteRequest{Method: "GET", URL: server.URL + "/static", CacheTTLMs: 60000,
+			Headers: map[string]string{"Authorization": token}}
+	}
+
+	first, err := ExecuteWithETags(context.Background(), req("a"), 9101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.FromCache {
+		t.Error("first response should not come from the cache")
+	}
+
+	second, err := ExecuteWithETags(context.Background(), req("a"), 9101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.FromCache || second.Body != "hello" || hits.Load() != 1 {
+		t.Errorf("second response: from_cache=%v body=%q, server hits %d; want a cache hit", second.FromCache, second.Body, hits.Load())
+	}
+
+	// Different headers or another user are different cache entries
+	if resp, _ := ExecuteWithETags(context.Background(), req("b"), 9101); resp.FromCache {
+		t.Error("a request with other headers was served from the cache")
+	}
+	if resp, _ := ExecuteWithETags(context.Background(), req("a"), 9102); resp.FromCache {
+		t.Error("another user's request was served from the cache")
+	}
+	if hits.Load() != 3 {
+		t.Errorf("server hits = %d, want 3", hits.Load())
+	}
+}
+
+func TestExecuteCacheMissAfterExpiry(t *testing.T) {
+	useLoopback(t)
+	server, hits := countingServer(t, "")
+	req := func() *models.ExecuteRequest {
+		return &models.ExecuteRequest{Method: "GET", URL: server.URL + "/expiring", CacheTTLMs: 20}
+	}
+
+	if _, err := ExecuteWithETags(context.Background(), req(), 9103); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	resp, err := ExecuteWithETags(context.Background(), req(), 9103)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FromCache || hits.Load() != 2 {
+		t.Errorf("from_cache=%v, server hits %d; want the expired entry refetched", resp.FromCache, hits.Load())
+	}
+}
+
+func TestExecuteCacheSkipsNoStoreAndOtherMethods(t *testing.T) {
+	useLoopback(t)
+	server, hits := countingServer(t, "private, no-store")
+
+	for i := 0; i < 2; i++ {
+		resp, err := ExecuteWithETags(context.Background(),
+			&models.ExecuteRequest{Method: "GET", URL: server.URL + "/secret", CacheTTLMs: 60000}, 9104)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.FromCache {
+			t.Error("a no-store response was served from the cache")
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("server hits = %d, want 2", hits.Load())
+	}
+
+	plain, plainHits := countingServer(t, "")
+	for i := 0; i < 2; i++ {
+		resp, err := ExecuteWithETags(context.Background(),
+			&models.ExecuteRequest{Method: "POST", URL: plain.URL, Body: "{}", CacheTTLMs: 60000}, 9104)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.FromCache {
+			t.Error("a POST was served from the cache")
+		}
+	}
+	if plainHits.Load() != 2 {
+		t.Errorf("POST server hits = %d, want 2", plainHits.Load())
+	}
+}
+
+func TestExecuteCacheSkipsNotModified(t *testing.T) {
+	useLoopback(t)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	req := func() *models.ExecuteRequest {
+		return &models.ExecuteRequest{Method: "GET", URL: server.URL + "/etag", CacheTTLMs: 60000,
+			Headers: map[string]string{"If-None-Match": `"v1"`}}
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := ExecuteWithETags(context.Background(), req(), 9105)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.FromCache || resp.Status != http.StatusNotModified {
+			t.Errorf("request %d: status %d, from_cache=%v; want a live 304", i+1, resp.Status, resp.FromCache)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("server hits = %d, want 2", hits.Load())
+	}
+}
+
+func TestResponseCacheKeyIncludesETagAndTransfer(t *testing.T) {
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://api.test/", nil)
+	base := &models.ExecuteRequest{Method: "GET", URL: "http://api.test/"}
+	key := responseCacheKey("1", base, httpReq)
+	for _, req := range []*models.ExecuteRequest{
+		{Method: "GET", URL: "http://api.test/", UseETag: true},
+		{Method: "GET", URL: "http://api.test/", BodyTransfer: "chunked"},
+	} {
+		if responseCacheKey("1", req, httpReq) == key {
+			t.Errorf("Expected %+v to have its own cache key", req)
+		}
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	for i := 0; i < maxCachedResponses; i++ {
+		cacheResponse("lru-"+strconv.Itoa(i), &models.ExecuteResponse{Status: 200}, time.Minute, now)
+	}
+	// Touch the oldest so the second oldest is evicted instead
+	if _, ok := cachedResponse("lru-"+strconv.Itoa(0), now); !ok {
+		t.Fatal("oldest entry missing before eviction")
+	}
+	cacheResponse("lru-new", &models.ExecuteResponse{Status: 200}, time.Minute, now)
+
+	if _, ok := cachedResponse("lru-"+strconv.Itoa(0), now); !ok {
+		t.Error("recently used entry was evicted")
+	}
+	if _, ok := cachedResponse("lru-"+strconv.Itoa(1), now); ok {
+		t.Error("least recently used entry was kept")
+	}
+}
+
+func TestValidateCacheTTL(t *testing.T) {
+	for ttl, valid := range map[int64]bool{0: true, 5000: true, 3600000: true, -1: false, 3600001: false} {
+		if err := ValidateCacheTTL(ttl); (err == nil) != valid {
+			t.Errorf("ValidateCacheTTL(%d) = %v, want valid=%v", ttl, err, valid)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"encoding/json"
+
+	"postmanxodja/models"
+)
+
+// EncodeFormFields serializes form-data field definitions for a SavedTab.
+// File fields are stored without a value since uploads aren't kept.
+func EncodeFormFields(fields []models.FormField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	stored := make([]models.FormField, len(fields))
+	for i, field := range fields {
+		stored[i] = field
+		if field.Type == "file" {
+			stored[i].Value = ""
+		}
+	}
+
+	data, _ := json.Marshal(stored)
+	return string(data)
+}
+
+// DecodeFormFields reverses EncodeFormFields; invalid data yields no fields
+func DecodeFormFields(data string) []models.FormField {
+	fields := []models.FormField{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &fields)
+	}
+	return fields
+}
+
+// SavedTextFields returns the enabled text fields of a saved form, the ones
+// that can be replayed without a new upload
+func SavedTextFields(fields []models.FormField) []models.FormField {
+	var text []models.FormField
+	for _, field := range fields {
+		if field.Type != "file" && !field.Disabled {
+			text = append(text, field)
+		}
+	}
+	return text
+}
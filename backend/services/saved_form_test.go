@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"postmanxodja/models"
+)
+
+func TestFormFieldsRoundTrip(t *testing.T) {
+	fields := []models.FormField{
+		{Key: "title", Value: "Quarterly report", Type: "text"},
+		{Key: "tags", Value: "finance,q3", Type: "text", Disabled: true},
+		{Key: "attachment", Value: "C:\\fakepath\\report.pdf", Type: "file", FileName: "report.pdf"},
+	}
+
+	decoded := DecodeFormFields(EncodeFormFields(fields))
+
+	if len(decoded) != 3 {
+		t.Fatalf("Expected 3 fields, got %d", len(decoded))
+	}
+	if decoded[0] != fields[0] || decoded[1] != fields[1] {
+		t.Errorf("Expected text fields to round-trip, got %+v", decoded[:2])
+	}
+	if decoded[2].Key != "attachment" || decoded[2].FileName != "report.pdf" {
+		t.Errorf("Expected file key and name to be kept, got %+v", decoded[2])
+	}
+	if decoded[2].Value != "" {
+		t.Errorf("Expected file value to be dropped, got '%s'", decoded[2].Value)
+	}
+
+	text := SavedTextFields(decoded)
+	if len(text) != 1 || text[0].Key != "title" {
+		t.Errorf("Expected only the enabled text field to replay, got %+v", text)
+	}
+}
+
+func TestDecodeFormFieldsEmpty(t *testing.T) {
+	if EncodeFormFields(nil) != "" {
+		t.Error("Expected no fields to encode as empty string")
+	}
+	if fields := DecodeFormFields(""); fields == nil || len(fields) != 0 {
+		t.Errorf("Expected an empty, non-nil list, got %v", fields)
+	}
+	if fields := DecodeFormFields("not json"); len(fields) != 0 {
+		t.Errorf("Expected invalid data to yield no fields, got %v", fields)
+	}
+}
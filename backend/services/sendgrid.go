@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"postmanxodja/config"
+)
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender sends email through SendGrid's HTTP API, for hosts where
+// outbound SMTP is blocked
+type SendGridSender struct {
+	apiKey string
+	from   string
+	url    string
+	client *http.Client
+}
+
+func NewSendGridSender() *SendGridSender {
+	return &SendGridSender{
+		apiKey: config.AppConfig.SendGridAPIKey,
+		from:   config.AppConfig.SMTPFrom,
+		url:    sendGridURL,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *SendGridSender) IsConfigured() bool {
+	return s.apiKey != "" && s.from != ""
+}
+
+// sendGridAddress is an address in SendGrid's mail/send payload
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (s *SendGridSender) SendEmail(to, subject, htmlBody string) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("email service not configured")
+	}
+
+	// SMTP_FROM may be "Display Name <email@example.com>"
+	from := sendGridAddress{Email: extractEmail(s.from)}
+	if address, err := mail.ParseAddress(s.from); err == nil {
+		from = sendGridAddress{Email: address.Address, Name: address.Name}
+	}
+	payload, err := json.Marshal(sendGridMessage{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: to}}}},
+		From:             from,
+		Subject:          subject,
+		Content:          []sendGridContent{{Type: "text/html", Value: htmlBody}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("SendGrid returned %s: %s", resp.Status, sendGridErrors(resp.Body))
+}
+
+// sendGridErrors joins the messages of a SendGrid error response
+func sendGridErrors(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 64<<10))
+	var parsed struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil || len(parsed.Errors) == 0 {
+		return strings.TrimSpace(string(data))
+	}
+	messages := make([]string, len(parsed.Errors))
+	for i, e := range parsed.Errors {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (s *SendGridSender) SendTeamInviteEmail(to, inviterName, teamName, inviteToken string) error {
+	subject, body, err := renderTeamInviteEmail(inviterName, teamName, inviteToken)
+	if err != nil {
+		return err
+	}
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *SendGridSender) SendSecurityAlertEmail(to, event, ip string, at time.Time) error {
+	subject, body, err := renderSecurityAlertEmail(event, ip, at)
+	if err != nil {
+		return err
+	}
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *SendGridSender) SendTestEmail(to string) error {
+	subject, body := renderTestEmail()
+	return s.SendEmail(to, subject, body)
+}
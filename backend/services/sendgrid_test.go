@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"postmanxodja/config"
+)
+
+// roundTripFunc answers requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func sendGridSenderWith(transport roundTripFunc) *SendGridSender {
+	return &SendGridSender{
+		apiKey: "SG.test-key",
+		from:   "PostmanXodja <noreply@example.com>",
+		url:    sendGridURL,
+		client: &http.Client{Transport: transport},
+	}
+}
+
+func TestSendGridSenderPayload(t *testing.T) {
+	var got *http.Request
+	var payload map[string]any
+	sender := sendGridSenderWith(func(req *http.Request) (*http.Response, error) {
+		got = req
+		body, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("Expected a JSON payload, got '%s'", body)
+		}
+		return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	if err := sender.SendEmail("ada@example.com", "Hello", "<p>Hi Ada</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || got.URL.String() != sendGridURL {
+		t.Errorf("Expected POST %s, got %s %s", sendGridURL, got.Method, got.URL)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer SG.test-key" {
+		t.Errorf("Expected the API key as a bearer token, got '%s'", auth)
+	}
+
+	want := map[string]any{
+		"personalizations": []any{map[string]any{"to": []any{map[string]any{"email": "ada@example.com"}}}},
+		"from":             map[string]any{"email": "noreply@example.com", "name": "PostmanXodja"},
+		"subject":          "Hello",
+		"content":          []any{map[string]any{"type": "text/html", "value": "<p>Hi Ada</p>"}},
+	}
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(payload)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Expected payload %s, got %s", wantJSON, gotJSON)
+	}
+}
+
+func TestSendGridSenderRendersTemplates(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{FrontendURL: "https://app.example.com"}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	var message sendGridMessage
+	sender := sendGridSenderWith(func(req *http.Request) (*http.Response, error) {
+		json.NewDecoder(req.Body).Decode(&message)
+		return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	if err := sender.SendTeamInviteEmail("bob@example.com", "Ada", "Platform", "tok123"); err != nil {
+		t.Fatal(err)
+	}
+	if message.Subject != "Ada invited you to join Platform on PostmanXodja" {
+		t.Errorf("Expected the invite subject, got '%s'", message.Subject)
+	}
+	if len(message.Content) != 1 || !strings.Contains(message.Content[0].Value, "https://app.example.com/invite/tok123") {
+		t.Errorf("Expected the rendered invite with its link, got %+v", message.Content)
+	}
+}
+
+func TestSendGridSenderReportsErrors(t *testing.T) {
+	sender := sendGridSenderWith(func(req *http.Request) (*http.Response, error) {
+		body := `{"errors":[{"message":"The provided authorization grant is invalid, expired, or revoked","field":null}]}`
+		return &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	err := sender.SendEmail("ada@example.com", "Hello", "<p>Hi</p>")
+	if err == nil || !strings.Contains(err.Error(), "authorization grant is invalid") {
+		t.Fatalf("Expected SendGrid's error message, got %v", err)
+	}
+	if strings.Contains(err.Error(), "SG.test-key") {
+		t.Errorf("Expected the error not to include the API key, got %v", err)
+	}
+}
+
+func TestNewEmailSenderProvider(t *testing.T) {
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	config.AppConfig = &config.Config{EmailProvider: "sendgrid"}
+	if _, ok := NewEmailSender().(*SendGridSender); !ok {
+		t.Error("Expected EMAIL_PROVIDER=sendgrid to pick SendGrid")
+	}
+	for _, provider := range []string{"", "smtp"} {
+		config.AppConfig = &config.Config{EmailProvider: provider}
+		if _, ok := NewEmailSender().(*EmailService); !ok {
+			t.Errorf("Expected EMAIL_PROVIDER=%q to fall back to SMTP", provider)
+		}
+	}
+}
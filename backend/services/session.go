@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func refreshLifetime() time.Duration {
+	return time.Duration(config.AppConfig.RefreshExpirationDays) * 24 * time.Hour
+}
+
+// createSession stores a new session for the user's refresh token
+func createSession(userID uint, refreshToken string) (*models.Session, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     userID,
+		TokenHash:  hashToken(refreshToken),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshLifetime()),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RefreshSession exchanges a refresh token for a new token pair. The refresh
+// token is rotated, so each one can only be used once.
+func RefreshSession(refreshToken string) (*models.AuthResponse, error) {
+	var session models.Session
+	if err := database.DB.Where("token_hash = ?", hashToken(refreshToken)).First(&session).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, session.UserID).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&session).Updates(map[string]interface{}{
+		"token_hash":   hashToken(newRefreshToken),
+		"last_used_at": time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresIn, err := generateAccessToken(&user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    expiresIn,
+		User:         user,
+	}, nil
+}
+
+// IsSessionActive reports whether the session hasn't been revoked
+func IsSessionActive(sessionID uint) bool {
+	var count int64
+	database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Count(&count)
+	return count > 0
+}
+
+// RevokeSession revokes a single session (logout)
+func RevokeSession(sessionID uint) error {
+	return database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes all of the user's sessions except keepSessionID
+// (0 revokes every session). Call it whenever credentials change so a stolen
+// refresh token stops working.
+func RevokeUserSessions(userID, keepSessionID uint) (int64, error) {
+	result := database.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	log.Printf("Revoked %d session(s) for user %d", result.RowsAffected, userID)
+	return result.RowsAffected, nil
+}
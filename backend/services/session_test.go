@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+func TestRefreshLifetimeFollowsConfig(t *testing.T) {
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	for _, days := range []int{1, 7, 30} {
+		config.AppConfig = &config.Config{JWTExpirationHours: 1, RefreshExpirationDays: days}
+		want := time.Duration(days) * 24 * time.Hour
+		if got := refreshLifetime(); got != want {
+			t.Errorf("Expected refresh lifetime %v for %d days, got %v", want, days, got)
+		}
+	}
+}
+
+func useTokenConfig(t *testing.T, jwtHours, refreshDays int) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", JWTExpirationHours: jwtHours, RefreshExpirationDays: refreshDays}
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	useTestDB(t)
+	useTokenConfig(t, 1, 7)
+	user := createTestUser(t, "user@example.com")
+
+	pair, err := GenerateTokenPair(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := RefreshSession(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected the refresh token to work, got %v", err)
+	}
+	if refreshed.RefreshToken == pair.RefreshToken {
+		t.Error("Expected a new refresh token")
+	}
+	if _, err := RefreshSession(pair.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("Expected the old refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestRefreshSessionRejectsRevokedAndExpired(t *testing.T) {
+	useTestDB(t)
+	useTokenConfig(t, 1, 7)
+	user := createTestUser(t, "user@example.com")
+
+	revoked, _ := createSession(user.ID, "revoked-token")
+	RevokeSession(revoked.ID)
+	if _, err := RefreshSession("revoked-token"); err != ErrInvalidRefreshToken {
+		t.Errorf("Expected a revoked session to be rejected, got %v", err)
+	}
+
+	expired, _ := createSession(user.ID, "expired-token")
+	database.DB.Model(expired).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := RefreshSession("expired-token"); err != ErrInvalidRefreshToken {
+		t.Errorf("Expected an expired session to be rejected, got %v", err)
+	}
+}
+
+func TestCreateSessionUsesRefreshWindow(t *testing.T) {
+	useTestDB(t)
+	useTokenConfig(t, 1, 3)
+	user := createTestUser(t, "user@example.com")
+
+	before := time.Now()
+	session, err := createSession(user.ID, "refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.Session
+	database.DB.First(&stored, session.ID)
+	earliest := before.Add(3 * 24 * time.Hour)
+	latest := time.Now().Add(3 * 24 * time.Hour)
+	if stored.ExpiresAt.Before(earliest.Add(-time.Second)) || stored.ExpiresAt.After(latest.Add(time.Second)) {
+		t.Errorf("Expected the session to expire in 3 days, got %v", stored.ExpiresAt.Sub(before))
+	}
+}
@@ -1,14 +1,24 @@
 package services
 
 import (
+	"errors"
+	"log"
+	"time"
+
+	"postmanxodja/config"
 	"postmanxodja/database"
 	"postmanxodja/models"
+
+	"gorm.io/gorm"
 )
 
+// UserBelongsToTeam reports whether the user is a member of a team that
+// hasn't been deleted
 func UserBelongsToTeam(userID, teamID uint) bool {
 	var count int64
 	database.DB.Model(&models.TeamMember{}).
-		Where("user_id = ? AND team_id = ?", userID, teamID).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ? AND team_members.team_id = ?", userID, teamID).
 		Count(&count)
 	return count > 0
 }
@@ -35,10 +45,30 @@ func GetUserTeams(userID uint) ([]models.Team, error) {
 	return teams, result.Error
 }
 
+// GetUserTeamsWithRoles returns the user's teams together with the user's role,
+// the member count and the owner of each team, in a single query.
+func GetUserTeamsWithRoles(userID uint) ([]models.TeamResponse, error) {
+	var teams []models.TeamResponse
+	result := database.DB.Model(&models.Team{}).
+		Select("teams.*, team_members.role AS your_role, "+
+			"(SELECT COUNT(*) FROM team_members AS tm WHERE tm.team_id = teams.id) AS member_count, "+
+			"owners.user_id AS owner_id, owner_users.name AS owner_name").
+		Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Joins("LEFT JOIN team_members AS owners ON owners.team_id = teams.id AND owners.role = ?", "owner").
+		Joins("LEFT JOIN users AS owner_users ON owner_users.id = owners.user_id").
+		Where("team_members.user_id = ?", userID).
+		Order("teams.id").
+		Scan(&teams)
+	return teams, result.Error
+}
+
 func CreateTeamWithOwner(name string, userID uint) (*models.Team, error) {
+	return createTeamWithOwner(&models.Team{Name: name}, userID)
+}
+
+func createTeamWithOwner(team *models.Team, userID uint) (*models.Team, error) {
 	tx := database.DB.Begin()
 
-	team := &models.Team{Name: name}
 	if err := tx.Create(team).Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -58,6 +88,196 @@ func CreateTeamWithOwner(name string, userID uint) (*models.Team, error) {
 	return team, nil
 }
 
+// CreatePersonalTeam returns the user's personal team, creating it only when
+// they don't have one, so it's safe to call more than once
 func CreatePersonalTeam(userID uint) (*models.Team, error) {
-	return CreateTeamWithOwner("Personal", userID)
+	var team models.Team
+	err := database.DB.
+		Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Where("team_members.user_id = ? AND team_members.role = ? AND teams.is_personal = ?", userID, "owner", true).
+		First(&team).Error
+	if err == nil {
+		return &team, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return createTeamWithOwner(&models.Team{Name: "Personal", IsPersonal: true}, userID)
+}
+
+// EnsureUserHasTeam gives a user who doesn't belong to any team a personal
+// one, e.g. when creating it failed at sign-up
+func EnsureUserHasTeam(userID uint) error {
+	teams, err := GetUserTeams(userID)
+	if err != nil {
+		return err
+	}
+	if len(teams) > 0 {
+		return nil
+	}
+	_, err = CreatePersonalTeam(userID)
+	return err
+}
+
+// TeamRestoreWindow is how long a deleted team can still be restored
+func TeamRestoreWindow() time.Duration {
+	return time.Duration(config.AppConfig.TeamRestoreWindowHours) * time.Hour
+}
+
+// SoftDeleteTeam marks a team and its collections, environments and invites
+// as deleted. Every row gets the same timestamp so RestoreTeam can bring back
+// exactly what was removed together with the team.
+//
+// API keys and AI settings hold credentials, so they're removed outright
+// rather than left usable against a deleted team; a restored team starts
+// without them.
+func SoftDeleteTeam(teamID uint) error {
+	now := time.Now()
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Collection{}, &models.Environment{}, &models.TeamInvite{}} {
+			if err := tx.Model(model).Where("team_id = ?", teamID).Update("deleted_at", now).Error; err != nil {
+				return err
+			}
+		}
+		for _, model := range []interface{}{&models.TeamAPIKey{}, &models.TeamAISettings{}} {
+			if err := tx.Where("team_id = ?", teamID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Team{}).Where("id = ?", teamID).Update("deleted_at", now).Error
+	})
+	if err == nil {
+		MarkMembershipChanged(teamID)
+	}
+	return err
+}
+
+// RestoreTeam undoes SoftDeleteTeam for a team deleted at deletedAt
+func RestoreTeam(teamID uint, deletedAt time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Collection{}, &models.Environment{}, &models.TeamInvite{}} {
+			if err := tx.Unscoped().Model(model).
+				Where("team_id = ? AND deleted_at = ?", teamID, deletedAt).
+				Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&models.Team{}).Where("id = ?", teamID).Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeDeletedTeams permanently removes teams deleted before the cutoff,
+// along with everything that belongs to them
+func PurgeDeletedTeams(cutoff time.Time) (int, error) {
+	var teamIDs []uint
+	if err := database.DB.Unscoped().Model(&models.Team{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &teamIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(teamIDs) == 0 {
+		return 0, nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.TeamMember{},
+			&models.TeamInvite{},
+			&models.Collection{},
+			&models.Environment{},
+			&models.TeamAPIKey{},
+			&models.TeamAPIKeySettings{},
+			&models.TeamAISettings{},
+			&models.RequestTemplate{},
+			&models.Webhook{},
+			&models.TeamHostPolicy{},
+			&models.Workflow{},
+			&models.TeamRequestDefaults{},
+			&models.CollectionItemTiming{},
+		} {
+			if err := tx.Unscoped().Where("team_id IN ?", teamIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", teamIDs).Delete(&models.Team{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(teamIDs), nil
+}
+
+// RemoveMemberFromTeam deletes a membership and tidies up what the departing
+// member left behind in the team: pending invites they sent are cancelled,
+// the API keys they created are disabled (they may have kept a copy, and the
+// keys stay theirs in the audit trail; an owner can re-enable one), and the
+// webhooks, request templates and workflows they created are handed to the
+// team owner so they keep working. It reports false when the user wasn't a
+// member.
+func RemoveMemberFromTeam(teamID, memberUserID uint) (bool, error) {
+	removed := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("team_id = ? AND user_id = ?", teamID, memberUserID).Delete(&models.TeamMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		removed = true
+
+		if err := tx.Model(&models.TeamInvite{}).
+			Where("team_id = ? AND inviter_id = ? AND status = ?", teamID, memberUserID, "pending").
+			Update("status", InviteStatusCancelled).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.TeamAPIKey{}).
+			Where("team_id = ? AND created_by = ?", teamID, memberUserID).
+			Update("enabled", false).Error; err != nil {
+			return err
+		}
+
+		var owner models.TeamMember
+		if err := tx.Where("team_id = ? AND role = ?", teamID, "owner").First(&owner).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.Webhook{}, &models.RequestTemplate{}, &models.Workflow{}} {
+			if err := tx.Model(model).
+				Where("team_id = ? AND created_by = ?", teamID, memberUserID).
+				Update("created_by", owner.UserID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if removed && err == nil {
+		MarkMembershipChanged(teamID)
+	}
+	return removed, err
+}
+
+// StartTeamPurger periodically purges teams whose restore window has passed.
+// A non-positive TEAM_PURGE_INTERVAL_MINUTES disables it.
+func StartTeamPurger() {
+	interval := time.Duration(config.AppConfig.TeamPurgeIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := PurgeDeletedTeams(time.Now().Add(-TeamRestoreWindow()))
+			if err != nil {
+				log.Printf("Failed to purge deleted teams: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted teams", purged)
+			}
+		}
+	}()
 }
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// maxTeamClaims caps how many team IDs go into a token; members of more
+// teams are checked against the database for the rest
+const maxTeamClaims = 20
+
+// teamClaimsGrace is how long a token's team list is trusted, 0 when team
+// claims are off
+func teamClaimsGrace() time.Duration {
+	cfg := config.AppConfig
+	if cfg == nil || !cfg.JWTTeamClaims || cfg.JWTTeamClaimsGraceMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.JWTTeamClaimsGraceMinutes) * time.Minute
+}
+
+// tokenTeamIDs lists the teams to embed in a new access token for the user
+func tokenTeamIDs(userID uint) []uint {
+	if teamClaimsGrace() == 0 {
+		return nil
+	}
+	var teamIDs []uint
+	if err := database.DB.Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ?", userID).
+		Order("team_members.team_id").
+		Limit(maxTeamClaims).
+		Pluck("team_members.team_id", &teamIDs).Error; err != nil {
+		log.Printf("Failed to load team claims for user %d: %v", userID, err)
+		return nil
+	}
+	return teamIDs
+}
+
+// membershipChanges records when each team last lost a member (or was
+// deleted), so tokens issued before that don't vouch for it any more. It
+// only knows about changes made by this process.
+var membershipChanges = struct {
+	sync.Mutex
+	at map[uint]time.Time
+}{at: make(map[uint]time.Time)}
+
+// MarkMembershipChanged stops existing tokens' team claims for the team
+// from being trusted
+func MarkMembershipChanged(teamID uint) {
+	now := time.Now()
+	grace := teamClaimsGrace()
+
+	membershipChanges.Lock()
+	defer membershipChanges.Unlock()
+	membershipChanges.at[teamID] = now
+	// Tokens older than the grace period aren't trusted anyway
+	for id, at := range membershipChanges.at {
+		if now.Sub(at) > grace {
+			delete(membershipChanges.at, id)
+		}
+	}
+}
+
+// TrustTeamClaim reports whether the token's team list can stand in for the
+// membership query for teamID: team claims are on, the token lists the
+// team, it is within the grace period and the team hasn't lost a member
+// since it was issued
+func TrustTeamClaim(claims *JWTClaims, teamID uint, now time.Time) bool {
+	grace := teamClaimsGrace()
+	if grace == 0 || claims.IssuedAt == nil || !slices.Contains(claims.TeamIDs, teamID) {
+		return false
+	}
+	issuedAt := claims.IssuedAt.Time
+	if now.Sub(issuedAt) > grace {
+		return false
+	}
+
+	membershipChanges.Lock()
+	changedAt, changed := membershipChanges.at[teamID]
+	membershipChanges.Unlock()
+	return !changed || issuedAt.After(changedAt)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"postmanxodja/config"
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// useTeamClaims turns team claims on with the given grace period and forgets
+// membership changes recorded by earlier tests
+func useTeamClaims(t *testing.T, graceMinutes int) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{
+		JWTSecret:                 "test-secret",
+		JWTExpirationHours:        1,
+		RefreshExpirationDays:     1,
+		JWTTeamClaims:             graceMinutes > 0,
+		JWTTeamClaimsGraceMinutes: graceMinutes,
+	}
+	clear := func() {
+		membershipChanges.Lock()
+		membershipChanges.at = make(map[uint]time.Time)
+		membershipChanges.Unlock()
+	}
+	clear()
+	t.Cleanup(func() {
+		config.AppConfig = previous
+		clear()
+	})
+}
+
+func tokenClaims(t *testing.T, user *models.User) *JWTClaims {
+	t.Helper()
+	auth, err := GenerateTokenPair(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateJWT(auth.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestTeamClaimsTrusted(t *testing.T) {
+	useTestDB(t)
+	useTeamClaims(t, 5)
+	owner := createTestUser(t, "owner@example.com")
+	member := createTestUser(t, "member@example.com")
+	team, _ := CreateTeamWithOwner("Acme", owner.ID)
+	deleted, _ := CreateTeamWithOwner("Gone", member.ID)
+	addTestMember(t, team.ID, member.ID, "member")
+	database.DB.Delete(&models.Team{}, deleted.ID)
+
+	claims := tokenClaims(t, member)
+	if !slices.Contains(claims.TeamIDs, team.ID) || slices.Contains(claims.TeamIDs, deleted.ID) {
+		t.Fatalf("Expected the token to list team %d only, got %v", team.ID, claims.TeamIDs)
+	}
+	if !TrustTeamClaim(claims, team.ID, time.Now()) {
+		t.Error("Expected a fresh token's team to be trusted")
+	}
+	if TrustTeamClaim(claims, deleted.ID, time.Now()) {
+		t.Error("Expected a team missing from the token not to be trusted")
+	}
+	if TrustTeamClaim(claims, team.ID, time.Now().Add(6*time.Minute)) {
+		t.Error("Expected the claim not to be trusted after the grace period")
+	}
+}
+
+func TestTeamClaimsFallBackAfterMembershipChange(t *testing.T) {
+	useTestDB(t)
+	useTeamClaims(t, 5)
+	owner := createTestUser(t, "owner@example.com")
+	member := createTestUser(t, "member@example.com")
+	other := createTestUser(t, "other@example.com")
+	team, _ := CreateTeamWithOwner("Acme", owner.ID)
+	addTestMember(t, team.ID, member.ID, "member")
+	addTestMember(t, team.ID, other.ID, "member")
+
+	claims := tokenClaims(t, member)
+	if _, err := RemoveMemberFromTeam(team.ID, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	if TrustTeamClaim(claims, team.ID, time.Now()) {
+		t.Error("Expected a token issued before a removal to fall back to the database")
+	}
+}
+
+func TestTeamClaimsOff(t *testing.T) {
+	useTestDB(t)
+	useTeamClaims(t, 0)
+	owner := createTestUser(t, "owner@example.com")
+	team, _ := CreateTeamWithOwner("Acme", owner.ID)
+
+	claims := tokenClaims(t, owner)
+	if len(claims.TeamIDs) != 0 {
+		t.Errorf("Expected no team claims when turned off, got %v", claims.TeamIDs)
+	}
+	claims.TeamIDs = []uint{team.ID}
+	if TrustTeamClaim(claims, team.ID, time.Now()) {
+		t.Error("Expected team claims to be ignored when turned off")
+	}
+}
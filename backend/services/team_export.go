@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+
+	"gorm.io/gorm"
+)
+
+// BuildTeamExport collects everything that belongs to a team into a backup
+// bundle, leaving out secrets
+func BuildTeamExport(teamID uint) (*models.TeamExport, error) {
+	db := database.DB
+
+	var team models.Team
+	if err := db.First(&team, teamID).Error; err != nil {
+		return nil, err
+	}
+
+	var members []models.TeamMember
+	if err := db.Preload("User").Where("team_id = ?", teamID).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	var environments []models.Environment
+	if err := db.Where("team_id = ?", teamID).Order("id").Find(&environments).Error; err != nil {
+		return nil, err
+	}
+	var collections []models.Collection
+	if err := db.Where("team_id = ?", teamID).Order("id").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	var apiKeys []models.TeamAPIKey
+	if err := db.Where("team_id = ?", teamID).Order("id").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+	var aiSettings []models.TeamAISettings
+	if err := db.Where("team_id = ?", teamID).Limit(1).Find(&aiSettings).Error; err != nil {
+		return nil, err
+	}
+
+	return newTeamExport(team, members, environments, collections, apiKeys, aiSettings), nil
+}
+
+// newTeamExport maps the team's rows onto the bundle, copying only
+// non-secret fields
+func newTeamExport(team models.Team, members []models.TeamMember, environments []models.Environment,
+	collections []models.Collection, apiKeys []models.TeamAPIKey, aiSettings []models.TeamAISettings) *models.TeamExport {
+	export := &models.TeamExport{
+		Version:      models.TeamExportVersion,
+		ExportedAt:   time.Now().UTC(),
+		Team:         models.TeamExportTeam{Name: team.Name, CreatedAt: team.CreatedAt},
+		Members:      make([]models.TeamExportMember, 0, len(members)),
+		Environments: make([]models.TeamExportEnvironment, 0, len(environments)),
+		Collections:  make([]models.TeamExportCollection, 0, len(collections)),
+		APIKeys:      make([]models.TeamExportAPIKey, 0, len(apiKeys)),
+	}
+
+	for _, member := range members {
+		exported := models.TeamExportMember{Role: member.Role, JoinedAt: member.JoinedAt}
+		if member.User != nil {
+			exported.Email = member.User.Email
+			exported.Name = member.User.Name
+		}
+		export.Members = append(export.Members, exported)
+	}
+	for _, env := range environments {
+		export.Environments = append(export.Environments, models.TeamExportEnvironment{
+			ID:        env.ID,
+			Name:      env.Name,
+			Variables: env.Variables,
+		})
+	}
+	for _, collection := range collections {
+		export.Collections = append(export.Collections, models.TeamExportCollection{
+			Name:          collection.Name,
+			Description:   collection.Description,
+			RawJSON:       collection.RawJSON,
+			EnvironmentID: collection.EnvironmentID,
+		})
+	}
+	for _, key := range apiKeys {
+		export.APIKeys = append(export.APIKeys, models.TeamExportAPIKey{
+			Name:        key.Name,
+			KeyPrefix:   key.KeyPrefix,
+			Permissions: key.Permissions,
+			ExpiresAt:   key.ExpiresAt,
+			CreatedAt:   key.CreatedAt,
+		})
+	}
+	if len(aiSettings) > 0 {
+		export.AISettings = &models.TeamExportAISettings{
+			Provider:  aiSettings[0].Provider,
+			Model:     aiSettings[0].Model,
+			IsEnabled: aiSettings[0].IsEnabled,
+		}
+	}
+	return export
+}
+
+// ValidateTeamExport checks a bundle before anything is imported: a known
+// version, a team name, parseable collections with valid headers and
+// environment references that point into the bundle
+func ValidateTeamExport(export *models.TeamExport) error {
+	if export.Version != models.TeamExportVersion {
+		return fmt.Errorf("unsupported export version %d (expected %d)", export.Version, models.TeamExportVersion)
+	}
+	if export.Team.Name == "" {
+		return errors.New("team name is missing")
+	}
+
+	environmentIDs := make(map[uint]bool, len(export.Environments))
+	for _, env := range export.Environments {
+		if environmentIDs[env.ID] {
+			return fmt.Errorf("environment id %d appears twice", env.ID)
+		}
+		environmentIDs[env.ID] = true
+	}
+	for i, collection := range export.Collections {
+		parsed, err := ParsePostmanCollection(collection.RawJSON)
+		if err != nil {
+			return fmt.Errorf("collection %d (%q) is not valid Postman JSON: %v", i, collection.Name, err)
+		}
+		if err := ValidateCollectionHeaders(parsed); err != nil {
+			return fmt.Errorf("collection %d (%q): %v", i, collection.Name, err)
+		}
+		if collection.EnvironmentID != nil && !environmentIDs[*collection.EnvironmentID] {
+			return fmt.Errorf("collection %q refers to environment %d, which isn't in the export", collection.Name, *collection.EnvironmentID)
+		}
+	}
+	return nil
+}
+
+// ImportTeam recreates a team from a validated bundle with ownerID as its
+// owner. Collections and environments are restored (with collection links
+// remapped to the new environment IDs); members, API keys and AI settings
+// are not, because they need consent or secrets the bundle doesn't have.
+func ImportTeam(export *models.TeamExport, ownerID uint) (*models.Team, error) {
+	team := &models.Team{Name: export.Team.Name}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.TeamMember{TeamID: team.ID, UserID: ownerID, Role: "owner"}).Error; err != nil {
+			return err
+		}
+
+		newEnvironmentIDs := make(map[uint]uint, len(export.Environments))
+		for _, exported := range export.Environments {
+			env := models.Environment{Name: exported.Name, Variables: exported.Variables, TeamID: &team.ID}
+			if err := tx.Create(&env).Error; err != nil {
+				return err
+			}
+			newEnvironmentIDs[exported.ID] = env.ID
+		}
+
+		for _, exported := range export.Collections {
+			collection := models.Collection{
+				Name:        exported.Name,
+				Description: exported.Description,
+				RawJSON:     exported.RawJSON,
+				TeamID:      &team.ID,
+			}
+			if exported.EnvironmentID != nil {
+				envID := newEnvironmentIDs[*exported.EnvironmentID]
+				collection.EnvironmentID = &envID
+			}
+			if err := tx.Create(&collection).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
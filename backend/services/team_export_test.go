@@ -0,0 +1,190 @@
+package services
+
+import (
+	"encoding/json"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleTeamExport() *models.TeamExport {
+	envID := uint(7)
+	return newTeamExport(
+		models.Team{ID: 1, Name: "Platform"},
+		[]models.TeamMember{
+			{Role: "owner", User: &models.User{Email: "owner@example.com", Name: "Owner", PasswordHash: "$2a$hash"}},
+			{Role: "member", User: &models.User{Email: "dev@example.com", Name: "Dev"}},
+		},
+		[]models.Environment{{ID: envID, Name: "Staging", Variables: models.Variables{"host": "staging.example.com"}}},
+		[]models.Collection{{
+			Name:          "Orders",
+			RawJSON:       CreateEmptyCollection("Orders", "Order endpoints"),
+			EnvironmentID: &envID,
+		}},
+		[]models.TeamAPIKey{{Name: "CI", Key: "pmx_secretvalue", KeyPrefix: "pmx_secr", Permissions: "read"}},
+		[]models.TeamAISettings{{APIKey: "sk-secret", Provider: "openai", Model: "gpt-4o-mini", IsEnabled: true}},
+	)
+}
+
+func TestTeamExportRoundTrip(t *testing.T) {
+	data, err := json.Marshal(sampleTeamExport())
+	if err != nil {
+		t.Fatalf("Failed to marshal export: %v", err)
+	}
+	for _, secret := range []string{"pmx_secretvalue", "sk-secret", "$2a$hash"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected the export not to contain '%s'", secret)
+		}
+	}
+
+	var decoded models.TeamExport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if err := ValidateTeamExport(&decoded); err != nil {
+		t.Fatalf("Expected the export to validate, got %v", err)
+	}
+	if decoded.Team.Name != "Platform" || len(decoded.Members) != 2 || decoded.Members[1].Email != "dev@example.com" {
+		t.Errorf("Expected team and members to survive, got %+v", decoded)
+	}
+	if len(decoded.Collections) != 1 || *decoded.Collections[0].EnvironmentID != decoded.Environments[0].ID {
+		t.Errorf("Expected the collection to keep its environment link, got %+v", decoded.Collections)
+	}
+	if decoded.APIKeys[0].KeyPrefix != "pmx_secr" || decoded.AISettings.Model != "gpt-4o-mini" {
+		t.Errorf("Expected API key and AI metadata, got %+v / %+v", decoded.APIKeys, decoded.AISettings)
+	}
+}
+
+func TestValidateTeamExportRejects(t *testing.T) {
+	unknownEnv := uint(99)
+	cases := map[string]func(*models.TeamExport){
+		"version":     func(e *models.TeamExport) { e.Version = 2 },
+		"name":        func(e *models.TeamExport) { e.Team.Name = "" },
+		"raw json":    func(e *models.TeamExport) { e.Collections[0].RawJSON = "{" },
+		"environment": func(e *models.TeamExport) { e.Collections[0].EnvironmentID = &unknownEnv },
+		"header": func(e *models.TeamExport) {
+			e.Collections[0].RawJSON = `{"info":{"name":"Shop"},"item":[{"name":"Ping","request":{"method":"GET","url":"http://api.test","header":[{"key":"X-Note","value":"a\r\nX-Admin: true"}]}}]}`
+		},
+	}
+	for name, mutate := range cases {
+		export := sampleTeamExport()
+		mutate(export)
+		if err := ValidateTeamExport(export); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestImportTeamRemapsEnvironments(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+
+	// Take a real environment ID so the bundle's ID can't match by accident
+	database.DB.Create(&models.Environment{Name: "Unrelated"})
+
+	team, err := ImportTeam(sampleTeamExport(), owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsTeamOwner(owner.ID, team.ID) {
+		t.Error("Expected the importer to own the new team")
+	}
+
+	exported, err := BuildTeamExport(team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Members) != 1 || exported.Members[0].Email != "owner@example.com" {
+		t.Errorf("Expected only the importer as a member, got %+v", exported.Members)
+	}
+	if len(exported.APIKeys) != 0 || exported.AISettings != nil {
+		t.Errorf("Expected no API keys or AI settings, got %+v / %+v", exported.APIKeys, exported.AISettings)
+	}
+	if len(exported.Environments) != 1 || len(exported.Collections) != 1 {
+		t.Fatalf("Expected one environment and collection, got %+v", exported)
+	}
+	if *exported.Collections[0].EnvironmentID != exported.Environments[0].ID {
+		t.Errorf("Expected the collection to be linked to the imported environment %d, got %d",
+			exported.Environments[0].ID, *exported.Collections[0].EnvironmentID)
+	}
+	if exported.Environments[0].Variables["host"] != "staging.example.com" {
+		t.Errorf("Expected environment variables to be imported, got %v", exported.Environments[0].Variables)
+	}
+}
+
+func TestExportAndImportTeamIntoNewTeam(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+	importer := createTestUser(t, "importer@example.com")
+	source, _ := CreateTeamWithOwner("Platform", owner.ID)
+	dev := models.Environment{Name: "dev", TeamID: &source.ID, Variables: models.Variables{"host": "localhost"}}
+	prod := models.Environment{Name: "prod", TeamID: &source.ID, Variables: models.Variables{"host": "api.example.com", "port": "443"}}
+	database.DB.Create(&dev)
+	database.DB.Create(&prod)
+	for _, collection := range []models.Collection{
+		{Name: "Users", Description: "User API", RawJSON: `{"info":{"name":"Users"},"item":[]}`, TeamID: &source.ID, EnvironmentID: &prod.ID},
+		{Name: "Billing", RawJSON: `{"info":{"name":"Billing"},"item":[]}`, TeamID: &source.ID, EnvironmentID: &dev.ID},
+		{Name: "Scratch", RawJSON: `{"info":{"name":"Scratch"},"item":[]}`, TeamID: &source.ID},
+	} {
+		database.DB.Create(&collection)
+	}
+
+	export, err := BuildTeamExport(source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Go through JSON as a download and upload would
+	encoded, _ := json.Marshal(export)
+	var uploaded models.TeamExport
+	if err := json.Unmarshal(encoded, &uploaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateTeamExport(&uploaded); err != nil {
+		t.Fatalf("Expected the export to validate, got %v", err)
+	}
+	imported, err := ImportTeam(&uploaded, importer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// snapshot describes a team's collections and environments by name, so
+	// the two teams can be compared regardless of IDs
+	snapshot := func(teamID uint) (map[string]models.Variables, map[string][3]string) {
+		var environments []models.Environment
+		database.DB.Where("team_id = ?", teamID).Find(&environments)
+		envNames := make(map[uint]string, len(environments))
+		byName := make(map[string]models.Variables, len(environments))
+		for _, env := range environments {
+			envNames[env.ID] = env.Name
+			byName[env.Name] = env.Variables
+		}
+		var collections []models.Collection
+		database.DB.Where("team_id = ?", teamID).Find(&collections)
+		collectionsByName := make(map[string][3]string, len(collections))
+		for _, collection := range collections {
+			linked := ""
+			if collection.EnvironmentID != nil {
+				linked = envNames[*collection.EnvironmentID]
+			}
+			collectionsByName[collection.Name] = [3]string{collection.Description, collection.RawJSON, linked}
+		}
+		return byName, collectionsByName
+	}
+
+	wantEnvironments, wantCollections := snapshot(source.ID)
+	gotEnvironments, gotCollections := snapshot(imported.ID)
+	if len(wantEnvironments) != 2 || len(wantCollections) != 3 {
+		t.Fatalf("Expected the source team to have 2 environments and 3 collections, got %v and %v", wantEnvironments, wantCollections)
+	}
+	if !reflect.DeepEqual(gotEnvironments, wantEnvironments) {
+		t.Errorf("Expected environments %v, got %v", wantEnvironments, gotEnvironments)
+	}
+	if !reflect.DeepEqual(gotCollections, wantCollections) {
+		t.Errorf("Expected collections %v, got %v", wantCollections, gotCollections)
+	}
+	if imported.Name != "Platform" || !IsTeamOwner(importer.ID, imported.ID) || UserBelongsToTeam(owner.ID, imported.ID) {
+		t.Errorf("Expected a new Platform team owned only by the importer, got %+v", imported)
+	}
+}
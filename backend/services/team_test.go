@@ -0,0 +1,254 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// useTestDB points database.DB at a fresh in-memory SQLite database
+func useTestDB(t *testing.T) {
+	t.Helper()
+	previous := database.DB
+	t.Setenv("DATABASE_URL", "sqlite::memory:")
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = previous
+	})
+}
+
+func createTestUser(t *testing.T, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, Name: email}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+func addTestMember(t *testing.T, teamID, userID uint, role string) {
+	t.Helper()
+	if err := database.DB.Create(&models.TeamMember{TeamID: teamID, UserID: userID, Role: role}).Error; err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+}
+
+func TestSoftDeleteAndRestoreTeam(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+	team, err := CreateTeamWithOwner("Acme", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := CreateTeamWithOwner("Kept", owner.ID)
+	database.DB.Create(&models.Collection{Name: "API", TeamID: &team.ID})
+	database.DB.Create(&models.Environment{Name: "dev", TeamID: &team.ID})
+	teamNames := func() []string {
+		teams, err := GetUserTeams(owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(teams))
+		for _, team := range teams {
+			names = append(names, team.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if err := SoftDeleteTeam(team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if UserBelongsToTeam(owner.ID, team.ID) || !UserBelongsToTeam(owner.ID, kept.ID) {
+		t.Error("Expected only the deleted team to stop counting as the owner's team")
+	}
+	if names := teamNames(); !reflect.DeepEqual(names, []string{"Kept"}) {
+		t.Errorf("Expected the deleted team left out of the list, got %v", names)
+	}
+	var collections, environments int64
+	database.DB.Model(&models.Collection{}).Where("team_id = ?", team.ID).Count(&collections)
+	database.DB.Model(&models.Environment{}).Where("team_id = ?", team.ID).Count(&environments)
+	if collections != 0 || environments != 0 {
+		t.Errorf("Expected collections and environments hidden, got %d and %d", collections, environments)
+	}
+
+	var deleted models.Team
+	database.DB.Unscoped().First(&deleted, team.ID)
+	if err := RestoreTeam(team.ID, deleted.DeletedAt.Time); err != nil {
+		t.Fatal(err)
+	}
+	if !UserBelongsToTeam(owner.ID, team.ID) {
+		t.Error("Expected the restored team to be accessible again")
+	}
+	if names := teamNames(); !reflect.DeepEqual(names, []string{"Acme", "Kept"}) {
+		t.Errorf("Expected the restored team back in the list, got %v", names)
+	}
+	database.DB.Model(&models.Collection{}).Where("team_id = ?", team.ID).Count(&collections)
+	database.DB.Model(&models.Environment{}).Where("team_id = ?", team.ID).Count(&environments)
+	if collections != 1 || environments != 1 {
+		t.Errorf("Expected the collection and environment back, got %d and %d", collections, environments)
+	}
+}
+
+func TestPurgeDeletedTeams(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+	old, _ := CreateTeamWithOwner("Old", owner.ID)
+	recent, _ := CreateTeamWithOwner("Recent", owner.ID)
+	database.DB.Create(&models.Collection{Name: "API", TeamID: &old.ID})
+
+	SoftDeleteTeam(old.ID)
+	database.DB.Unscoped().Model(&models.Team{}).Where("id = ?", old.ID).Update("deleted_at", time.Now().Add(-100*time.Hour))
+	SoftDeleteTeam(recent.ID)
+
+	purged, err := PurgeDeletedTeams(time.Now().Add(-72 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged team, got %d", purged)
+	}
+
+	var teams, collections int64
+	database.DB.Unscoped().Model(&models.Team{}).Count(&teams)
+	database.DB.Unscoped().Model(&models.Collection{}).Where("team_id = ?", old.ID).Count(&collections)
+	if teams != 1 || collections != 0 {
+		t.Errorf("Expected only the recently deleted team left and no collections, got %d teams and %d collections", teams, collections)
+	}
+}
+
+func TestGetUserTeamsWithRoles(t *testing.T) {
+	useTestDB(t)
+	alice := createTestUser(t, "alice@example.com")
+	bob := createTestUser(t, "bob@example.com")
+	carol := createTestUser(t, "carol@example.com")
+
+	// alice owns a team of three, bob owns a team of two with alice as admin,
+	// and carol's solo team shouldn't show up for alice at all
+	big, _ := CreateTeamWithOwner("Big", alice.ID)
+	addTestMember(t, big.ID, bob.ID, "member")
+	addTestMember(t, big.ID, carol.ID, "member")
+	small, _ := CreateTeamWithOwner("Small", bob.ID)
+	addTestMember(t, small.ID, alice.ID, "admin")
+	CreateTeamWithOwner("Solo", carol.ID)
+
+	teams, err := GetUserTeamsWithRoles(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 2 {
+		t.Fatalf("Expected 2 teams, got %d", len(teams))
+	}
+	for i, want := range []struct {
+		name    string
+		role    string
+		members int64
+		owner   *models.User
+	}{
+		{"Big", "owner", 3, alice},
+		{"Small", "admin", 2, bob},
+	} {
+		got := teams[i]
+		if got.Name != want.name || got.YourRole != want.role || got.MemberCount != want.members {
+			t.Errorf("Expected %s with role '%s' and %d members, got %s with '%s' and %d",
+				want.name, want.role, want.members, got.Name, got.YourRole, got.MemberCount)
+		}
+		if got.OwnerID != want.owner.ID || got.OwnerName != want.owner.Name {
+			t.Errorf("%s: expected owner %d '%s', got %d '%s'", want.name, want.owner.ID, want.owner.Name, got.OwnerID, got.OwnerName)
+		}
+	}
+}
+
+func TestRemoveMemberFromTeamCleansUp(t *testing.T) {
+	useTestDB(t)
+	owner := createTestUser(t, "owner@example.com")
+	member := createTestUser(t, "member@example.com")
+	team, _ := CreateTeamWithOwner("Acme", owner.ID)
+	addTestMember(t, team.ID, member.ID, "admin")
+
+	invite := models.TeamInvite{TeamID: team.ID, InviterID: member.ID, InviteeEmail: "new@example.com",
+		Status: "pending", Token: "invite-token", ExpiresAt: time.Now().Add(time.Hour)}
+	database.DB.Create(&invite)
+	key := models.TeamAPIKey{TeamID: team.ID, Name: "CI", Key: "key", KeyPrefix: "pmx_", CreatedBy: member.ID}
+	database.DB.Create(&key)
+	webhook := models.Webhook{TeamID: team.ID, URL: "https://example.com/hook", Secret: "s", Events: "collection.updated", CreatedBy: member.ID}
+	database.DB.Create(&webhook)
+
+	removed, err := RemoveMemberFromTeam(team.ID, member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Fatal("Expected the member to be removed")
+	}
+	if UserBelongsToTeam(member.ID, team.ID) {
+		t.Error("Expected the membership to be gone")
+	}
+
+	database.DB.First(&invite, invite.ID)
+	if invite.Status != "cancelled" {
+		t.Errorf("Expected the member's invite to be cancelled, got '%s'", invite.Status)
+	}
+	database.DB.First(&key, key.ID)
+	database.DB.First(&webhook, webhook.ID)
+	if key.Enabled || key.CreatedBy != member.ID {
+		t.Errorf("Expected the member's key to be disabled and still theirs, got enabled=%v created_by=%d", key.Enabled, key.CreatedBy)
+	}
+	if webhook.CreatedBy != owner.ID {
+		t.Errorf("Expected the owner to take over the webhook, got %d", webhook.CreatedBy)
+	}
+
+	removed, err = RemoveMemberFromTeam(team.ID, member.ID)
+	if err != nil || removed {
+		t.Errorf("Expected removing a non-member to report false, got %v, %v", removed, err)
+	}
+}
+
+func TestCreatePersonalTeamIsIdempotent(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "user@example.com")
+
+	first, err := CreatePersonalTeam(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := CreatePersonalTeam(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID || !second.IsPersonal {
+		t.Errorf("Expected the same personal team twice, got %d and %d", first.ID, second.ID)
+	}
+	var teams, memberships int64
+	database.DB.Model(&models.Team{}).Count(&teams)
+	database.DB.Model(&models.TeamMember{}).Where("user_id = ?", user.ID).Count(&memberships)
+	if teams != 1 || memberships != 1 {
+		t.Errorf("Expected one team and one membership, got %d and %d", teams, memberships)
+	}
+}
+
+func TestEnsureUserHasTeam(t *testing.T) {
+	useTestDB(t)
+	user := createTestUser(t, "user@example.com")
+
+	if err := EnsureUserHasTeam(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureUserHasTeam(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	teams, _ := GetUserTeams(user.ID)
+	if len(teams) != 1 || !teams[0].IsPersonal {
+		t.Errorf("Expected exactly one personal team, got %+v", teams)
+	}
+}
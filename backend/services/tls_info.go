@@ -0,0 +1,44 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+
+	"postmanxodja/models"
+)
+
+// TLSInfoFrom summarizes a response's TLS connection state. It returns nil
+// for plain HTTP responses, which have no state.
+func TLSInfoFrom(state *tls.ConnectionState) *models.TLSInfo {
+	if state == nil {
+		return nil
+	}
+
+	info := &models.TLSInfo{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		ServerName:         state.ServerName,
+		Verified:           len(state.VerifiedChains) > 0,
+		Certificates:       make([]models.TLSCertificate, 0, len(state.PeerCertificates)),
+	}
+	for _, cert := range state.PeerCertificates {
+		fingerprint := sha256.Sum256(cert.Raw)
+		summary := models.TLSCertificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.String(),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			IsCA:         cert.IsCA,
+			SHA256:       hex.EncodeToString(fingerprint[:]),
+		}
+		for _, ip := range cert.IPAddresses {
+			summary.IPAddresses = append(summary.IPAddresses, ip.String())
+		}
+		info.Certificates = append(info.Certificates, summary)
+	}
+	return info
+}
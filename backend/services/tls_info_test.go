@@ -0,0 +1,71 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+func TestExecuteReturnsTLSInfo(t *testing.T) {
+	useLoopback(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: server.URL, IncludeTLSInfo: true})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.TLS == nil {
+		t.Fatal("Expected TLS info for an HTTPS request")
+	}
+	if !strings.HasPrefix(resp.TLS.Version, "TLS 1.") || resp.TLS.CipherSuite == "" {
+		t.Errorf("Expected the negotiated version and cipher suite, got '%s' / '%s'", resp.TLS.Version, resp.TLS.CipherSuite)
+	}
+	if resp.TLS.Verified {
+		t.Error("Expected localhost verification to be reported as skipped")
+	}
+	if len(resp.TLS.Certificates) == 0 {
+		t.Fatal("Expected the server certificate")
+	}
+
+	leaf := server.Certificate()
+	got := resp.TLS.Certificates[0]
+	if got.Subject != leaf.Subject.String() || !strings.Contains(got.Subject, "Acme Co") {
+		t.Errorf("Expected subject '%s', got '%s'", leaf.Subject.String(), got.Subject)
+	}
+	if !got.NotAfter.Equal(leaf.NotAfter) || len(got.SHA256) != 64 {
+		t.Errorf("Expected expiry %v and a SHA-256 fingerprint, got %v / '%s'", leaf.NotAfter, got.NotAfter, got.SHA256)
+	}
+	if len(got.IPAddresses) == 0 && len(got.DNSNames) == 0 {
+		t.Errorf("Expected the certificate's SANs, got %+v", got)
+	}
+}
+
+func TestExecuteOmitsTLSInfo(t *testing.T) {
+	useLoopback(t)
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plainServer.Close()
+
+	resp, err := ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: tlsServer.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS != nil {
+		t.Error("Expected no TLS info unless it's asked for")
+	}
+
+	resp, err = ExecuteHTTPRequest(&models.ExecuteRequest{Method: "GET", URL: plainServer.URL, IncludeTLSInfo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS != nil {
+		t.Error("Expected no TLS info for plain HTTP")
+	}
+}
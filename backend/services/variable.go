@@ -1,53 +1,151 @@
 package services
 
 import (
-	"log"
 	"postmanxodja/models"
 	"regexp"
+	"sort"
 	"strings"
 )
 
-// ReplaceVariables replaces {{variableName}} with actual values
-func ReplaceVariables(text string, variables models.Variables) string {
-	// Updated regex to support hyphens, underscores, dots, and other characters in variable names
-	re := regexp.MustCompile(`\{\{([^}]+)\}\}`)
+// variablePattern matches {{variableName}} placeholders. It supports hyphens,
+// underscores, dots, and other characters in variable names.
+var variablePattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
-	log.Printf("ReplaceVariables called with text: %s", text)
-	log.Printf("Available variables: %+v", variables)
+// bodyVariablePrefix marks a body that is entirely one variable's raw value,
+// as in {{@env:payload}}
+const bodyVariablePrefix = "@env:"
 
-	result := re.ReplaceAllStringFunc(text, func(match string) string {
-		// Extract variable name without {{ }}
-		varName := strings.TrimSuffix(strings.TrimPrefix(match, "{{"), "}}")
+// VariableReplacer substitutes variables and remembers the placeholders it
+// couldn't resolve
+type VariableReplacer struct {
+	variables  models.Variables
+	unresolved map[string]bool
+}
 
-		log.Printf("Found variable placeholder: %s, extracted name: %s", match, varName)
+func NewVariableReplacer(variables models.Variables) *VariableReplacer {
+	return &VariableReplacer{
+		variables:  variables,
+		unresolved: make(map[string]bool),
+	}
+}
 
-		if value, ok := variables[varName]; ok {
-			log.Printf("Replacing %s with: %s", varName, value)
+// Replace replaces {{variableName}} with actual values. Nothing is logged:
+// the variables and request are full of credentials.
+func (r *VariableReplacer) Replace(text string) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		// Extract variable name without {{ }}
+		varName := strings.TrimSuffix(strings.TrimPrefix(match, "{{"), "}}")
+		if value, ok := r.variables[varName]; ok {
 			return value
 		}
-		log.Printf("Variable %s not found in environment, keeping original", varName)
+		r.unresolved[varName] = true
 		return match // Return original if not found
 	})
+}
 
-	log.Printf("Result after replacement: %s", result)
-	return result
+// ReplaceBody replaces variables in a request body. A body that is just
+// {{@env:name}} becomes the variable's value as is, so a stored payload full
+// of braces and quotes is sent untouched and nothing inside it is substituted.
+func (r *VariableReplacer) ReplaceBody(body string) string {
+	name, ok := BodyVariableName(body)
+	if !ok {
+		return r.Replace(body)
+	}
+	if value, ok := r.variables[name]; ok {
+		return value
+	}
+	r.unresolved[name] = true
+	return body
+}
+
+// BodyVariableName returns the variable a {{@env:name}} body refers to
+func BodyVariableName(body string) (string, bool) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{{"+bodyVariablePrefix) || !strings.HasSuffix(trimmed, "}}") {
+		return "", false
+	}
+	name := strings.TrimSpace(trimmed[len("{{"+bodyVariablePrefix) : len(trimmed)-len("}}")])
+	if name == "" || strings.ContainsAny(name, "{}") {
+		return "", false
+	}
+	return name, true
+}
+
+// Unresolved returns the names of placeholders that had no value, sorted
+func (r *VariableReplacer) Unresolved() []string {
+	names := make([]string, 0, len(r.unresolved))
+	for name := range r.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-// ReplaceInRequest replaces variables in all parts of a request
-func ReplaceInRequest(req *models.ExecuteRequest, variables models.Variables) {
+// MergeVariables combines the environment's variables with per-request inline
+// ones. Inline values win over the environment. Neither input is modified.
+func MergeVariables(environment models.Variables, inline map[string]string) models.Variables {
+	merged := make(models.Variables, len(environment)+len(inline))
+	for key, value := range environment {
+		merged[key] = value
+	}
+	for key, value := range inline {
+		merged[key] = value
+	}
+	return merged
+}
+
+// ReplaceVariables replaces {{variableName}} with actual values
+func ReplaceVariables(text string, variables models.Variables) string {
+	return NewVariableReplacer(variables).Replace(text)
+}
+
+// ReplaceInRequest replaces variables in all parts of a request and returns
+// the names of placeholders that couldn't be resolved
+func ReplaceInRequest(req *models.ExecuteRequest, variables models.Variables) []string {
+	replacer := NewVariableReplacer(variables)
+
 	// Replace in URL
-	req.URL = ReplaceVariables(req.URL, variables)
+	req.URL = replacer.Replace(req.URL)
+	req.Host = replacer.Replace(req.Host)
 
 	// Replace in headers
 	for key, value := range req.Headers {
-		req.Headers[key] = ReplaceVariables(value, variables)
+		req.Headers[key] = replacer.Replace(value)
 	}
 
 	// Replace in body
-	req.Body = ReplaceVariables(req.Body, variables)
+	req.Body = replacer.ReplaceBody(req.Body)
+
+	// Replace in form-data text fields
+	for i := range req.FormData {
+		req.FormData[i].Key = replacer.Replace(req.FormData[i].Key)
+		req.FormData[i].Value = replacer.Replace(req.FormData[i].Value)
+	}
 
 	// Replace in query params
 	for key, value := range req.QueryParams {
-		req.QueryParams[key] = ReplaceVariables(value, variables)
+		req.QueryParams[key] = replacer.Replace(value)
+	}
+
+	// Replace in the ordered header / query lists
+	for i := range req.HeaderList {
+		req.HeaderList[i].Value = replacer.Replace(req.HeaderList[i].Value)
+	}
+	for i := range req.QueryList {
+		req.QueryList[i].Value = replacer.Replace(req.QueryList[i].Value)
 	}
+
+	// Replace in server-side auth credentials
+	if req.Auth != nil && req.Auth.NTLM != nil {
+		ntlm := req.Auth.NTLM
+		ntlm.Username = replacer.Replace(ntlm.Username)
+		ntlm.Password = replacer.Replace(ntlm.Password)
+		ntlm.Domain = replacer.Replace(ntlm.Domain)
+		ntlm.Workstation = replacer.Replace(ntlm.Workstation)
+	}
+	if req.Auth != nil && req.Auth.OAuth2 != nil {
+		ReplaceInOAuth2Config(req.Auth.OAuth2, replacer)
+	}
+
+	return replacer.Unresolved()
 }
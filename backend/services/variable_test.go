@@ -0,0 +1,136 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"postmanxodja/models"
+)
+
+func TestReplaceInRequestReportsUnresolved(t *testing.T) {
+	req := &models.ExecuteRequest{
+		URL:         "{{base_url}}/users/{{user_id}}",
+		Headers:     map[string]string{"Authorization": "Bearer {{token}}"},
+		Body:        `{"tenant": "{{tenant}}", "region": "{{region}}"}`,
+		QueryParams: map[string]string{"page": "{{page}}"},
+		QueryList:   []models.KeyValue{{Key: "sig", Value: "{{signature}}"}},
+	}
+	variables := models.Variables{
+		"base_url": "https://api.example.com",
+		"token":    "abc123",
+		"tenant":   "acme",
+	}
+
+	unresolved := ReplaceInRequest(req, variables)
+
+	if req.URL != "https://api.example.com/users/{{user_id}}" {
+		t.Errorf("Unexpected URL '%s'", req.URL)
+	}
+	if req.Headers["Authorization"] != "Bearer abc123" {
+		t.Errorf("Expected Authorization 'Bearer abc123', got '%s'", req.Headers["Authorization"])
+	}
+	if req.Body != `{"tenant": "acme", "region": "{{region}}"}` {
+		t.Errorf("Unexpected body '%s'", req.Body)
+	}
+
+	expected := "page,region,signature,user_id"
+	if strings.Join(unresolved, ",") != expected {
+		t.Errorf("Expected unresolved '%s', got '%s'", expected, strings.Join(unresolved, ","))
+	}
+}
+
+func TestReplaceInRequestAllResolved(t *testing.T) {
+	req := &models.ExecuteRequest{URL: "{{host}}/ping"}
+
+	unresolved := ReplaceInRequest(req, models.Variables{"host": "http://localhost"})
+
+	if len(unresolved) != 0 {
+		t.Errorf("Expected no unresolved variables, got %v", unresolved)
+	}
+	if unresolved == nil {
+		t.Error("Expected an empty list rather than nil")
+	}
+}
+
+func TestInlineVariablesOverrideEnvironment(t *testing.T) {
+	environment := models.Variables{
+		"base_url": "https://staging.example.com",
+		"token":    "env-token",
+	}
+	req := &models.ExecuteRequest{
+		URL:             "{{base_url}}/items?debug={{debug}}",
+		Headers:         map[string]string{"Authorization": "Bearer {{token}}"},
+		InlineVariables: map[string]string{"token": "one-off", "debug": "1"},
+	}
+
+	unresolved := ReplaceInRequest(req, MergeVariables(environment, req.InlineVariables))
+
+	if req.URL != "https://staging.example.com/items?debug=1" {
+		t.Errorf("Expected environment base_url and inline debug, got '%s'", req.URL)
+	}
+	if req.Headers["Authorization"] != "Bearer one-off" {
+		t.Errorf("Expected inline token to win, got '%s'", req.Headers["Authorization"])
+	}
+	if len(unresolved) != 0 {
+		t.Errorf("Expected no unresolved variables, got %v", unresolved)
+	}
+	if environment["token"] != "env-token" {
+		t.Errorf("Expected the environment to be left untouched, got '%s'", environment["token"])
+	}
+}
+
+func TestReplaceInRequestBodyFromVariable(t *testing.T) {
+	payload := `{"query": "{ user(id: 1) { name } }", "note": "say \"hi\"", "template": "{{nested}}", "cost": "$1"}`
+	req := &models.ExecuteRequest{
+		URL:  "{{host}}/graphql",
+		Body: "  {{@env:payload}}\n",
+	}
+	variables := models.Variables{
+		"host":    "http://localhost",
+		"payload": payload,
+		"nested":  "should not appear",
+	}
+
+	unresolved := ReplaceInRequest(req, variables)
+
+	if req.Body != payload {
+		t.Errorf("Expected the stored payload verbatim, got '%s'", req.Body)
+	}
+	if len(unresolved) != 0 {
+		t.Errorf("Expected no unresolved variables, got %v", unresolved)
+	}
+}
+
+func TestReplaceInRequestBodyFromMissingVariable(t *testing.T) {
+	req := &models.ExecuteRequest{URL: "http://localhost", Body: "{{@env:payload}}"}
+
+	unresolved := ReplaceInRequest(req, models.Variables{})
+
+	if req.Body != "{{@env:payload}}" {
+		t.Errorf("Expected the body to be kept, got '%s'", req.Body)
+	}
+	if strings.Join(unresolved, ",") != "payload" {
+		t.Errorf("Expected 'payload' to be unresolved, got %v", unresolved)
+	}
+}
+
+func TestBodyVariableName(t *testing.T) {
+	tests := []struct {
+		body string
+		name string
+		ok   bool
+	}{
+		{"{{@env:payload}}", "payload", true},
+		{" {{@env: big-body }} ", "big-body", true},
+		{"{{payload}}", "", false},
+		{"{{@env:}}", "", false},
+		{`{{@env:a}} and {{b}}`, "", false},
+		{`{"data": {{@env:payload}}}`, "", false},
+	}
+	for _, tt := range tests {
+		name, ok := BodyVariableName(tt.body)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("BodyVariableName(%q) = %q, %v; want %q, %v", tt.body, name, ok, tt.name, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"postmanxodja/models"
+)
+
+// CollectionVariableUsage finds the {{variable}} placeholders in every
+// request of a collection, including nested folders, and returns them sorted
+// by name. Disabled headers and fields count too, since turning them back on
+// needs the variable.
+func CollectionVariableUsage(collection *models.PostmanCollection) []models.VariableUsage {
+	byName := make(map[string][]models.VariableReference)
+	walkVariableUsage(collection.Item, nil, byName)
+
+	usages := make([]models.VariableUsage, 0, len(byName))
+	for name, requests := range byName {
+		usages = append(usages, models.VariableUsage{Name: name, Requests: requests})
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Name < usages[j].Name })
+	return usages
+}
+
+// VariableUsageOf returns the requests that reference one variable, named
+// with or without its braces
+func VariableUsageOf(collection *models.PostmanCollection, name string) models.VariableUsage {
+	name = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(name), "{{"), "}}")
+	for _, usage := range CollectionVariableUsage(collection) {
+		if usage.Name == name {
+			return usage
+		}
+	}
+	return models.VariableUsage{Name: name, Requests: []models.VariableReference{}}
+}
+
+func walkVariableUsage(items []models.PostmanItem, parent []string, byName map[string][]models.VariableReference) {
+	for _, item := range items {
+		path := append(append([]string{}, parent...), item.Name)
+		if item.Request != nil {
+			for name, locations := range requestVariables(item.Request) {
+				byName[name] = append(byName[name], models.VariableReference{
+					ItemPath:  path,
+					Method:    item.Request.Method,
+					Locations: locations,
+				})
+			}
+		}
+		walkVariableUsage(item.Item, path, byName)
+	}
+}
+
+// requestVariables maps each variable a request references to the parts of
+// the request it appears in, in a fixed order
+func requestVariables(req *models.PostmanRequest) map[string][]string {
+	found := make(map[string][]string)
+	add := func(location string, texts ...string) {
+		for _, text := range texts {
+			for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+				name := strings.TrimPrefix(match[1], bodyVariablePrefix)
+				if locations := found[name]; len(locations) == 0 || locations[len(locations)-1] != location {
+					found[name] = append(locations, location)
+				}
+			}
+		}
+	}
+
+	add("url", ResolveRequestURL(req.URL))
+	for _, h := range req.Header {
+		add("header", h.Key, stringValue(h.Value))
+	}
+	if body := req.Body; body != nil {
+		add("body", body.Raw)
+		for _, field := range body.FormData {
+			add("body", field.Key, field.Value)
+		}
+		for _, field := range body.Urlencoded {
+			add("body", field.Key, field.Value)
+		}
+	}
+	if auth := req.Auth; auth != nil {
+		for _, params := range [][]models.PostmanAuthParameter{auth.Bearer, auth.Basic, auth.Apikey, auth.OAuth2} {
+			for _, p := range params {
+				add("auth", stringValue(p.Value))
+			}
+		}
+	}
+	return found
+}
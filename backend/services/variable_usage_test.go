@@ -0,0 +1,71 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+const variableUsageCollection = `{
+	"info": {"name": "Shop"},
+	"item": [
+		{"name": "Login", "request": {
+			"method": "POST",
+			"url": "{{base_url}}/login",
+			"body": {"mode": "raw", "raw": "{\"tenant\":\"{{tenant}}\"}"}
+		}},
+		{"name": "Orders", "item": [
+			{"name": "List", "request": {
+				"method": "GET",
+				"url": {"raw": "{{base_url}}/orders?tenant={{tenant}}", "host": ["{{base_url}}"]},
+				"header": [{"key": "X-Tenant", "value": "{{tenant}}", "disabled": true}],
+				"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]}
+			}},
+			{"name": "Create", "request": {
+				"method": "POST",
+				"url": "{{base_url}}/orders",
+				"body": {"mode": "urlencoded", "urlencoded": [{"key": "owner", "value": "{{user}}"}]}
+			}}
+		]}
+	]
+}`
+
+func TestCollectionVariableUsage(t *testing.T) {
+	collection, err := ParsePostmanCollection(variableUsageCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usages := CollectionVariableUsage(collection)
+	var names []string
+	for _, usage := range usages {
+		names = append(names, usage.Name)
+	}
+	if want := []string{"base_url", "tenant", "token", "user"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected variables %v, got %v", want, names)
+	}
+
+	baseURL := usages[0]
+	if len(baseURL.Requests) != 3 {
+		t.Errorf("Expected base_url in 3 requests, got %d", len(baseURL.Requests))
+	}
+
+	tenant := VariableUsageOf(collection, "{{tenant}}")
+	if len(tenant.Requests) != 2 {
+		t.Fatalf("Expected tenant in 2 requests, got %+v", tenant.Requests)
+	}
+	login, list := tenant.Requests[0], tenant.Requests[1]
+	if !reflect.DeepEqual(login.ItemPath, []string{"Login"}) || !reflect.DeepEqual(login.Locations, []string{"body"}) {
+		t.Errorf("Expected tenant in the Login body, got %+v", login)
+	}
+	if !reflect.DeepEqual(list.ItemPath, []string{"Orders", "List"}) || list.Method != "GET" ||
+		!reflect.DeepEqual(list.Locations, []string{"url", "header"}) {
+		t.Errorf("Expected tenant in the List URL and header, got %+v", list)
+	}
+
+	if token := VariableUsageOf(collection, "token"); len(token.Requests) != 1 || token.Requests[0].Locations[0] != "auth" {
+		t.Errorf("Expected token in the List auth, got %+v", token.Requests)
+	}
+	if missing := VariableUsageOf(collection, "unused"); missing.Requests == nil || len(missing.Requests) != 0 {
+		t.Errorf("Expected an empty list for an unused variable, got %+v", missing)
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"time"
+)
+
+// Webhook deliveries go through an in-memory queue worked by a few
+// goroutines, so handlers never wait on a subscriber. Deliveries still queued
+// when the process stops are lost.
+const (
+	webhookQueueSize   = 1000
+	webhookWorkers     = 4
+	webhookMaxAttempts = 4
+)
+
+var (
+	// webhookRetryDelay is the wait before the first retry; it doubles after
+	// each failed attempt
+	webhookRetryDelay = 2 * time.Second
+	webhookClient     = &http.Client{Timeout: 10 * time.Second}
+	webhookQueue      chan webhookDelivery
+)
+
+type webhookDelivery struct {
+	webhookID uint
+	url       string
+	secret    string
+	payload   models.WebhookPayload
+}
+
+// GenerateWebhookSecret returns a random secret for signing deliveries
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature value for body:
+// "sha256=" followed by the hex HMAC-SHA256 of the body keyed with secret
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartWebhookDispatcher starts the delivery workers
+func StartWebhookDispatcher() {
+	if webhookQueue != nil {
+		return
+	}
+	webhookQueue = make(chan webhookDelivery, webhookQueueSize)
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for delivery := range webhookQueue {
+				deliverWithRetries(delivery)
+			}
+		}()
+	}
+}
+
+// DispatchEvent queues a delivery of event to every enabled team webhook
+// subscribed to it
+func DispatchEvent(teamID uint, event string, data interface{}) {
+	if webhookQueue == nil {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.DB.Where("team_id = ? AND is_enabled = ?", teamID, true).Find(&webhooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for team %d: %v", teamID, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		enqueueDelivery(webhookDelivery{
+			webhookID: webhook.ID,
+			url:       webhook.URL,
+			secret:    webhook.Secret,
+			payload: models.WebhookPayload{
+				ID:         newDeliveryID(),
+				Event:      event,
+				TeamID:     teamID,
+				OccurredAt: time.Now().UTC(),
+				Data:       data,
+			},
+		})
+	}
+}
+
+func enqueueDelivery(delivery webhookDelivery) {
+	select {
+	case webhookQueue <- delivery:
+	default:
+		log.Printf("Webhook queue full, dropping %s delivery to webhook %d", delivery.payload.Event, delivery.webhookID)
+	}
+}
+
+func deliverWithRetries(delivery webhookDelivery) {
+	delay := webhookRetryDelay
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		err := deliverWebhook(delivery)
+		if err == nil {
+			return
+		}
+		log.Printf("Webhook %d delivery %s (%s) attempt %d/%d failed: %v",
+			delivery.webhookID, delivery.payload.ID, delivery.payload.Event, attempt, webhookMaxAttempts, err)
+		if attempt < webhookMaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	log.Printf("Giving up on webhook %d delivery %s", delivery.webhookID, delivery.payload.ID)
+}
+
+// deliverWebhook POSTs one signed payload; any non-2xx answer is a failure
+func deliverWebhook(delivery webhookDelivery) error {
+	body, err := json.Marshal(delivery.payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PostmanXodja-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.payload.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.payload.ID)
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(delivery.secret, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"postmanxodja/database"
+	"postmanxodja/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type receivedWebhook struct {
+	body      []byte
+	event     string
+	signature string
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	received := make(chan receivedWebhook, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{
+			body:      body,
+			event:     r.Header.Get("X-Webhook-Event"),
+			signature: r.Header.Get("X-Webhook-Signature"),
+		}
+	}))
+	defer server.Close()
+
+	StartWebhookDispatcher()
+	secret, err := GenerateWebhookSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	enqueueDelivery(webhookDelivery{
+		webhookID: 1,
+		url:       server.URL,
+		secret:    secret,
+		payload: models.WebhookPayload{
+			ID:         newDeliveryID(),
+			Event:      models.EventMemberJoined,
+			TeamID:     7,
+			OccurredAt: time.Now().UTC(),
+			Data:       map[string]interface{}{"user_id": 3},
+		},
+	})
+
+	var got receivedWebhook
+	select {
+	case got = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the webhook to be delivered")
+	}
+
+	if expected := SignWebhookPayload(secret, got.body); got.signature != expected {
+		t.Errorf("Expected signature '%s', got '%s'", expected, got.signature)
+	}
+	if got.event != models.EventMemberJoined {
+		t.Errorf("Expected event header '%s', got '%s'", models.EventMemberJoined, got.event)
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("Expected a JSON payload, got '%s'", got.body)
+	}
+	if payload.TeamID != 7 || payload.Event != models.EventMemberJoined {
+		t.Errorf("Expected team 7 member.joined payload, got %+v", payload)
+	}
+}
+
+func TestWebhookDeliveryRetriesFailures(t *testing.T) {
+	previous := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = previous })
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	deliverWithRetries(webhookDelivery{
+		webhookID: 1,
+		url:       server.URL,
+		secret:    "whsec_test",
+		payload:   models.WebhookPayload{ID: "d1", Event: models.EventAPIKeyCreated},
+	})
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestDispatchEventDeliversToSubscribedWebhooks(t *testing.T) {
+	useTestDB(t)
+	received := make(chan receivedWebhook, 4)
+	paths := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		paths <- r.URL.Path
+		received <- receivedWebhook{
+			body:      body,
+			event:     r.Header.Get("X-Webhook-Event"),
+			signature: r.Header.Get("X-Webhook-Signature"),
+		}
+	}))
+	defer server.Close()
+
+	for _, webhook := range []models.Webhook{
+		{TeamID: 1, URL: server.URL + "/subscribed", Secret: "whsec_subscribed", Events: models.EventCollectionUpdated + "," + models.EventMemberJoined, IsEnabled: true},
+		{TeamID: 1, URL: server.URL + "/other-event", Secret: "whsec_other", Events: models.EventAPIKeyCreated, IsEnabled: true},
+		{TeamID: 1, URL: server.URL + "/disabled", Secret: "whsec_disabled", Events: models.EventMemberJoined, IsEnabled: false},
+		{TeamID: 2, URL: server.URL + "/other-team", Secret: "whsec_team", Events: models.EventMemberJoined, IsEnabled: true},
+	} {
+		if err := database.DB.Create(&webhook).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	StartWebhookDispatcher()
+	DispatchEvent(1, models.EventMemberJoined, map[string]interface{}{"user_id": 3})
+
+	var got receivedWebhook
+	select {
+	case path := <-paths:
+		if path != "/subscribed" {
+			t.Errorf("Expected only the subscribed webhook, got '%s'", path)
+		}
+		got = <-received
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the subscribed webhook to be delivered")
+	}
+	if expected := SignWebhookPayload("whsec_subscribed", got.body); got.signature != expected {
+		t.Errorf("Expected the body signed with the webhook's secret ('%s'), got '%s'", expected, got.signature)
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("Expected a JSON payload, got '%s'", got.body)
+	}
+	if got.event != models.EventMemberJoined || payload.Event != models.EventMemberJoined || payload.TeamID != 1 || payload.ID == "" {
+		t.Errorf("Expected a team 1 member.joined delivery, got header '%s' and %+v", got.event, payload)
+	}
+
+	select {
+	case path := <-paths:
+		t.Errorf("Expected a single delivery, also got '%s'", path)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+// maxWorkflowSteps bounds how many requests one workflow run can make
+const maxWorkflowSteps = 50
+
+// maxWorkflowDataRows bounds how many iterations one run with data rows can
+// have
+const maxWorkflowDataRows = 100
+
+var (
+	workflowSources   = map[string]bool{"status": true, "header": true, "body": true}
+	workflowOperators = map[string]bool{"equals": true, "not_equals": true, "contains": true, "exists": true, "not_exists": true}
+)
+
+// ValidateWorkflow normalizes a workflow (trimmed name, default sources and
+// operators) and checks its steps
+func ValidateWorkflow(req *models.WorkflowRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Steps) == 0 {
+		return fmt.Errorf("a workflow needs at least one step")
+	}
+	if len(req.Steps) > maxWorkflowSteps {
+		return fmt.Errorf("a workflow can have at most %d steps", maxWorkflowSteps)
+	}
+
+	for i := range req.Steps {
+		step := &req.Steps[i]
+		if step.CollectionID == 0 || len(step.ItemPath) == 0 {
+			return fmt.Errorf("step %d: collection_id and item_path are required", i+1)
+		}
+		if err := ValidateCacheTTL(step.CacheTTLMs); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+		for j := range step.Extract {
+			extractor := &step.Extract[j]
+			if err := ValidateVariableKey(extractor.Variable); err != nil {
+				return fmt.Errorf("step %d: %v", i+1, err)
+			}
+			if extractor.Source == "" {
+				extractor.Source = "body"
+			}
+			if err := validateWorkflowSource(extractor.Source, extractor.Path); err != nil {
+				return fmt.Errorf("step %d: extract %q: %v", i+1, extractor.Variable, err)
+			}
+		}
+		for j := range step.Assertions {
+			assertion := &step.Assertions[j]
+			if assertion.Operator == "" {
+				assertion.Operator = "equals"
+			}
+			if !workflowOperators[assertion.Operator] {
+				return fmt.Errorf("step %d: unknown assertion operator %q", i+1, assertion.Operator)
+			}
+			if err := validateWorkflowSource(assertion.Source, assertion.Path); err != nil {
+				return fmt.Errorf("step %d: assertion %d: %v", i+1, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateWorkflowSource(source, path string) error {
+	if !workflowSources[source] {
+		return fmt.Errorf("source must be status, header or body, got %q", source)
+	}
+	if source == "header" && strings.TrimSpace(path) == "" {
+		return fmt.Errorf("header name is required")
+	}
+	if source == "body" && path != "" {
+		if err := validatePath(ToGJSONPath(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateWorkflowDataRows checks the data rows of a run: not too many, and
+// only valid variable names
+func ValidateWorkflowDataRows(rows []map[string]string) error {
+	if len(rows) > maxWorkflowDataRows {
+		return fmt.Errorf("a run can have at most %d data rows", maxWorkflowDataRows)
+	}
+	for i, row := range rows {
+		for key := range row {
+			if err := ValidateVariableKey(key); err != nil {
+				return fmt.Errorf("data row %d: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RunWorkflow executes a workflow's steps in order, starting from variables.
+// Each step sees the variables extracted by the steps before it. A failed
+// step (request error, failed assertion or missing extracted value) stops
+// the run unless the workflow continues on failure. Only cancellation of ctx
+// and failing to load the team's default headers are returned as errors;
+// everything else is reported in the result.
+func RunWorkflow(ctx context.Context, workflow *models.Workflow, variables models.Variables) (*models.WorkflowRunResult, error) {
+	defaults, err := TeamDefaultHeaders(workflow.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	iteration, err := runWorkflowIteration(ctx, workflow, variables, make(map[uint]*models.PostmanCollection), defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WorkflowRunResult{
+		WorkflowID: workflow.ID,
+		Passed:     iteration.Passed,
+		Steps:      iteration.Steps,
+		Variables:  iteration.Variables,
+		Time:       iteration.Time,
+	}, nil
+}
+
+// RunWorkflowDataRows runs the workflow once per data row, in order. Each
+// iteration starts from variables with its row merged over them and doesn't
+// see what earlier iterations extracted. A failing row doesn't stop the rows
+// after it; the run passes only when every row does. Every step of every row
+// takes its own execution slot, so a long run counts against the team's
+// concurrency cap request by request and can't hold one slot throughout.
+func RunWorkflowDataRows(ctx context.Context, workflow *models.Workflow, variables models.Variables, rows []map[string]string) (*models.WorkflowRunResult, error) {
+	start := time.Now()
+	result := &models.WorkflowRunResult{
+		WorkflowID: workflow.ID,
+		Passed:     true,
+		Steps:      []models.WorkflowStepResult{},
+		Iterations: make([]models.WorkflowIterationResult, 0, len(rows)),
+	}
+
+	defaults, err := TeamDefaultHeaders(workflow.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make(map[uint]*models.PostmanCollection)
+	for i, row := range rows {
+		iteration, err := runWorkflowIteration(ctx, workflow, MergeVariables(variables, row), collections, defaults)
+		if err != nil {
+			return nil, err
+		}
+		iteration.Row = i
+		result.Iterations = append(result.Iterations, *iteration)
+		if !iteration.Passed {
+			result.Passed = false
+		}
+	}
+
+	result.Time = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// runWorkflowIteration runs the workflow's steps once, starting from
+// variables. Steps often share a collection, so parsed collections are kept
+// in collections for the rest of the run.
+func runWorkflowIteration(ctx context.Context, workflow *models.Workflow, variables models.Variables,
+	collections map[uint]*models.PostmanCollection, defaults map[string]string) (*models.WorkflowIterationResult, error) {
+	start := time.Now()
+	result := &models.WorkflowIterationResult{
+		Passed:    true,
+		Steps:     []models.WorkflowStepResult{},
+		Variables: MergeVariables(variables, nil),
+	}
+
+	for i := range workflow.Steps {
+		stepResult, err := runWorkflowStep(ctx, workflow.TeamID, &workflow.Steps[i], result.Variables, collections, defaults)
+		if err != nil {
+			return nil, err
+		}
+		stepResult.Index = i
+		result.Steps = append(result.Steps, *stepResult)
+
+		if !stepResult.Passed {
+			result.Passed = false
+			if !workflow.ContinueOnFailure {
+				break
+			}
+		}
+	}
+
+	result.Time = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// runWorkflowStep runs one step, adding what it extracts to scope
+func runWorkflowStep(ctx context.Context, teamID uint, step *models.WorkflowStep, scope models.Variables,
+	collections map[uint]*models.PostmanCollection, defaults map[string]string) (*models.WorkflowStepResult, error) {
+	result := &models.WorkflowStepResult{
+		Name:       step.Name,
+		Assertions: []models.WorkflowAssertionResult{},
+		Extracted:  map[string]string{},
+	}
+	if result.Name == "" {
+		result.Name = strings.Join(step.ItemPath, " / ")
+	}
+
+	execReq, collectionVariables, err := loadWorkflowRequest(teamID, step, collections)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	execReq.CacheTTLMs = step.CacheTTLMs
+	ApplyDefaultHeaders(execReq, defaults)
+	// Collection variables, overridden by the run's scope
+	unresolved := ReplaceInRequest(execReq, MergeVariables(collectionVariables, scope))
+	if execReq.URL, err = NormalizeRequestURL(execReq.URL); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	if err := CheckHostPolicy(teamID, execReq.URL); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	release, err := AcquireExecutionSlot(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	response, err := ExecuteCached(WithHostPolicy(ctx, teamID), fmt.Sprintf("team:%d", teamID), execReq)
+	release()
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	if err := RecordItemTiming(teamID, step.CollectionID, step.ItemPath, response.Time, time.Now()); err != nil {
+		log.Printf("Failed to record timing of collection %d item %v: %v", step.CollectionID, step.ItemPath, err)
+	}
+	response.UnresolvedVariables = unresolved
+	result.Response = response
+
+	result.Passed = true
+	for _, assertion := range step.Assertions {
+		outcome := checkWorkflowAssertion(response, assertion)
+		result.Assertions = append(result.Assertions, outcome)
+		if !outcome.Passed {
+			result.Passed = false
+		}
+	}
+
+	for _, extractor := range step.Extract {
+		value, found, err := workflowResponseValue(response, extractor.Source, extractor.Path)
+		if err == nil && !found {
+			err = fmt.Errorf("nothing found at %s %q", extractor.Source, extractor.Path)
+		}
+		if err != nil {
+			result.Passed = false
+			result.Error = fmt.Sprintf("extract %q: %v", extractor.Variable, err)
+			continue
+		}
+		scope[extractor.Variable] = value
+		result.Extracted[extractor.Variable] = value
+	}
+	return result, nil
+}
+
+// loadWorkflowRequest builds the request for a step from the team's
+// collection, with inherited auth, and returns the collection's variables
+func loadWorkflowRequest(teamID uint, step *models.WorkflowStep,
+	collections map[uint]*models.PostmanCollection) (*models.ExecuteRequest, models.Variables, error) {
+	parsed, ok := collections[step.CollectionID]
+	if !ok {
+		var collection models.Collection
+		if err := database.GetDB().Where("id = ? AND team_id = ?", step.CollectionID, teamID).
+			First(&collection).Error; err != nil {
+			return nil, nil, fmt.Errorf("collection %d not found", step.CollectionID)
+		}
+		var err error
+		if parsed, err = ParsePostmanCollection(collection.RawJSON); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse collection %d", step.CollectionID)
+		}
+		collections[step.CollectionID] = parsed
+	}
+
+	item, err := FindCollectionItem(parsed, step.ItemPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	execReq, err := ItemToExecuteRequest(item, EffectiveAuth(parsed, step.ItemPath))
+	if err != nil {
+		return nil, nil, err
+	}
+	return execReq, CollectionVariables(parsed), nil
+}
+
+func checkWorkflowAssertion(response *models.ExecuteResponse, assertion models.WorkflowAssertion) models.WorkflowAssertionResult {
+	outcome := models.WorkflowAssertionResult{WorkflowAssertion: assertion}
+	actual, found, err := workflowResponseValue(response, assertion.Source, assertion.Path)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	outcome.Actual = actual
+
+	switch assertion.Operator {
+	case "equals":
+		outcome.Passed = found && actual == assertion.Value
+	case "not_equals":
+		outcome.Passed = !found || actual != assertion.Value
+	case "contains":
+		outcome.Passed = found && strings.Contains(actual, assertion.Value)
+	case "exists":
+		outcome.Passed = found
+	case "not_exists":
+		outcome.Passed = !found
+	}
+	return outcome
+}
+
+// workflowResponseValue reads a value from a response as text: the status
+// code, a header, or the body (whole, or at a JSON path). Strings found at a
+// JSON path are unquoted; other JSON values are returned as JSON.
+func workflowResponseValue(response *models.ExecuteResponse, source, path string) (string, bool, error) {
+	switch source {
+	case "status":
+		return strconv.Itoa(response.Status), true, nil
+	case "header":
+		if value, ok := response.Headers[http.CanonicalHeaderKey(path)]; ok {
+			return value, true, nil
+		}
+		for name, value := range response.Headers {
+			if strings.EqualFold(name, path) {
+				return value, true, nil
+			}
+		}
+		return "", false, nil
+	}
+
+	if path == "" {
+		return response.Body, response.BodyPresent, nil
+	}
+	extracted, err := ExtractJSONPath(response.Body, path)
+	if err != nil || !extracted.Found {
+		return "", false, err
+	}
+	if extracted.Type == "string" {
+		var value string
+		json.Unmarshal(extracted.Value, &value)
+		return value, true, nil
+	}
+	return string(extracted.Value), true, nil
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"postmanxodja/database"
+	"postmanxodja/models"
+)
+
+const workflowCollection = `{
+	"info": {"name": "Accounts"},
+	"item": [
+		{"name": "Login", "request": {
+			"method": "POST",
+			"url": "{{base_url}}/login",
+			"body": {"mode": "raw", "raw": "{\"user\":\"ada\"}"}
+		}},
+		{"name": "Get user", "request": {
+			"method": "GET",
+			"url": "{{base_url}}/users/{{user_id}}",
+			"header": [{"key": "Authorization", "value": "Bearer {{token}}"}]
+		}},
+		{"name": "Delete user", "request": {
+			"method": "DELETE",
+			"url": "{{base_url}}/users/{{user_id}}"
+		}}
+	]
+}`
+
+// workflowServer logs in with a token and only serves users to that token
+func workflowServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/login":
+			w.Header().Set("X-Session", "s-1")
+			w.Write([]byte(`{"token":"abc","user":{"id":7}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/7":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"name":"Ada","roles":["admin"]}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createWorkflowCollection(t *testing.T) (*models.Team, *models.Collection) {
+	user := createTestUser(t, "owner@example.com")
+	team, err := CreateTeamWithOwner("Acme", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := models.Collection{Name: "Accounts", RawJSON: workflowCollection, TeamID: &team.ID}
+	if err := database.DB.Create(&collection).Error; err != nil {
+		t.Fatal(err)
+	}
+	return team, &collection
+}
+
+func TestRunWorkflowThreadsExtractedVariables(t *testing.T) {
+	useTestDB(t)
+	useLoopback(t)
+	server := workflowServer(t)
+	team, collection := createWorkflowCollection(t)
+
+	workflow := &models.Workflow{TeamID: team.ID, Steps: models.WorkflowSteps{
+		{
+			CollectionID: collection.ID,
+			ItemPath:     []string{"Login"},
+			Assertions:   []models.WorkflowAssertion{{Source: "status", Operator: "equals", Value: "200"}},
+			Extract: []models.WorkflowExtractor{
+				{Variable: "token", Source: "body", Path: "token"},
+				{Variable: "user_id", Source: "body", Path: "$.user.id"},
+				{Variable: "session", Source: "header", Path: "x-session"},
+			},
+		},
+		{
+			Name:         "Fetch profile",
+			CollectionID: collection.ID,
+			ItemPath:     []string{"Get user"},
+			Assertions: []models.WorkflowAssertion{
+				{Source: "status", Operator: "equals", Value: "200"},
+				{Source: "body", Path: "name", Operator: "equals", Value: "Ada"},
+				{Source: "body", Path: "roles", Operator: "contains", Value: "admin"},
+			},
+		},
+	}}
+
+	result, err := RunWorkflow(context.Background(), workflow, models.Variables{"base_url": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Passed || len(result.Steps) != 2 {
+		data, _ := json.Marshal(result)
+		t.Fatalf("Expected both steps to pass, got %s", data)
+	}
+	if result.Steps[0].Name != "Login" || result.Steps[1].Name != "Fetch profile" {
+		t.Errorf("Expected step names 'Login' and 'Fetch profile', got '%s' and '%s'", result.Steps[0].Name, result.Steps[1].Name)
+	}
+	if result.Steps[0].Extracted["user_id"] != "7" || result.Steps[0].Extracted["session"] != "s-1" {
+		t.Errorf("Expected user_id and session to be extracted, got %v", result.Steps[0].Extracted)
+	}
+	if result.Variables["token"] != "abc" || result.Variables["base_url"] != server.URL {
+		t.Errorf("Expected the final scope to hold the starting and extracted variables, got %v", result.Variables)
+	}
+	// Steps count towards the collection's item timings
+	var timings int64
+	database.DB.Model(&models.CollectionItemTiming{}).Where("collection_id = ?", collection.ID).Count(&timings)
+	if timings != 2 {
+		t.Errorf("Expected a timing for each step's item, got %d", timings)
+	}
+}
+
+func TestRunWorkflowStopsOnFailedAssertion(t *testing.T) {
+	useTestDB(t)
+	useLoopback(t)
+	server := workflowServer(t)
+	team, collection := createWorkflowCollection(t)
+
+	steps := models.WorkflowSteps{
+		// No login, so the token is missing and the user lookup is refused
+		{CollectionID: collection.ID, ItemPath: []string{"Get user"},
+			Assertions: []models.WorkflowAssertion{{Source: "status", Operator: "equals", Value: "200"}}},
+		{CollectionID: collection.ID, ItemPath: []string{"Login"}},
+	}
+	variables := models.Variables{"base_url": server.URL, "user_id": "7"}
+
+	result, err := RunWorkflow(context.Background(), &models.Workflow{TeamID: team.ID, Steps: steps}, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Passed || len(result.Steps) != 1 {
+		t.Fatalf("Expected the run to stop after the failed step, got %d steps", len(result.Steps))
+	}
+	if assertion := result.Steps[0].Assertions[0]; assertion.Passed || assertion.Actual != "401" {
+		t.Errorf("Expected a failed status assertion with actual '401', got %+v", assertion)
+	}
+
+	result, err = RunWorkflow(context.Background(), &models.Workflow{TeamID: team.ID, Steps: steps, ContinueOnFailure: true}, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Passed || len(result.Steps) != 2 || !result.Steps[1].Passed {
+		t.Errorf("Expected the run to continue past the failure, got %d steps", len(result.Steps))
+	}
+}
+
+func TestRunWorkflowReportsMissingExtraction(t *testing.T) {
+	useTestDB(t)
+	useLoopback(t)
+	server := workflowServer(t)
+	team, collection := createWorkflowCollection(t)
+
+	workflow := &models.Workflow{TeamID: team.ID, Steps: models.WorkflowSteps{
+		{CollectionID: collection.ID, ItemPath: []string{"Login"},
+			Extract: []models.WorkflowExtractor{{Variable: "refresh", Source: "body", Path: "refresh_token"}}},
+		{CollectionID: 999, ItemPath: []string{"Login"}},
+	}}
+
+	result, err := RunWorkflow(context.Background(), workflow, models.Variables{"base_url": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Passed || len(result.Steps) != 1 || !strings.Contains(result.Steps[0].Error, `extract "refresh"`) {
+		t.Errorf("Expected the missing value to fail the first step, got %+v", result.Steps)
+	}
+
+	workflow.ContinueOnFailure = true
+	result, _ = RunWorkflow(context.Background(), workflow, models.Variables{"base_url": server.URL})
+	if len(result.Steps) != 2 || result.Steps[1].Error != "collection 999 not found" {
+		t.Errorf("Expected another team's or a missing collection to fail the step, got %+v", result.Steps)
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	req := models.WorkflowRequest{Name: " Signup ", Steps: []models.WorkflowStep{{
+		CollectionID: 1,
+		ItemPath:     []string{"Login"},
+		Extract:      []models.WorkflowExtractor{{Variable: "token", Path: "token"}},
+		Assertions:   []models.WorkflowAssertion{{Source: "status", Value: "200"}},
+	}}}
+	if err := ValidateWorkflow(&req); err != nil {
+		t.Fatalf("Expected a valid workflow, got %v", err)
+	}
+	if req.Name != "Signup" || req.Steps[0].Extract[0].Source != "body" || req.Steps[0].Assertions[0].Operator != "equals" {
+		t.Errorf("Expected defaults to be filled in, got %+v", req)
+	}
+
+	for name, step := range map[string]models.WorkflowStep{
+		"no collection": {ItemPath: []string{"Login"}},
+		"no item":       {CollectionID: 1},
+		"bad variable":  {CollectionID: 1, ItemPath: []string{"a"}, Extract: []models.WorkflowExtractor{{Variable: "{x}"}}},
+		"bad source":    {CollectionID: 1, ItemPath: []string{"a"}, Assertions: []models.WorkflowAssertion{{Source: "cookie"}}},
+		"bad operator":  {CollectionID: 1, ItemPath: []string{"a"}, Assertions: []models.WorkflowAssertion{{Source: "status", Operator: "gt"}}},
+		"no header":     {CollectionID: 1, ItemPath: []string{"a"}, Assertions: []models.WorkflowAssertion{{Source: "header"}}},
+		"bad path":      {CollectionID: 1, ItemPath: []string{"a"}, Extract: []models.WorkflowExtractor{{Variable: "x", Path: "items[0"}}},
+	} {
+		bad := models.WorkflowRequest{Name: "Bad", Steps: []models.WorkflowStep{step}}
+		if err := ValidateWorkflow(&bad); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+	if err := ValidateWorkflow(&models.WorkflowRequest{Name: "Empty"}); err == nil {
+		t.Error("Expected a workflow without steps to be rejected")
+	}
+}
+
+func TestRunWorkflowDataRows(t *testing.T) {
+	useTestDB(t)
+	useLoopback(t)
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/users/1" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	team, collection := createWorkflowCollection(t)
+
+	workflow := &models.Workflow{TeamID: team.ID, Steps: models.WorkflowSteps{{
+		CollectionID: collection.ID,
+		ItemPath:     []string{"Delete user"},
+		Assertions:   []models.WorkflowAssertion{{Source: "status", Operator: "equals", Value: "200"}},
+	}}}
+	rows := []map[string]string{{"user_id": "1"}, {"user_id": "2"}}
+
+	result, err := RunWorkflowDataRows(context.Background(), workflow, models.Variables{"base_url": server.URL, "user_id": "0"}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != "/users/1,/users/2" {
+		t.Errorf("Expected one request per row, got %v", paths)
+	}
+	if len(result.Iterations) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(result.Iterations))
+	}
+	first, second := result.Iterations[0], result.Iterations[1]
+	if first.Row != 0 || !first.Passed || first.Variables["user_id"] != "1" {
+		t.Errorf("Expected row 0 to pass with its own user_id, got %+v", first)
+	}
+	if second.Row != 1 || second.Passed || second.Steps[0].Response.Status != http.StatusNotFound {
+		t.Errorf("Expected row 1 to fail with a 404, got %+v", second)
+	}
+	if result.Passed {
+		t.Error("Expected the run to fail when one row does")
+	}
+}
+
+// useExecutionLimiter swaps the execution limiter for the test
+func useExecutionLimiter(t *testing.T, limiter *executionLimiter) {
+	previous := defaultExecutionLimiter()
+	executionSlots = limiter
+	t.Cleanup(func() { executionSlots = previous })
+}
+
+func TestRunWorkflowDataRowsTakesASlotPerRow(t *testing.T) {
+	useTestDB(t)
+	useLoopback(t)
+	useExecutionLimiter(t, newExecutionLimiter(0, 1, 0))
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if active := CurrentExecutionStats().Active; active != 1 {
+			t.Errorf("Expected the request to hold the one slot, got %d active", active)
+		}
+	}))
+	t.Cleanup(server.Close)
+	team, collection := createWorkflowCollection(t)
+	workflow := &models.Workflow{TeamID: team.ID, Steps: models.WorkflowSteps{{
+		CollectionID: collection.ID,
+		ItemPath:     []string{"Delete user"},
+	}}}
+	rows := []map[string]string{{"user_id": "1"}, {"user_id": "2"}, {"user_id": "3"}}
+	variables := models.Variables{"base_url": server.URL}
+
+	// With the team's only slot taken, every row is turned away
+	release, err := AcquireExecutionSlot(context.Background(), team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := RunWorkflowDataRows(context.Background(), workflow, variables, rows)
+	release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 || result.Passed {
+		t.Fatalf("Expected no requests while the team is at its cap, got %d", requests)
+	}
+	for _, iteration := range result.Iterations {
+		if iteration.Steps[0].Error != ErrExecutionQueueTimeout.Error() {
+			t.Errorf("Row %d: expected the concurrency cap to refuse it, got '%s'", iteration.Row, iteration.Steps[0].Error)
+		}
+	}
+
+	// Each row's request takes the slot and gives it back for the next one
+	result, err = RunWorkflowDataRows(context.Background(), workflow, variables, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || !result.Passed {
+		t.Errorf("Expected all 3 rows to run one after another, got %d requests: %+v", requests, result)
+	}
+	if active := CurrentExecutionStats().Active; active != 0 {
+		t.Errorf("Expected every slot to be released, got %d active", active)
+	}
+}
+
+func TestValidateWorkflowDataRows(t *testing.T) {
+	if err := ValidateWorkflowDataRows([]map[string]string{{"id": "1"}}); err != nil {
+		t.Errorf("Expected a valid row, got %v", err)
+	}
+	if err := ValidateWorkflowDataRows([]map[string]string{{"{{id}}": "1"}}); err == nil {
+		t.Error("Expected an invalid variable name to be rejected")
+	}
+	if err := ValidateWorkflowDataRows(make([]map[string]string, maxWorkflowDataRows+1)); err == nil {
+		t.Error("Expected too many rows to be rejected")
+	}
+}
@@ -4,6 +4,8 @@ import {
   getAISettings,
   updateAISettings,
   deleteAISettings,
+  getAIModels,
+  modelOptions,
   AI_MODELS,
   type AISettingsResponse,
 } from '../services/ai';
@@ -18,6 +20,7 @@ export default function AISettingsModal({ isOpen, onClose }: Props) {
   const [settings, setSettings] = useState<AISettingsResponse | null>(null);
   const [apiKey, setApiKey] = useState('');
   const [model, setModel] = useState('gpt-4o-mini');
+  const [availableModels, setAvailableModels] = useState(AI_MODELS);
   const [loading, setLoading] = useState(false);
   const [saving, setSaving] = useState(false);
   const [error, setError] = useState<string | null>(null);
@@ -38,6 +41,9 @@ export default function AISettingsModal({ isOpen, onClose }: Props) {
       setSettings(data);
       setModel(data.model || 'gpt-4o-mini');
       setApiKey(''); // Never pre-fill the key
+      getAIModels(currentTeam.id)
+        .then((list) => setAvailableModels(modelOptions(list.models)))
+        .catch(() => setAvailableModels(AI_MODELS));
     } catch (err: any) {
       setError(err.message);
     } finally {
@@ -166,7 +172,7 @@ export default function AISettingsModal({ isOpen, onClose }: Props) {
                   Model
                 </label>
                 <div className="grid grid-cols-2 gap-2">
-                  {AI_MODELS.map((m) => (
+                  {availableModels.map((m) => (
                     <button
                       key={m.value}
                       onClick={() => setModel(m.value)}
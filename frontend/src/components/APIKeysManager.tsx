@@ -252,6 +252,11 @@ export default function APIKeysManager() {
           <div>
             <label className="text-xs font-medium text-muted-foreground uppercase tracking-wider">Endpoints</label>
             <div className="mt-1 space-y-1">
+              <div className="grid grid-cols-[auto_1fr_auto] gap-2 items-center p-2 bg-background rounded-lg hover:bg-accent transition-colors">
+                <span className="px-2 py-0.5 bg-primary/10 text-primary rounded text-xs font-bold w-16 text-center">GET</span>
+                <code className="text-sm text-muted-foreground font-mono">/whoami</code>
+                <span className="text-xs text-muted-foreground">Check key</span>
+              </div>
               <div className="grid grid-cols-[auto_1fr_auto] gap-2 items-center p-2 bg-background rounded-lg hover:bg-accent transition-colors">
                 <span className="px-2 py-0.5 bg-primary/10 text-primary rounded text-xs font-bold w-16 text-center">GET</span>
                 <code className="text-sm text-muted-foreground font-mono">/collections</code>
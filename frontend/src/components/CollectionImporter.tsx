@@ -2,6 +2,7 @@ import { useState } from 'react';
 import { importCollection, createCollection } from '../services/api';
 import { useTeam } from '../contexts/TeamContext';
 import InputModal from './InputModal';
+import { getApiError, getErrorMessage } from '../utils/apiError';
 
 interface Props {
   onImportSuccess: () => void;
@@ -31,16 +32,16 @@ export default function CollectionImporter({ onImportSuccess, onUCodeImport }: P
     e.target.value = '';
 
     try {
-      await importCollection(currentTeam.id, text);
+      await importCollection(currentTeam.id, text, undefined, true);
       onImportSuccess();
     } catch (err: any) {
       if (err.response?.status === 409) {
         setDuplicateInfo({
-          name: err.response.data.name,
+          name: getApiError(err.response.data)?.details?.name,
           collectionJSON: text,
         });
       } else {
-        setError(err.response?.data?.error || 'Failed to import collection');
+        setError(getErrorMessage(err.response?.data, 'Failed to import collection'));
       }
     } finally {
       setLoading(false);
@@ -54,10 +55,10 @@ export default function CollectionImporter({ onImportSuccess, onUCodeImport }: P
     setError(null);
 
     try {
-      await importCollection(currentTeam.id, duplicateInfo.collectionJSON, mode);
+      await importCollection(currentTeam.id, duplicateInfo.collectionJSON, mode, true);
       onImportSuccess();
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to import collection');
+      setError(getErrorMessage(err.response?.data, 'Failed to import collection'));
     } finally {
       setLoading(false);
       setDuplicateInfo(null);
@@ -75,7 +76,7 @@ export default function CollectionImporter({ onImportSuccess, onUCodeImport }: P
       onImportSuccess();
       setShowCreateModal(false);
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to create collection');
+      setError(getErrorMessage(err.response?.data, 'Failed to create collection'));
     } finally {
       setLoading(false);
     }
@@ -2,6 +2,7 @@ import { useState, useEffect, useCallback, useRef } from 'react';
 import { getEnvironments, createEnvironment, updateEnvironment, deleteEnvironment } from '../services/api';
 import { useTeam } from '../contexts/TeamContext';
 import ConfirmModal from './ConfirmModal';
+import { getApiError } from '../utils/apiError';
 import type { Environment } from '../types';
 
 interface Props {
@@ -232,8 +233,21 @@ export default function EnvironmentPanel({ onUpdate }: Props) {
     try {
       await deleteEnvironment(currentTeam.id, deleteTargetId);
       loadEnvironments();
-    } catch (err) {
-      console.error('Failed to delete environment:', err);
+    } catch (err: any) {
+      const apiError = getApiError(err.response?.data);
+      if (apiError?.code === 'ENVIRONMENT_IN_USE') {
+        const names = (apiError.details?.references || []).map((ref: { name: string }) => ref.name).join(', ');
+        if (window.confirm(`This environment is the default for: ${names}. Delete it anyway?`)) {
+          try {
+            await deleteEnvironment(currentTeam.id, deleteTargetId, true);
+            loadEnvironments();
+          } catch (forceErr) {
+            console.error('Failed to delete environment:', forceErr);
+          }
+        }
+      } else {
+        console.error('Failed to delete environment:', err);
+      }
     }
     setDeleteTargetId(null);
   };
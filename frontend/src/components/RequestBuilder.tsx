@@ -6,6 +6,8 @@ import AuthorizationPanel from './AuthorizationPanel';
 import { SaveButton } from './ui/save-button';
 import { parseCurl, generateCurl } from '../utils/curlParser';
 import type { ExecuteRequest, ExecuteResponse, Environment, RequestTab, BodyType, FormDataItem, SentRequest, Authorization, PostmanKeyValue } from '../types';
+import { getErrorMessage } from '../utils/apiError';
+import { useTeam } from '../contexts/TeamContext';
 
 interface Props {
     environments: Environment[];
@@ -37,6 +39,7 @@ export default function RequestBuilder({
                                            onEnvironmentChange,
                                            onSaveToCollection,
                                        }: Props) {
+    const { currentTeam } = useTeam();
     const [method, setMethod] = useState(initialMethod);
     const [url, setUrl] = useState(initialUrl);
     const [headers, setHeaders] = useState<PostmanKeyValue[]>(() =>
@@ -154,10 +157,7 @@ export default function RequestBuilder({
             headersWithAuth['X-AWS-Signature'] = JSON.stringify(auth.awssig);
         }
 
-        if (auth.type === 'ntlm' && auth.ntlm?.username) {
-            // NTLM requires complex negotiation - typically handled by backend
-            headersWithAuth['X-NTLM-Auth'] = JSON.stringify(auth.ntlm);
-        }
+        // NTLM isn't a header: the backend performs the handshake (see request.auth)
 
         if (auth.type === 'apikey' && auth.apikey?.key && auth.apikey?.value) {
             if (auth.apikey.addTo === 'header') {
@@ -307,7 +307,11 @@ export default function RequestBuilder({
                 body_type: bodyType,
                 form_data: bodyType === 'form-data' ? formData.filter(f => f.key) : undefined,
                 query_params: {},
-                environment_id: selectedEnvId
+                environment_id: selectedEnvId,
+                team_id: currentTeam?.id,
+                auth: auth?.type === 'ntlm' && auth.ntlm?.username
+                    ? { type: 'ntlm', ntlm: { username: auth.ntlm.username, password: auth.ntlm.password || '', domain: auth.ntlm.domain, workstation: auth.ntlm.workstation } }
+                    : undefined
             };
 
             const response = await executeRequest(request);
@@ -336,7 +340,7 @@ export default function RequestBuilder({
         } catch (err: any) {
             console.error('Request failed:', err);
             console.error('Error details:', err.response);
-            alert(err.response?.data?.error || err.message || 'Request failed');
+            alert(getErrorMessage(err.response?.data, err.message || 'Request failed'));
         } finally {
             setLoading(false);
         }
@@ -1051,8 +1055,6 @@ export default function RequestBuilder({
                                             tempHeadersWithAuth['X-Hawk-Auth'] = JSON.stringify(newAuth.hawk);
                                         } else if (newAuth.type === 'awssig' && newAuth.awssig?.accessKey) {
                                             tempHeadersWithAuth['X-AWS-Signature'] = JSON.stringify(newAuth.awssig);
-                                        } else if (newAuth.type === 'ntlm' && newAuth.ntlm?.username) {
-                                            tempHeadersWithAuth['X-NTLM-Auth'] = JSON.stringify(newAuth.ntlm);
                                         } else if (newAuth.type === 'apikey' && newAuth.apikey?.key && newAuth.apikey?.value && newAuth.apikey?.addTo === 'header') {
                                             tempHeadersWithAuth[newAuth.apikey.key] = newAuth.apikey.value;
                                         } else if (newAuth.type === 'akamai' && newAuth.akamai?.clientToken) {
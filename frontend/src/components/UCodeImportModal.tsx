@@ -40,7 +40,7 @@ export default function UCodeImportModal({ isOpen, onClose, onImport }: Props) {
 
   // Check if AI is available for the current team
   useEffect(() => {
-    if (isOpen && currentTeam && currentTeam.name !== 'Personal') {
+    if (isOpen && currentTeam && !currentTeam.is_personal) {
       getAISettings(currentTeam.id)
         .then((settings) => {
           setAiAvailable(settings.is_enabled && settings.has_api_key);
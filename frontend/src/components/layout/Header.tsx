@@ -159,7 +159,7 @@ export default function Header({ onToggleSidebar }: HeaderProps) {
                 </svg>
                 <span>Members</span>
               </button>
-              {isTeamOwner && currentTeam.name !== 'Personal' && (
+              {isTeamOwner && !currentTeam.is_personal && (
                 <>
                   <button
                     onClick={() => setShowInvite(true)}
@@ -278,7 +278,7 @@ export default function Header({ onToggleSidebar }: HeaderProps) {
                         >
                           Members
                         </button>
-                        {isTeamOwner && currentTeam.name !== 'Personal' && (
+                        {isTeamOwner && !currentTeam.is_personal && (
                           <>
                             <button
                               onClick={() => { setShowUserMenu(false); setShowInvite(true); }}
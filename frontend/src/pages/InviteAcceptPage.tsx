@@ -3,6 +3,7 @@ import { useParams, useNavigate, Link } from 'react-router-dom';
 import { useAuth } from '../contexts/AuthContext';
 import { useTeam } from '../contexts/TeamContext';
 import api from '../services/api';
+import { getErrorMessage } from '../utils/apiError';
 
 interface InviteDetails {
   team_name: string;
@@ -29,7 +30,7 @@ export default function InviteAcceptPage() {
         const data = await response.json();
 
         if (!response.ok) {
-          setError(data.error || 'Invalid invite');
+          setError(getErrorMessage(data, 'Invalid invite'));
           return;
         }
 
@@ -62,7 +63,7 @@ export default function InviteAcceptPage() {
 
       navigate('/', { replace: true });
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to accept invite');
+      setError(getErrorMessage(err.response?.data, 'Failed to accept invite'));
       setAccepting(false);
     }
   };
@@ -2,6 +2,8 @@
  * AI Settings service - manages team OpenAI configuration and DBML analysis
  */
 
+import { getApiError, getErrorMessage } from '../utils/apiError';
+
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
 
 const getAuthHeaders = () => {
@@ -80,6 +82,25 @@ export const AI_MODELS = [
   { value: 'o3-mini', label: 'O3 Mini', description: 'Latest reasoning model' },
 ];
 
+export interface AIModelsResponse {
+  provider: string;
+  models: string[];
+  source: 'provider' | 'static' | 'fallback';
+}
+
+// Chat models the team's key can use, as reported by the provider
+export const getAIModels = async (teamId: number): Promise<AIModelsResponse> => {
+  const response = await fetch(`${API_BASE_URL}/teams/${teamId}/ai/models`, {
+    headers: getAuthHeaders(),
+  });
+  if (!response.ok) throw new Error('Failed to get AI models');
+  return response.json();
+};
+
+// modelOptions describes the given model IDs, using AI_MODELS where known
+export const modelOptions = (ids: string[]) =>
+  ids.map((id) => AI_MODELS.find((m) => m.value === id) ?? { value: id, label: id, description: '' });
+
 export const getAISettings = async (teamId: number): Promise<AISettingsResponse> => {
   const response = await fetch(`${API_BASE_URL}/teams/${teamId}/ai-settings`, {
     headers: getAuthHeaders(),
@@ -96,7 +117,7 @@ export const updateAISettings = async (teamId: number, settings: AISettingsReque
   });
   if (!response.ok) {
     const err = await response.json();
-    throw new Error(err.error || 'Failed to update AI settings');
+    throw new Error(getErrorMessage(err, 'Failed to update AI settings'));
   }
   return response.json();
 };
@@ -123,7 +144,11 @@ export const analyzeDBML = async (teamId: number, data: {
   });
   if (!response.ok) {
     const err = await response.json();
-    throw new Error(err.error || 'AI analysis failed');
+    const apiError = getApiError(err);
+    if (apiError?.code === 'INVALID_DBML' && apiError.details?.problem) {
+      throw new Error(`${apiError.message}: ${apiError.details.problem}`);
+    }
+    throw new Error(getErrorMessage(err, 'AI analysis failed'));
   }
   return response.json();
 };
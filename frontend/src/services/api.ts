@@ -1,5 +1,5 @@
 import axios from 'axios';
-import type { Collection, ExecuteRequest, ExecuteResponse, Environment } from '../types';
+import type { Collection, ExecuteRequest, ExecuteResponse, Environment, GraphQLSchema, OAuth2Config, OAuth2Token, ServerAuth } from '../types';
 import { executeRequestDirect, isBackendReachable } from './offlineExecutor';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
@@ -43,8 +43,17 @@ export const createCollection = async (teamId: number, name: string, description
   return response.data;
 };
 
-export const importCollection = async (teamId: number, collectionJSON: string, mode?: 'replace' | 'duplicate'): Promise<Collection> => {
-  const response = await api.post(`/teams/${teamId}/collections/import`, { collection_json: collectionJSON, mode: mode || '' });
+export const importCollection = async (
+  teamId: number,
+  collectionJSON: string,
+  mode?: 'replace' | 'duplicate',
+  lenient = false
+): Promise<Collection> => {
+  const response = await api.post(
+    `/teams/${teamId}/collections/import`,
+    { collection_json: collectionJSON, mode: mode || '' },
+    { params: lenient ? { lenient: true } : undefined }
+  );
   return response.data;
 };
 
@@ -62,11 +71,18 @@ export const deleteCollection = async (teamId: number, id: number): Promise<void
   await api.delete(`/teams/${teamId}/collections/${id}`);
 };
 
-export const updateCollection = async (teamId: number, id: number, data: { raw_json?: string; name?: string }): Promise<Collection> => {
+export const updateCollection = async (teamId: number, id: number, data: { raw_json?: string; name?: string; description?: string; environment_id?: number | null }): Promise<Collection> => {
   const response = await api.put(`/teams/${teamId}/collections/${id}`, data);
   return response.data;
 };
 
+export const setCollectionPinned = async (teamId: number, id: number, pinned: boolean): Promise<Collection> => {
+  const response = pinned
+    ? await api.put(`/teams/${teamId}/collections/${id}/pin`)
+    : await api.delete(`/teams/${teamId}/collections/${id}/pin`);
+  return response.data;
+};
+
 export const setCollectionEnvironment = async (
   teamId: number,
   collectionId: number,
@@ -78,6 +94,122 @@ export const setCollectionEnvironment = async (
   return response.data;
 };
 
+// Each folder's items by name, in their new order; an empty path is the top level
+export interface FolderOrder {
+  path: string[];
+  items: string[];
+}
+
+export const reorderCollection = async (teamId: number, collectionId: number, folders: FolderOrder[]): Promise<Collection> => {
+  const response = await api.post(`/teams/${teamId}/collections/${collectionId}/reorder`, { folders });
+  return response.data;
+};
+
+// Re-read the name, description and request count from the stored raw JSON
+export const resyncCollection = async (
+  teamId: number,
+  collectionId: number
+): Promise<{ collection: Collection; changed: ('name' | 'description' | 'request_count')[] }> => {
+  const response = await api.post(`/teams/${teamId}/collections/${collectionId}/resync`);
+  return response.data;
+};
+
+export interface VariableReference {
+  item_path: string[];
+  method: string;
+  locations: ('url' | 'header' | 'body' | 'auth')[];
+}
+
+export interface VariableUsage {
+  name: string;
+  requests: VariableReference[];
+}
+
+export const getVariableUsage = async (teamId: number, collectionId: number, variable: string): Promise<VariableUsage> => {
+  const response = await api.get(`/teams/${teamId}/collections/${collectionId}/variable-usage`, { params: { var: variable } });
+  return response.data;
+};
+
+export const getAllVariableUsage = async (teamId: number, collectionId: number): Promise<VariableUsage[]> => {
+  const response = await api.get(`/teams/${teamId}/collections/${collectionId}/variable-usage`);
+  return response.data.variables;
+};
+
+// A likely mistake in one of the collection's requests
+export interface LintFinding {
+  rule: 'missing-url' | 'hardcoded-credential' | 'missing-auth' | 'duplicate-name' | string;
+  severity: 'error' | 'warning';
+  item_path: string[];
+  message: string;
+}
+
+export const lintCollection = async (teamId: number, collectionId: number): Promise<LintFinding[]> => {
+  const response = await api.get(`/teams/${teamId}/collections/${collectionId}/lint`);
+  return response.data.findings;
+};
+
+// A collection with an environment's variables substituted; secrets show as "***"
+// unless revealSecrets is set
+export interface PreviewRequest {
+  method: string;
+  url: string;
+  headers: { key: string; value: string }[];
+  body?: string;
+  unresolved_variables?: string[];
+  error?: string;
+}
+
+export interface PreviewItem {
+  name: string;
+  request?: PreviewRequest;
+  item?: PreviewItem[];
+}
+
+export interface CollectionPreview {
+  items: PreviewItem[];
+  unresolved_variables: string[];
+  masked_variables: string[];
+}
+
+export const previewCollection = async (
+  teamId: number,
+  collectionId: number,
+  environmentId?: number,
+  revealSecrets = false
+): Promise<CollectionPreview> => {
+  const response = await api.get(`/teams/${teamId}/collections/${collectionId}/preview`, {
+    params: { environment_id: environmentId, reveal_secrets: revealSecrets || undefined },
+  });
+  return response.data;
+};
+
+// Response time rollups of a collection item's executions; p50/p95 are approximate
+export interface ItemTimingStats {
+  count: number;
+  min_ms: number;
+  max_ms: number;
+  mean_ms: number;
+  p50_ms: number;
+  p95_ms: number;
+}
+
+export interface ItemTimings extends ItemTimingStats {
+  item_path: string[];
+  days: (ItemTimingStats & { day: string })[];
+}
+
+export const getItemTimings = async (
+  teamId: number,
+  collectionId: number,
+  options: { days?: number; itemPath?: string[] } = {}
+): Promise<ItemTimings[]> => {
+  const response = await api.get(`/teams/${teamId}/collections/${collectionId}/items/timings`, {
+    params: { days: options.days, item_path: options.itemPath },
+    paramsSerializer: { indexes: null },
+  });
+  return response.data.items;
+};
+
 export const exportCollection = async (teamId: number, id: number, collectionName: string): Promise<void> => {
   try {
     const response = await api.get(`/teams/${teamId}/collections/${id}/export`, {
@@ -100,6 +232,58 @@ export const exportCollection = async (teamId: number, id: number, collectionNam
   }
 };
 
+export interface ExportAllOptions {
+  order?: 'name' | 'created';
+  includeEnvironments?: boolean;
+  concurrency?: number; // 1 to 8
+}
+
+export const exportAllCollections = async (
+  teamId: number,
+  teamName: string,
+  options: ExportAllOptions = {}
+): Promise<void> => {
+  try {
+    const response = await api.get(`/teams/${teamId}/collections/export-all`, {
+      params: {
+        order: options.order,
+        include_environments: options.includeEnvironments || undefined,
+        concurrency: options.concurrency,
+      },
+      responseType: 'blob',
+    });
+
+    const blob = new Blob([response.data], { type: 'application/zip' });
+    const url = window.URL.createObjectURL(blob);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = `${teamName}.collections.zip`;
+    document.body.appendChild(link);
+    link.click();
+    document.body.removeChild(link);
+    window.URL.revokeObjectURL(url);
+  } catch (error) {
+    console.error('Export failed:', error);
+    throw error;
+  }
+};
+
+// Saves a binary response body (ExecuteResponse.artifact_id) as a file
+export const downloadResponseArtifact = async (artifactId: string): Promise<void> => {
+  const response = await api.get(`/requests/response/${artifactId}/download`, { responseType: 'blob' });
+  const disposition: string = response.headers['content-disposition'] || '';
+  const filename = /filename\*?=(?:UTF-8'')?"?([^";]+)"?/i.exec(disposition)?.[1];
+
+  const url = window.URL.createObjectURL(response.data);
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = filename ? decodeURIComponent(filename) : 'response';
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+  window.URL.revokeObjectURL(url);
+};
+
 // Request execution
 export const executeRequest = async (request: ExecuteRequest): Promise<ExecuteResponse> => {
   // Detect if the target is a localhost / loopback / private-network address.
@@ -141,7 +325,10 @@ export const executeRequest = async (request: ExecuteRequest): Promise<ExecuteRe
         headers: request.headers,
         query_params: request.query_params,
         environment_id: request.environment_id,
+        team_id: request.team_id,
         body_type: request.body_type,
+        body_transfer: request.body_transfer,
+        host: request.host,
       }));
 
       // Add form data items
@@ -163,8 +350,15 @@ export const executeRequest = async (request: ExecuteRequest): Promise<ExecuteRe
       return response.data;
     }
 
-    // For regular requests
-    const response = await api.post('/requests/execute', request);
+    // For regular requests. Text-only form data is sent as typed fields and
+    // the backend builds the multipart body; file rows without a file picked
+    // have nothing to send.
+    const response = await api.post('/requests/execute', {
+      ...request,
+      form_data: request.form_data
+        ?.filter(item => item.type !== 'file')
+        .map(({ key, value, type }) => ({ key, value, type })),
+    });
     return response.data;
   } catch (err: any) {
     // If the backend request itself failed (network error, not HTTP error
@@ -193,8 +387,154 @@ export const updateEnvironment = async (teamId: number, id: number, environment:
   return response.data;
 };
 
-export const deleteEnvironment = async (teamId: number, id: number): Promise<void> => {
-  await api.delete(`/teams/${teamId}/environments/${id}`);
+// Copies the listed variables (all of them without keys) from another team
+// environment; existing ones are only replaced with overwrite
+export const copyEnvironmentVariables = async (
+  teamId: number,
+  id: number,
+  sourceId: number,
+  options: { keys?: string[]; overwrite?: boolean } = {}
+): Promise<Environment> => {
+  const response = await api.post(`/teams/${teamId}/environments/${id}/copy-from`, { source_id: sourceId, ...options });
+  return response.data;
+};
+
+// Fails with 409 ENVIRONMENT_IN_USE while collections still use the
+// environment, unless force is set
+export const deleteEnvironment = async (teamId: number, id: number, force = false): Promise<void> => {
+  await api.delete(`/teams/${teamId}/environments/${id}`, { params: force ? { force: true } : undefined });
+};
+
+// Request templates (team-scoped)
+export interface RequestTemplate {
+  id?: number;
+  team_id?: number;
+  name: string;
+  method: string;
+  url: string;
+  headers: Record<string, string>;
+  query_params: Record<string, string>;
+  body: string;
+}
+
+export const getRequestTemplates = async (teamId: number): Promise<RequestTemplate[]> => {
+  const response = await api.get(`/teams/${teamId}/request-templates`);
+  return response.data;
+};
+
+export const createRequestTemplate = async (teamId: number, template: RequestTemplate): Promise<RequestTemplate> => {
+  const response = await api.post(`/teams/${teamId}/request-templates`, template);
+  return response.data;
+};
+
+export const updateRequestTemplate = async (teamId: number, id: number, template: RequestTemplate): Promise<RequestTemplate> => {
+  const response = await api.put(`/teams/${teamId}/request-templates/${id}`, template);
+  return response.data;
+};
+
+export const deleteRequestTemplate = async (teamId: number, id: number): Promise<void> => {
+  await api.delete(`/teams/${teamId}/request-templates/${id}`);
+};
+
+// applyRequestTemplate returns a new tab prefilled from the template
+export const applyRequestTemplate = async (teamId: number, id: number, tab: { tab_id?: string; name?: string } = {}) => {
+  const response = await api.post(`/teams/${teamId}/request-templates/${id}/apply`, tab);
+  return response.data;
+};
+
+// Workflows (team-scoped): collection requests run in order, with values
+// extracted from one step's response available to the next
+export interface WorkflowExtractor {
+  variable: string;
+  source?: 'body' | 'header' | 'status';
+  path?: string;
+}
+
+export interface WorkflowAssertion {
+  source: 'status' | 'header' | 'body';
+  path?: string;
+  operator?: 'equals' | 'not_equals' | 'contains' | 'exists' | 'not_exists';
+  value?: string;
+}
+
+export interface WorkflowStep {
+  name?: string;
+  collection_id: number;
+  item_path: string[];
+  extract?: WorkflowExtractor[];
+  assertions?: WorkflowAssertion[];
+}
+
+export interface Workflow {
+  id?: number;
+  team_id?: number;
+  name: string;
+  description?: string;
+  steps: WorkflowStep[];
+  environment_id?: number | null;
+  continue_on_failure?: boolean;
+}
+
+export interface WorkflowStepResult {
+  index: number;
+  name: string;
+  passed: boolean;
+  error?: string;
+  response?: ExecuteResponse;
+  assertions: (WorkflowAssertion & { passed: boolean; actual: string; error?: string })[];
+  extracted: Record<string, string>;
+}
+
+export interface WorkflowRunResult {
+  workflow_id: number;
+  execution_id: string;
+  passed: boolean;
+  steps: WorkflowStepResult[];
+  variables: Record<string, string>;
+  // One per data row when the run was given data_rows
+  iterations?: WorkflowIterationResult[];
+  time: number;
+}
+
+export interface WorkflowIterationResult {
+  row: number;
+  passed: boolean;
+  steps: WorkflowStepResult[];
+  variables: Record<string, string>;
+  time: number;
+}
+
+export const getWorkflows = async (teamId: number): Promise<Workflow[]> => {
+  const response = await api.get(`/teams/${teamId}/workflows`);
+  return response.data;
+};
+
+export const createWorkflow = async (teamId: number, workflow: Workflow): Promise<Workflow> => {
+  const response = await api.post(`/teams/${teamId}/workflows`, workflow);
+  return response.data;
+};
+
+export const updateWorkflow = async (teamId: number, id: number, workflow: Workflow): Promise<Workflow> => {
+  const response = await api.put(`/teams/${teamId}/workflows/${id}`, workflow);
+  return response.data;
+};
+
+export const deleteWorkflow = async (teamId: number, id: number): Promise<void> => {
+  await api.delete(`/teams/${teamId}/workflows/${id}`);
+};
+
+export const runWorkflow = async (
+  teamId: number,
+  id: number,
+  options: {
+    environment_id?: number;
+    inline_variables?: Record<string, string>;
+    execution_id?: string;
+    data_rows?: Record<string, string>[];
+  } = {},
+): Promise<WorkflowRunResult> => {
+  const response = await api.post(`/teams/${teamId}/workflows/${id}/run`, options);
+  return response.data;
 };
 
 // API Keys
@@ -205,13 +545,16 @@ export interface APIKey {
   key?: string; // Only returned on creation
   key_prefix: string;
   permissions: string;
+  enabled: boolean; // disabled keys are rejected until re-enabled
   last_used_at: string | null;
   expires_at: string | null;
   created_at: string;
+  days_until_expiry?: number | null; // Negative once expired
+  expiring_soon?: boolean;
 }
 
 export interface CreateAPIKeyRequest {
-  name: string;
+  name: string; // unique per team, ignoring case (409 API_KEY_NAME_TAKEN)
   permissions?: string; // read, write, read_write
   expires_in?: number; // Days until expiration
 }
@@ -226,10 +569,185 @@ export const createAPIKey = async (teamId: number, data: CreateAPIKeyRequest): P
   return response.data;
 };
 
+// Permission new keys get when created without one (read unless the team
+// owner changed it), and whether public API deletes need X-Confirm-Delete: true
+export interface APIKeySettings {
+  default_permission: string;
+  require_delete_confirmation: boolean;
+}
+
+export const getAPIKeySettings = async (teamId: number): Promise<APIKeySettings> => {
+  const response = await api.get(`/teams/${teamId}/api-key-settings`);
+  return response.data;
+};
+
+export const updateAPIKeySettings = async (
+  teamId: number,
+  defaultPermission: string,
+  requireDeleteConfirmation?: boolean
+): Promise<APIKeySettings> => {
+  const response = await api.put(`/teams/${teamId}/api-key-settings`, {
+    default_permission: defaultPermission,
+    require_delete_confirmation: requireDeleteConfirmation,
+  });
+  return response.data;
+};
+
+export const setAPIKeyEnabled = async (teamId: number, keyId: number, enabled: boolean): Promise<APIKey> => {
+  const response = await api.patch(`/teams/${teamId}/api-keys/${keyId}`, { enabled });
+  return response.data;
+};
+
 export const deleteAPIKey = async (teamId: number, keyId: number): Promise<void> => {
   await api.delete(`/teams/${teamId}/api-keys/${keyId}`);
 };
 
+// OAuth2 client-credentials tokens, fetched by the backend and cached per environment
+export const fetchOAuth2Token = async (
+  config: OAuth2Config & { environment_id?: number; force_refresh?: boolean }
+): Promise<OAuth2Token> => {
+  const response = await api.post('/requests/oauth2/token', config);
+  return response.data;
+};
+
+// Fetches a GraphQL endpoint's schema through the backend's introspection query
+export const introspectGraphQL = async (request: {
+  url: string;
+  headers?: Record<string, string>;
+  environment_id?: number;
+  team_id?: number;
+  inline_variables?: Record<string, string>;
+  auth?: ServerAuth;
+}): Promise<GraphQLSchema> => {
+  const response = await api.post('/requests/graphql/introspect', request);
+  return response.data;
+};
+
+// Request history
+export interface RequestHistoryEntry {
+  id: number;
+  user_id: number;
+  team_id: number | null;
+  method: string;
+  url: string;
+  status: number;
+  time: number;
+  error?: string;
+  created_at: string;
+  // Sensitive values (REDACTED_HEADERS) are stored as ***
+  request_headers?: Record<string, string>;
+  response_headers?: Record<string, string>;
+}
+
+export interface RequestHistoryFilter {
+  method?: string[];
+  status_min?: number;
+  status_max?: number;
+  url?: string;
+  from?: string;
+  to?: string;
+  cursor?: number;
+  limit?: number;
+}
+
+export interface RequestHistoryPage {
+  entries: RequestHistoryEntry[];
+  total: number;
+  next_cursor: number | null;
+}
+
+export const getRequestHistory = async (filter: RequestHistoryFilter = {}): Promise<RequestHistoryPage> => {
+  const params = { ...filter, method: filter.method?.join(',') || undefined };
+  const response = await api.get('/requests/history', { params });
+  return response.data;
+};
+
+export const clearRequestHistory = async (): Promise<number> => {
+  const response = await api.post('/requests/history/clear');
+  return response.data.deleted;
+};
+
+// Host policy
+export interface HostPolicy {
+  team_id: number;
+  allow: string[];
+  deny: string[];
+  updated_at?: string;
+}
+
+export const getHostPolicy = async (teamId: number): Promise<HostPolicy> => {
+  const response = await api.get(`/teams/${teamId}/host-policy`);
+  return response.data;
+};
+
+export const updateHostPolicy = async (teamId: number, policy: { allow: string[]; deny: string[] }): Promise<HostPolicy> => {
+  const response = await api.put(`/teams/${teamId}/host-policy`, policy);
+  return response.data;
+};
+
+export const deleteHostPolicy = async (teamId: number): Promise<void> => {
+  await api.delete(`/teams/${teamId}/host-policy`);
+};
+
+// Team default headers
+export interface RequestDefaults {
+  team_id: number;
+  headers: Record<string, string>;
+  updated_at?: string;
+}
+
+export const getRequestDefaults = async (teamId: number): Promise<RequestDefaults> => {
+  const response = await api.get(`/teams/${teamId}/request-defaults`);
+  return response.data;
+};
+
+export const updateRequestDefaults = async (teamId: number, headers: Record<string, string>): Promise<RequestDefaults> => {
+  const response = await api.put(`/teams/${teamId}/request-defaults`, { headers });
+  return response.data;
+};
+
+export const deleteRequestDefaults = async (teamId: number): Promise<void> => {
+  await api.delete(`/teams/${teamId}/request-defaults`);
+};
+
+// Webhooks
+export type WebhookEvent = 'collection.updated' | 'member.joined' | 'api_key.created';
+
+export interface Webhook {
+  id: number;
+  team_id: number;
+  url: string;
+  secret?: string; // Only returned on creation
+  events: WebhookEvent[];
+  is_enabled: boolean;
+  created_at: string;
+}
+
+export interface WebhookRequest {
+  url: string;
+  events: WebhookEvent[];
+  is_enabled?: boolean;
+}
+
+export const getWebhooks = async (teamId: number): Promise<Webhook[]> => {
+  const response = await api.get(`/teams/${teamId}/webhooks`);
+  return response.data;
+};
+
+export const createWebhook = async (teamId: number, data: WebhookRequest): Promise<Webhook> => {
+  const response = await api.post(`/teams/${teamId}/webhooks`, data);
+  return response.data;
+};
+
+export const updateWebhook = async (teamId: number, id: number, data: WebhookRequest): Promise<Webhook> => {
+  const response = await api.put(`/teams/${teamId}/webhooks/${id}`, data);
+  return response.data;
+};
+
+export const deleteWebhook = async (teamId: number, id: number): Promise<void> => {
+  await api.delete(`/teams/${teamId}/webhooks/${id}`);
+};
+
 // Saved tabs APIs
 export interface SavedTab {
   tab_id: string;
@@ -240,6 +758,7 @@ export interface SavedTab {
   body: string;
   query_params: Record<string, string>;
   is_active: boolean;
+  pinned?: boolean;
   sort_order: number;
 }
 
@@ -252,4 +771,54 @@ export const saveTabs = async (tabs: SavedTab[], activeTabId: string): Promise<v
   await api.post('/tabs', { tabs, active_tab_id: activeTabId });
 };
 
+export const setTabPinned = async (tabId: string, pinned: boolean): Promise<void> => {
+  if (pinned) {
+    await api.put(`/tabs/${encodeURIComponent(tabId)}/pin`);
+  } else {
+    await api.delete(`/tabs/${encodeURIComponent(tabId)}/pin`);
+  }
+};
+
 export default api;
+
+// Admin
+export const sendTestEmail = async (): Promise<{ message: string; to: string }> => {
+  const response = await api.post('/admin/test-email');
+  return response.data;
+};
+
+// Rows corrected by a reindex, per denormalized field
+export interface ReindexResult {
+  collection_request_counts: number;
+  collection_updated_at: number;
+  total: number;
+}
+
+export const reindex = async (): Promise<ReindexResult> => {
+  const response = await api.post('/admin/reindex');
+  return response.data;
+};
+
+// Which team owns a collection or environment (support debugging; no content)
+export interface ResourceOwner {
+  type: 'collection' | 'environment';
+  id: number;
+  name: string;
+  created_at: string;
+  deleted_at?: string;
+  request_count?: number;
+  variable_count?: number;
+  team: {
+    id: number;
+    name: string;
+    is_personal: boolean;
+    organization_id?: number;
+    member_count: number;
+    deleted_at?: string;
+  } | null;
+}
+
+export const getResourceOwner = async (type: ResourceOwner['type'], id: number): Promise<ResourceOwner> => {
+  const response = await api.get('/admin/resource-owner', { params: { type, id } });
+  return response.data;
+};
@@ -1,4 +1,5 @@
 import type { AuthResponse, LoginRequest, RegisterRequest, User } from '../types';
+import { getErrorMessage } from '../utils/apiError';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
 
@@ -11,7 +12,7 @@ export const login = async (credentials: LoginRequest): Promise<AuthResponse> =>
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Login failed');
+    throw new Error(getErrorMessage(error, 'Login failed'));
   }
 
   return response.json();
@@ -26,7 +27,7 @@ export const register = async (data: RegisterRequest): Promise<AuthResponse> =>
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Registration failed');
+    throw new Error(getErrorMessage(error, 'Registration failed'));
   }
 
   return response.json();
@@ -109,7 +110,7 @@ export const getGoogleAuthUrl = async (): Promise<string> => {
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Google OAuth not available');
+    throw new Error(getErrorMessage(error, 'Google OAuth not available'));
   }
 
   const data = await response.json();
@@ -1,4 +1,5 @@
-import type { Team, TeamMember, TeamInvite } from '../types';
+import type { Team, TeamMember, TeamInvite, InviteStatusFilter, Organization, OrganizationMember } from '../types';
+import { getErrorMessage } from '../utils/apiError';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
 
@@ -31,7 +32,7 @@ export const createTeam = async (name: string): Promise<Team> => {
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Failed to create team');
+    throw new Error(getErrorMessage(error, 'Failed to create team'));
   }
 
   return response.json();
@@ -117,14 +118,22 @@ export const createInvite = async (teamId: number, email: string): Promise<TeamI
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Failed to create invite');
+    throw new Error(getErrorMessage(error, 'Failed to create invite'));
   }
 
   return response.json();
 };
 
-export const getTeamInvites = async (teamId: number): Promise<TeamInvite[]> => {
-  const response = await fetch(`${API_BASE_URL}/teams/${teamId}/invites`, {
+export const getTeamInvites = async (
+  teamId: number,
+  options: { status?: InviteStatusFilter; cursor?: number; limit?: number } = {}
+): Promise<TeamInvite[]> => {
+  const params = new URLSearchParams();
+  if (options.status) params.set('status', options.status);
+  if (options.cursor) params.set('cursor', String(options.cursor));
+  if (options.limit) params.set('limit', String(options.limit));
+  const query = params.toString();
+  const response = await fetch(`${API_BASE_URL}/teams/${teamId}/invites${query ? `?${query}` : ''}`, {
     headers: getAuthHeaders(),
   });
 
@@ -155,7 +164,7 @@ export const acceptInvite = async (token: string): Promise<{ message: string; te
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || 'Failed to accept invite');
+    throw new Error(getErrorMessage(error, 'Failed to accept invite'));
   }
 
   return response.json();
@@ -171,3 +180,100 @@ export const declineInvite = async (token: string): Promise<void> => {
     throw new Error('Failed to decline invite');
   }
 };
+
+export const getOrganizations = async (): Promise<Organization[]> => {
+  const response = await fetch(`${API_BASE_URL}/organizations`, {
+    headers: getAuthHeaders(),
+  });
+
+  if (!response.ok) {
+    throw new Error('Failed to get organizations');
+  }
+
+  return response.json();
+};
+
+export const createOrganization = async (name: string): Promise<Organization> => {
+  const response = await fetch(`${API_BASE_URL}/organizations`, {
+    method: 'POST',
+    headers: getAuthHeaders(),
+    body: JSON.stringify({ name }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(getErrorMessage(error, 'Failed to create organization'));
+  }
+
+  return response.json();
+};
+
+export const getOrganizationTeams = async (orgId: number): Promise<Team[]> => {
+  const response = await fetch(`${API_BASE_URL}/organizations/${orgId}/teams`, {
+    headers: getAuthHeaders(),
+  });
+
+  if (!response.ok) {
+    throw new Error('Failed to get organization teams');
+  }
+
+  return response.json();
+};
+
+export const addOrganizationTeam = async (orgId: number, teamId: number): Promise<Team> => {
+  const response = await fetch(`${API_BASE_URL}/organizations/${orgId}/teams`, {
+    method: 'POST',
+    headers: getAuthHeaders(),
+    body: JSON.stringify({ team_id: teamId }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(getErrorMessage(error, 'Failed to add team'));
+  }
+
+  return response.json();
+};
+
+export const removeOrganizationTeam = async (orgId: number, teamId: number): Promise<void> => {
+  const response = await fetch(`${API_BASE_URL}/organizations/${orgId}/teams/${teamId}`, {
+    method: 'DELETE',
+    headers: getAuthHeaders(),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(getErrorMessage(error, 'Failed to remove team'));
+  }
+};
+
+export const addOrganizationMember = async (
+  orgId: number,
+  email: string,
+  role: 'admin' | 'member' = 'member'
+): Promise<OrganizationMember> => {
+  const response = await fetch(`${API_BASE_URL}/organizations/${orgId}/members`, {
+    method: 'POST',
+    headers: getAuthHeaders(),
+    body: JSON.stringify({ email, role }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(getErrorMessage(error, 'Failed to add member'));
+  }
+
+  return response.json();
+};
+
+export const removeOrganizationMember = async (orgId: number, userId: number): Promise<void> => {
+  const response = await fetch(`${API_BASE_URL}/organizations/${orgId}/members/${userId}`, {
+    method: 'DELETE',
+    headers: getAuthHeaders(),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(getErrorMessage(error, 'Failed to remove member'));
+  }
+};
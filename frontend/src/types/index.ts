@@ -3,6 +3,7 @@ export interface User {
     id: number;
     email: string;
     name: string;
+    last_active_at?: string | null;
     created_at: string;
 }
 
@@ -11,6 +12,9 @@ export interface AuthResponse {
     refresh_token: string;
     expires_in: number;
     user: User;
+    // Filled in by login and register only
+    teams?: Team[];
+    pending_invite_count?: number;
 }
 
 export interface LoginRequest {
@@ -28,6 +32,8 @@ export interface RegisterRequest {
 export interface Team {
     id: number;
     name: string;
+    is_personal?: boolean;
+    organization_id?: number;
     created_at: string;
     members?: TeamMember[];
 }
@@ -45,23 +51,47 @@ export interface TeamInvite {
     id: number;
     team_id: number;
     invitee_email: string;
-    status: 'pending' | 'accepted' | 'declined';
+    status: 'pending' | 'accepted' | 'declined' | 'cancelled';
     expires_at: string;
     created_at: string;
     team?: Team;
     inviter?: User;
+    invitee_user_id?: number; // when the invited email has an account
     token?: string;
 }
 
+export type InviteStatusFilter = 'pending' | 'accepted' | 'declined' | 'cancelled' | 'expired' | 'all';
+
+// Organizations group teams; org admins see every team in them
+export interface Organization {
+    id: number;
+    name: string;
+    created_at: string;
+    your_role?: 'admin' | 'member';
+    team_count?: number;
+}
+
+export interface OrganizationMember {
+    id: number;
+    organization_id: number;
+    user_id: number;
+    role: 'admin' | 'member';
+    joined_at: string;
+    user?: User;
+}
+
 // Collection types
 export interface Collection {
     id: number;
     name: string;
     description: string;
     raw_json?: string;
+    request_count?: number;
     environment_id?: number | null;
     team_id?: number;
+    pinned?: boolean;
     created_at: string;
+    updated_at?: string;
 }
 
 export interface PostmanCollection {
@@ -275,10 +305,92 @@ export interface ExecuteRequest {
     url: string;
     headers: Record<string, string>;
     body: string;
+    body_encoding?: 'text' | 'base64'; // base64 for binary bodies (e.g. protobuf)
     body_type?: BodyType;
     form_data?: FormDataItem[];
     query_params: Record<string, string>;
     environment_id?: number;
+    team_id?: number; // the team whose host policy applies
+    auth?: ServerAuth;
+    // Adds the server certificate chain and TLS parameters to HTTPS responses
+    include_tls_info?: boolean;
+    preview_bytes?: number;
+    // Read the body as newline-delimited JSON into ExecuteResponse.events
+    ndjson?: { max_lines?: number; timeout_seconds?: number };
+    // Cache a GET/HEAD response for this many ms (at most an hour)
+    cache_ttl_ms?: number;
+    // Reject a JSON body that doesn't parse (400 INVALID_JSON_BODY with its
+    // line and column) instead of sending it; formatting implies the check
+    validate_json_body?: boolean;
+    json_body_format?: 'minify' | 'pretty';
+    // Force Content-Length (buffering a streamed upload) or chunked framing
+    body_transfer?: 'content-length' | 'chunked';
+    // Host the server sees, overriding the URL's (a Host header does the same)
+    host?: string;
+}
+
+// Auth the backend performs itself (schemes that can't be sent as a header).
+// Not supported for multipart requests.
+export interface ServerAuth {
+    type: 'ntlm' | 'oauth2';
+    ntlm?: {
+        username: string;
+        password: string;
+        domain?: string;
+        workstation?: string;
+    };
+    oauth2?: OAuth2Config;
+}
+
+export interface OAuth2Config {
+    token_url: string;
+    client_id: string;
+    client_secret: string;
+    scopes?: string[];
+    grant_type?: 'client_credentials';
+    client_auth?: 'body' | 'header';
+}
+
+export interface OAuth2Token {
+    access_token: string;
+    token_type: string;
+    expires_in?: number;
+    expires_at?: string;
+    scope?: string;
+    cached: boolean;
+}
+
+// Simplified GraphQL introspection result; types are written as in SDL
+export interface GraphQLInput {
+    name: string;
+    description?: string;
+    type: string; // e.g. "[String!]!"
+    default_value?: string;
+}
+
+export interface GraphQLField {
+    name: string;
+    description?: string;
+    type: string;
+    args?: GraphQLInput[];
+    deprecated?: boolean;
+}
+
+export interface GraphQLType {
+    name: string;
+    kind: 'OBJECT' | 'INTERFACE' | 'UNION' | 'ENUM' | 'INPUT_OBJECT' | 'SCALAR';
+    description?: string;
+    fields?: GraphQLField[];
+    input_fields?: GraphQLInput[];
+    enum_values?: string[];
+    possible_types?: string[];
+}
+
+export interface GraphQLSchema {
+    query_type?: string;
+    mutation_type?: string;
+    subscription_type?: string;
+    types: GraphQLType[];
 }
 
 export interface ExecuteResponse {
@@ -287,6 +399,37 @@ export interface ExecuteResponse {
     headers: Record<string, string>;
     body: string;
     time: number;
+    tls?: TLSInfo;
+    truncated?: boolean;
+    content_length?: number;
+    charset?: string;
+    events?: unknown[]; // parsed NDJSON lines
+    from_cache?: boolean; // answered from the cache instead of fetched
+    // Binary bodies are left out of body; download them with
+    // downloadResponseArtifact until artifact_expires_at
+    artifact_id?: string;
+    artifact_expires_at?: string;
+}
+
+export interface TLSCertificate {
+    subject: string;
+    issuer: string;
+    dns_names?: string[];
+    ip_addresses?: string[];
+    serial_number: string;
+    not_before: string;
+    not_after: string;
+    is_ca: boolean;
+    sha256_fingerprint: string;
+}
+
+export interface TLSInfo {
+    version: string;
+    cipher_suite: string;
+    negotiated_protocol?: string;
+    server_name?: string;
+    verified: boolean;
+    certificates: TLSCertificate[];
 }
 
 // Request info that was sent (for display in response viewer like Swagger)
@@ -0,0 +1,26 @@
+// Error bodies from the backend look like
+// { error: { code: "TEAM_NOT_FOUND", message: "Team not found", details?: {...} } }
+export interface ApiError {
+  code: string;
+  message: string;
+  details?: Record<string, any>;
+}
+
+export const getApiError = (data: any): ApiError | undefined => {
+  const error = data?.error;
+  if (!error) return undefined;
+  // Older responses used a plain string
+  if (typeof error === 'string') return { code: '', message: error };
+  return error as ApiError;
+};
+
+// Validation failures carry a { field: problem } map in details, which reads
+// better than the generic "Validation failed"
+export const getErrorMessage = (data: any, fallback: string): string => {
+  const apiError = getApiError(data);
+  if (apiError?.code === 'INVALID_REQUEST' && apiError.message === 'Validation failed' && apiError.details) {
+    const problems = Object.entries(apiError.details).map(([field, problem]) => `${field} ${problem}`);
+    if (problems.length > 0) return problems.join('; ');
+  }
+  return apiError?.message || fallback;
+};